@@ -32,7 +32,7 @@ func (h *AIConfigHandler) CreateConfig(c *gin.Context) {
 
 	config, err := h.aiService.CreateConfig(&req)
 	if err != nil {
-		response.InternalError(c, "创建失败")
+		respondServiceError(c, err, "创建失败")
 		return
 	}
 
@@ -49,11 +49,7 @@ func (h *AIConfigHandler) GetConfig(c *gin.Context) {
 
 	config, err := h.aiService.GetConfig(uint(configID))
 	if err != nil {
-		if err.Error() == "config not found" {
-			response.NotFound(c, "配置不存在")
-			return
-		}
-		response.InternalError(c, "获取失败")
+		respondServiceError(c, err, "获取失败")
 		return
 	}
 
@@ -89,11 +85,7 @@ func (h *AIConfigHandler) UpdateConfig(c *gin.Context) {
 
 	config, err := h.aiService.UpdateConfig(uint(configID), &req)
 	if err != nil {
-		if err.Error() == "config not found" {
-			response.NotFound(c, "配置不存在")
-			return
-		}
-		response.InternalError(c, "更新失败")
+		respondServiceError(c, err, "更新失败")
 		return
 	}
 
@@ -109,11 +101,7 @@ func (h *AIConfigHandler) DeleteConfig(c *gin.Context) {
 	}
 
 	if err := h.aiService.DeleteConfig(uint(configID)); err != nil {
-		if err.Error() == "config not found" {
-			response.NotFound(c, "配置不存在")
-			return
-		}
-		response.InternalError(c, "删除失败")
+		respondServiceError(c, err, "删除失败")
 		return
 	}
 
@@ -36,7 +36,7 @@ func (h *AssetHandler) CreateAsset(c *gin.Context) {
 	asset, err := h.assetService.CreateAsset(&req)
 	if err != nil {
 		h.log.Errorw("Failed to create asset", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -60,7 +60,7 @@ func (h *AssetHandler) UpdateAsset(c *gin.Context) {
 	asset, err := h.assetService.UpdateAsset(uint(assetID), &req)
 	if err != nil {
 		h.log.Errorw("Failed to update asset", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -159,7 +159,7 @@ func (h *AssetHandler) ListAssets(c *gin.Context) {
 	assets, total, err := h.assetService.ListAssets(req)
 	if err != nil {
 		h.log.Errorw("Failed to list assets", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -176,7 +176,7 @@ func (h *AssetHandler) DeleteAsset(c *gin.Context) {
 
 	if err := h.assetService.DeleteAsset(uint(assetID)); err != nil {
 		h.log.Errorw("Failed to delete asset", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -194,7 +194,7 @@ func (h *AssetHandler) ImportFromImageGen(c *gin.Context) {
 	asset, err := h.assetService.ImportFromImageGen(uint(imageGenID))
 	if err != nil {
 		h.log.Errorw("Failed to import from image gen", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -212,7 +212,7 @@ func (h *AssetHandler) ImportFromVideoGen(c *gin.Context) {
 	asset, err := h.assetService.ImportFromVideoGen(uint(videoGenID))
 	if err != nil {
 		h.log.Errorw("Failed to import from video gen", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
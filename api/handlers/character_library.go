@@ -78,12 +78,8 @@ func (h *CharacterLibraryHandler) GetLibraryItem(c *gin.Context) {
 
 	item, err := h.libraryService.GetLibraryItem(itemID)
 	if err != nil {
-		if err.Error() == "library item not found" {
-			response.NotFound(c, "角色库项不存在")
-			return
-		}
 		h.log.Errorw("Failed to get library item", "error", err)
-		response.InternalError(c, "获取失败")
+		respondServiceError(c, err, "获取失败")
 		return
 	}
 
@@ -96,12 +92,8 @@ func (h *CharacterLibraryHandler) DeleteLibraryItem(c *gin.Context) {
 	itemID := c.Param("id")
 
 	if err := h.libraryService.DeleteLibraryItem(itemID); err != nil {
-		if err.Error() == "library item not found" {
-			response.NotFound(c, "角色库项不存在")
-			return
-		}
 		h.log.Errorw("Failed to delete library item", "error", err)
-		response.InternalError(c, "删除失败")
+		respondServiceError(c, err, "删除失败")
 		return
 	}
 
@@ -126,16 +118,8 @@ func (h *CharacterLibraryHandler) UploadCharacterImage(c *gin.Context) {
 	}
 
 	if err := h.libraryService.UploadCharacterImage(characterID, req.ImageURL); err != nil {
-		if err.Error() == "character not found" {
-			response.NotFound(c, "角色不存在")
-			return
-		}
-		if err.Error() == "unauthorized" {
-			response.Forbidden(c, "无权限")
-			return
-		}
 		h.log.Errorw("Failed to upload character image", "error", err)
-		response.InternalError(c, "上传失败")
+		respondServiceError(c, err, "上传失败")
 		return
 	}
 
@@ -157,20 +141,8 @@ func (h *CharacterLibraryHandler) ApplyLibraryItemToCharacter(c *gin.Context) {
 	}
 
 	if err := h.libraryService.ApplyLibraryItemToCharacter(characterID, req.LibraryItemID); err != nil {
-		if err.Error() == "library item not found" {
-			response.NotFound(c, "角色库项不存在")
-			return
-		}
-		if err.Error() == "character not found" {
-			response.NotFound(c, "角色不存在")
-			return
-		}
-		if err.Error() == "unauthorized" {
-			response.Forbidden(c, "无权限")
-			return
-		}
 		h.log.Errorw("Failed to apply library item", "error", err)
-		response.InternalError(c, "应用失败")
+		respondServiceError(c, err, "应用失败")
 		return
 	}
 
@@ -193,20 +165,8 @@ func (h *CharacterLibraryHandler) AddCharacterToLibrary(c *gin.Context) {
 
 	item, err := h.libraryService.AddCharacterToLibrary(characterID, req.Category)
 	if err != nil {
-		if err.Error() == "character not found" {
-			response.NotFound(c, "角色不存在")
-			return
-		}
-		if err.Error() == "unauthorized" {
-			response.Forbidden(c, "无权限")
-			return
-		}
-		if err.Error() == "character has no image" {
-			response.BadRequest(c, "角色还没有形象图片")
-			return
-		}
 		h.log.Errorw("Failed to add character to library", "error", err)
-		response.InternalError(c, "添加失败")
+		respondServiceError(c, err, "添加失败")
 		return
 	}
 
@@ -225,16 +185,8 @@ func (h *CharacterLibraryHandler) UpdateCharacter(c *gin.Context) {
 	}
 
 	if err := h.libraryService.UpdateCharacter(characterID, &req); err != nil {
-		if err.Error() == "character not found" {
-			response.NotFound(c, "角色不存在")
-			return
-		}
-		if err.Error() == "unauthorized" {
-			response.Forbidden(c, "无权限")
-			return
-		}
 		h.log.Errorw("Failed to update character", "error", err)
-		response.InternalError(c, "更新失败")
+		respondServiceError(c, err, "更新失败")
 		return
 	}
 
@@ -253,15 +205,7 @@ func (h *CharacterLibraryHandler) DeleteCharacter(c *gin.Context) {
 
 	if err := h.libraryService.DeleteCharacter(uint(characterID)); err != nil {
 		h.log.Errorw("Failed to delete character", "error", err, "id", characterID)
-		if err.Error() == "character not found" {
-			response.NotFound(c, "角色不存在")
-			return
-		}
-		if err.Error() == "unauthorized" {
-			response.Forbidden(c, "无权删除此角色")
-			return
-		}
-		response.InternalError(c, "删除失败")
+		respondServiceError(c, err, "删除失败")
 		return
 	}
 
@@ -280,7 +224,7 @@ func (h *CharacterLibraryHandler) ExtractCharacters(c *gin.Context) {
 	taskID, err := h.libraryService.ExtractCharactersFromScript(uint(episodeID))
 	if err != nil {
 		h.log.Errorw("Failed to extract characters", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"github.com/drama-generator/backend/application/services"
 	"github.com/drama-generator/backend/pkg/response"
 	"github.com/gin-gonic/gin"
 )
@@ -19,16 +20,8 @@ func (h *CharacterLibraryHandler) GenerateCharacterImage(c *gin.Context) {
 
 	imageGen, err := h.libraryService.GenerateCharacterImage(characterID, h.imageService, req.Model, req.Style)
 	if err != nil {
-		if err.Error() == "character not found" {
-			response.NotFound(c, "角色不存在")
-			return
-		}
-		if err.Error() == "unauthorized" {
-			response.Forbidden(c, "无权限")
-			return
-		}
 		h.log.Errorw("Failed to generate character image", "error", err)
-		response.InternalError(c, "生成失败")
+		respondServiceError(c, err, "生成失败")
 		return
 	}
 
@@ -37,3 +30,28 @@ func (h *CharacterLibraryHandler) GenerateCharacterImage(c *gin.Context) {
 		"image_generation": imageGen,
 	})
 }
+
+// RegenerateCharacterPortrait 使用当前形象图的生成参数复现角色形象，可覆盖提示词、种子或模型
+func (h *CharacterLibraryHandler) RegenerateCharacterPortrait(c *gin.Context) {
+	characterID := c.Param("id")
+
+	var req services.RegenerateCharacterPortraitRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+	}
+
+	imageGen, err := h.libraryService.RegenerateCharacterPortrait(characterID, h.imageService, &req)
+	if err != nil {
+		h.log.Errorw("Failed to regenerate character portrait", "error", err, "character_id", characterID)
+		respondServiceError(c, err, "重新生成失败")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"message":          "角色形象重新生成已启动",
+		"image_generation": imageGen,
+	})
+}
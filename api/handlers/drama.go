@@ -51,11 +51,7 @@ func (h *DramaHandler) GetDrama(c *gin.Context) {
 
 	drama, err := h.dramaService.GetDrama(dramaID)
 	if err != nil {
-		if err.Error() == "drama not found" {
-			response.NotFound(c, "剧本不存在")
-			return
-		}
-		response.InternalError(c, "获取失败")
+		respondServiceError(c, err, "获取失败")
 		return
 	}
 
@@ -98,11 +94,7 @@ func (h *DramaHandler) UpdateDrama(c *gin.Context) {
 
 	drama, err := h.dramaService.UpdateDrama(dramaID, &req)
 	if err != nil {
-		if err.Error() == "drama not found" {
-			response.NotFound(c, "剧本不存在")
-			return
-		}
-		response.InternalError(c, "更新失败")
+		respondServiceError(c, err, "更新失败")
 		return
 	}
 
@@ -114,15 +106,33 @@ func (h *DramaHandler) DeleteDrama(c *gin.Context) {
 	dramaID := c.Param("id")
 
 	if err := h.dramaService.DeleteDrama(dramaID); err != nil {
-		if err.Error() == "drama not found" {
-			response.NotFound(c, "剧本不存在")
+		respondServiceError(c, err, "删除失败")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "删除成功"})
+}
+
+// CloneDrama 以已有剧本为模板克隆新剧本，请求体可省略
+func (h *DramaHandler) CloneDrama(c *gin.Context) {
+
+	dramaID := c.Param("id")
+
+	var opts services.CloneOptions
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&opts); err != nil {
+			response.BadRequest(c, err.Error())
 			return
 		}
-		response.InternalError(c, "删除失败")
+	}
+
+	drama, err := h.dramaService.CloneDrama(dramaID, opts)
+	if err != nil {
+		respondServiceError(c, err, "克隆失败")
 		return
 	}
 
-	response.Success(c, gin.H{"message": "删除成功"})
+	response.Created(c, drama)
 }
 
 func (h *DramaHandler) GetDramaStats(c *gin.Context) {
@@ -147,11 +157,7 @@ func (h *DramaHandler) SaveOutline(c *gin.Context) {
 	}
 
 	if err := h.dramaService.SaveOutline(dramaID, &req); err != nil {
-		if err.Error() == "drama not found" {
-			response.NotFound(c, "剧本不存在")
-			return
-		}
-		response.InternalError(c, "保存失败")
+		respondServiceError(c, err, "保存失败")
 		return
 	}
 
@@ -170,15 +176,7 @@ func (h *DramaHandler) GetCharacters(c *gin.Context) {
 
 	characters, err := h.dramaService.GetCharacters(dramaID, episodeIDPtr)
 	if err != nil {
-		if err.Error() == "drama not found" {
-			response.NotFound(c, "剧本不存在")
-			return
-		}
-		if err.Error() == "episode not found" {
-			response.NotFound(c, "章节不存在")
-			return
-		}
-		response.InternalError(c, "获取角色失败")
+		respondServiceError(c, err, "获取角色失败")
 		return
 	}
 
@@ -234,11 +232,7 @@ func (h *DramaHandler) SaveCharacters(c *gin.Context) {
 	}
 
 	if err := h.dramaService.SaveCharacters(dramaID, &req); err != nil {
-		if err.Error() == "drama not found" {
-			response.NotFound(c, "剧本不存在")
-			return
-		}
-		response.InternalError(c, "保存失败")
+		respondServiceError(c, err, "保存失败")
 		return
 	}
 
@@ -255,16 +249,62 @@ func (h *DramaHandler) SaveEpisodes(c *gin.Context) {
 		return
 	}
 
-	if err := h.dramaService.SaveEpisodes(dramaID, &req); err != nil {
-		if err.Error() == "drama not found" {
-			response.NotFound(c, "剧本不存在")
-			return
-		}
-		response.InternalError(c, "保存失败")
+	result, err := h.dramaService.SaveEpisodes(dramaID, &req)
+	if err != nil {
+		respondServiceError(c, err, "保存失败")
 		return
 	}
 
-	response.Success(c, gin.H{"message": "保存成功"})
+	response.Success(c, gin.H{
+		"message":    "保存成功",
+		"renumbered": result.Renumbered,
+		"gaps":       result.Gaps,
+	})
+}
+
+// RenumberEpisodes 修正剧集的重复和缺失集数，按当前顺序重新编号为 1..n
+func (h *DramaHandler) RenumberEpisodes(c *gin.Context) {
+	dramaID := c.Param("id")
+
+	changes, err := h.dramaService.RenumberEpisodes(dramaID)
+	if err != nil {
+		respondServiceError(c, err, "重新编号失败")
+		return
+	}
+
+	response.Success(c, gin.H{"renumbered": changes})
+}
+
+// ListEpisodes 获取剧本的剧集列表，可通过 tag 参数按标签筛选
+func (h *DramaHandler) ListEpisodes(c *gin.Context) {
+	dramaID := c.Param("id")
+
+	episodes, err := h.dramaService.ListEpisodes(dramaID, c.Query("tag"))
+	if err != nil {
+		respondServiceError(c, err, "获取失败")
+		return
+	}
+
+	response.Success(c, episodes)
+}
+
+// UpdateEpisode 更新剧集标题、简介和标签
+func (h *DramaHandler) UpdateEpisode(c *gin.Context) {
+	episodeID := c.Param("episode_id")
+
+	var req services.UpdateEpisodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	episode, err := h.dramaService.UpdateEpisode(episodeID, &req)
+	if err != nil {
+		respondServiceError(c, err, "更新失败")
+		return
+	}
+
+	response.Success(c, episode)
 }
 
 func (h *DramaHandler) SaveProgress(c *gin.Context) {
@@ -278,11 +318,7 @@ func (h *DramaHandler) SaveProgress(c *gin.Context) {
 	}
 
 	if err := h.dramaService.SaveProgress(dramaID, &req); err != nil {
-		if err.Error() == "drama not found" {
-			response.NotFound(c, "剧本不存在")
-			return
-		}
-		response.InternalError(c, "保存失败")
+		respondServiceError(c, err, "保存失败")
 		return
 	}
 
@@ -312,7 +348,7 @@ func (h *DramaHandler) FinalizeEpisode(c *gin.Context) {
 	result, err := h.videoMergeService.FinalizeEpisode(episodeID, timelineData)
 	if err != nil {
 		h.log.Errorw("Failed to finalize episode", "error", err, "episode_id", episodeID)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"github.com/drama-generator/backend/application/services"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// EpisodePipelineHandler 处理剧集流水线请求
+type EpisodePipelineHandler struct {
+	pipelineService *services.EpisodePipelineService
+	log             *logger.Logger
+}
+
+// NewEpisodePipelineHandler 创建剧集流水线处理器
+func NewEpisodePipelineHandler(pipelineService *services.EpisodePipelineService, log *logger.Logger) *EpisodePipelineHandler {
+	return &EpisodePipelineHandler{
+		pipelineService: pipelineService,
+		log:             log,
+	}
+}
+
+// ResumeEpisodePipeline 从第一个未完成的阶段继续执行剧集流水线（异步）
+// POST /api/v1/episodes/:episode_id/pipeline/resume
+func (h *EpisodePipelineHandler) ResumeEpisodePipeline(c *gin.Context) {
+	episodeID := c.Param("episode_id")
+
+	taskID, stage, err := h.pipelineService.ResumeEpisodePipeline(episodeID)
+	if err != nil {
+		h.log.Errorw("Failed to resume episode pipeline", "error", err, "episode_id", episodeID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"task_id":    taskID,
+		"status":     "pending",
+		"from_stage": stage,
+		"message":    "流水线已从阶段 " + stage + " 继续执行",
+	})
+}
+
+// EstimateEpisodePipeline 预估剧集流水线剩余阶段的费用和耗时，不执行任何生成
+// GET /api/v1/episodes/:episode_id/pipeline/estimate
+func (h *EpisodePipelineHandler) EstimateEpisodePipeline(c *gin.Context) {
+	episodeID := c.Param("episode_id")
+
+	estimate, err := h.pipelineService.EstimateEpisodePipeline(episodeID)
+	if err != nil {
+		h.log.Errorw("Failed to estimate episode pipeline", "error", err, "episode_id", episodeID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, estimate)
+}
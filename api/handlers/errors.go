@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/drama-generator/backend/application/services"
+	"github.com/drama-generator/backend/pkg/ai"
+	"github.com/drama-generator/backend/pkg/image"
+	"github.com/drama-generator/backend/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// serviceErrorMessages 具体服务层错误对应的提示信息
+var serviceErrorMessages = map[error]string{
+	services.ErrDramaNotFound:           "剧本不存在",
+	services.ErrEpisodeNotFound:         "剧集不存在",
+	services.ErrStoryboardNotFound:      "分镜不存在",
+	services.ErrSceneNotFound:           "场景不存在",
+	services.ErrCharacterNotFound:       "角色不存在",
+	services.ErrLibraryItemNotFound:     "角色库项不存在",
+	services.ErrConfigNotFound:          "配置不存在",
+	services.ErrImageGenerationNotFound: "图片生成记录不存在",
+	services.ErrAssetNotFound:           "素材不存在",
+	services.ErrUnauthorized:            "无权限",
+	services.ErrCharacterHasNoImage:     "角色还没有形象图片",
+	services.ErrGenerationInProgress:    "该剧集正在生成分镜，请等待当前任务完成",
+}
+
+// respondServiceError 按服务层错误分类返回 404/403/400/409，被服务商安全策略拦截返回 422，未分类的错误返回 500
+// fallback 为 500 时的提示信息，为空时使用错误本身的消息
+func respondServiceError(c *gin.Context, err error, fallback string) {
+	message := err.Error()
+	for target, text := range serviceErrorMessages {
+		if errors.Is(err, target) {
+			message = text
+			break
+		}
+	}
+
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		response.NotFound(c, message)
+	case errors.Is(err, services.ErrForbidden):
+		response.Forbidden(c, message)
+	case errors.Is(err, services.ErrInvalidInput):
+		response.BadRequest(c, message)
+	case errors.Is(err, services.ErrConflict):
+		response.Conflict(c, message)
+	case errors.Is(err, ai.ErrContentPolicy), errors.Is(err, image.ErrContentPolicy):
+		response.Error(c, http.StatusUnprocessableEntity, "CONTENT_POLICY", "内容被服务商安全策略拦截，请调整内容或安全设置")
+	default:
+		if fallback == "" {
+			fallback = err.Error()
+		}
+		response.InternalError(c, fallback)
+	}
+}
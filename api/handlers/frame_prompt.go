@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"strconv"
+
 	"github.com/drama-generator/backend/application/services"
 	"github.com/drama-generator/backend/pkg/logger"
 	"github.com/drama-generator/backend/pkg/response"
@@ -21,15 +23,16 @@ func NewFramePromptHandler(framePromptService *services.FramePromptService, log
 	}
 }
 
-// GenerateFramePrompt 生成指定类型的帧提示词
+// GenerateFramePrompt 生成指定类型的帧提示词，frame_types 可一次指定多种（如首帧+关键帧+尾帧）
 // POST /api/v1/storyboards/:id/frame-prompt
 func (h *FramePromptHandler) GenerateFramePrompt(c *gin.Context) {
 	storyboardID := c.Param("id")
 
 	var req struct {
-		FrameType  string `json:"frame_type"`
-		PanelCount int    `json:"panel_count"`
-		Model      string `json:"model"`
+		FrameType  string   `json:"frame_type"`
+		FrameTypes []string `json:"frame_types"`
+		PanelCount int      `json:"panel_count"`
+		Model      string   `json:"model"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.BadRequest(c, err.Error())
@@ -41,12 +44,15 @@ func (h *FramePromptHandler) GenerateFramePrompt(c *gin.Context) {
 		FrameType:    services.FrameType(req.FrameType),
 		PanelCount:   req.PanelCount,
 	}
+	for _, ft := range req.FrameTypes {
+		serviceReq.FrameTypes = append(serviceReq.FrameTypes, services.FrameType(ft))
+	}
 
 	// 直接调用服务层的异步方法，该方法会创建任务并返回任务ID
 	taskID, err := h.framePromptService.GenerateFramePrompt(serviceReq, req.Model)
 	if err != nil {
 		h.log.Errorw("Failed to generate frame prompt", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -57,3 +63,45 @@ func (h *FramePromptHandler) GenerateFramePrompt(c *gin.Context) {
 		"message": "帧提示词生成任务已创建，正在后台处理...",
 	})
 }
+
+// ListStoryboardFramePrompts 分页查询镜头的帧提示词
+// GET /api/v1/storyboards/:id/frame-prompts?frame_type=first&page=1&page_size=20
+func (h *FramePromptHandler) ListStoryboardFramePrompts(c *gin.Context) {
+	storyboardID := c.Param("id")
+	frameType := c.Query("frame_type")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	framePrompts, total, err := h.framePromptService.ListFramePrompts(storyboardID, frameType, page, pageSize)
+	if err != nil {
+		h.log.Errorw("Failed to query frame prompts", "error", err)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.SuccessWithPagination(c, framePrompts, total, page, pageSize)
+}
+
+// ListEpisodeFramePrompts 查询整集的帧提示词，按镜头编号排序
+// GET /api/v1/episodes/:episode_id/frame-prompts?frame_type=first
+func (h *FramePromptHandler) ListEpisodeFramePrompts(c *gin.Context) {
+	episodeID := c.Param("episode_id")
+
+	framePrompts, err := h.framePromptService.ListFramePromptsForEpisode(episodeID, c.Query("frame_type"))
+	if err != nil {
+		h.log.Errorw("Failed to query episode frame prompts", "error", err, "episode_id", episodeID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"frame_prompts": framePrompts,
+	})
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"github.com/drama-generator/backend/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// ListGenerationHistory 列出实体的图片生成历史
+// GET /api/v1/images/history/:entity_type/:entity_id
+func (h *ImageGenerationHandler) ListGenerationHistory(c *gin.Context) {
+	entityType := c.Param("entity_type")
+	entityID := c.Param("entity_id")
+
+	history, err := h.imageService.ListGenerationHistory(entityType, entityID)
+	if err != nil {
+		h.log.Errorw("Failed to list generation history", "error", err, "entity_type", entityType, "entity_id", entityID)
+		respondServiceError(c, err, "获取生成历史失败")
+		return
+	}
+
+	response.Success(c, history)
+}
+
+// RestoreGenerationHistory 将实体图片恢复为某条历史记录
+// POST /api/v1/images/history/:id/restore
+func (h *ImageGenerationHandler) RestoreGenerationHistory(c *gin.Context) {
+	historyID := c.Param("id")
+
+	record, err := h.imageService.RestoreGenerationHistory(historyID)
+	if err != nil {
+		h.log.Errorw("Failed to restore generation history", "error", err, "history_id", historyID)
+		respondServiceError(c, err, "恢复图片失败")
+		return
+	}
+
+	response.Success(c, record)
+}
@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/drama-generator/backend/application/services"
 	"github.com/drama-generator/backend/domain/models"
 	"github.com/drama-generator/backend/infrastructure/storage"
 	"github.com/drama-generator/backend/pkg/config"
+	"github.com/drama-generator/backend/pkg/image"
 	"github.com/drama-generator/backend/pkg/logger"
 	"github.com/drama-generator/backend/pkg/response"
 	"github.com/gin-gonic/gin"
@@ -39,10 +43,34 @@ func (h *ImageGenerationHandler) GenerateImage(c *gin.Context) {
 		return
 	}
 
+	if err := services.NormalizeImageSize(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
 	imageGen, err := h.imageService.GenerateImage(&req)
 	if err != nil {
 		h.log.Errorw("Failed to generate image", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, imageGen)
+}
+
+// GeneratePreview 生成低成本预览图，用于在正式生成前确认构图，结果不写回分镜/场景/角色/道具
+func (h *ImageGenerationHandler) GeneratePreview(c *gin.Context) {
+	var req services.GenerateImageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	req.PreviewMode = true
+
+	imageGen, err := h.imageService.GenerateImage(&req)
+	if err != nil {
+		h.log.Errorw("Failed to generate preview image", "error", err)
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -56,7 +84,7 @@ func (h *ImageGenerationHandler) GenerateImagesForScene(c *gin.Context) {
 	images, err := h.imageService.GenerateImagesForScene(sceneID)
 	if err != nil {
 		h.log.Errorw("Failed to generate images for scene", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -70,25 +98,48 @@ func (h *ImageGenerationHandler) GetBackgroundsForEpisode(c *gin.Context) {
 	backgrounds, err := h.imageService.GetScencesForEpisode(episodeID)
 	if err != nil {
 		h.log.Errorw("Failed to get backgrounds", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
+	// 可选按生成状态过滤：pending/generating/generated/failed
+	if status := c.Query("status"); status != "" {
+		filtered := make([]*models.Scene, 0, len(backgrounds))
+		for _, scene := range backgrounds {
+			if scene.ImageGenerationStatus != nil && *scene.ImageGenerationStatus == status {
+				filtered = append(filtered, scene)
+			}
+		}
+		backgrounds = filtered
+	}
+
 	response.Success(c, backgrounds)
 }
 
 func (h *ImageGenerationHandler) ExtractBackgroundsForEpisode(c *gin.Context) {
 	episodeID := c.Param("episode_id")
 
-	// 接收可选的 model 和 style 参数
+	// 接收可选的 model、style 和 dedup 参数
 	var req struct {
 		Model string `json:"model"`
 		Style string `json:"style"`
+		Dedup string `json:"dedup"` // exact（默认）或 embedding
+		Mode  string `json:"mode"`  // replace（默认）或 merge
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		// 如果没有提供body或者解析失败，使用空字符串（使用默认模型和风格）
 		req.Model = ""
 		req.Style = ""
+		req.Dedup = ""
+		req.Mode = ""
+	}
+	if req.Dedup != "" && req.Dedup != services.SceneDedupExact && req.Dedup != services.SceneDedupEmbedding {
+		response.BadRequest(c, "dedup 只能是 exact 或 embedding")
+		return
+	}
+	if req.Mode != "" && req.Mode != services.SceneExtractReplace && req.Mode != services.SceneExtractMerge {
+		response.BadRequest(c, "mode 只能是 replace 或 merge")
+		return
 	}
 	// 如果style为空，从episode获取drama的style
 	if req.Style == "" {
@@ -99,10 +150,10 @@ func (h *ImageGenerationHandler) ExtractBackgroundsForEpisode(c *gin.Context) {
 	}
 
 	// 直接调用服务层的异步方法，该方法会创建任务并返回任务ID
-	taskID, err := h.imageService.ExtractBackgroundsForEpisode(episodeID, req.Model, req.Style)
+	taskID, err := h.imageService.ExtractBackgroundsForEpisode(episodeID, req.Model, req.Style, req.Dedup, req.Mode)
 	if err != nil {
 		h.log.Errorw("Failed to extract backgrounds", "error", err, "episode_id", episodeID)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -118,14 +169,42 @@ func (h *ImageGenerationHandler) BatchGenerateForEpisode(c *gin.Context) {
 
 	episodeID := c.Param("episode_id")
 
-	images, err := h.imageService.BatchGenerateImagesForEpisode(episodeID)
+	// mode=composed 时以角色形象图为参考生成完整画面，默认仅生成背景
+	mode := c.DefaultQuery("mode", services.BatchImageModeBackground)
+	if mode != services.BatchImageModeBackground && mode != services.BatchImageModeComposed {
+		response.BadRequest(c, "mode 只能是 background 或 composed")
+		return
+	}
+
+	// concurrency 限制本批同时生成的图片数，不传时使用配置默认值
+	concurrency := 0
+	if value := c.Query("concurrency"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			response.BadRequest(c, "concurrency 必须是非负整数")
+			return
+		}
+		concurrency = parsed
+	}
+
+	result, err := h.imageService.BatchGenerateImagesForEpisode(episodeID, mode, concurrency)
 	if err != nil {
 		h.log.Errorw("Failed to batch generate images", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
-	response.Success(c, images)
+	response.Success(c, result)
+}
+
+// GetProviderCapabilities 获取各图片服务商支持的参数矩阵
+func (h *ImageGenerationHandler) GetProviderCapabilities(c *gin.Context) {
+	if provider := c.Query("provider"); provider != "" {
+		response.Success(c, h.imageService.ProviderCapabilities(provider))
+		return
+	}
+
+	response.Success(c, image.AllCapabilities())
 }
 
 func (h *ImageGenerationHandler) GetImageGeneration(c *gin.Context) {
@@ -145,6 +224,124 @@ func (h *ImageGenerationHandler) GetImageGeneration(c *gin.Context) {
 	response.Success(c, imageGen)
 }
 
+// RetryImageGeneration 重试失败的图片生成
+func (h *ImageGenerationHandler) RetryImageGeneration(c *gin.Context) {
+	imageGenID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	imageGen, err := h.imageService.RetryImageGeneration(uint(imageGenID))
+	if err != nil {
+		h.log.Errorw("Failed to retry image generation", "error", err, "id", imageGenID)
+		if errors.Is(err, services.ErrNotFound) {
+			respondServiceError(c, err, "")
+			return
+		}
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, imageGen)
+}
+
+// ResumeImagePolling 按已保存的服务商任务ID恢复轮询，不重新生成
+func (h *ImageGenerationHandler) ResumeImagePolling(c *gin.Context) {
+	imageGenID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	imageGen, err := h.imageService.ResumeImagePolling(uint(imageGenID))
+	if err != nil {
+		h.log.Errorw("Failed to resume image polling", "error", err, "id", imageGenID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, imageGen)
+}
+
+// RegenerateAllSceneImages 重新生成剧本下所有场景的图片（异步）
+func (h *ImageGenerationHandler) RegenerateAllSceneImages(c *gin.Context) {
+	dramaID := c.Param("id")
+
+	taskID, err := h.imageService.RegenerateAllSceneImages(dramaID)
+	if err != nil {
+		h.log.Errorw("Failed to regenerate scene images", "error", err, "drama_id", dramaID)
+		if errors.Is(err, services.ErrNotFound) {
+			respondServiceError(c, err, "")
+			return
+		}
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{
+		"task_id": taskID,
+		"status":  "pending",
+		"message": "场景图片重新生成任务已创建，正在后台处理...",
+	})
+}
+
+// RetryFailedImages 重新提交剧本下所有失败的图片生成（未达到重试上限的）
+func (h *ImageGenerationHandler) RetryFailedImages(c *gin.Context) {
+	dramaID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	taskID, count, err := h.imageService.RetryFailedImages(uint(dramaID))
+	if err != nil {
+		h.log.Errorw("Failed to retry failed images", "error", err, "drama_id", dramaID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"task_id": taskID,
+		"status":  "pending",
+		"count":   count,
+		"message": fmt.Sprintf("已创建重试任务，共 %d 张失败图片", count),
+	})
+}
+
+// GetImageGenerationStatuses 批量查询图片生成状态，ids 以逗号分隔
+func (h *ImageGenerationHandler) GetImageGenerationStatuses(c *gin.Context) {
+	var ids []uint
+	for _, idStr := range strings.Split(c.Query("ids"), ",") {
+		idStr = strings.TrimSpace(idStr)
+		if idStr == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(idStr, 10, 32)
+		if err != nil {
+			response.BadRequest(c, "无效的ID: "+idStr)
+			return
+		}
+		ids = append(ids, uint(id))
+	}
+	if len(ids) == 0 {
+		response.BadRequest(c, "ids不能为空")
+		return
+	}
+
+	imageGens, err := h.imageService.GetImageGenerationStatuses(ids)
+	if err != nil {
+		h.log.Errorw("Failed to get image generation statuses", "error", err)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"items":       imageGens,
+		"queue_depth": h.imageService.QueueDepth(),
+	})
+}
+
 func (h *ImageGenerationHandler) ListImageGenerations(c *gin.Context) {
 	var sceneID *uint
 	if sceneIDStr := c.Query("scene_id"); sceneIDStr != "" {
@@ -166,6 +363,7 @@ func (h *ImageGenerationHandler) ListImageGenerations(c *gin.Context) {
 
 	frameType := c.Query("frame_type")
 	status := c.Query("status")
+	tag := c.Query("tag")
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
 
@@ -183,17 +381,121 @@ func (h *ImageGenerationHandler) ListImageGenerations(c *gin.Context) {
 		dramaIDUint = &didUint
 	}
 
-	images, total, err := h.imageService.ListImageGenerations(dramaIDUint, sceneID, storyboardID, frameType, status, page, pageSize)
+	images, total, err := h.imageService.ListImageGenerations(dramaIDUint, sceneID, storyboardID, frameType, status, tag, page, pageSize)
 
 	if err != nil {
 		h.log.Errorw("Failed to list images", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
 	response.SuccessWithPagination(c, images, total, page, pageSize)
 }
 
+// GetSceneGallery 按场景分组的剧本图片画廊，分页以场景为单位
+// GET /api/v1/dramas/:id/gallery?page=1&page_size=20
+func (h *ImageGenerationHandler) GetSceneGallery(c *gin.Context) {
+	dramaID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的剧本ID")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	entries, total, err := h.imageService.GetSceneGallery(uint(dramaID), page, pageSize)
+	if err != nil {
+		h.log.Errorw("Failed to get scene gallery", "error", err, "drama_id", dramaID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.SuccessWithPagination(c, entries, total, page, pageSize)
+}
+
+// CheckStyleConsistency 创建剧本场景图画风检查任务，可通过 reference_scene_id 指定参考场景
+func (h *ImageGenerationHandler) CheckStyleConsistency(c *gin.Context) {
+	dramaID := c.Param("id")
+
+	var referenceSceneID uint64
+	if ref := c.Query("reference_scene_id"); ref != "" {
+		id, err := strconv.ParseUint(ref, 10, 32)
+		if err != nil {
+			response.BadRequest(c, "无效的参考场景ID")
+			return
+		}
+		referenceSceneID = id
+	}
+
+	taskID, err := h.imageService.CheckStyleConsistency(dramaID, uint(referenceSceneID))
+	if err != nil {
+		h.log.Errorw("Failed to check style consistency", "error", err, "drama_id", dramaID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"task_id": taskID,
+		"status":  "pending",
+		"message": "画风检查任务已创建，正在后台处理...",
+	})
+}
+
+// CompareImageGenerations 对比两条图片生成记录的参数
+// GET /api/v1/images/compare?a=&b=
+func (h *ImageGenerationHandler) CompareImageGenerations(c *gin.Context) {
+	a, errA := strconv.ParseUint(c.Query("a"), 10, 32)
+	b, errB := strconv.ParseUint(c.Query("b"), 10, 32)
+	if errA != nil || errB != nil {
+		response.BadRequest(c, "a 和 b 必须是有效的图片生成记录ID")
+		return
+	}
+
+	result, err := h.imageService.CompareImageGenerations(uint(a), uint(b))
+	if err != nil {
+		h.log.Errorw("Failed to compare image generations", "error", err, "a", a, "b", b)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// ListImageBatch 查询同一批次的图片生成记录
+func (h *ImageGenerationHandler) ListImageBatch(c *gin.Context) {
+	batchID := c.Param("batch_id")
+
+	images, err := h.imageService.ListImageBatch(batchID)
+	if err != nil {
+		h.log.Errorw("Failed to list image batch", "error", err, "batch_id", batchID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, images)
+}
+
+// GetEffectiveDramaConfig 获取剧本生成时实际生效的服务商、模型、尺寸和风格等配置
+func (h *ImageGenerationHandler) GetEffectiveDramaConfig(c *gin.Context) {
+	dramaID := c.Param("id")
+
+	result, err := h.imageService.GetEffectiveDramaConfig(dramaID)
+	if err != nil {
+		h.log.Errorw("Failed to resolve effective drama config", "error", err, "drama_id", dramaID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, result)
+}
+
 func (h *ImageGenerationHandler) DeleteImageGeneration(c *gin.Context) {
 
 	imageGenID, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -204,7 +506,7 @@ func (h *ImageGenerationHandler) DeleteImageGeneration(c *gin.Context) {
 
 	if err := h.imageService.DeleteImageGeneration(uint(imageGenID)); err != nil {
 		h.log.Errorw("Failed to delete image", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -236,9 +538,48 @@ func (h *ImageGenerationHandler) UploadImage(c *gin.Context) {
 
 	if err != nil {
 		h.log.Errorw("Failed to create image from upload", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
 	response.Success(c, imageGen)
 }
+
+// ExportImagesZip 将剧本或剧集的所有已完成图片打包为 ZIP 流式下载
+// GET /api/v1/images/export?drama_id=&episode_id=
+func (h *ImageGenerationHandler) ExportImagesZip(c *gin.Context) {
+	var dramaID, episodeID *uint
+	if s := c.Query("drama_id"); s != "" {
+		id, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			response.BadRequest(c, "drama_id 无效")
+			return
+		}
+		uid := uint(id)
+		dramaID = &uid
+	}
+	if s := c.Query("episode_id"); s != "" {
+		id, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			response.BadRequest(c, "episode_id 无效")
+			return
+		}
+		uid := uint(id)
+		episodeID = &uid
+	}
+
+	export, err := h.imageService.ExportImagesZip(dramaID, episodeID)
+	if err != nil {
+		h.log.Errorw("Failed to export images", "error", err, "drama_id", dramaID, "episode_id", episodeID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.Filename))
+	c.Status(200)
+	// 响应头已发送，写入失败（如客户端断开）只能记录日志
+	if err := export.WriteZip(c.Writer); err != nil {
+		h.log.Errorw("Failed to write image export", "error", err, "file", export.Filename)
+	}
+}
@@ -40,7 +40,7 @@ func (h *PropHandler) ListProps(c *gin.Context) {
 
 	props, err := h.propService.ListProps(uint(dramaID))
 	if err != nil {
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -56,7 +56,7 @@ func (h *PropHandler) CreateProp(c *gin.Context) {
 	}
 
 	if err := h.propService.CreateProp(&prop); err != nil {
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -79,7 +79,7 @@ func (h *PropHandler) UpdateProp(c *gin.Context) {
 	}
 
 	if err := h.propService.UpdateProp(uint(id), updates); err != nil {
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -96,7 +96,7 @@ func (h *PropHandler) DeleteProp(c *gin.Context) {
 	}
 
 	if err := h.propService.DeleteProp(uint(id)); err != nil {
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -114,7 +114,7 @@ func (h *PropHandler) ExtractProps(c *gin.Context) {
 
 	taskID, err := h.propService.ExtractPropsFromScript(uint(episodeID))
 	if err != nil {
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -133,7 +133,7 @@ func (h *PropHandler) GenerateImage(c *gin.Context) {
 	taskID, err := h.propService.GeneratePropImage(uint(id))
 	if err != nil {
 		h.log.Errorw("Failed to generate prop image", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -158,7 +158,7 @@ func (h *PropHandler) AssociateProps(c *gin.Context) {
 	}
 
 	if err := h.propService.AssociatePropsWithStoryboard(uint(storyboardID), req.PropIDs); err != nil {
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"strconv"
+
 	services2 "github.com/drama-generator/backend/application/services"
 	"github.com/drama-generator/backend/pkg/logger"
 	"github.com/drama-generator/backend/pkg/response"
@@ -9,14 +11,16 @@ import (
 )
 
 type SceneHandler struct {
-	sceneService *services2.StoryboardCompositionService
-	log          *logger.Logger
+	sceneService    *services2.StoryboardCompositionService
+	imageGenService *services2.ImageGenerationService
+	log             *logger.Logger
 }
 
 func NewSceneHandler(db *gorm.DB, log *logger.Logger, imageGenService *services2.ImageGenerationService) *SceneHandler {
 	return &SceneHandler{
-		sceneService: services2.NewStoryboardCompositionService(db, log, imageGenService),
-		log:          log,
+		sceneService:    services2.NewStoryboardCompositionService(db, log, imageGenService),
+		imageGenService: imageGenService,
+		log:             log,
 	}
 }
 
@@ -26,7 +30,7 @@ func (h *SceneHandler) GetStoryboardsForEpisode(c *gin.Context) {
 	storyboards, err := h.sceneService.GetScenesForEpisode(episodeID)
 	if err != nil {
 		h.log.Errorw("Failed to get storyboards for episode", "error", err, "episode_id", episodeID)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -47,7 +51,7 @@ func (h *SceneHandler) UpdateScene(c *gin.Context) {
 
 	if err := h.sceneService.UpdateSceneInfo(sceneID, &req); err != nil {
 		h.log.Errorw("Failed to update scene", "error", err, "scene_id", sceneID)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -64,7 +68,7 @@ func (h *SceneHandler) GenerateSceneImage(c *gin.Context) {
 	imageGen, err := h.sceneService.GenerateSceneImage(&req)
 	if err != nil {
 		h.log.Errorw("Failed to generate scene image", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -74,6 +78,34 @@ func (h *SceneHandler) GenerateSceneImage(c *gin.Context) {
 	})
 }
 
+// RegenerateSceneImage 重新生成单个场景的背景图，可选覆盖提示词
+// POST /api/v1/scenes/:scene_id/regenerate
+func (h *SceneHandler) RegenerateSceneImage(c *gin.Context) {
+	sceneID := c.Param("scene_id")
+
+	var req struct {
+		Prompt string `json:"prompt" binding:"max=2000"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+	}
+
+	imageGen, err := h.imageGenService.RegenerateSceneImage(sceneID, req.Prompt)
+	if err != nil {
+		h.log.Errorw("Failed to regenerate scene image", "error", err, "scene_id", sceneID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"message":          "场景图片重新生成任务已创建",
+		"image_generation": imageGen,
+	})
+}
+
 func (h *SceneHandler) UpdateScenePrompt(c *gin.Context) {
 	sceneID := c.Param("scene_id")
 
@@ -85,27 +117,43 @@ func (h *SceneHandler) UpdateScenePrompt(c *gin.Context) {
 
 	if err := h.sceneService.UpdateScenePrompt(sceneID, &req); err != nil {
 		h.log.Errorw("Failed to update scene prompt", "error", err, "scene_id", sceneID)
-		if err.Error() == "scene not found" {
-			response.NotFound(c, "场景不存在")
-			return
-		}
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
 	response.Success(c, gin.H{"message": "场景提示词已更新"})
 }
 
+// BulkUpdateScenePrompts 批量查找替换或追加剧本所有场景的提示词，preview=true 时只返回将受影响的场景
+func (h *SceneHandler) BulkUpdateScenePrompts(c *gin.Context) {
+	dramaID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的剧本ID")
+		return
+	}
+
+	var req services2.BulkScenePromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request")
+		return
+	}
+
+	result, err := h.sceneService.BulkUpdateScenePrompts(uint(dramaID), &req)
+	if err != nil {
+		h.log.Errorw("Failed to bulk update scene prompts", "error", err, "drama_id", dramaID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, result)
+}
+
 func (h *SceneHandler) DeleteScene(c *gin.Context) {
 	sceneID := c.Param("scene_id")
 
 	if err := h.sceneService.DeleteScene(sceneID); err != nil {
 		h.log.Errorw("Failed to delete scene", "error", err, "scene_id", sceneID)
-		if err.Error() == "scene not found" {
-			response.NotFound(c, "场景不存在")
-			return
-		}
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -127,9 +175,32 @@ func (h *SceneHandler) CreateScene(c *gin.Context) {
 	scene, err := h.sceneService.CreateScene(&req)
 	if err != nil {
 		h.log.Errorw("Failed to create scene", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
 	response.Success(c, scene)
 }
+
+// ReassignScene 将场景移动到同一剧本的另一集
+// PUT /api/v1/scenes/:scene_id/episode
+func (h *SceneHandler) ReassignScene(c *gin.Context) {
+	sceneID := c.Param("scene_id")
+
+	var req struct {
+		EpisodeID uint `json:"episode_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "episode_id is required")
+		return
+	}
+
+	result, err := h.sceneService.ReassignScene(sceneID, req.EpisodeID)
+	if err != nil {
+		h.log.Errorw("Failed to reassign scene", "error", err, "scene_id", sceneID, "episode_id", req.EpisodeID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, result)
+}
@@ -34,7 +34,7 @@ func (h *ScriptGenerationHandler) GenerateCharacters(c *gin.Context) {
 	taskID, err := h.scriptService.GenerateCharacters(&req)
 	if err != nil {
 		h.log.Errorw("Failed to generate characters", "error", err, "drama_id", req.DramaID)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -45,3 +45,21 @@ func (h *ScriptGenerationHandler) GenerateCharacters(c *gin.Context) {
 		"message": "角色生成任务已创建，正在后台处理...",
 	})
 }
+
+// GenerateCharactersForDrama 逐集提取全剧角色并去重（异步）
+func (h *ScriptGenerationHandler) GenerateCharactersForDrama(c *gin.Context) {
+	dramaID := c.Param("id")
+
+	taskID, err := h.scriptService.GenerateCharactersForDrama(dramaID)
+	if err != nil {
+		h.log.Errorw("Failed to generate characters for drama", "error", err, "drama_id", dramaID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"task_id": taskID,
+		"status":  "pending",
+		"message": "全剧角色生成任务已创建，正在后台处理...",
+	})
+}
@@ -22,7 +22,7 @@ func NewSettingsHandler(cfg *config.Config, log *logger.Logger) *SettingsHandler
 
 // GetLanguage 获取当前系统语言
 func (h *SettingsHandler) GetLanguage(c *gin.Context) {
-	language := h.config.App.Language
+	language := config.CurrentOr(h.config).App.Language
 	if language == "" {
 		language = "zh" // 默认中文
 	}
@@ -32,6 +32,22 @@ func (h *SettingsHandler) GetLanguage(c *gin.Context) {
 	})
 }
 
+// ReloadConfig 重新读取配置文件并热替换，无需重启服务
+func (h *SettingsHandler) ReloadConfig(c *gin.Context) {
+	cfg, err := config.ReloadConfig()
+	if err != nil {
+		h.log.Errorw("Failed to reload config", "error", err)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	h.log.Infow("Config reloaded", "language", cfg.App.Language, "debug", cfg.App.Debug)
+	response.Success(c, gin.H{
+		"message":  "配置已重新加载",
+		"language": cfg.App.Language,
+	})
+}
+
 // UpdateLanguage 更新系统语言
 func (h *SettingsHandler) UpdateLanguage(c *gin.Context) {
 	var req struct {
@@ -43,8 +59,10 @@ func (h *SettingsHandler) UpdateLanguage(c *gin.Context) {
 		return
 	}
 
-	// 更新内存中的配置
-	h.config.App.Language = req.Language
+	// 更新内存中的配置（写时复制，不影响正在处理的请求）
+	config.Update(h.config, func(cfg *config.Config) {
+		cfg.App.Language = req.Language
+	})
 
 	// 更新配置文件
 	viper.Set("app.language", req.Language)
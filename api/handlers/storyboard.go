@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/drama-generator/backend/application/services"
@@ -29,20 +30,22 @@ func NewStoryboardHandler(db *gorm.DB, cfg *config.Config, log *logger.Logger) *
 func (h *StoryboardHandler) GenerateStoryboard(c *gin.Context) {
 	episodeID := c.Param("episode_id")
 
-	// 接收可选的 model 参数
+	// 接收可选的 model 和风格参考剧集参数
 	var req struct {
-		Model string `json:"model"`
+		Model                   string `json:"model"`
+		StyleReferenceEpisodeID *uint  `json:"style_reference_episode_id"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		// 如果没有提供body或者解析失败，使用空字符串（使用默认模型）
 		req.Model = ""
+		req.StyleReferenceEpisodeID = nil
 	}
 
 	// 调用生成服务，该服务已经是异步的，会返回任务ID
-	taskID, err := h.storyboardService.GenerateStoryboard(episodeID, req.Model)
+	taskID, err := h.storyboardService.GenerateStoryboard(episodeID, req.Model, req.StyleReferenceEpisodeID)
 	if err != nil {
 		h.log.Errorw("Failed to generate storyboard", "error", err, "episode_id", episodeID)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -54,6 +57,98 @@ func (h *StoryboardHandler) GenerateStoryboard(c *gin.Context) {
 	})
 }
 
+// GenerateStoryboardsForDrama 为剧本下所有有剧本内容的剧集批量生成分镜（异步）
+// POST /api/v1/dramas/:id/storyboards/generate
+func (h *StoryboardHandler) GenerateStoryboardsForDrama(c *gin.Context) {
+	dramaID := c.Param("id")
+
+	var req struct {
+		Model string `json:"model"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+	}
+
+	taskID, err := h.storyboardService.GenerateStoryboardsForDrama(dramaID, req.Model)
+	if err != nil {
+		h.log.Errorw("Failed to generate storyboards for drama", "error", err, "drama_id", dramaID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"task_id": taskID,
+		"status":  "pending",
+		"message": "批量分镜生成任务已创建，正在后台处理...",
+	})
+}
+
+// PreviewStoryboardPrompt 预览生成分镜时发送给AI的完整提示词，不消耗生成额度
+// GET /api/v1/episodes/:episode_id/storyboard/prompt-preview?style_reference_episode_id=1
+func (h *StoryboardHandler) PreviewStoryboardPrompt(c *gin.Context) {
+	episodeID := c.Param("episode_id")
+
+	var styleReferenceEpisodeID *uint
+	if refStr := c.Query("style_reference_episode_id"); refStr != "" {
+		refID, err := strconv.ParseUint(refStr, 10, 32)
+		if err != nil {
+			response.BadRequest(c, "invalid style_reference_episode_id")
+			return
+		}
+		ref := uint(refID)
+		styleReferenceEpisodeID = &ref
+	}
+
+	preview, err := h.storyboardService.PreviewStoryboardPrompt(episodeID, styleReferenceEpisodeID)
+	if err != nil {
+		h.log.Errorw("Failed to preview storyboard prompt", "error", err, "episode_id", episodeID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, preview)
+}
+
+// RecalculateEpisodeDuration 按当前分镜重新计算剧集时长
+// POST /api/v1/episodes/:episode_id/duration/recalculate
+func (h *StoryboardHandler) RecalculateEpisodeDuration(c *gin.Context) {
+	episodeID := c.Param("episode_id")
+
+	result, err := h.storyboardService.RecalculateEpisodeDuration(episodeID)
+	if err != nil {
+		h.log.Errorw("Failed to recalculate episode duration", "error", err, "episode_id", episodeID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// LinkStoryboardsToScenes 重新匹配分镜与场景（异步）
+func (h *StoryboardHandler) LinkStoryboardsToScenes(c *gin.Context) {
+	episodeID := c.Param("episode_id")
+
+	taskID, err := h.storyboardService.LinkStoryboardsToScenes(episodeID)
+	if err != nil {
+		h.log.Errorw("Failed to link storyboards to scenes", "error", err, "episode_id", episodeID)
+		if errors.Is(err, services.ErrNotFound) {
+			respondServiceError(c, err, "")
+			return
+		}
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{
+		"task_id": taskID,
+		"status":  "pending",
+		"message": "场景匹配任务已创建，正在后台处理...",
+	})
+}
+
 // UpdateStoryboard 更新分镜
 func (h *StoryboardHandler) UpdateStoryboard(c *gin.Context) {
 	storyboardID := c.Param("id")
@@ -65,14 +160,209 @@ func (h *StoryboardHandler) UpdateStoryboard(c *gin.Context) {
 		return
 	}
 
-	err := h.storyboardService.UpdateStoryboard(storyboardID, req)
+	result, err := h.storyboardService.UpdateStoryboard(storyboardID, req)
 	if err != nil {
 		h.log.Errorw("Failed to update storyboard", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"message":         "Storyboard updated successfully",
+		"changed_fields":  result.ChangedFields,
+		"updated_prompts": result.UpdatedPrompts,
+	})
+}
+
+// RegenerateVideoPrompts 以新的风格/画面比例重新生成剧集所有分镜的视频提示词
+func (h *StoryboardHandler) RegenerateVideoPrompts(c *gin.Context) {
+	episodeID := c.Param("episode_id")
+
+	var req struct {
+		Style string `json:"style"`
+		Ratio string `json:"ratio"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	count, err := h.storyboardService.RegenerateVideoPrompts(episodeID, req.Style, req.Ratio)
+	if err != nil {
+		h.log.Errorw("Failed to regenerate video prompts", "error", err, "episode_id", episodeID)
+		response.BadRequest(c, err.Error())
 		return
 	}
 
-	response.Success(c, gin.H{"message": "Storyboard updated successfully"})
+	response.Success(c, gin.H{"updated": count})
+}
+
+// BulkUpdateStoryboardCharacters 批量更新多个分镜的角色关联
+func (h *StoryboardHandler) BulkUpdateStoryboardCharacters(c *gin.Context) {
+	var req struct {
+		Updates map[uint][]uint `json:"updates" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.storyboardService.BulkUpdateStoryboardCharacters(req.Updates); err != nil {
+		h.log.Errorw("Failed to bulk update storyboard characters", "error", err)
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"updated": len(req.Updates)})
+}
+
+// GenerateStoryboardFromImages 根据参考图片反推分镜（异步）
+func (h *StoryboardHandler) GenerateStoryboardFromImages(c *gin.Context) {
+	episodeID := c.Param("episode_id")
+
+	var req struct {
+		ImageURLs []string `json:"image_urls" binding:"required,min=1"`
+		Model     string   `json:"model"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	taskID, err := h.storyboardService.GenerateStoryboardFromImages(episodeID, req.ImageURLs, req.Model)
+	if err != nil {
+		h.log.Errorw("Failed to generate storyboard from images", "error", err, "episode_id", episodeID)
+		if errors.Is(err, services.ErrConflict) {
+			respondServiceError(c, err, "")
+			return
+		}
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{
+		"task_id": taskID,
+		"status":  "pending",
+		"message": "分镜头生成任务已创建，正在后台处理...",
+	})
+}
+
+// EnrichBgmPrompts 根据镜头情绪和氛围用AI重写剧集所有分镜的BGM提示词（异步）
+func (h *StoryboardHandler) EnrichBgmPrompts(c *gin.Context) {
+	episodeID := c.Param("episode_id")
+
+	var req struct {
+		Model string `json:"model"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+	}
+
+	taskID, err := h.storyboardService.EnrichBgmPrompts(episodeID, req.Model)
+	if err != nil {
+		h.log.Errorw("Failed to enrich BGM prompts", "error", err, "episode_id", episodeID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"task_id": taskID,
+		"status":  "pending",
+		"message": "BGM提示词生成任务已创建，正在后台处理...",
+	})
+}
+
+// ImportStoryboards 导入外部编辑的分镜JSON，替换剧集现有分镜
+func (h *StoryboardHandler) ImportStoryboards(c *gin.Context) {
+	episodeID := c.Param("episode_id")
+
+	var req struct {
+		Storyboards []services.Storyboard `json:"storyboards" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	count, err := h.storyboardService.ImportStoryboards(episodeID, req.Storyboards)
+	if err != nil {
+		h.log.Errorw("Failed to import storyboards", "error", err, "episode_id", episodeID)
+		respondServiceError(c, err, "导入分镜失败")
+		return
+	}
+
+	response.Success(c, gin.H{"imported": count})
+}
+
+// CompositePanels 将分镜已生成的帧图片拼接为多格图（异步）
+func (h *StoryboardHandler) CompositePanels(c *gin.Context) {
+	storyboardID := c.Param("id")
+
+	var req services.CompositePanelsRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+	}
+
+	taskID, err := h.storyboardService.CompositePanels(storyboardID, &req)
+	if err != nil {
+		h.log.Errorw("Failed to composite panels", "error", err, "storyboard_id", storyboardID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"task_id": taskID,
+		"status":  "pending",
+		"message": "拼图任务已创建，正在后台处理...",
+	})
+}
+
+// ExportEpisodeRenderPlan 导出剧集渲染计划，供外部渲染服务一次性获取全部镜头数据
+func (h *StoryboardHandler) ExportEpisodeRenderPlan(c *gin.Context) {
+	episodeID := c.Param("episode_id")
+
+	plan, err := h.storyboardService.ExportEpisodeRenderPlan(episodeID)
+	if err != nil {
+		h.log.Errorw("Failed to export render plan", "error", err, "episode_id", episodeID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, plan)
+}
+
+// GetEpisodeGenerationGraph 获取剧集分镜、场景、角色、帧提示词和图片生成记录的完整关系，用于排查问题
+func (h *StoryboardHandler) GetEpisodeGenerationGraph(c *gin.Context) {
+	episodeID := c.Param("episode_id")
+
+	graph, err := h.storyboardService.GetEpisodeGenerationGraph(episodeID)
+	if err != nil {
+		h.log.Errorw("Failed to get episode generation graph", "error", err, "episode_id", episodeID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, graph)
+}
+
+// RefreshStoryboardPrompts 根据编辑后的分镜重新生成图片和视频提示词
+func (h *StoryboardHandler) RefreshStoryboardPrompts(c *gin.Context) {
+	storyboardID := c.Param("id")
+
+	sb, err := h.storyboardService.RefreshStoryboardPrompts(storyboardID)
+	if err != nil {
+		h.log.Errorw("Failed to refresh storyboard prompts", "error", err, "storyboard_id", storyboardID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, sb)
 }
 
 // CreateStoryboard 创建分镜
@@ -86,7 +376,7 @@ func (h *StoryboardHandler) CreateStoryboard(c *gin.Context) {
 	sb, err := h.storyboardService.CreateStoryboard(&req)
 	if err != nil {
 		h.log.Errorw("Failed to create storyboard", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -104,9 +394,35 @@ func (h *StoryboardHandler) DeleteStoryboard(c *gin.Context) {
 
 	if err := h.storyboardService.DeleteStoryboard(uint(storyboardID)); err != nil {
 		h.log.Errorw("Failed to delete storyboard", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
 	response.Success(c, nil)
 }
+
+// SetStoryboardLocked 锁定或解锁分镜，锁定的镜头不会被重新生成覆盖
+// PATCH /api/v1/storyboards/:id/lock
+func (h *StoryboardHandler) SetStoryboardLocked(c *gin.Context) {
+	storyboardID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "Invalid ID")
+		return
+	}
+
+	var req struct {
+		Locked *bool `json:"locked" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.storyboardService.SetStoryboardLocked(uint(storyboardID), *req.Locked); err != nil {
+		h.log.Errorw("Failed to update storyboard lock", "error", err, "storyboard_id", storyboardID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	response.Success(c, gin.H{"id": storyboardID, "locked": *req.Locked})
+}
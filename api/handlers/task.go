@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"io"
+	"time"
+
 	"github.com/drama-generator/backend/application/services"
 	"github.com/drama-generator/backend/pkg/logger"
 	"github.com/drama-generator/backend/pkg/response"
@@ -31,13 +34,56 @@ func (h *TaskHandler) GetTaskStatus(c *gin.Context) {
 			return
 		}
 		h.log.Errorw("Failed to get task", "error", err, "task_id", taskID)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
 	response.Success(c, task)
 }
 
+// taskStreamInterval SSE 推送任务状态时查询数据库的间隔
+const taskStreamInterval = time.Second
+
+// StreamTaskStatus 以 SSE 推送任务状态，任务有更新（包括阶段性结果）时发送 task 事件，任务结束后关闭连接
+func (h *TaskHandler) StreamTaskStatus(c *gin.Context) {
+	taskID := c.Param("task_id")
+
+	task, err := h.taskService.GetTask(taskID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.NotFound(c, "任务不存在")
+			return
+		}
+		h.log.Errorw("Failed to get task", "error", err, "task_id", taskID)
+		respondServiceError(c, err, "")
+		return
+	}
+
+	var lastUpdate time.Time
+	ticker := time.NewTicker(taskStreamInterval)
+	defer ticker.Stop()
+	c.Stream(func(w io.Writer) bool {
+		if task != nil && !task.UpdatedAt.Equal(lastUpdate) {
+			lastUpdate = task.UpdatedAt
+			c.SSEvent("task", task)
+			if task.Status == "completed" || task.Status == "failed" {
+				return false
+			}
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-ticker.C:
+		}
+		if task, err = h.taskService.GetTask(taskID); err != nil {
+			h.log.Warnw("Failed to reload task for stream", "error", err, "task_id", taskID)
+			return false
+		}
+		return true
+	})
+}
+
 // GetResourceTasks 获取资源相关的所有任务
 func (h *TaskHandler) GetResourceTasks(c *gin.Context) {
 	resourceID := c.Query("resource_id")
@@ -49,7 +95,7 @@ func (h *TaskHandler) GetResourceTasks(c *gin.Context) {
 	tasks, err := h.taskService.GetTasksByResource(resourceID)
 	if err != nil {
 		h.log.Errorw("Failed to get resource tasks", "error", err, "resource_id", resourceID)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -4,11 +4,9 @@ import (
 	"strconv"
 
 	"github.com/drama-generator/backend/application/services"
-	"github.com/drama-generator/backend/infrastructure/storage"
 	"github.com/drama-generator/backend/pkg/logger"
 	"github.com/drama-generator/backend/pkg/response"
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
 type VideoGenerationHandler struct {
@@ -16,9 +14,9 @@ type VideoGenerationHandler struct {
 	log          *logger.Logger
 }
 
-func NewVideoGenerationHandler(db *gorm.DB, transferService *services.ResourceTransferService, localStorage *storage.LocalStorage, aiService *services.AIService, log *logger.Logger, promptI18n *services.PromptI18n) *VideoGenerationHandler {
+func NewVideoGenerationHandler(videoService *services.VideoGenerationService, log *logger.Logger) *VideoGenerationHandler {
 	return &VideoGenerationHandler{
-		videoService: services.NewVideoGenerationService(db, transferService, localStorage, aiService, log, promptI18n),
+		videoService: videoService,
 		log:          log,
 	}
 }
@@ -34,7 +32,7 @@ func (h *VideoGenerationHandler) GenerateVideo(c *gin.Context) {
 	videoGen, err := h.videoService.GenerateVideo(&req)
 	if err != nil {
 		h.log.Errorw("Failed to generate video", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -52,7 +50,7 @@ func (h *VideoGenerationHandler) GenerateVideoFromImage(c *gin.Context) {
 	videoGen, err := h.videoService.GenerateVideoFromImage(uint(imageGenID))
 	if err != nil {
 		h.log.Errorw("Failed to generate video from image", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -66,7 +64,7 @@ func (h *VideoGenerationHandler) BatchGenerateForEpisode(c *gin.Context) {
 	videos, err := h.videoService.BatchGenerateVideosForEpisode(episodeID)
 	if err != nil {
 		h.log.Errorw("Failed to batch generate videos", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -124,7 +122,7 @@ func (h *VideoGenerationHandler) ListVideoGenerations(c *gin.Context) {
 
 	if err != nil {
 		h.log.Errorw("Failed to list videos", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -141,7 +139,7 @@ func (h *VideoGenerationHandler) DeleteVideoGeneration(c *gin.Context) {
 
 	if err := h.videoService.DeleteVideoGeneration(uint(videoGenID)); err != nil {
 		h.log.Errorw("Failed to delete video", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
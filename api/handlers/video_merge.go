@@ -32,7 +32,7 @@ func (h *VideoMergeHandler) MergeVideos(c *gin.Context) {
 	merge, err := h.mergeService.MergeVideos(&req)
 	if err != nil {
 		h.log.Errorw("Failed to merge videos", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -74,7 +74,7 @@ func (h *VideoMergeHandler) ListMerges(c *gin.Context) {
 	merges, total, err := h.mergeService.ListMerges(episodeIDPtr, status, page, pageSize)
 	if err != nil {
 		h.log.Errorw("Failed to list merges", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -96,7 +96,7 @@ func (h *VideoMergeHandler) DeleteMerge(c *gin.Context) {
 
 	if err := h.mergeService.DeleteMerge(uint(mergeID)); err != nil {
 		h.log.Errorw("Failed to delete merge", "error", err)
-		response.InternalError(c, err.Error())
+		respondServiceError(c, err, "")
 		return
 	}
 
@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/drama-generator/backend/pkg/config"
+	"github.com/drama-generator/backend/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuthMiddleware 校验管理接口令牌（Authorization: Bearer <token> 或 X-Admin-Token），未配置令牌时拒绝所有请求
+func AdminAuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 每次请求读取最新配置，令牌轮换后立即生效
+		expected := config.CurrentOr(cfg).Server.AdminToken
+		if expected == "" {
+			response.Forbidden(c, "管理接口未启用")
+			c.Abort()
+			return
+		}
+
+		token := c.GetHeader("X-Admin-Token")
+		if token == "" {
+			token = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			response.Unauthorized(c, "管理令牌无效")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
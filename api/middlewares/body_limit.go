@@ -0,0 +1,34 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/drama-generator/backend/pkg/config"
+	"github.com/drama-generator/backend/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// BodySizeLimitMiddleware 限制请求体大小（limits.max_request_body_kb），文件上传（multipart）不受此限制
+func BodySizeLimitMiddleware(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := config.CurrentOr(cfg).Limits.RequestBodyBytes()
+		if limit <= 0 || c.Request.Body == nil ||
+			strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			response.Error(c, http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE",
+				fmt.Sprintf("请求体超过上限 %d KB", limit/1024))
+			c.Abort()
+			return
+		}
+
+		// 未声明长度（chunked）的请求在读取超限时报错
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
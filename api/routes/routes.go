@@ -7,6 +7,7 @@ import (
 	storage2 "github.com/drama-generator/backend/infrastructure/storage"
 	"github.com/drama-generator/backend/pkg/config"
 	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/metrics"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -29,6 +30,9 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 		})
 	})
 
+	// Prometheus 指标
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
 	aiService := services2.NewAIService(db, log)
 	localStoragePtr := localStorage.(*storage2.LocalStorage)
 	transferService := services2.NewResourceTransferService(db, log)
@@ -38,7 +42,8 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 	scriptGenHandler := handlers2.NewScriptGenerationHandler(db, cfg, log)
 	imageGenService := services2.NewImageGenerationService(db, cfg, transferService, localStoragePtr, log)
 	imageGenHandler := handlers2.NewImageGenerationHandler(db, cfg, log, transferService, localStoragePtr)
-	videoGenHandler := handlers2.NewVideoGenerationHandler(db, transferService, localStoragePtr, aiService, log, promptI18n)
+	videoGenService := services2.NewVideoGenerationService(db, transferService, localStoragePtr, aiService, log, promptI18n)
+	videoGenHandler := handlers2.NewVideoGenerationHandler(videoGenService, log)
 	videoMergeHandler := handlers2.NewVideoMergeHandler(db, nil, cfg.Storage.LocalPath, cfg.Storage.BaseURL, log)
 	assetHandler := handlers2.NewAssetHandler(db, cfg, log)
 	characterLibraryService := services2.NewCharacterLibraryService(db, log, cfg)
@@ -52,6 +57,8 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 	taskHandler := handlers2.NewTaskHandler(db, log)
 	framePromptService := services2.NewFramePromptService(db, cfg, log)
 	framePromptHandler := handlers2.NewFramePromptHandler(framePromptService, log)
+	episodePipelineHandler := handlers2.NewEpisodePipelineHandler(
+		services2.NewEpisodePipelineService(db, cfg, log, imageGenService, framePromptService, videoGenService), log)
 	audioExtractionHandler := handlers2.NewAudioExtractionHandler(log, cfg.Storage.LocalPath)
 	settingsHandler := handlers2.NewSettingsHandler(cfg, log)
 	propHandler := handlers2.NewPropHandler(db, cfg, log, aiService, imageGenService)
@@ -59,6 +66,7 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 	api := r.Group("/api/v1")
 	{
 		api.Use(middlewares2.RateLimitMiddleware())
+		api.Use(middlewares2.BodySizeLimitMiddleware(cfg))
 
 		dramas := api.Group("/dramas")
 		{
@@ -68,13 +76,24 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 			dramas.GET("/:id", dramaHandler.GetDrama)
 			dramas.PUT("/:id", dramaHandler.UpdateDrama)
 			dramas.DELETE("/:id", dramaHandler.DeleteDrama)
+			dramas.POST("/:id/clone", dramaHandler.CloneDrama)
 
 			dramas.PUT("/:id/outline", dramaHandler.SaveOutline)
 			dramas.GET("/:id/characters", dramaHandler.GetCharacters)
 			dramas.PUT("/:id/characters", dramaHandler.SaveCharacters)
+			dramas.GET("/:id/episodes", dramaHandler.ListEpisodes)
 			dramas.PUT("/:id/episodes", dramaHandler.SaveEpisodes)
+			dramas.POST("/:id/episodes/renumber", dramaHandler.RenumberEpisodes)
 			dramas.PUT("/:id/progress", dramaHandler.SaveProgress)
 			dramas.GET("/:id/props", propHandler.ListProps) // Added prop list route
+			dramas.POST("/:id/scenes/regenerate", imageGenHandler.RegenerateAllSceneImages)
+			dramas.POST("/:id/images/retry-failed", imageGenHandler.RetryFailedImages)
+			dramas.POST("/:id/storyboards/generate", storyboardHandler.GenerateStoryboardsForDrama)
+			dramas.POST("/:id/characters/generate", scriptGenHandler.GenerateCharactersForDrama)
+			dramas.GET("/:id/gallery", imageGenHandler.GetSceneGallery)
+			dramas.POST("/:id/scenes/style-check", imageGenHandler.CheckStyleConsistency)
+			dramas.GET("/:id/effective-config", imageGenHandler.GetEffectiveDramaConfig)
+			dramas.POST("/:id/scenes/prompts/bulk", sceneHandler.BulkUpdateScenePrompts)
 		}
 
 		aiConfigs := api.Group("/ai-configs")
@@ -108,6 +127,7 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 			characters.DELETE("/:id", characterLibraryHandler.DeleteCharacter)
 			characters.POST("/batch-generate-images", characterLibraryHandler.BatchGenerateCharacterImages)
 			characters.POST("/:id/generate-image", characterLibraryHandler.GenerateCharacterImage)
+			characters.POST("/:id/regenerate-portrait", characterLibraryHandler.RegenerateCharacterPortrait)
 			characters.POST("/:id/upload-image", uploadHandler.UploadCharacterImage)
 			characters.PUT("/:id/image", characterLibraryHandler.UploadCharacterImage)
 			characters.PUT("/:id/image-from-library", characterLibraryHandler.ApplyLibraryItemToCharacter)
@@ -132,11 +152,24 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 		episodes := api.Group("/episodes")
 		{
 			// 分镜头
+			episodes.PUT("/:episode_id", dramaHandler.UpdateEpisode)
 			episodes.POST("/:episode_id/storyboards", storyboardHandler.GenerateStoryboard)
+			episodes.POST("/:episode_id/storyboards/link-scenes", storyboardHandler.LinkStoryboardsToScenes)
+			episodes.POST("/:episode_id/storyboards/from-images", storyboardHandler.GenerateStoryboardFromImages)
+			episodes.POST("/:episode_id/storyboards/video-prompts", storyboardHandler.RegenerateVideoPrompts)
+			episodes.POST("/:episode_id/storyboards/bgm-prompts", storyboardHandler.EnrichBgmPrompts)
+			episodes.POST("/:episode_id/storyboards/import", storyboardHandler.ImportStoryboards)
 			episodes.POST("/:episode_id/props/extract", propHandler.ExtractProps)
 			episodes.POST("/:episode_id/characters/extract", characterLibraryHandler.ExtractCharacters)
 			episodes.GET("/:episode_id/storyboards", sceneHandler.GetStoryboardsForEpisode)
+			episodes.GET("/:episode_id/storyboard/prompt-preview", storyboardHandler.PreviewStoryboardPrompt)
+			episodes.GET("/:episode_id/frame-prompts", framePromptHandler.ListEpisodeFramePrompts)
+			episodes.POST("/:episode_id/duration/recalculate", storyboardHandler.RecalculateEpisodeDuration)
+			episodes.GET("/:episode_id/render-plan", storyboardHandler.ExportEpisodeRenderPlan)
+			episodes.GET("/:episode_id/graph", storyboardHandler.GetEpisodeGenerationGraph)
 			episodes.POST("/:episode_id/finalize", dramaHandler.FinalizeEpisode)
+			episodes.POST("/:episode_id/pipeline/resume", episodePipelineHandler.ResumeEpisodePipeline)
+			episodes.GET("/:episode_id/pipeline/estimate", episodePipelineHandler.EstimateEpisodePipeline)
 			episodes.GET("/:episode_id/download", dramaHandler.DownloadEpisodeVideo)
 		}
 
@@ -144,6 +177,7 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 		tasks := api.Group("/tasks")
 		{
 			tasks.GET("/:task_id", taskHandler.GetTaskStatus)
+			tasks.GET("/:task_id/stream", taskHandler.StreamTaskStatus)
 			tasks.GET("", taskHandler.GetResourceTasks)
 		}
 
@@ -152,6 +186,8 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 		{
 			scenes.PUT("/:scene_id", sceneHandler.UpdateScene)
 			scenes.PUT("/:scene_id/prompt", sceneHandler.UpdateScenePrompt)
+			scenes.PUT("/:scene_id/episode", sceneHandler.ReassignScene)
+			scenes.POST("/:scene_id/regenerate", sceneHandler.RegenerateSceneImage)
 			scenes.DELETE("/:scene_id", sceneHandler.DeleteScene)
 
 			scenes.POST("/generate-image", sceneHandler.GenerateSceneImage)
@@ -162,8 +198,18 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 		{
 			images.GET("", imageGenHandler.ListImageGenerations)
 			images.POST("", imageGenHandler.GenerateImage)
+			images.POST("/preview", imageGenHandler.GeneratePreview)
+			images.GET("/capabilities", imageGenHandler.GetProviderCapabilities) // 放在/:id之前
+			images.GET("/status", imageGenHandler.GetImageGenerationStatuses)
+			images.GET("/history/:entity_type/:entity_id", imageGenHandler.ListGenerationHistory)
+			images.POST("/history/:id/restore", imageGenHandler.RestoreGenerationHistory)
+			images.GET("/compare", imageGenHandler.CompareImageGenerations)
+			images.GET("/export", imageGenHandler.ExportImagesZip)
+			images.GET("/batches/:batch_id", imageGenHandler.ListImageBatch)
 			images.GET("/:id", imageGenHandler.GetImageGeneration)
 			images.DELETE("/:id", imageGenHandler.DeleteImageGeneration)
+			images.POST("/:id/retry", imageGenHandler.RetryImageGeneration)
+			images.POST("/:id/resume", imageGenHandler.ResumeImagePolling)
 			images.POST("/scene/:scene_id", imageGenHandler.GenerateImagesForScene)
 			images.POST("/upload", imageGenHandler.UploadImage)
 			images.GET("/episode/:episode_id/backgrounds", imageGenHandler.GetBackgroundsForEpisode)
@@ -204,11 +250,15 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 		{
 			storyboards.GET("/episode/:episode_id/generate", storyboardHandler.GenerateStoryboard)
 			storyboards.POST("", storyboardHandler.CreateStoryboard)
+			storyboards.PUT("/batch/characters", storyboardHandler.BulkUpdateStoryboardCharacters)
 			storyboards.PUT("/:id", storyboardHandler.UpdateStoryboard)
 			storyboards.DELETE("/:id", storyboardHandler.DeleteStoryboard)
+			storyboards.PATCH("/:id/lock", storyboardHandler.SetStoryboardLocked)
+			storyboards.POST("/:id/refresh-prompts", storyboardHandler.RefreshStoryboardPrompts)
 			storyboards.POST("/:id/props", propHandler.AssociateProps)
 			storyboards.POST("/:id/frame-prompt", framePromptHandler.GenerateFramePrompt)
-			storyboards.GET("/:id/frame-prompts", handlers2.GetStoryboardFramePrompts(db, log))
+			storyboards.GET("/:id/frame-prompts", framePromptHandler.ListStoryboardFramePrompts)
+			storyboards.POST("/:id/composite-panels", storyboardHandler.CompositePanels)
 		}
 
 		audio := api.Group("/audio")
@@ -217,6 +267,12 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 			audio.POST("/extract/batch", audioExtractionHandler.BatchExtractAudio)
 		}
 
+		admin := api.Group("/admin")
+		admin.Use(middlewares2.AdminAuthMiddleware(cfg))
+		{
+			admin.POST("/config/reload", settingsHandler.ReloadConfig)
+		}
+
 		settings := api.Group("/settings")
 		{
 			settings.GET("/language", settingsHandler.GetLanguage)
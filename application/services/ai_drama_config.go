@@ -0,0 +1,135 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/ai"
+)
+
+// dramaPinnedConfig 剧本绑定的指定类型AI配置，未绑定时返回 nil；绑定的配置被删除或停用时返回错误，不会回退到全局默认配置
+func (s *AIService) dramaPinnedConfig(serviceType string, dramaID uint) (*models.AIServiceConfig, error) {
+	var drama models.Drama
+	if err := s.db.Select("id", "text_config_id", "image_config_id").Where("id = ?", dramaID).First(&drama).Error; err != nil {
+		return nil, nil
+	}
+
+	var configID *uint
+	switch serviceType {
+	case "text":
+		configID = drama.TextConfigID
+	case "image":
+		configID = drama.ImageConfigID
+	}
+	if configID == nil {
+		return nil, nil
+	}
+
+	var config models.AIServiceConfig
+	if err := s.db.Where("id = ? AND service_type = ? AND is_active = ?", *configID, serviceType, true).
+		First(&config).Error; err != nil {
+		return nil, fmt.Errorf("剧本绑定的%s配置(ID %d)不存在或未启用", serviceType, *configID)
+	}
+	return &config, nil
+}
+
+// pinnedModel 绑定配置下实际使用的模型：请求的模型属于该配置时使用请求的模型，否则使用配置的第一个模型
+func pinnedModel(config *models.AIServiceConfig, model string) string {
+	for _, m := range config.Model {
+		if m == model {
+			return model
+		}
+	}
+	if len(config.Model) > 0 {
+		return config.Model[0]
+	}
+	return model
+}
+
+// GetAIClientForDrama 获取剧本使用的AI客户端，并返回实际使用的模型名
+// 剧本绑定了配置时始终使用该配置；否则 model 非空时按模型查找配置，找不到时回退默认配置
+func (s *AIService) GetAIClientForDrama(serviceType string, dramaID uint, model string) (ai.AIClient, string, error) {
+	pinned, err := s.dramaPinnedConfig(serviceType, dramaID)
+	if err != nil {
+		return nil, "", err
+	}
+	if pinned != nil {
+		model = pinnedModel(pinned, model)
+		s.log.Debugw("Using drama pinned AI config", "service_type", serviceType, "drama_id", dramaID, "config_id", pinned.ID, "model", model)
+		return s.newAIClient(pinned, model, serviceType), model, nil
+	}
+
+	if model != "" {
+		client, err := s.GetAIClientForModel(serviceType, model)
+		if err == nil {
+			return client, model, nil
+		}
+		s.log.Warnw("Failed to get client for specified model, using default", "model", model, "error", err)
+	}
+
+	config, err := s.GetDefaultConfig(serviceType)
+	if err != nil {
+		return nil, "", err
+	}
+	model = ""
+	if len(config.Model) > 0 {
+		model = config.Model[0]
+	}
+	return s.newAIClient(config, model, serviceType), model, nil
+}
+
+// GetVisionClientForDrama 获取剧本使用的视觉客户端：剧本绑定了文本配置时使用该配置，否则同 GetVisionClient
+func (s *AIService) GetVisionClientForDrama(dramaID uint, model string) (ai.VisionClient, error) {
+	pinned, err := s.dramaPinnedConfig("text", dramaID)
+	if err != nil {
+		return nil, err
+	}
+	if pinned == nil {
+		return s.GetVisionClient(model)
+	}
+
+	model = pinnedModel(pinned, model)
+	if !SupportsVision(pinned, model) {
+		return nil, fmt.Errorf("剧本绑定的文本模型不支持图片输入: %s", model)
+	}
+	return s.newAIClient(pinned, model, "text"), nil
+}
+
+// GenerateTextForDrama 用剧本的文本配置生成文本，选择规则同 GetAIClientForDrama
+func (s *AIService) GenerateTextForDrama(dramaID uint, model, prompt, systemPrompt string, options ...func(*ai.ChatCompletionRequest)) (string, error) {
+	client, _, err := s.GetAIClientForDrama("text", dramaID, model)
+	if err != nil {
+		return "", fmt.Errorf("failed to get AI client: %w", err)
+	}
+	return client.GenerateText(prompt, systemPrompt, options...)
+}
+
+// validatePinnedConfig 校验要绑定到剧本的配置存在、已启用且类型匹配
+func (s *DramaService) validatePinnedConfig(configID uint, serviceType string) error {
+	var count int64
+	if err := s.db.Model(&models.AIServiceConfig{}).
+		Where("id = ? AND service_type = ? AND is_active = ?", configID, serviceType, true).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf("%s配置(ID %d)不存在或未启用", serviceType, configID)}
+	}
+	return nil
+}
+
+// applyPinnedConfig 将绑定配置的变更写入 updates：nil 不修改，0 解除绑定，其余值校验后绑定
+func (s *DramaService) applyPinnedConfig(updates map[string]interface{}, column, serviceType string, configID *uint) error {
+	if configID == nil {
+		return nil
+	}
+	if *configID == 0 {
+		updates[column] = nil
+		return nil
+	}
+	if err := s.validatePinnedConfig(*configID, serviceType); err != nil {
+		return err
+	}
+	updates[column] = *configID
+	return nil
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/config"
+)
+
+// resolveProviderModel 确定调用服务商时使用的模型并校验：
+// 指定了模型时必须在配置的模型列表中；未指定时依次使用 ai.default_models 中该服务商的默认模型、配置的第一个模型
+// 查找默认模型前先按 provider_aliases 和内置别名解析出规范的服务商名称
+// 都没有时返回明确的配置错误，避免把空模型名交给服务商
+func resolveProviderModel(aiConfig *models.AIServiceConfig, provider string, requested string) (string, error) {
+	if requested != "" {
+		if len(aiConfig.Model) > 0 && !modelInList(aiConfig.Model, requested) {
+			return "", &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf(
+				"model %q is not configured for provider %s (config %q), available: %s",
+				requested, provider, aiConfig.Name, strings.Join(aiConfig.Model, ", "))}
+		}
+		return requested, nil
+	}
+
+	var aliases map[string]string
+	cfg := config.Current()
+	if cfg != nil {
+		aliases = cfg.AI.ProviderAliases
+	}
+	canonical := resolveProviderAlias(provider, aliases)
+
+	if cfg != nil {
+		model := cfg.AI.DefaultModels[canonical]
+		if model == "" {
+			model = cfg.AI.DefaultModels[strings.ToLower(provider)]
+		}
+		if model != "" {
+			if len(aiConfig.Model) > 0 && !modelInList(aiConfig.Model, model) {
+				return "", &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf(
+					"default model %q for provider %s is not in config %q, available: %s",
+					model, provider, aiConfig.Name, strings.Join(aiConfig.Model, ", "))}
+			}
+			return model, nil
+		}
+	}
+
+	if len(aiConfig.Model) > 0 {
+		return aiConfig.Model[0], nil
+	}
+	return "", &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf(
+		"no model configured for provider %s: add a model to config %q or set ai.default_models.%s",
+		provider, aiConfig.Name, canonical)}
+}
+
+// modelInList 判断模型是否在配置的模型列表中
+func modelInList(list []string, model string) bool {
+	for _, m := range list {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/config"
+)
+
+func TestResolveProviderModel(t *testing.T) {
+	previous := config.Current()
+	t.Cleanup(func() { config.Store(previous) })
+
+	cfg := &config.Config{}
+	cfg.AI.ProviderAliases = map[string]string{"my-ark-proxy": "volcengine"}
+	cfg.AI.DefaultModels = map[string]string{"volcengine": "doubao-seedream", "openai": "dall-e-3"}
+	config.Store(cfg)
+
+	tests := []struct {
+		name      string
+		provider  string
+		models    []string
+		requested string
+		want      string
+		wantErr   bool
+	}{
+		{"requested in list", "openai", []string{"gpt-image-1", "dall-e-3"}, "gpt-image-1", "gpt-image-1", false},
+		{"requested without list", "openai", nil, "gpt-image-1", "gpt-image-1", false},
+		{"requested not in list", "openai", []string{"dall-e-3"}, "gpt-image-1", "", true},
+		{"default model via alias", "my-ark-proxy", []string{"other", "doubao-seedream"}, "", "doubao-seedream", false},
+		{"default model not in list", "openai", []string{"gpt-image-1"}, "", "", true},
+		{"first configured model", "gemini", []string{"imagen-3", "imagen-4"}, "", "imagen-3", false},
+		{"no model", "gemini", nil, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aiConfig := &models.AIServiceConfig{Name: "test", Provider: tt.provider, Model: tt.models}
+			got, err := resolveProviderModel(aiConfig, tt.provider, tt.requested)
+			if tt.wantErr {
+				var serviceErr *ServiceError
+				if !errors.As(err, &serviceErr) || serviceErr.Kind != ErrInvalidInput {
+					t.Fatalf("err = %v, want invalid input ServiceError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("model = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drama-generator/backend/pkg/ai"
+	"github.com/drama-generator/backend/pkg/config"
+	"github.com/drama-generator/backend/pkg/logger"
+)
+
+// defaultRateLimitKey ai.text_rate_limits 中对未单独配置的服务商生效的 key
+const defaultRateLimitKey = "default"
+
+// tokenBucket 单个服务商的令牌桶，按预约方式排队：令牌不足时返回需要等待的时长而不是失败
+type tokenBucket struct {
+	mu       sync.Mutex
+	rpm      int
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(rpm int) *tokenBucket {
+	// 允许短时突发约 10 秒的请求量
+	capacity := float64(rpm) / 6
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &tokenBucket{rpm: rpm, capacity: capacity, tokens: capacity, last: time.Now()}
+}
+
+// reserve 预约一个令牌，返回调用方需要等待的时长；令牌可以透支，后来者依次排在后面
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	perSecond := float64(b.rpm) / 60
+	b.tokens += now.Sub(b.last).Seconds() * perSecond
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / perSecond * float64(time.Second))
+}
+
+// textRateLimiter 所有 AIService 实例共享的文本模型限流器，按服务商分桶
+type textRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+var sharedTextRateLimiter = &textRateLimiter{buckets: make(map[string]*tokenBucket)}
+
+// rpmFor 读取服务商的每分钟请求数上限，0 表示不限流
+func rpmFor(provider string) int {
+	cfg := config.Current()
+	if cfg == nil {
+		return 0
+	}
+	if rpm, ok := cfg.AI.TextRateLimits[strings.ToLower(provider)]; ok {
+		return rpm
+	}
+	return cfg.AI.TextRateLimits[defaultRateLimitKey]
+}
+
+// wait 按服务商限流，令牌不足时排队等待
+func (l *textRateLimiter) wait(provider string) time.Duration {
+	rpm := rpmFor(provider)
+	if rpm <= 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	bucket, ok := l.buckets[provider]
+	// 配置热更新后按新的速率重建
+	if !ok || bucket.rpm != rpm {
+		bucket = newTokenBucket(rpm)
+		l.buckets[provider] = bucket
+	}
+	l.mu.Unlock()
+
+	delay := bucket.reserve()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return delay
+}
+
+// rateLimitedClient 在文本生成调用前经过共享限流器的 AI 客户端
+type rateLimitedClient struct {
+	ai.AIClient
+	provider string
+	log      *logger.Logger
+}
+
+// withTextRateLimit 为文本客户端加上按服务商的限流
+func withTextRateLimit(client ai.AIClient, provider string, log *logger.Logger) ai.AIClient {
+	return &rateLimitedClient{AIClient: client, provider: provider, log: log}
+}
+
+func (c *rateLimitedClient) throttle() {
+	if waited := sharedTextRateLimiter.wait(c.provider); waited > 0 {
+		c.log.Debugw("Text AI request throttled", "provider", c.provider, "waited_ms", waited.Milliseconds())
+	}
+}
+
+func (c *rateLimitedClient) GenerateText(prompt string, systemPrompt string, options ...func(*ai.ChatCompletionRequest)) (string, error) {
+	c.throttle()
+	return c.AIClient.GenerateText(prompt, systemPrompt, options...)
+}
+
+func (c *rateLimitedClient) GenerateTextWithImages(prompt string, systemPrompt string, images []string, options ...func(*ai.ChatCompletionRequest)) (string, error) {
+	c.throttle()
+	return c.AIClient.GenerateTextWithImages(prompt, systemPrompt, images, options...)
+}
@@ -3,62 +3,79 @@ package services
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/drama-generator/backend/domain/models"
 	"github.com/drama-generator/backend/pkg/ai"
+	"github.com/drama-generator/backend/pkg/config"
 	"github.com/drama-generator/backend/pkg/logger"
 	"gorm.io/gorm"
 )
 
 type AIService struct {
-	db  *gorm.DB
-	log *logger.Logger
+	db          *gorm.DB
+	log         *logger.Logger
+	taskService *TaskService
 }
 
 func NewAIService(db *gorm.DB, log *logger.Logger) *AIService {
 	return &AIService{
-		db:  db,
-		log: log,
+		db:          db,
+		log:         log,
+		taskService: NewTaskService(db, log),
 	}
 }
 
 type CreateAIConfigRequest struct {
-	ServiceType   string            `json:"service_type" binding:"required,oneof=text image video"`
-	Name          string            `json:"name" binding:"required,min=1,max=100"`
-	Provider      string            `json:"provider" binding:"required"`
-	BaseURL       string            `json:"base_url" binding:"required,url"`
-	APIKey        string            `json:"api_key" binding:"required"`
-	Model         models.ModelField `json:"model" binding:"required"`
-	Endpoint      string            `json:"endpoint"`
-	QueryEndpoint string            `json:"query_endpoint"`
-	Priority      int               `json:"priority"`
-	IsDefault     bool              `json:"is_default"`
-	Settings      string            `json:"settings"`
+	ServiceType    string            `json:"service_type" binding:"required,oneof=text image video embedding"`
+	Name           string            `json:"name" binding:"required,min=1,max=100"`
+	Provider       string            `json:"provider" binding:"required"`
+	BaseURL        string            `json:"base_url" binding:"required,url"`
+	APIKey         string            `json:"api_key" binding:"required"`
+	Model          models.ModelField `json:"model" binding:"required"`
+	Endpoint       string            `json:"endpoint"`
+	QueryEndpoint  string            `json:"query_endpoint"`
+	Priority       int               `json:"priority"`
+	Weight         *int              `json:"weight" binding:"omitempty,min=0"`
+	IsDefault      bool              `json:"is_default"`
+	Settings       string            `json:"settings"`
+	ExtraHeaders   map[string]string `json:"extra_headers"`
+	SafetySettings map[string]string `json:"safety_settings"`
+	VisionSupport  *bool             `json:"vision_support"`
 }
 
 type UpdateAIConfigRequest struct {
-	Name          string             `json:"name" binding:"omitempty,min=1,max=100"`
-	Provider      string             `json:"provider"`
-	BaseURL       string             `json:"base_url" binding:"omitempty,url"`
-	APIKey        string             `json:"api_key"`
-	Model         *models.ModelField `json:"model"`
-	Endpoint      string             `json:"endpoint"`
-	QueryEndpoint string             `json:"query_endpoint"`
-	Priority      *int               `json:"priority"`
-	IsDefault     bool               `json:"is_default"`
-	IsActive      bool               `json:"is_active"`
-	Settings      string             `json:"settings"`
+	Name           string             `json:"name" binding:"omitempty,min=1,max=100"`
+	Provider       string             `json:"provider"`
+	BaseURL        string             `json:"base_url" binding:"omitempty,url"`
+	APIKey         string             `json:"api_key"`
+	Model          *models.ModelField `json:"model"`
+	Endpoint       string             `json:"endpoint"`
+	QueryEndpoint  string             `json:"query_endpoint"`
+	Priority       *int               `json:"priority"`
+	Weight         *int               `json:"weight" binding:"omitempty,min=0"`
+	IsDefault      bool               `json:"is_default"`
+	IsActive       bool               `json:"is_active"`
+	Settings       string             `json:"settings"`
+	ExtraHeaders   map[string]string  `json:"extra_headers"`
+	SafetySettings map[string]string  `json:"safety_settings"`
+	VisionSupport  *bool              `json:"vision_support"`
 }
 
 type TestConnectionRequest struct {
-	BaseURL  string            `json:"base_url" binding:"required,url"`
-	APIKey   string            `json:"api_key" binding:"required"`
-	Model    models.ModelField `json:"model" binding:"required"`
-	Provider string            `json:"provider"`
-	Endpoint string            `json:"endpoint"`
+	BaseURL      string            `json:"base_url" binding:"required,url"`
+	APIKey       string            `json:"api_key" binding:"required"`
+	Model        models.ModelField `json:"model" binding:"required"`
+	Provider     string            `json:"provider"`
+	Endpoint     string            `json:"endpoint"`
+	ExtraHeaders map[string]string `json:"extra_headers"`
 }
 
 func (s *AIService) CreateConfig(req *CreateAIConfigRequest) (*models.AIServiceConfig, error) {
+	if err := validateSafetySettings(req.SafetySettings); err != nil {
+		return nil, err
+	}
+
 	// 根据 provider 和 service_type 自动设置 endpoint
 	endpoint := req.Endpoint
 	queryEndpoint := req.QueryEndpoint
@@ -76,6 +93,8 @@ func (s *AIService) CreateConfig(req *CreateAIConfigRequest) (*models.AIServiceC
 				endpoint = "/chat/completions"
 			} else if req.ServiceType == "image" {
 				endpoint = "/images/generations"
+			} else if req.ServiceType == "embedding" {
+				endpoint = "/embeddings"
 			} else if req.ServiceType == "video" {
 				endpoint = "/videos"
 				if queryEndpoint == "" {
@@ -87,6 +106,8 @@ func (s *AIService) CreateConfig(req *CreateAIConfigRequest) (*models.AIServiceC
 				endpoint = "/chat/completions"
 			} else if req.ServiceType == "image" {
 				endpoint = "/images/generations"
+			} else if req.ServiceType == "embedding" {
+				endpoint = "/embeddings"
 			} else if req.ServiceType == "video" {
 				endpoint = "/video/generations"
 				if queryEndpoint == "" {
@@ -106,23 +127,33 @@ func (s *AIService) CreateConfig(req *CreateAIConfigRequest) (*models.AIServiceC
 				endpoint = "/chat/completions"
 			} else if req.ServiceType == "image" {
 				endpoint = "/images/generations"
+			} else if req.ServiceType == "embedding" {
+				endpoint = "/embeddings"
 			}
 		}
 	}
 
 	config := &models.AIServiceConfig{
-		ServiceType:   req.ServiceType,
-		Name:          req.Name,
-		Provider:      req.Provider,
-		BaseURL:       req.BaseURL,
-		APIKey:        req.APIKey,
-		Model:         req.Model,
-		Endpoint:      endpoint,
-		QueryEndpoint: queryEndpoint,
-		Priority:      req.Priority,
-		IsDefault:     req.IsDefault,
-		IsActive:      true,
-		Settings:      req.Settings,
+		ServiceType:    req.ServiceType,
+		Name:           req.Name,
+		Provider:       req.Provider,
+		BaseURL:        req.BaseURL,
+		APIKey:         req.APIKey,
+		Model:          req.Model,
+		Endpoint:       endpoint,
+		QueryEndpoint:  queryEndpoint,
+		Priority:       req.Priority,
+		IsDefault:      req.IsDefault,
+		IsActive:       true,
+		Settings:       req.Settings,
+		ExtraHeaders:   req.ExtraHeaders,
+		SafetySettings: req.SafetySettings,
+		VisionSupport:  req.VisionSupport,
+	}
+	// 未指定权重时默认为 1；不使用数据库默认值，否则显式传入的 0 会被替换为默认值
+	config.Weight = 1
+	if req.Weight != nil {
+		config.Weight = *req.Weight
 	}
 
 	if err := s.db.Create(config).Error; err != nil {
@@ -131,7 +162,7 @@ func (s *AIService) CreateConfig(req *CreateAIConfigRequest) (*models.AIServiceC
 	}
 
 	s.log.Infow("AI config created", "config_id", config.ID, "provider", req.Provider, "endpoint", endpoint)
-	return config, nil
+	return maskExtraHeaders(config), nil
 }
 
 func (s *AIService) GetConfig(configID uint) (*models.AIServiceConfig, error) {
@@ -139,11 +170,11 @@ func (s *AIService) GetConfig(configID uint) (*models.AIServiceConfig, error) {
 	err := s.db.Where("id = ? ", configID).First(&config).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("config not found")
+			return nil, ErrConfigNotFound
 		}
 		return nil, err
 	}
-	return &config, nil
+	return maskExtraHeaders(&config), nil
 }
 
 func (s *AIService) ListConfigs(serviceType string) ([]models.AIServiceConfig, error) {
@@ -160,14 +191,21 @@ func (s *AIService) ListConfigs(serviceType string) ([]models.AIServiceConfig, e
 		return nil, err
 	}
 
+	for i := range configs {
+		maskExtraHeaders(&configs[i])
+	}
 	return configs, nil
 }
 
 func (s *AIService) UpdateConfig(configID uint, req *UpdateAIConfigRequest) (*models.AIServiceConfig, error) {
+	if err := validateSafetySettings(req.SafetySettings); err != nil {
+		return nil, err
+	}
+
 	var config models.AIServiceConfig
 	if err := s.db.Where("id = ? ", configID).First(&config).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("config not found")
+			return nil, ErrConfigNotFound
 		}
 		return nil, err
 	}
@@ -195,6 +233,9 @@ func (s *AIService) UpdateConfig(configID uint, req *UpdateAIConfigRequest) (*mo
 	if req.Priority != nil {
 		updates["priority"] = *req.Priority
 	}
+	if req.Weight != nil {
+		updates["weight"] = *req.Weight
+	}
 
 	// 如果提供了 provider，根据 provider 和 service_type 自动设置 endpoint
 	if req.Provider != "" && req.Endpoint == "" {
@@ -234,6 +275,27 @@ func (s *AIService) UpdateConfig(configID uint, req *UpdateAIConfigRequest) (*mo
 	if req.Settings != "" {
 		updates["settings"] = req.Settings
 	}
+	if req.ExtraHeaders != nil {
+		// 传空对象可清空附加请求头；值为遮盖占位符时保留原值
+		config.ExtraHeaders = unmaskExtraHeaders(req.ExtraHeaders, config.ExtraHeaders)
+		if err := tx.Model(&config).Select("extra_headers").Updates(&config).Error; err != nil {
+			tx.Rollback()
+			s.log.Errorw("Failed to update AI config extra headers", "error", err)
+			return nil, err
+		}
+	}
+	if req.SafetySettings != nil {
+		// 传空对象可恢复服务端默认阈值
+		config.SafetySettings = req.SafetySettings
+		if err := tx.Model(&config).Select("safety_settings").Updates(&config).Error; err != nil {
+			tx.Rollback()
+			s.log.Errorw("Failed to update AI config safety settings", "error", err)
+			return nil, err
+		}
+	}
+	if req.VisionSupport != nil {
+		updates["vision_support"] = *req.VisionSupport
+	}
 	updates["is_default"] = req.IsDefault
 	updates["is_active"] = req.IsActive
 
@@ -248,7 +310,39 @@ func (s *AIService) UpdateConfig(configID uint, req *UpdateAIConfigRequest) (*mo
 	}
 
 	s.log.Infow("AI config updated", "config_id", configID)
-	return &config, nil
+	return maskExtraHeaders(&config), nil
+}
+
+// maskedHeaderValue 接口响应中附加请求头值的占位符
+const maskedHeaderValue = "******"
+
+// maskExtraHeaders 遮盖配置中附加请求头的值，附加请求头常用于传递凭证，不在接口响应中返回原值
+func maskExtraHeaders(config *models.AIServiceConfig) *models.AIServiceConfig {
+	if len(config.ExtraHeaders) == 0 {
+		return config
+	}
+	masked := make(map[string]string, len(config.ExtraHeaders))
+	for name := range config.ExtraHeaders {
+		masked[name] = maskedHeaderValue
+	}
+	config.ExtraHeaders = masked
+	return config
+}
+
+// unmaskExtraHeaders 将更新请求中原样传回的占位符还原为已保存的值，已保存配置中不存在的请求头丢弃
+func unmaskExtraHeaders(headers, stored map[string]string) map[string]string {
+	result := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if value == maskedHeaderValue {
+			old, ok := stored[name]
+			if !ok {
+				continue
+			}
+			value = old
+		}
+		result[name] = value
+	}
+	return result
 }
 
 func (s *AIService) DeleteConfig(configID uint) error {
@@ -260,7 +354,7 @@ func (s *AIService) DeleteConfig(configID uint) error {
 	}
 
 	if result.RowsAffected == 0 {
-		return errors.New("config not found")
+		return ErrConfigNotFound
 	}
 
 	s.log.Infow("AI config deleted", "config_id", configID)
@@ -305,6 +399,8 @@ func (s *AIService) TestConnection(req *TestConnectionRequest) error {
 		client = ai.NewOpenAIClient(req.BaseURL, req.APIKey, model, endpoint)
 	}
 
+	applyExtraHeaders(client, req.ExtraHeaders)
+
 	s.log.Infow("Calling TestConnection on client", "endpoint", endpoint)
 	err := client.TestConnection()
 	if err != nil {
@@ -316,20 +412,35 @@ func (s *AIService) TestConnection(req *TestConnectionRequest) error {
 }
 
 func (s *AIService) GetDefaultConfig(serviceType string) (*models.AIServiceConfig, error) {
-	var config models.AIServiceConfig
-	// 按优先级降序获取第一个激活的配置
+	return s.selectConfig(serviceType, "")
+}
+
+// GetStickyConfig 获取默认配置，加权选择模式下同一 stickyKey（如任务ID）始终落到同一配置
+// 用于提交后还需轮询结果的任务，避免提交和查询打到不同服务商
+func (s *AIService) GetStickyConfig(serviceType string, stickyKey string) (*models.AIServiceConfig, error) {
+	return s.selectConfig(serviceType, stickyKey)
+}
+
+// selectConfig 按 ai.model_selection 选择配置：priority 取优先级最高的一个，weighted 按权重在所有激活配置中分配
+func (s *AIService) selectConfig(serviceType string, stickyKey string) (*models.AIServiceConfig, error) {
+	var configs []models.AIServiceConfig
 	err := s.db.Where("service_type = ? AND is_active = ?", serviceType, true).
 		Order("priority DESC, created_at DESC").
-		First(&config).Error
-
+		Find(&configs).Error
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("no active config found")
-		}
 		return nil, err
 	}
+	if len(configs) == 0 {
+		return nil, errors.New("no active config found")
+	}
+
+	if cfg := config.Current(); cfg == nil || cfg.AI.ModelSelection != ModelSelectionWeighted {
+		return &configs[0], nil
+	}
 
-	return &config, nil
+	selected := pickWeightedConfig(configs, stickyKey)
+	s.log.Debugw("Weighted config selected", "service_type", serviceType, "config_id", selected.ID, "provider", selected.Provider, "sticky_key", stickyKey)
+	return selected, nil
 }
 
 // GetConfigForModel 根据服务类型和模型名称获取优先级最高的激活配置
@@ -367,6 +478,11 @@ func (s *AIService) GetAIClient(serviceType string) (ai.AIClient, error) {
 		model = config.Model[0]
 	}
 
+	return s.newAIClient(config, model, serviceType), nil
+}
+
+// newAIClient 按配置和模型创建AI客户端，文本客户端经过服务商限流
+func (s *AIService) newAIClient(config *models.AIServiceConfig, model string, serviceType string) ai.AIClient {
 	// 使用数据库配置中的 endpoint，如果为空则根据 provider 设置默认值
 	endpoint := config.Endpoint
 	if endpoint == "" {
@@ -379,13 +495,21 @@ func (s *AIService) GetAIClient(serviceType string) (ai.AIClient, error) {
 	}
 
 	// 根据 provider 创建对应的客户端
+	var client ai.AIClient
 	switch config.Provider {
 	case "gemini", "google":
-		return ai.NewGeminiClient(config.BaseURL, config.APIKey, model, endpoint), nil
+		client = ai.NewGeminiClient(config.BaseURL, config.APIKey, model, endpoint)
 	default:
 		// openai, chatfire 等其他厂商都使用 OpenAI 格式
-		return ai.NewOpenAIClient(config.BaseURL, config.APIKey, model, endpoint), nil
+		client = ai.NewOpenAIClient(config.BaseURL, config.APIKey, model, endpoint)
 	}
+	applyExtraHeaders(client, config.ExtraHeaders)
+	applySafetySettings(client, config.SafetySettings)
+
+	if serviceType == "text" {
+		return withTextRateLimit(client, config.Provider, s.log)
+	}
+	return client
 }
 
 // GetAIClientForModel 根据服务类型和模型名称获取对应的AI客户端
@@ -394,25 +518,83 @@ func (s *AIService) GetAIClientForModel(serviceType string, modelName string) (a
 	if err != nil {
 		return nil, err
 	}
+	return s.newAIClient(config, modelName, serviceType), nil
+}
 
-	// 使用数据库配置中的 endpoint，如果为空则根据 provider 设置默认值
-	endpoint := config.Endpoint
-	if endpoint == "" {
-		switch config.Provider {
-		case "gemini", "google":
-			endpoint = "/v1beta/models/{model}:generateContent"
-		default:
-			endpoint = "/chat/completions"
+// GetVisionClient 获取支持图片输入的文本客户端，model 为空时使用默认文本配置
+func (s *AIService) GetVisionClient(model string) (ai.VisionClient, error) {
+	var config *models.AIServiceConfig
+	var err error
+	if model != "" {
+		config, err = s.GetConfigForModel("text", model)
+	} else {
+		config, err = s.GetDefaultConfig("text")
+		if err == nil && len(config.Model) > 0 {
+			model = config.Model[0]
 		}
 	}
+	if err != nil {
+		return nil, err
+	}
 
-	// 根据 provider 创建对应的客户端
-	switch config.Provider {
-	case "gemini", "google":
-		return ai.NewGeminiClient(config.BaseURL, config.APIKey, modelName, endpoint), nil
-	default:
-		// openai, chatfire 等其他厂商都使用 OpenAI 格式
-		return ai.NewOpenAIClient(config.BaseURL, config.APIKey, modelName, endpoint), nil
+	if !SupportsVision(config, model) {
+		return nil, fmt.Errorf("当前文本模型不支持图片输入: %s", model)
+	}
+
+	if model != "" {
+		return s.GetAIClientForModel("text", model)
+	}
+	return s.GetAIClient("text")
+}
+
+// SupportsVision 判断配置下的模型是否支持图片输入：优先使用配置中的 vision_support，否则按模型名判断
+func SupportsVision(config *models.AIServiceConfig, model string) bool {
+	if config.VisionSupport != nil {
+		return *config.VisionSupport
+	}
+	return ai.ModelSupportsVision(model)
+}
+
+// geminiSafetyThresholds Gemini 支持的拦截阈值
+var geminiSafetyThresholds = map[string]bool{
+	"HARM_BLOCK_THRESHOLD_UNSPECIFIED": true,
+	"BLOCK_LOW_AND_ABOVE":              true,
+	"BLOCK_MEDIUM_AND_ABOVE":           true,
+	"BLOCK_ONLY_HIGH":                  true,
+	"BLOCK_NONE":                       true,
+	"OFF":                              true,
+}
+
+// validateSafetySettings 校验安全过滤配置：类别需为 HARM_CATEGORY_*，阈值需为 Gemini 支持的取值
+func validateSafetySettings(settings map[string]string) error {
+	for category, threshold := range settings {
+		if !strings.HasPrefix(category, "HARM_CATEGORY_") {
+			return &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf("无效的安全类别: %s", category)}
+		}
+		if !geminiSafetyThresholds[threshold] {
+			return &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf("无效的安全阈值: %s", threshold)}
+		}
+	}
+	return nil
+}
+
+// applySafetySettings 为支持安全过滤配置的客户端（Gemini 文本、图片）附加配置中的阈值
+func applySafetySettings(client interface{}, settings map[string]string) {
+	if len(settings) == 0 {
+		return
+	}
+	if setter, ok := client.(interface{ SetSafetySettings(map[string]string) }); ok {
+		setter.SetSafetySettings(settings)
+	}
+}
+
+// applyExtraHeaders 为支持自定义请求头的客户端（文本、图片、向量）附加配置中的请求头
+func applyExtraHeaders(client interface{}, headers map[string]string) {
+	if len(headers) == 0 {
+		return
+	}
+	if setter, ok := client.(interface{ SetExtraHeaders(map[string]string) }); ok {
+		setter.SetExtraHeaders(headers)
 	}
 }
 
@@ -425,6 +607,33 @@ func (s *AIService) GenerateText(prompt string, systemPrompt string, options ...
 	return client.GenerateText(prompt, systemPrompt, options...)
 }
 
+// GetEmbeddingClient 获取默认的向量化客户端（OpenAI 兼容格式）
+func (s *AIService) GetEmbeddingClient() (ai.EmbeddingClient, error) {
+	config, err := s.GetDefaultConfig("embedding")
+	if err != nil {
+		return nil, err
+	}
+
+	model := ""
+	if len(config.Model) > 0 {
+		model = config.Model[0]
+	}
+
+	client := ai.NewOpenAIEmbeddingClient(config.BaseURL, config.APIKey, model, config.Endpoint)
+	client.SetExtraHeaders(config.ExtraHeaders)
+
+	return client, nil
+}
+
+func (s *AIService) CreateEmbeddings(inputs []string) ([][]float64, error) {
+	client, err := s.GetEmbeddingClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get embedding client: %w", err)
+	}
+
+	return client.CreateEmbeddings(inputs)
+}
+
 func (s *AIService) GenerateImage(prompt string, size string, n int) ([]string, error) {
 	client, err := s.GetAIClient("image")
 	if err != nil {
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/drama-generator/backend/pkg/ai"
+	"github.com/drama-generator/backend/pkg/config"
+	"github.com/drama-generator/backend/pkg/utils"
+)
+
+// defaultJSONRepairAttempts AI输出无法解析为JSON时默认请求修复的次数
+const defaultJSONRepairAttempts = 2
+
+// defaultMaxResponseKB 解析AI输出前默认允许的最大KB数
+const defaultMaxResponseKB = 1024
+
+// maxResponseBytes 配置的AI输出大小上限（字节），0 表示不限制
+func maxResponseBytes() int {
+	limit := 0
+	if cfg := config.Current(); cfg != nil {
+		limit = cfg.AI.MaxResponseKB
+	}
+	if limit == 0 {
+		limit = defaultMaxResponseKB
+	}
+	return max(limit, 0) * 1024
+}
+
+// checkResponseSize 解析前检查AI输出大小，超过 ai.max_response_kb 时记录大小并返回错误
+func (s *AIService) checkResponseSize(text string) error {
+	limit := maxResponseBytes()
+	if limit == 0 || len(text) <= limit {
+		return nil
+	}
+	s.log.Errorw("AI response too large, rejected before parsing",
+		"response_bytes", len(text),
+		"limit_bytes", limit)
+	return fmt.Errorf("AI返回内容过大（%d KB），超过上限 %d KB", len(text)/1024, limit/1024)
+}
+
+// textGenerator 能生成文本的AI客户端，用于请求修复输出
+type textGenerator interface {
+	GenerateText(prompt string, systemPrompt string, options ...func(*ai.ChatCompletionRequest)) (string, error)
+}
+
+// jsonRepairAttempts 配置的修复次数，0 使用默认值，负数关闭修复
+func jsonRepairAttempts() int {
+	attempts := 0
+	if cfg := config.Current(); cfg != nil {
+		attempts = cfg.AI.JSONRepairAttempts
+	}
+	if attempts == 0 {
+		return defaultJSONRepairAttempts
+	}
+	return max(attempts, 0)
+}
+
+// parseAIJSON 使用 SafeParseAIJSON 将AI输出解析为 T
+func parseAIJSON[T any](text string) (T, error) {
+	var result T
+	err := utils.SafeParseAIJSON(text, &result)
+	return result, err
+}
+
+// GenerateStructured 调用AI并将输出解析为 T，解析失败时把原始输出交给AI修复后重新解析
+// client 由调用方按剧本解析（GetAIClientForDrama），不回退到全局默认配置；i18n 决定修复请求的语言
+func GenerateStructured[T any](s *AIService, client ai.AIClient, i18n *PromptI18n, prompt, systemPrompt string, options ...func(*ai.ChatCompletionRequest)) (T, error) {
+	var zero T
+	if client == nil {
+		return zero, fmt.Errorf("no AI client")
+	}
+
+	text, err := client.GenerateText(prompt, systemPrompt, options...)
+	if err != nil {
+		return zero, err
+	}
+	return repairStructured(s, client, i18n, text, systemPrompt, parseAIJSON[T], options...)
+}
+
+// repairStructured 用 parse 解析AI输出，失败时请求AI修复，最多修复 json_repair_attempts 次
+// format 为期望的JSON格式说明（原系统提示词或明确的结构），作为修复请求的系统提示词；原请求没有系统提示词时调用方需传入格式说明
+// i18n 为原请求使用的语言，options 应与原请求一致（如温度、max_tokens）
+// 全部失败时返回最后一次的解析错误；输出超过 ai.max_response_kb 时不解析也不修复，直接返回错误
+func repairStructured[T any](s *AIService, client textGenerator, i18n *PromptI18n, text, format string, parse func(string) (T, error), options ...func(*ai.ChatCompletionRequest)) (T, error) {
+	var result T
+	if err := s.checkResponseSize(text); err != nil {
+		return result, err
+	}
+	result, err := parse(text)
+	if err == nil {
+		return result, nil
+	}
+
+	// 修复请求使用生成输出的同一客户端，保证剧本绑定的配置生效；没有客户端时不修复
+	attempts := jsonRepairAttempts()
+	if client == nil {
+		attempts = 0
+	}
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		s.log.Warnw("AI output is not valid JSON, asking model to repair it",
+			"attempt", attempt,
+			"max_attempts", attempts,
+			"error", err,
+			"output_length", len(text))
+
+		fixed, genErr := client.GenerateText(i18n.FormatUserPrompt("json_repair_request", err, text), format, options...)
+		if genErr != nil {
+			s.log.Warnw("AI output repair request failed", "error", genErr, "attempt", attempt)
+			break
+		}
+		text = fixed
+		if err := s.checkResponseSize(text); err != nil {
+			return result, err
+		}
+		if result, err = parse(text); err == nil {
+			s.log.Infow("AI output repaired", "attempt", attempt)
+			return result, nil
+		}
+	}
+	return result, err
+}
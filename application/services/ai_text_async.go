@@ -0,0 +1,87 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/drama-generator/backend/pkg/ai"
+)
+
+// TextTaskRequest 异步文本生成任务的参数
+type TextTaskRequest struct {
+	TaskType     string // 任务类型，如 character_extraction
+	ResourceID   string // 任务关联的资源ID
+	DramaID      uint   // 按剧本绑定的文本配置选择客户端，规则同 GetAIClientForDrama
+	Model        string
+	Prompt       string
+	SystemPrompt string
+	Message      string // 生成期间的任务状态消息，为空时使用默认消息
+	ErrorPrefix  string // 生成失败时任务错误信息的前缀
+	Options      []func(*ai.ChatCompletionRequest)
+}
+
+// TextTaskHandler 处理生成的文本，返回值写入任务结果，返回错误时任务标记为失败
+type TextTaskHandler func(taskID, text string) (interface{}, error)
+
+// GenerateTextAsync 创建任务并在后台生成文本，立即返回任务ID
+// handle 为 nil 时任务结果为 {"text": 生成的文本}
+func (s *AIService) GenerateTextAsync(req TextTaskRequest, handle TextTaskHandler) (string, error) {
+	client, model, err := s.GetAIClientForDrama("text", req.DramaID, req.Model)
+	if err != nil {
+		return "", fmt.Errorf("failed to get AI client: %w", err)
+	}
+
+	task, err := s.taskService.CreateTask(req.TaskType, req.ResourceID)
+	if err != nil {
+		s.log.Errorw("Failed to create text generation task", "error", err, "task_type", req.TaskType)
+		return "", fmt.Errorf("创建任务失败: %w", err)
+	}
+
+	s.log.Infow("Text generation task created", "task_id", task.ID, "task_type", req.TaskType, "drama_id", req.DramaID, "model", model)
+	go s.runTextTask(task.ID, client, req, handle)
+
+	return task.ID, nil
+}
+
+// runTextTask 后台执行文本生成并交由 handle 处理结果
+func (s *AIService) runTextTask(taskID string, client ai.AIClient, req TextTaskRequest, handle TextTaskHandler) {
+	message := req.Message
+	if message == "" {
+		message = "正在生成文本..."
+	}
+	if err := s.taskService.UpdateTaskStatus(taskID, "processing", 10, message); err != nil {
+		s.log.Errorw("Failed to update task status", "error", err, "task_id", taskID)
+		return
+	}
+
+	text, err := client.GenerateText(req.Prompt, req.SystemPrompt, req.Options...)
+	if err != nil {
+		s.log.Errorw("Async text generation failed", "error", err, "task_id", taskID, "task_type", req.TaskType)
+		if req.ErrorPrefix != "" {
+			err = fmt.Errorf("%s: %w", req.ErrorPrefix, err)
+		}
+		s.failTextTask(taskID, err)
+		return
+	}
+
+	var result interface{} = map[string]interface{}{"text": text}
+	if handle != nil {
+		if result, err = handle(taskID, text); err != nil {
+			s.log.Errorw("Failed to handle generated text", "error", err, "task_id", taskID, "task_type", req.TaskType)
+			s.failTextTask(taskID, err)
+			return
+		}
+	}
+
+	if err := s.taskService.UpdateTaskResult(taskID, result); err != nil {
+		s.log.Errorw("Failed to update task result", "error", err, "task_id", taskID)
+		return
+	}
+	s.log.Infow("Async text generation completed", "task_id", taskID, "task_type", req.TaskType, "length", len(text))
+}
+
+// failTextTask 将任务标记为失败
+func (s *AIService) failTextTask(taskID string, err error) {
+	if updateErr := s.taskService.UpdateTaskError(taskID, err); updateErr != nil {
+		s.log.Errorw("Failed to update task error", "error", updateErr, "task_id", taskID)
+	}
+}
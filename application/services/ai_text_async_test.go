@@ -0,0 +1,130 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/ai"
+	"github.com/drama-generator/backend/pkg/logger"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	_ "modernc.org/sqlite"
+)
+
+// stubTextClient 返回固定文本或错误的文本客户端
+type stubTextClient struct {
+	text string
+	err  error
+}
+
+func (c *stubTextClient) GenerateText(prompt, systemPrompt string, options ...func(*ai.ChatCompletionRequest)) (string, error) {
+	return c.text, c.err
+}
+
+func (c *stubTextClient) GenerateTextWithImages(prompt, systemPrompt string, images []string, options ...func(*ai.ChatCompletionRequest)) (string, error) {
+	return c.text, c.err
+}
+
+func (c *stubTextClient) GenerateImage(prompt, size string, n int) ([]string, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *stubTextClient) TestConnection() error {
+	return nil
+}
+
+func newTextTaskTestService(t *testing.T) *AIService {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Dialector{DriverName: "sqlite", DSN: ":memory:"}, &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	// 内存数据库每个连接相互独立，限制为单连接
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get sql db: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&models.AsyncTask{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return NewAIService(db, logger.NewLogger(false))
+}
+
+func TestRunTextTask(t *testing.T) {
+	tests := []struct {
+		name       string
+		client     *stubTextClient
+		handle     TextTaskHandler
+		wantStatus string
+		wantResult map[string]interface{}
+		wantError  string
+	}{
+		{
+			name:       "raw text result",
+			client:     &stubTextClient{text: "hello"},
+			wantStatus: "completed",
+			wantResult: map[string]interface{}{"text": "hello"},
+		},
+		{
+			name:   "handler result",
+			client: &stubTextClient{text: "hello"},
+			handle: func(taskID, text string) (interface{}, error) {
+				return map[string]interface{}{"length": len(text)}, nil
+			},
+			wantStatus: "completed",
+			wantResult: map[string]interface{}{"length": float64(5)},
+		},
+		{
+			name:       "generation error",
+			client:     &stubTextClient{err: errors.New("timeout")},
+			wantStatus: "failed",
+			wantError:  "生成失败: timeout",
+		},
+		{
+			name:   "handler error",
+			client: &stubTextClient{text: "hello"},
+			handle: func(taskID, text string) (interface{}, error) {
+				return nil, errors.New("bad json")
+			},
+			wantStatus: "failed",
+			wantError:  "bad json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTextTaskTestService(t)
+			task, err := s.taskService.CreateTask("text_generation", "1")
+			if err != nil {
+				t.Fatalf("create task: %v", err)
+			}
+
+			s.runTextTask(task.ID, tt.client, TextTaskRequest{TaskType: "text_generation", ErrorPrefix: "生成失败"}, tt.handle)
+
+			got, err := s.taskService.GetTask(task.ID)
+			if err != nil {
+				t.Fatalf("get task: %v", err)
+			}
+			if got.Status != tt.wantStatus {
+				t.Fatalf("status = %q, want %q", got.Status, tt.wantStatus)
+			}
+			if got.Error != tt.wantError {
+				t.Errorf("error = %q, want %q", got.Error, tt.wantError)
+			}
+			if tt.wantResult == nil {
+				return
+			}
+			var result map[string]interface{}
+			if err := json.Unmarshal([]byte(got.Result), &result); err != nil {
+				t.Fatalf("unmarshal result %q: %v", got.Result, err)
+			}
+			for k, v := range tt.wantResult {
+				if result[k] != v {
+					t.Errorf("result[%q] = %v, want %v", k, result[k], v)
+				}
+			}
+		})
+	}
+}
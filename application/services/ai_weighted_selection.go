@@ -0,0 +1,48 @@
+package services
+
+import (
+	"hash/fnv"
+	"math/rand"
+
+	"github.com/drama-generator/backend/domain/models"
+)
+
+// 配置选择模式
+const (
+	ModelSelectionPriority = "priority" // 始终使用优先级最高的配置（默认）
+	ModelSelectionWeighted = "weighted" // 按权重在多个配置间分配请求
+)
+
+// pickWeightedConfig 按权重随机选择配置，stickyKey 非空时改为按 key 的哈希确定性选择
+// 所有权重都为 0 时退回优先级最高的配置；configs 需已按优先级排序且非空
+func pickWeightedConfig(configs []models.AIServiceConfig, stickyKey string) *models.AIServiceConfig {
+	total := 0
+	for _, c := range configs {
+		if c.Weight > 0 {
+			total += c.Weight
+		}
+	}
+	if total == 0 {
+		return &configs[0]
+	}
+
+	var n int
+	if stickyKey != "" {
+		h := fnv.New32a()
+		h.Write([]byte(stickyKey))
+		n = int(h.Sum32() % uint32(total))
+	} else {
+		n = rand.Intn(total)
+	}
+
+	for i := range configs {
+		if configs[i].Weight <= 0 {
+			continue
+		}
+		if n < configs[i].Weight {
+			return &configs[i]
+		}
+		n -= configs[i].Weight
+	}
+	return &configs[0]
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -80,7 +81,7 @@ func (s *AssetService) CreateAsset(req *CreateAssetRequest) (*models.Asset, erro
 	if dramaID != nil {
 		var drama models.Drama
 		if err := s.db.Where("id = ?", *dramaID).First(&drama).Error; err != nil {
-			return nil, fmt.Errorf("drama not found")
+			return nil, ErrDramaNotFound
 		}
 	}
 
@@ -113,7 +114,10 @@ func (s *AssetService) CreateAsset(req *CreateAssetRequest) (*models.Asset, erro
 func (s *AssetService) UpdateAsset(assetID uint, req *UpdateAssetRequest) (*models.Asset, error) {
 	var asset models.Asset
 	if err := s.db.Where("id = ?", assetID).First(&asset).Error; err != nil {
-		return nil, fmt.Errorf("asset not found")
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAssetNotFound
+		}
+		return nil, fmt.Errorf("failed to find asset: %w", err)
 	}
 
 	updates := make(map[string]interface{})
@@ -212,7 +216,7 @@ func (s *AssetService) DeleteAsset(assetID uint) error {
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("asset not found")
+		return ErrAssetNotFound
 	}
 	return nil
 }
@@ -220,7 +224,7 @@ func (s *AssetService) DeleteAsset(assetID uint) error {
 func (s *AssetService) ImportFromImageGen(imageGenID uint) (*models.Asset, error) {
 	var imageGen models.ImageGeneration
 	if err := s.db.Where("id = ? ", imageGenID).First(&imageGen).Error; err != nil {
-		return nil, fmt.Errorf("image generation not found")
+		return nil, ErrImageGenerationNotFound
 	}
 
 	if imageGen.Status != models.ImageStatusCompleted || imageGen.ImageURL == nil {
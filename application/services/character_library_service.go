@@ -125,7 +125,7 @@ func (s *CharacterLibraryService) GetLibraryItem(itemID string) (*models.Charact
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("library item not found")
+			return nil, ErrLibraryItemNotFound
 		}
 		s.log.Errorw("Failed to get library item", "error", err)
 		return nil, err
@@ -144,7 +144,7 @@ func (s *CharacterLibraryService) DeleteLibraryItem(itemID string) error {
 	}
 
 	if result.RowsAffected == 0 {
-		return errors.New("library item not found")
+		return ErrLibraryItemNotFound
 	}
 
 	s.log.Infow("Library item deleted", "item_id", itemID)
@@ -157,7 +157,7 @@ func (s *CharacterLibraryService) ApplyLibraryItemToCharacter(characterID string
 	var libraryItem models.CharacterLibrary
 	if err := s.db.Where("id = ? ", libraryItemID).First(&libraryItem).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("library item not found")
+			return ErrLibraryItemNotFound
 		}
 		return err
 	}
@@ -166,7 +166,7 @@ func (s *CharacterLibraryService) ApplyLibraryItemToCharacter(characterID string
 	var character models.Character
 	if err := s.db.Where("id = ?", characterID).First(&character).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("character not found")
+			return ErrCharacterNotFound
 		}
 		return err
 	}
@@ -175,20 +175,20 @@ func (s *CharacterLibraryService) ApplyLibraryItemToCharacter(characterID string
 	var drama models.Drama
 	if err := s.db.Where("id = ? ", character.DramaID).First(&drama).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("unauthorized")
+			return ErrUnauthorized
 		}
 		return err
 	}
 
-	// 更新角色的 local_path 和 image_url
-	updates := map[string]interface{}{}
+	// 更新角色的 local_path 和 image_url，图片不再来自AI生成，清除生成参数
+	updates := map[string]interface{}{"portrait_params": nil}
 	if libraryItem.LocalPath != nil && *libraryItem.LocalPath != "" {
 		updates["local_path"] = libraryItem.LocalPath
 	}
 	if libraryItem.ImageURL != "" {
 		updates["image_url"] = libraryItem.ImageURL
 	}
-	if len(updates) > 0 {
+	if len(updates) > 1 {
 		if err := s.db.Model(&character).Updates(updates).Error; err != nil {
 			s.log.Errorw("Failed to update character image", "error", err)
 			return err
@@ -205,7 +205,7 @@ func (s *CharacterLibraryService) UploadCharacterImage(characterID string, image
 	var character models.Character
 	if err := s.db.Where("id = ?", characterID).First(&character).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("character not found")
+			return ErrCharacterNotFound
 		}
 		return err
 	}
@@ -214,13 +214,16 @@ func (s *CharacterLibraryService) UploadCharacterImage(characterID string, image
 	var drama models.Drama
 	if err := s.db.Where("id = ? ", character.DramaID).First(&drama).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("unauthorized")
+			return ErrUnauthorized
 		}
 		return err
 	}
 
-	// 更新图片URL
-	if err := s.db.Model(&character).Update("image_url", imageURL).Error; err != nil {
+	// 更新图片URL，上传的图片没有生成参数可复现
+	if err := s.db.Model(&character).Updates(map[string]interface{}{
+		"image_url":       imageURL,
+		"portrait_params": nil,
+	}).Error; err != nil {
 		s.log.Errorw("Failed to update character image", "error", err)
 		return err
 	}
@@ -235,7 +238,7 @@ func (s *CharacterLibraryService) AddCharacterToLibrary(characterID string, cate
 	var character models.Character
 	if err := s.db.Where("id = ?", characterID).First(&character).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("character not found")
+			return nil, ErrCharacterNotFound
 		}
 		return nil, err
 	}
@@ -244,14 +247,14 @@ func (s *CharacterLibraryService) AddCharacterToLibrary(characterID string, cate
 	var drama models.Drama
 	if err := s.db.Where("id = ? ", character.DramaID).First(&drama).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("unauthorized")
+			return nil, ErrUnauthorized
 		}
 		return nil, err
 	}
 
 	// 检查是否有图片
 	if character.ImageURL == nil || *character.ImageURL == "" {
-		return nil, fmt.Errorf("角色还没有形象图片")
+		return nil, ErrCharacterHasNoImage
 	}
 
 	// 创建角色库项
@@ -278,7 +281,7 @@ func (s *CharacterLibraryService) DeleteCharacter(characterID uint) error {
 	var character models.Character
 	if err := s.db.Where("id = ?", characterID).First(&character).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("character not found")
+			return ErrCharacterNotFound
 		}
 		return err
 	}
@@ -287,7 +290,7 @@ func (s *CharacterLibraryService) DeleteCharacter(characterID uint) error {
 	var drama models.Drama
 	if err := s.db.Where("id = ? ", character.DramaID).First(&drama).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("unauthorized")
+			return ErrUnauthorized
 		}
 		return err
 	}
@@ -308,7 +311,7 @@ func (s *CharacterLibraryService) GenerateCharacterImage(characterID string, ima
 	var character models.Character
 	if err := s.db.Where("id = ?", characterID).First(&character).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("character not found")
+			return nil, ErrCharacterNotFound
 		}
 		return nil, err
 	}
@@ -317,7 +320,7 @@ func (s *CharacterLibraryService) GenerateCharacterImage(characterID string, ima
 	var drama models.Drama
 	if err := s.db.Where("id = ? ", character.DramaID).First(&drama).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("unauthorized")
+			return nil, ErrUnauthorized
 		}
 		return nil, err
 	}
@@ -350,6 +353,7 @@ func (s *CharacterLibraryService) GenerateCharacterImage(characterID string, ima
 		Model:       modelName,   // 使用用户指定的模型
 		Size:        "2560x1440", // 3,686,400像素，满足API最低要求（16:9比例）
 		Quality:     "standard",
+		Seed:        newPortraitSeed(), // 记录种子，之后可用相同参数复现形象
 	}
 
 	imageGen, err := imageService.GenerateImage(req)
@@ -418,7 +422,7 @@ func (s *CharacterLibraryService) UpdateCharacter(characterID string, req *Updat
 	var character models.Character
 	if err := s.db.Where("id = ?", characterID).First(&character).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("character not found")
+			return ErrCharacterNotFound
 		}
 		return err
 	}
@@ -427,11 +431,13 @@ func (s *CharacterLibraryService) UpdateCharacter(characterID string, req *Updat
 	var drama models.Drama
 	if err := s.db.Where("id = ? ", character.DramaID).First(&drama).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("unauthorized")
+			return ErrUnauthorized
 		}
 		return err
 	}
 
+	sanitizeStrings(req.Name, req.Role, req.Appearance, req.Personality, req.Description)
+
 	// 构建更新数据
 	updates := make(map[string]interface{})
 
@@ -452,6 +458,7 @@ func (s *CharacterLibraryService) UpdateCharacter(characterID string, req *Updat
 	}
 	if req.ImageURL != nil {
 		updates["image_url"] = *req.ImageURL
+		updates["portrait_params"] = nil
 	}
 	if req.LocalPath != nil {
 		updates["local_path"] = *req.LocalPath
@@ -503,46 +510,34 @@ func (s *CharacterLibraryService) BatchGenerateCharacterImages(characterIDs []st
 func (s *CharacterLibraryService) ExtractCharactersFromScript(episodeID uint) (string, error) {
 	var episode models.Episode
 	if err := s.db.First(&episode, episodeID).Error; err != nil {
-		return "", fmt.Errorf("episode not found")
+		return "", ErrEpisodeNotFound
 	}
 
 	if episode.ScriptContent == nil || *episode.ScriptContent == "" {
 		return "", fmt.Errorf("剧本内容为空")
 	}
 
-	task, err := s.taskService.CreateTask("character_extraction", fmt.Sprintf("%d", episode.DramaID))
-	if err != nil {
-		return "", fmt.Errorf("创建任务失败: %w", err)
-	}
-
-	go s.processCharacterExtraction(task.ID, episode)
-
-	return task.ID, nil
-}
-
-func (s *CharacterLibraryService) processCharacterExtraction(taskID string, episode models.Episode) {
-	s.taskService.UpdateTaskStatus(taskID, "processing", 0, "正在分析剧本...")
-
-	script := ""
-	if episode.ScriptContent != nil {
-		script = *episode.ScriptContent
-	}
-
 	// 获取 drama 的 style 信息
 	var drama models.Drama
 	if err := s.db.First(&drama, episode.DramaID).Error; err != nil {
 		s.log.Warnw("Failed to load drama", "error", err, "drama_id", episode.DramaID)
 	}
 
-	prompt := s.promptI18n.GetCharacterExtractionPrompt(drama.Style)
-	userPrompt := fmt.Sprintf("【剧本内容】\n%s", script)
-
-	response, err := s.aiService.GenerateText(userPrompt, prompt, ai.WithMaxTokens(3000))
-	if err != nil {
-		s.taskService.UpdateTaskError(taskID, err)
-		return
-	}
+	return s.aiService.GenerateTextAsync(TextTaskRequest{
+		TaskType:     "character_extraction",
+		ResourceID:   fmt.Sprintf("%d", episode.DramaID),
+		DramaID:      episode.DramaID,
+		Prompt:       fmt.Sprintf("【剧本内容】\n%s", *episode.ScriptContent),
+		SystemPrompt: s.promptI18n.GetCharacterExtractionPrompt(drama.Style),
+		Message:      "正在分析剧本...",
+		Options:      []func(*ai.ChatCompletionRequest){ai.WithMaxTokens(3000)},
+	}, func(taskID, response string) (interface{}, error) {
+		return s.saveExtractedCharacters(taskID, episode, response)
+	})
+}
 
+// saveExtractedCharacters 解析AI提取的角色，关联已有同名角色或创建新角色
+func (s *CharacterLibraryService) saveExtractedCharacters(taskID string, episode models.Episode, response string) (interface{}, error) {
 	s.taskService.UpdateTaskStatus(taskID, "processing", 50, "正在整理角色数据...")
 
 	var extractedCharacters []struct {
@@ -555,8 +550,7 @@ func (s *CharacterLibraryService) processCharacterExtraction(taskID string, epis
 
 	if err := utils.SafeParseAIJSON(response, &extractedCharacters); err != nil {
 		s.log.Errorw("Failed to parse AI response for characters", "error", err, "response", response)
-		s.taskService.UpdateTaskError(taskID, fmt.Errorf("解析AI响应失败"))
-		return
+		return nil, fmt.Errorf("解析AI响应失败")
 	}
 
 	var savedCharacters []models.Character
@@ -594,8 +588,8 @@ func (s *CharacterLibraryService) processCharacterExtraction(taskID string, epis
 		}
 	}
 
-	s.taskService.UpdateTaskResult(taskID, map[string]interface{}{
+	return map[string]interface{}{
 		"characters": savedCharacters,
 		"count":      len(savedCharacters),
-	})
+	}, nil
 }
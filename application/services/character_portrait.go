@@ -0,0 +1,110 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+
+	"github.com/drama-generator/backend/domain/models"
+	"gorm.io/gorm"
+)
+
+// maxPortraitSeed 随机种子上限，多数服务商只接受 32 位种子
+const maxPortraitSeed = 1<<31 - 1
+
+// newPortraitSeed 为角色形象生成随机种子，记录下来以便复现
+func newPortraitSeed() *int64 {
+	seed := rand.Int63n(maxPortraitSeed)
+	return &seed
+}
+
+// portraitParamsFrom 从已完成的生成记录提取形象图生成参数
+func portraitParamsFrom(gen *models.ImageGeneration) *models.PortraitParams {
+	return &models.PortraitParams{
+		ImageGenerationID: gen.ID,
+		Prompt:            gen.Prompt,
+		NegativePrompt:    gen.NegPrompt,
+		Provider:          gen.Provider,
+		Model:             gen.Model,
+		Size:              gen.Size,
+		Quality:           gen.Quality,
+		Seed:              gen.Seed,
+	}
+}
+
+// recordCharacterPortrait 角色形象图生成完成后保存其生成参数，供后续复现
+func (s *ImageGenerationService) recordCharacterPortrait(gen *models.ImageGeneration) {
+	params := portraitParamsFrom(gen)
+	character := models.Character{PortraitParams: params}
+	if params.Seed != nil {
+		seed := strconv.FormatInt(*params.Seed, 10)
+		character.SeedValue = &seed
+	}
+	if err := s.db.Model(&models.Character{ID: *gen.CharacterID}).
+		Select("portrait_params", "seed_value").Updates(&character).Error; err != nil {
+		s.log.Warnw("Failed to record character portrait params", "error", err, "character_id", *gen.CharacterID)
+	}
+}
+
+// RegenerateCharacterPortraitRequest 复现角色形象图的参数覆盖，未提供的字段沿用当前形象图的生成参数
+type RegenerateCharacterPortraitRequest struct {
+	Prompt *string `json:"prompt" binding:"omitempty,min=5,max=2000"`
+	Seed   *int64  `json:"seed"`
+	Model  *string `json:"model"`
+}
+
+// RegenerateCharacterPortrait 以当前形象图的生成参数（种子、模型、提示词）重新生成角色形象，可按需覆盖其中的字段
+func (s *CharacterLibraryService) RegenerateCharacterPortrait(characterID string, imageService *ImageGenerationService, req *RegenerateCharacterPortraitRequest) (*models.ImageGeneration, error) {
+	var character models.Character
+	if err := s.db.Where("id = ?", characterID).First(&character).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCharacterNotFound
+		}
+		return nil, err
+	}
+	params := character.PortraitParams
+	if params == nil {
+		return nil, &ServiceError{Kind: ErrInvalidInput, Message: "角色形象不是由AI生成的，没有可复用的生成参数"}
+	}
+
+	genReq := &GenerateImageRequest{
+		DramaID:        fmt.Sprintf("%d", character.DramaID),
+		CharacterID:    &character.ID,
+		ImageType:      string(models.ImageTypeCharacter),
+		Prompt:         params.Prompt,
+		NegativePrompt: params.NegativePrompt,
+		Provider:       params.Provider,
+		Model:          params.Model,
+		Size:           params.Size,
+		Quality:        params.Quality,
+		Seed:           params.Seed,
+	}
+	if req.Prompt != nil {
+		genReq.Prompt = *req.Prompt
+	}
+	if req.Seed != nil {
+		genReq.Seed = req.Seed
+	}
+	if req.Model != nil && *req.Model != "" {
+		genReq.Model = *req.Model
+	}
+	if genReq.Seed == nil {
+		genReq.Seed = newPortraitSeed()
+	}
+
+	imageGen, err := imageService.GenerateImage(genReq)
+	if err != nil {
+		s.log.Errorw("Failed to regenerate character portrait", "error", err, "character_id", characterID)
+		return nil, fmt.Errorf("图片生成失败: %w", err)
+	}
+
+	go s.waitAndUpdateCharacterImage(character.ID, imageGen.ID)
+
+	s.log.Infow("Character portrait regeneration started",
+		"character_id", characterID,
+		"image_gen_id", imageGen.ID,
+		"from_image_gen_id", params.ImageGenerationID,
+		"seed", *genReq.Seed)
+	return imageGen, nil
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/drama-generator/backend/domain/models"
+	"gorm.io/gorm"
+)
+
+// CloneOptions 克隆剧本时的可选内容，角色总是会被复制
+type CloneOptions struct {
+	Title           string `json:"title" binding:"omitempty,min=1,max=100"` // 新剧本标题，为空时使用 "原标题（副本）"
+	IncludeEpisodes bool   `json:"include_episodes"`                        // 复制剧集结构
+	IncludeScripts  bool   `json:"include_scripts"`                         // 复制剧集剧本内容（需同时开启 include_episodes）
+	IncludeScenes   bool   `json:"include_scenes"`                          // 复制场景
+	IncludeProps    bool   `json:"include_props"`                           // 复制道具
+	IncludeImages   bool   `json:"include_images"`                          // 保留已生成的图片，默认清空
+}
+
+// CloneDrama 以已有剧本为模板深拷贝出一个新剧本，所有记录使用新 ID，关联关系按新 ID 重新映射
+func (s *DramaService) CloneDrama(dramaID string, opts CloneOptions) (*models.Drama, error) {
+	var source models.Drama
+	if err := s.db.Where("id = ?", dramaID).
+		Preload("Characters").
+		Preload("Episodes.Characters").
+		First(&source).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDramaNotFound
+		}
+		return nil, err
+	}
+
+	title := opts.Title
+	if title == "" {
+		title = source.Title + "（副本）"
+	}
+
+	clone := models.Drama{
+		Title:             title,
+		Description:       source.Description,
+		Genre:             source.Genre,
+		Style:             source.Style,
+		TotalEpisodes:     source.TotalEpisodes,
+		Status:            "draft",
+		Watermark:         source.Watermark,
+		Language:          source.Language,
+		Tags:              source.Tags,
+		Metadata:          source.Metadata,
+		TextConfigID:      source.TextConfigID,
+		ImageConfigID:     source.ImageConfigID,
+		ImagePromptSuffix: source.ImagePromptSuffix,
+		VideoPromptSuffix: source.VideoPromptSuffix,
+	}
+	if opts.IncludeImages {
+		clone.Thumbnail = source.Thumbnail
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&clone).Error; err != nil {
+			return fmt.Errorf("创建剧本失败: %w", err)
+		}
+
+		characterIDs := make(map[uint]uint, len(source.Characters))
+		for _, char := range source.Characters {
+			newChar := models.Character{
+				DramaID:     clone.ID,
+				Name:        char.Name,
+				Role:        char.Role,
+				Description: char.Description,
+				Appearance:  char.Appearance,
+				Personality: char.Personality,
+				VoiceStyle:  char.VoiceStyle,
+				SeedValue:   char.SeedValue,
+				SortOrder:   char.SortOrder,
+				// 生成参数不依赖图片本身，保留以便在副本中复现同一形象
+				PortraitParams: char.PortraitParams,
+			}
+			if opts.IncludeImages {
+				newChar.ImageURL = char.ImageURL
+				newChar.LocalPath = char.LocalPath
+				newChar.ReferenceImages = char.ReferenceImages
+			}
+			if err := tx.Create(&newChar).Error; err != nil {
+				return fmt.Errorf("复制角色失败: %w", err)
+			}
+			characterIDs[char.ID] = newChar.ID
+		}
+
+		episodeIDs := make(map[uint]uint, len(source.Episodes))
+		if opts.IncludeEpisodes {
+			for _, ep := range source.Episodes {
+				newEp := models.Episode{
+					DramaID:     clone.ID,
+					EpisodeNum:  ep.EpisodeNum,
+					Title:       ep.Title,
+					Description: ep.Description,
+					VideoRatio:  ep.VideoRatio,
+					Tags:        ep.Tags,
+					Status:      "draft",
+				}
+				if opts.IncludeScripts {
+					newEp.ScriptContent = ep.ScriptContent
+				}
+				if err := tx.Create(&newEp).Error; err != nil {
+					return fmt.Errorf("复制剧集失败: %w", err)
+				}
+				episodeIDs[ep.ID] = newEp.ID
+
+				var linked []models.Character
+				for _, char := range ep.Characters {
+					if newCharID, ok := characterIDs[char.ID]; ok {
+						linked = append(linked, models.Character{ID: newCharID})
+					}
+				}
+				if len(linked) > 0 {
+					if err := tx.Model(&newEp).Association("Characters").Append(linked); err != nil {
+						return fmt.Errorf("复制剧集角色关联失败: %w", err)
+					}
+				}
+			}
+
+			// 风格参考剧集只在源剧集也被复制时保留
+			for _, ep := range source.Episodes {
+				if ep.StyleReferenceEpisodeID == nil {
+					continue
+				}
+				refID, ok := episodeIDs[*ep.StyleReferenceEpisodeID]
+				if !ok {
+					continue
+				}
+				if err := tx.Model(&models.Episode{}).Where("id = ?", episodeIDs[ep.ID]).
+					Update("style_reference_episode_id", refID).Error; err != nil {
+					return fmt.Errorf("复制风格参考失败: %w", err)
+				}
+			}
+		}
+
+		if opts.IncludeScenes {
+			var scenes []models.Scene
+			if err := tx.Where("drama_id = ?", source.ID).Find(&scenes).Error; err != nil {
+				return fmt.Errorf("获取场景失败: %w", err)
+			}
+			for _, scene := range scenes {
+				newScene := models.Scene{
+					DramaID:         clone.ID,
+					Location:        scene.Location,
+					Time:            scene.Time,
+					Prompt:          scene.Prompt,
+					StoryboardCount: scene.StoryboardCount,
+					Status:          "pending",
+					EditedFields:    scene.EditedFields,
+					ExtractKey:      scene.ExtractKey,
+				}
+				// 场景归属的剧集未被复制时，场景挂在剧本级别
+				if scene.EpisodeID != nil {
+					if newEpID, ok := episodeIDs[*scene.EpisodeID]; ok {
+						newScene.EpisodeID = &newEpID
+					}
+				}
+				if opts.IncludeImages {
+					newScene.ImageURL = scene.ImageURL
+					newScene.LocalPath = scene.LocalPath
+					newScene.Status = scene.Status
+				}
+				if err := tx.Create(&newScene).Error; err != nil {
+					return fmt.Errorf("复制场景失败: %w", err)
+				}
+			}
+		}
+
+		if opts.IncludeProps {
+			var props []models.Prop
+			if err := tx.Where("drama_id = ?", source.ID).Find(&props).Error; err != nil {
+				return fmt.Errorf("获取道具失败: %w", err)
+			}
+			for _, prop := range props {
+				newProp := models.Prop{
+					DramaID:     clone.ID,
+					Name:        prop.Name,
+					Type:        prop.Type,
+					Description: prop.Description,
+					Prompt:      prop.Prompt,
+				}
+				if opts.IncludeImages {
+					newProp.ImageURL = prop.ImageURL
+					newProp.LocalPath = prop.LocalPath
+					newProp.ReferenceImages = prop.ReferenceImages
+				}
+				if err := tx.Create(&newProp).Error; err != nil {
+					return fmt.Errorf("复制道具失败: %w", err)
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		s.log.Errorw("Failed to clone drama", "error", err, "source_drama_id", dramaID)
+		return nil, err
+	}
+
+	s.log.Infow("Drama cloned",
+		"source_drama_id", source.ID,
+		"drama_id", clone.ID,
+		"include_episodes", opts.IncludeEpisodes,
+		"include_scenes", opts.IncludeScenes,
+		"include_props", opts.IncludeProps,
+		"include_images", opts.IncludeImages)
+
+	return &clone, nil
+}
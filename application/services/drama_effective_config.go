@@ -0,0 +1,130 @@
+package services
+
+import (
+	"github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/image"
+)
+
+// defaultImageClientSize 请求未指定尺寸时各图片客户端使用的默认尺寸
+const defaultImageClientSize = "1920x1920"
+
+// 生效配置的来源
+const (
+	configSourceDrama   = "drama"   // 剧本自身的设置或绑定的AI配置
+	configSourceGlobal  = "global"  // 全局配置文件或优先级最高的默认AI配置
+	configSourceBuiltin = "builtin" // 代码内置默认值
+	configSourceRequest = "request" // 没有默认值，只使用每次请求中的参数
+)
+
+// EffectiveValue 一项生效的设置及其来源
+type EffectiveValue struct {
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// EffectiveModelConfig 实际使用的AI配置和模型，Error 非空表示该类型当前无法生成
+type EffectiveModelConfig struct {
+	ConfigID   uint   `json:"config_id,omitempty"`
+	ConfigName string `json:"config_name,omitempty"`
+	Provider   string `json:"provider,omitempty"`
+	Model      string `json:"model,omitempty"`
+	Source     string `json:"source,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// EffectiveImageConfig 图片生成实际使用的配置、默认尺寸和服务商支持的参数
+type EffectiveImageConfig struct {
+	EffectiveModelConfig
+	Size         EffectiveValue     `json:"size"`
+	Capabilities image.Capabilities `json:"capabilities"`
+}
+
+// EffectiveDramaConfig 剧本生成时实际生效的配置（剧本设置 > 全局配置 > 内置默认值）
+type EffectiveDramaConfig struct {
+	DramaID           uint                 `json:"drama_id"`
+	Language          EffectiveValue       `json:"language"`
+	Text              EffectiveModelConfig `json:"text"`
+	Image             EffectiveImageConfig `json:"image"`
+	Style             EffectiveValue       `json:"style"`
+	NegativePrompt    EffectiveValue       `json:"negative_prompt"`
+	ImagePromptSuffix EffectiveValue       `json:"image_prompt_suffix"`
+	VideoPromptSuffix EffectiveValue       `json:"video_prompt_suffix"`
+	Watermark         bool                 `json:"watermark"`
+}
+
+// GetEffectiveDramaConfig 解析剧本生成时实际使用的服务商、模型、尺寸、风格等配置，用于排查生成结果与预期不符的原因
+// 请求中显式传入的参数（如 model、size）仍会覆盖这里的默认值
+func (s *ImageGenerationService) GetEffectiveDramaConfig(dramaID string) (*EffectiveDramaConfig, error) {
+	var drama models.Drama
+	if err := s.db.Where("id = ?", dramaID).First(&drama).Error; err != nil {
+		return nil, ErrDramaNotFound
+	}
+	cfg := s.cfg()
+
+	result := &EffectiveDramaConfig{
+		DramaID:        drama.ID,
+		Text:           s.effectiveModelConfig("text", drama.ID),
+		Image:          EffectiveImageConfig{EffectiveModelConfig: s.effectiveModelConfig("image", drama.ID)},
+		NegativePrompt: EffectiveValue{Source: configSourceRequest},
+		Watermark:      drama.Watermark && cfg.Watermark.Enabled,
+	}
+
+	result.Language = EffectiveValue{Value: drama.Language, Source: configSourceDrama}
+	if drama.Language == "" {
+		result.Language = EffectiveValue{Value: s.promptI18n.GetLanguage(), Source: configSourceGlobal}
+	}
+
+	result.Style = EffectiveValue{Value: drama.Style, Source: configSourceDrama}
+	if drama.Style == "" {
+		result.Style = EffectiveValue{Value: resolveImageStyle(""), Source: configSourceGlobal}
+	}
+
+	result.ImagePromptSuffix = effectiveSetting(drama.ImagePromptSuffix, cfg.Style.ImagePromptSuffix)
+	result.VideoPromptSuffix = effectiveSetting(drama.VideoPromptSuffix, cfg.Style.VideoPromptSuffix)
+
+	result.Image.Size = EffectiveValue{Value: defaultImageClientSize, Source: configSourceBuiltin}
+	if result.Image.Provider != "" {
+		result.Image.Capabilities = s.ProviderCapabilities(result.Image.Provider)
+		result.Image.Provider = s.resolveImageProvider(result.Image.Provider)
+	}
+	return result, nil
+}
+
+// effectiveModelConfig 按生成时的顺序解析配置：剧本绑定的配置优先，否则使用默认配置；图片模型按服务商默认模型解析
+func (s *ImageGenerationService) effectiveModelConfig(serviceType string, dramaID uint) EffectiveModelConfig {
+	source := configSourceDrama
+	config, err := s.aiService.dramaPinnedConfig(serviceType, dramaID)
+	if err == nil && config == nil {
+		source = configSourceGlobal
+		config, err = s.aiService.GetDefaultConfig(serviceType)
+	}
+	if err != nil {
+		return EffectiveModelConfig{Source: source, Error: err.Error()}
+	}
+
+	result := EffectiveModelConfig{
+		ConfigID:   config.ID,
+		ConfigName: config.Name,
+		Provider:   config.Provider,
+		Source:     source,
+	}
+	if serviceType == "image" {
+		// 图片生成按 ai.default_models 解析默认模型
+		model, err := resolveProviderModel(config, config.Provider, "")
+		if err != nil {
+			result.Error = err.Error()
+		}
+		result.Model = model
+	} else if len(config.Model) > 0 {
+		result.Model = config.Model[0]
+	}
+	return result
+}
+
+// effectiveSetting 剧本设置非空时使用剧本设置，否则使用全局配置
+func effectiveSetting(dramaValue, globalValue string) EffectiveValue {
+	if dramaValue != "" {
+		return EffectiveValue{Value: dramaValue, Source: configSourceDrama}
+	}
+	return EffectiveValue{Value: globalValue, Source: configSourceGlobal}
+}
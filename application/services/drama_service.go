@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/drama-generator/backend/domain/models"
@@ -33,6 +34,7 @@ type CreateDramaRequest struct {
 	Genre       string `json:"genre"`
 	Style       string `json:"style"`
 	Tags        string `json:"tags"`
+	Language    string `json:"language" binding:"omitempty,oneof=zh en"`
 }
 
 type UpdateDramaRequest struct {
@@ -42,6 +44,14 @@ type UpdateDramaRequest struct {
 	Style       string `json:"style"`
 	Tags        string `json:"tags"`
 	Status      string `json:"status" binding:"omitempty,oneof=draft planning production completed archived"`
+	Watermark   *bool  `json:"watermark"`
+	Language    string `json:"language" binding:"omitempty,oneof=zh en"`
+	// 绑定的AI配置ID，传 0 解除绑定
+	TextConfigID  *uint `json:"text_config_id"`
+	ImageConfigID *uint `json:"image_config_id"`
+	// 提示词画质后缀，传空字符串恢复使用配置默认值
+	ImagePromptSuffix *string `json:"image_prompt_suffix" binding:"omitempty,max=500"`
+	VideoPromptSuffix *string `json:"video_prompt_suffix" binding:"omitempty,max=500"`
 }
 
 type DramaListQuery struct {
@@ -68,6 +78,7 @@ func (s *DramaService) CreateDrama(req *CreateDramaRequest) (*models.Drama, erro
 	if req.Style != "" {
 		drama.Style = req.Style
 	}
+	drama.Language = req.Language
 
 	if err := s.db.Create(drama).Error; err != nil {
 		s.log.Errorw("Failed to create drama", "error", err)
@@ -94,7 +105,7 @@ func (s *DramaService) GetDrama(dramaID string) (*models.Drama, error) {
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("drama not found")
+			return nil, ErrDramaNotFound
 		}
 		s.log.Errorw("Failed to get drama", "error", err)
 		return nil, err
@@ -260,7 +271,7 @@ func (s *DramaService) UpdateDrama(dramaID string, req *UpdateDramaRequest) (*mo
 	var drama models.Drama
 	if err := s.db.Where("id = ? ", dramaID).First(&drama).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("drama not found")
+			return nil, ErrDramaNotFound
 		}
 		return nil, err
 	}
@@ -285,6 +296,24 @@ func (s *DramaService) UpdateDrama(dramaID string, req *UpdateDramaRequest) (*mo
 	if req.Status != "" {
 		updates["status"] = req.Status
 	}
+	if req.Watermark != nil {
+		updates["watermark"] = *req.Watermark
+	}
+	if req.Language != "" {
+		updates["language"] = req.Language
+	}
+	if err := s.applyPinnedConfig(updates, "text_config_id", "text", req.TextConfigID); err != nil {
+		return nil, err
+	}
+	if err := s.applyPinnedConfig(updates, "image_config_id", "image", req.ImageConfigID); err != nil {
+		return nil, err
+	}
+	if req.ImagePromptSuffix != nil {
+		updates["image_prompt_suffix"] = strings.TrimSpace(*req.ImagePromptSuffix)
+	}
+	if req.VideoPromptSuffix != nil {
+		updates["video_prompt_suffix"] = strings.TrimSpace(*req.VideoPromptSuffix)
+	}
 
 	updates["updated_at"] = time.Now()
 
@@ -306,7 +335,7 @@ func (s *DramaService) DeleteDrama(dramaID string) error {
 	}
 
 	if result.RowsAffected == 0 {
-		return errors.New("drama not found")
+		return ErrDramaNotFound
 	}
 
 	s.log.Infow("Drama deleted", "drama_id", dramaID)
@@ -357,14 +386,15 @@ type SaveProgressRequest struct {
 }
 
 type SaveEpisodesRequest struct {
-	Episodes []models.Episode `json:"episodes" binding:"required"`
+	Episodes     []models.Episode `json:"episodes" binding:"required"`
+	AutoRenumber bool             `json:"auto_renumber"` // 按顺序重新编号为 1..n，修正重复和缺失的集数
 }
 
 func (s *DramaService) SaveOutline(dramaID string, req *SaveOutlineRequest) error {
 	var drama models.Drama
 	if err := s.db.Where("id = ? ", dramaID).First(&drama).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("drama not found")
+			return ErrDramaNotFound
 		}
 		return err
 	}
@@ -401,7 +431,7 @@ func (s *DramaService) GetCharacters(dramaID string, episodeID *string) ([]model
 	var drama models.Drama
 	if err := s.db.Where("id = ? ", dramaID).First(&drama).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("drama not found")
+			return nil, ErrDramaNotFound
 		}
 		return nil, err
 	}
@@ -413,7 +443,7 @@ func (s *DramaService) GetCharacters(dramaID string, episodeID *string) ([]model
 		var episode models.Episode
 		if err := s.db.Preload("Characters").Where("id = ? AND drama_id = ?", *episodeID, dramaID).First(&episode).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return nil, errors.New("episode not found")
+				return nil, ErrEpisodeNotFound
 			}
 			return nil, err
 		}
@@ -463,7 +493,7 @@ func (s *DramaService) SaveCharacters(dramaID string, req *SaveCharactersRequest
 	var drama models.Drama
 	if err := s.db.Where("id = ? ", dramaIDUint).First(&drama).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("drama not found")
+			return ErrDramaNotFound
 		}
 		return err
 	}
@@ -473,7 +503,7 @@ func (s *DramaService) SaveCharacters(dramaID string, req *SaveCharactersRequest
 		var episode models.Episode
 		if err := s.db.Where("id = ? AND drama_id = ?", *req.EpisodeID, dramaIDUint).First(&episode).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return errors.New("episode not found")
+				return ErrEpisodeNotFound
 			}
 			return err
 		}
@@ -576,26 +606,57 @@ func (s *DramaService) SaveCharacters(dramaID string, req *SaveCharactersRequest
 	return nil
 }
 
-func (s *DramaService) SaveEpisodes(dramaID string, req *SaveEpisodesRequest) error {
+// SaveEpisodes 保存剧本的剧集列表（替换已有剧集），重复或无效的集数在未开启 auto_renumber 时返回错误
+func (s *DramaService) SaveEpisodes(dramaID string, req *SaveEpisodesRequest) (*SaveEpisodesResult, error) {
 	// 转换dramaID
 	id, err := strconv.ParseUint(dramaID, 10, 32)
 	if err != nil {
-		return fmt.Errorf("invalid drama ID")
+		return nil, fmt.Errorf("invalid drama ID")
 	}
 	dramaIDUint := uint(id)
 
 	var drama models.Drama
 	if err := s.db.Where("id = ? ", dramaIDUint).First(&drama).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("drama not found")
+			return nil, ErrDramaNotFound
+		}
+		return nil, err
+	}
+
+	// 先校验规模上限，避免删除旧剧集后才发现无法保存
+	if err := checkLimit("剧集数量", len(req.Episodes), currentLimits().EpisodesPerDrama()); err != nil {
+		return nil, err
+	}
+	for _, ep := range req.Episodes {
+		if ep.ScriptContent == nil {
+			continue
+		}
+		if err := checkScriptLength(*ep.ScriptContent); err != nil {
+			return nil, fmt.Errorf("第%d集%w", ep.EpisodeNum, err)
 		}
-		return err
+	}
+	for _, ep := range req.Episodes {
+		if ep.VideoRatio == nil || *ep.VideoRatio == "" {
+			continue
+		}
+		if err := validateVideoRatio(*ep.VideoRatio); err != nil {
+			return nil, err
+		}
+	}
+	for _, ep := range req.Episodes {
+		if len(normalizeEpisodeTags(ep.Tags)) > maxEpisodeTags {
+			return nil, &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf("第%d集标签过多，每集最多 %d 个", ep.EpisodeNum, maxEpisodeTags)}
+		}
+	}
+	result, err := applyEpisodeNumbering(req.Episodes, req.AutoRenumber)
+	if err != nil {
+		return nil, err
 	}
 
 	// 删除旧剧集
 	if err := s.db.Where("drama_id = ?", dramaIDUint).Delete(&models.Episode{}).Error; err != nil {
 		s.log.Errorw("Failed to delete old episodes", "error", err)
-		return err
+		return nil, err
 	}
 
 	// 创建新剧集（不包含场景，场景由后续步骤生成）
@@ -607,6 +668,8 @@ func (s *DramaService) SaveEpisodes(dramaID string, req *SaveEpisodesRequest) er
 			Description:   ep.Description,
 			ScriptContent: ep.ScriptContent,
 			Duration:      ep.Duration,
+			VideoRatio:    ep.VideoRatio,
+			Tags:          normalizeEpisodeTags(ep.Tags),
 			Status:        "draft",
 		}
 
@@ -620,15 +683,15 @@ func (s *DramaService) SaveEpisodes(dramaID string, req *SaveEpisodesRequest) er
 		s.log.Errorw("Failed to update drama timestamp", "error", err)
 	}
 
-	s.log.Infow("Episodes saved", "drama_id", dramaID, "count", len(req.Episodes))
-	return nil
+	s.log.Infow("Episodes saved", "drama_id", dramaID, "count", len(req.Episodes), "renumbered", len(result.Renumbered))
+	return result, nil
 }
 
 func (s *DramaService) SaveProgress(dramaID string, req *SaveProgressRequest) error {
 	var drama models.Drama
 	if err := s.db.Where("id = ? ", dramaID).First(&drama).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("drama not found")
+			return ErrDramaNotFound
 		}
 		return err
 	}
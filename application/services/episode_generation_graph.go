@@ -0,0 +1,163 @@
+package services
+
+import (
+	"fmt"
+
+	models "github.com/drama-generator/backend/domain/models"
+)
+
+// EpisodeGenerationGraph 剧集从分镜到图片生成的完整关系，用于排查成片问题
+type EpisodeGenerationGraph struct {
+	EpisodeID     uint             `json:"episode_id"`
+	DramaID       uint             `json:"drama_id"`
+	EpisodeNumber int              `json:"episode_number"`
+	Title         string           `json:"title"`
+	Shots         []GraphShot      `json:"shots"`
+	Summary       map[string]int64 `json:"summary"` // 各状态的图片生成记录数
+}
+
+// GraphShot 单个镜头及其场景、角色、帧提示词和全部图片生成记录
+type GraphShot struct {
+	StoryboardID     uint                     `json:"storyboard_id"`
+	StoryboardNumber int                      `json:"storyboard_number"`
+	Title            string                   `json:"title"`
+	Status           string                   `json:"status"`
+	Locked           bool                     `json:"locked"`
+	ImagePrompt      string                   `json:"image_prompt"`
+	VideoPrompt      string                   `json:"video_prompt"`
+	ComposedImage    string                   `json:"composed_image"`
+	VideoURL         string                   `json:"video_url"`
+	Scene            *GraphScene              `json:"scene"`
+	Characters       []GraphCharacter         `json:"characters"`
+	FramePrompts     []models.FramePrompt     `json:"frame_prompts"`
+	ImageGenerations []models.ImageGeneration `json:"image_generations"`
+}
+
+// GraphScene 镜头关联的场景及其场景图生成记录
+type GraphScene struct {
+	ID               uint                     `json:"id"`
+	Location         string                   `json:"location"`
+	Time             string                   `json:"time"`
+	Prompt           string                   `json:"prompt"`
+	Status           string                   `json:"status"`
+	ImageURL         string                   `json:"image_url"`
+	ImageGenerations []models.ImageGeneration `json:"image_generations"`
+}
+
+// GraphCharacter 镜头关联的角色
+type GraphCharacter struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	ImageURL string `json:"image_url"`
+}
+
+// GetEpisodeGenerationGraph 一次性返回剧集所有镜头的场景、角色、帧提示词和图片生成记录（含失败和进行中的记录）
+func (s *StoryboardService) GetEpisodeGenerationGraph(episodeID string) (*EpisodeGenerationGraph, error) {
+	var episode models.Episode
+	if err := s.db.Where("id = ?", episodeID).First(&episode).Error; err != nil {
+		return nil, ErrEpisodeNotFound
+	}
+
+	var storyboards []models.Storyboard
+	if err := s.db.Where("episode_id = ?", episode.ID).
+		Preload("Characters").
+		Preload("Background").
+		Order("storyboard_number ASC").
+		Find(&storyboards).Error; err != nil {
+		return nil, fmt.Errorf("获取分镜失败: %w", err)
+	}
+
+	storyboardIDs := make([]uint, 0, len(storyboards))
+	sceneIDs := make([]uint, 0, len(storyboards))
+	seenScenes := make(map[uint]bool)
+	for _, sb := range storyboards {
+		storyboardIDs = append(storyboardIDs, sb.ID)
+		if sb.SceneID != nil && !seenScenes[*sb.SceneID] {
+			seenScenes[*sb.SceneID] = true
+			sceneIDs = append(sceneIDs, *sb.SceneID)
+		}
+	}
+
+	framesByStoryboard := make(map[uint][]models.FramePrompt)
+	imagesByStoryboard := make(map[uint][]models.ImageGeneration)
+	imagesByScene := make(map[uint][]models.ImageGeneration)
+	summary := make(map[string]int64)
+
+	if len(storyboardIDs) > 0 {
+		var framePrompts []models.FramePrompt
+		if err := s.db.Where("storyboard_id IN ?", storyboardIDs).Order("id ASC").Find(&framePrompts).Error; err != nil {
+			return nil, fmt.Errorf("获取帧提示词失败: %w", err)
+		}
+		for _, fp := range framePrompts {
+			framesByStoryboard[fp.StoryboardID] = append(framesByStoryboard[fp.StoryboardID], fp)
+		}
+
+		var imageGens []models.ImageGeneration
+		if err := s.db.Where("storyboard_id IN ?", storyboardIDs).Order("id ASC").Find(&imageGens).Error; err != nil {
+			return nil, fmt.Errorf("获取分镜图片生成记录失败: %w", err)
+		}
+		for _, ig := range imageGens {
+			imagesByStoryboard[*ig.StoryboardID] = append(imagesByStoryboard[*ig.StoryboardID], ig)
+			summary[string(ig.Status)]++
+		}
+	}
+
+	if len(sceneIDs) > 0 {
+		var sceneGens []models.ImageGeneration
+		if err := s.db.Where("scene_id IN ? AND image_type = ? AND storyboard_id IS NULL", sceneIDs, string(models.ImageTypeScene)).
+			Order("id ASC").Find(&sceneGens).Error; err != nil {
+			return nil, fmt.Errorf("获取场景图片生成记录失败: %w", err)
+		}
+		for _, ig := range sceneGens {
+			imagesByScene[*ig.SceneID] = append(imagesByScene[*ig.SceneID], ig)
+			summary[string(ig.Status)]++
+		}
+	}
+
+	graph := &EpisodeGenerationGraph{
+		EpisodeID:     episode.ID,
+		DramaID:       episode.DramaID,
+		EpisodeNumber: episode.EpisodeNum,
+		Title:         episode.Title,
+		Shots:         make([]GraphShot, 0, len(storyboards)),
+		Summary:       summary,
+	}
+
+	for _, sb := range storyboards {
+		shot := GraphShot{
+			StoryboardID:     sb.ID,
+			StoryboardNumber: sb.StoryboardNumber,
+			Title:            getString(sb.Title),
+			Status:           sb.Status,
+			Locked:           sb.Locked,
+			ImagePrompt:      getString(sb.ImagePrompt),
+			VideoPrompt:      getString(sb.VideoPrompt),
+			ComposedImage:    getString(sb.ComposedImage),
+			VideoURL:         getString(sb.VideoURL),
+			Characters:       make([]GraphCharacter, 0, len(sb.Characters)),
+			FramePrompts:     framesByStoryboard[sb.ID],
+			ImageGenerations: imagesByStoryboard[sb.ID],
+		}
+		if bg := sb.Background; bg != nil {
+			shot.Scene = &GraphScene{
+				ID:               bg.ID,
+				Location:         bg.Location,
+				Time:             bg.Time,
+				Prompt:           bg.Prompt,
+				Status:           bg.Status,
+				ImageURL:         getString(bg.ImageURL),
+				ImageGenerations: imagesByScene[bg.ID],
+			}
+		}
+		for _, char := range sb.Characters {
+			shot.Characters = append(shot.Characters, GraphCharacter{
+				ID:       char.ID,
+				Name:     char.Name,
+				ImageURL: getString(char.ImageURL),
+			})
+		}
+		graph.Shots = append(graph.Shots, shot)
+	}
+
+	return graph, nil
+}
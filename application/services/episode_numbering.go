@@ -0,0 +1,156 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"gorm.io/gorm"
+)
+
+// EpisodeRenumber 一集的编号变更
+type EpisodeRenumber struct {
+	EpisodeID uint   `json:"episode_id,omitempty"`
+	Title     string `json:"title"`
+	From      int    `json:"from"`
+	To        int    `json:"to"`
+}
+
+// SaveEpisodesResult 保存剧集的结果：Renumbered 为自动重新编号的剧集，Gaps 为保存后仍缺失的集数
+type SaveEpisodesResult struct {
+	Renumbered []EpisodeRenumber `json:"renumbered"`
+	Gaps       []int             `json:"gaps"`
+}
+
+// episodeNumberIssues 检查集数：invalid 为小于 1 的编号，duplicates 为重复的编号，gaps 为 1 到最大编号之间缺失的编号
+func episodeNumberIssues(nums []int) (invalid, duplicates, gaps []int) {
+	seen := make(map[int]int, len(nums))
+	maxNum := 0
+	for _, n := range nums {
+		if n < 1 {
+			invalid = append(invalid, n)
+			continue
+		}
+		seen[n]++
+		if seen[n] == 2 {
+			duplicates = append(duplicates, n)
+		}
+		maxNum = max(maxNum, n)
+	}
+	for n := 1; n <= maxNum; n++ {
+		if seen[n] == 0 {
+			gaps = append(gaps, n)
+		}
+	}
+	sort.Ints(duplicates)
+	return invalid, duplicates, gaps
+}
+
+// resequenceEpisodeNumbers 按原编号排序后重新编号为 1..n，编号相同的保持原有顺序，无效编号排在最后
+func resequenceEpisodeNumbers(nums []int) []int {
+	order := make([]int, len(nums))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		na, nb := nums[order[a]], nums[order[b]]
+		if (na < 1) != (nb < 1) {
+			return nb < 1
+		}
+		return na < nb
+	})
+
+	result := make([]int, len(nums))
+	for pos, i := range order {
+		result[i] = pos + 1
+	}
+	return result
+}
+
+// applyEpisodeNumbering 校验请求中的集数：开启 autoRenumber 时重复、缺失或无效的编号按顺序重新编号并返回变更，
+// 否则重复或无效的编号返回错误，缺失的编号只作为提示返回
+func applyEpisodeNumbering(episodes []models.Episode, autoRenumber bool) (*SaveEpisodesResult, error) {
+	nums := make([]int, len(episodes))
+	for i := range episodes {
+		nums[i] = episodes[i].EpisodeNum
+	}
+	invalid, duplicates, gaps := episodeNumberIssues(nums)
+	result := &SaveEpisodesResult{Renumbered: []EpisodeRenumber{}, Gaps: []int{}}
+
+	if !autoRenumber {
+		if len(invalid) > 0 {
+			return nil, &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf("集数必须大于 0：%s，可设置 auto_renumber 自动编号", joinInts(invalid))}
+		}
+		if len(duplicates) > 0 {
+			return nil, &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf("集数重复：%s，可设置 auto_renumber 自动编号", joinInts(duplicates))}
+		}
+		if gaps != nil {
+			result.Gaps = gaps
+		}
+		return result, nil
+	}
+
+	for i, to := range resequenceEpisodeNumbers(nums) {
+		if to == nums[i] {
+			continue
+		}
+		result.Renumbered = append(result.Renumbered, EpisodeRenumber{Title: episodes[i].Title, From: nums[i], To: to})
+		episodes[i].EpisodeNum = to
+	}
+	return result, nil
+}
+
+// RenumberEpisodes 检查剧本已有剧集的重复和缺失编号，按当前顺序（编号、创建先后）重新编号为 1..n，返回变更的剧集
+func (s *DramaService) RenumberEpisodes(dramaID string) ([]EpisodeRenumber, error) {
+	var drama models.Drama
+	if err := s.db.Select("id").Where("id = ?", dramaID).First(&drama).Error; err != nil {
+		return nil, ErrDramaNotFound
+	}
+
+	var episodes []models.Episode
+	if err := s.db.Select("id", "episode_number", "title").Where("drama_id = ?", drama.ID).
+		Order("episode_number ASC, id ASC").Find(&episodes).Error; err != nil {
+		return nil, fmt.Errorf("获取剧集失败: %w", err)
+	}
+
+	nums := make([]int, len(episodes))
+	for i := range episodes {
+		nums[i] = episodes[i].EpisodeNum
+	}
+
+	changes := []EpisodeRenumber{}
+	for i, to := range resequenceEpisodeNumbers(nums) {
+		if to != nums[i] {
+			changes = append(changes, EpisodeRenumber{EpisodeID: episodes[i].ID, Title: episodes[i].Title, From: nums[i], To: to})
+		}
+	}
+	if len(changes) == 0 {
+		return changes, nil
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, change := range changes {
+			if err := tx.Model(&models.Episode{}).Where("id = ?", change.EpisodeID).
+				Update("episode_number", change.To).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("重新编号失败: %w", err)
+	}
+
+	s.log.Infow("Episodes renumbered", "drama_id", drama.ID, "changed", len(changes))
+	return changes, nil
+}
+
+// joinInts 将编号列表格式化为逗号分隔的字符串
+func joinInts(nums []int) string {
+	parts := make([]string, len(nums))
+	for i, n := range nums {
+		parts[i] = fmt.Sprintf("%d", n)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,452 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/config"
+	"github.com/drama-generator/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+const (
+	// pipelinePollInterval 等待阶段完成时的轮询间隔
+	pipelinePollInterval = 5 * time.Second
+	// pipelineStageTimeout 单个阶段的最长等待时间
+	pipelineStageTimeout = 2 * time.Hour
+	// pipelineFramePromptConcurrency 帧提示词阶段同时执行的生成任务数
+	pipelineFramePromptConcurrency = 4
+)
+
+// activePipelines 正在执行流水线的剧集，避免同一剧集重复恢复
+var activePipelines sync.Map
+
+// EpisodePipelineService 按 提取场景 → 分镜 → 帧提示词 → 图片 → 视频 → 合成 的顺序执行剧集流水线，
+// 每个阶段完成后记录到 episodes.pipeline_stage，中途失败后可从记录的下一个阶段继续
+type EpisodePipelineService struct {
+	db                 *gorm.DB
+	config             *config.Config
+	log                *logger.Logger
+	aiService          *AIService
+	taskService        *TaskService
+	imageService       *ImageGenerationService
+	storyboardService  *StoryboardService
+	framePromptService *FramePromptService
+	videoService       *VideoGenerationService
+	videoMergeService  *VideoMergeService
+}
+
+func NewEpisodePipelineService(db *gorm.DB, cfg *config.Config, log *logger.Logger, imageService *ImageGenerationService, framePromptService *FramePromptService, videoService *VideoGenerationService) *EpisodePipelineService {
+	return &EpisodePipelineService{
+		db:                 db,
+		config:             cfg,
+		log:                log,
+		aiService:          NewAIService(db, log),
+		taskService:        NewTaskService(db, log),
+		imageService:       imageService,
+		storyboardService:  NewStoryboardService(db, cfg, log),
+		framePromptService: framePromptService,
+		videoService:       videoService,
+		videoMergeService:  NewVideoMergeService(db, nil, cfg.Storage.LocalPath, cfg.Storage.BaseURL, log),
+	}
+}
+
+// pipelineStage 流水线阶段：done 根据已有数据判断阶段是否已完成，run 启动阶段并等待其结束
+type pipelineStage struct {
+	name string
+	done func(episode *models.Episode) (bool, error)
+	run  func(episode *models.Episode) error
+}
+
+func (s *EpisodePipelineService) stages() []pipelineStage {
+	return []pipelineStage{
+		{models.PipelineStageExtract, s.scenesExtracted, s.runExtract},
+		{models.PipelineStageStoryboard, s.storyboardsGenerated, s.runStoryboard},
+		{models.PipelineStageFrames, s.framePromptsGenerated, s.runFramePrompts},
+		{models.PipelineStageImages, s.imagesGenerated, s.runImages},
+		{models.PipelineStageVideos, s.videosGenerated, s.runVideos},
+		{models.PipelineStageFinalize, s.episodeFinalized, s.runFinalize},
+	}
+}
+
+// ResumeEpisodePipeline 从 pipeline_stage 记录的最后完成阶段的下一个阶段继续执行剧集流水线（异步），返回任务ID和起始阶段
+// 没有记录（流水线从未完成过阶段）时按实际数据判断第一个未完成的阶段
+func (s *EpisodePipelineService) ResumeEpisodePipeline(episodeID string) (string, string, error) {
+	var episode models.Episode
+	if err := s.db.Where("id = ?", episodeID).First(&episode).Error; err != nil {
+		return "", "", ErrEpisodeNotFound
+	}
+
+	stages := s.stages()
+	start, err := s.resumeStageIndex(&episode, stages)
+	if err != nil {
+		return "", "", err
+	}
+	if start < 0 {
+		return "", "", &ServiceError{Kind: ErrConflict, Message: "该剧集的流水线已全部完成"}
+	}
+
+	if _, running := activePipelines.LoadOrStore(episode.ID, struct{}{}); running {
+		return "", "", &ServiceError{Kind: ErrConflict, Message: "该剧集的流水线正在执行中"}
+	}
+
+	task, err := s.taskService.CreateTask("episode_pipeline", episodeID)
+	if err != nil {
+		activePipelines.Delete(episode.ID)
+		s.log.Errorw("Failed to create pipeline task", "error", err, "episode_id", episodeID)
+		return "", "", fmt.Errorf("创建任务失败: %w", err)
+	}
+
+	go s.processPipeline(task.ID, episode, stages[start:])
+
+	s.log.Infow("Episode pipeline resumed", "task_id", task.ID, "episode_id", episodeID, "from_stage", stages[start].name)
+	return task.ID, stages[start].name, nil
+}
+
+// processPipeline 依次执行剩余阶段，任一阶段失败时停止，已完成的阶段保留
+func (s *EpisodePipelineService) processPipeline(taskID string, episode models.Episode, stages []pipelineStage) {
+	defer activePipelines.Delete(episode.ID)
+
+	var completed []string
+	for i, stage := range stages {
+		s.taskService.UpdateTaskStatus(taskID, "processing", i*100/len(stages),
+			fmt.Sprintf("正在执行阶段 %s（%d/%d）", stage.name, i+1, len(stages)))
+
+		if err := stage.run(&episode); err != nil {
+			s.log.Errorw("Episode pipeline stage failed", "error", err, "stage", stage.name, "episode_id", episode.ID, "task_id", taskID)
+			s.taskService.UpdateTaskError(taskID, fmt.Errorf("阶段 %s 失败: %w", stage.name, err))
+			return
+		}
+		s.markStageCompleted(episode.ID, stage.name)
+		completed = append(completed, stage.name)
+	}
+
+	s.taskService.UpdateTaskResult(taskID, map[string]interface{}{
+		"episode_id":       episode.ID,
+		"completed_stages": completed,
+	})
+	s.log.Infow("Episode pipeline completed", "task_id", taskID, "episode_id", episode.ID, "stages", completed)
+}
+
+// resumeStageIndex 返回继续执行的起始阶段下标，全部完成时返回 -1
+func (s *EpisodePipelineService) resumeStageIndex(episode *models.Episode, stages []pipelineStage) (int, error) {
+	if episode.PipelineStage != "" {
+		for i, stage := range stages {
+			if stage.name == episode.PipelineStage {
+				if i+1 == len(stages) {
+					return -1, nil
+				}
+				return i + 1, nil
+			}
+		}
+		s.log.Warnw("Unknown recorded pipeline stage, detecting progress from data", "episode_id", episode.ID, "stage", episode.PipelineStage)
+	}
+
+	for i, stage := range stages {
+		done, err := stage.done(episode)
+		if err != nil {
+			return 0, fmt.Errorf("检查阶段 %s 失败: %w", stage.name, err)
+		}
+		if !done {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+// markStageCompleted 记录最后完成的阶段
+func (s *EpisodePipelineService) markStageCompleted(episodeID uint, stage string) {
+	if err := s.db.Model(&models.Episode{}).Where("id = ?", episodeID).Update("pipeline_stage", stage).Error; err != nil {
+		s.log.Warnw("Failed to record pipeline stage", "error", err, "episode_id", episodeID, "stage", stage)
+	}
+}
+
+// waitForTask 等待异步任务结束，任务失败时返回任务的错误信息
+func (s *EpisodePipelineService) waitForTask(taskID string) error {
+	return waitUntil(func() (bool, error) {
+		task, err := s.taskService.GetTask(taskID)
+		if err != nil {
+			return false, fmt.Errorf("获取任务失败: %w", err)
+		}
+		switch task.Status {
+		case "completed":
+			return true, nil
+		case "failed":
+			if task.Error != "" {
+				return false, errors.New(task.Error)
+			}
+			return false, errors.New(task.Message)
+		}
+		return false, nil
+	})
+}
+
+// waitUntil 轮询直到 check 返回 true 或出错，超过 pipelineStageTimeout 时返回超时错误
+func waitUntil(check func() (bool, error)) error {
+	deadline := time.Now().Add(pipelineStageTimeout)
+	for {
+		done, err := check()
+		if err != nil || done {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("等待超时（%s）", pipelineStageTimeout)
+		}
+		time.Sleep(pipelinePollInterval)
+	}
+}
+
+func (s *EpisodePipelineService) scenesExtracted(episode *models.Episode) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.Scene{}).Where("episode_id = ?", episode.ID).Count(&count).Error
+	return count > 0, err
+}
+
+func (s *EpisodePipelineService) runExtract(episode *models.Episode) error {
+	taskID, err := s.imageService.ExtractBackgroundsForEpisode(fmt.Sprintf("%d", episode.ID), "", "", "", "")
+	if err != nil {
+		return err
+	}
+	return s.waitForTask(taskID)
+}
+
+func (s *EpisodePipelineService) storyboardsGenerated(episode *models.Episode) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.Storyboard{}).Where("episode_id = ?", episode.ID).Count(&count).Error
+	return count > 0, err
+}
+
+func (s *EpisodePipelineService) runStoryboard(episode *models.Episode) error {
+	taskID, err := s.storyboardService.GenerateStoryboard(fmt.Sprintf("%d", episode.ID), "", nil)
+	if err != nil {
+		return err
+	}
+	return s.waitForTask(taskID)
+}
+
+// storyboardsWithoutFirstFrame 还没有首帧提示词的分镜
+func (s *EpisodePipelineService) storyboardsWithoutFirstFrame(episodeID uint) ([]uint, error) {
+	var ids []uint
+	err := s.db.Model(&models.Storyboard{}).
+		Where("episode_id = ? AND id NOT IN (?)", episodeID,
+			s.db.Model(&models.FramePrompt{}).Select("storyboard_id").Where("frame_type = ?", string(FrameTypeFirst))).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (s *EpisodePipelineService) framePromptsGenerated(episode *models.Episode) (bool, error) {
+	ids, err := s.storyboardsWithoutFirstFrame(episode.ID)
+	return len(ids) == 0, err
+}
+
+// runFramePrompts 为缺少首帧提示词的分镜生成提示词，每次最多同时执行 pipelineFramePromptConcurrency 个任务
+func (s *EpisodePipelineService) runFramePrompts(episode *models.Episode) error {
+	ids, err := s.storyboardsWithoutFirstFrame(episode.ID)
+	if err != nil {
+		return err
+	}
+	for start := 0; start < len(ids); start += pipelineFramePromptConcurrency {
+		var taskIDs []string
+		for _, id := range ids[start:min(start+pipelineFramePromptConcurrency, len(ids))] {
+			taskID, err := s.framePromptService.GenerateFramePrompt(GenerateFramePromptRequest{
+				StoryboardID: fmt.Sprintf("%d", id),
+				FrameType:    FrameTypeFirst,
+			}, "")
+			if err != nil {
+				return fmt.Errorf("分镜 %d: %w", id, err)
+			}
+			taskIDs = append(taskIDs, taskID)
+		}
+		for _, taskID := range taskIDs {
+			if err := s.waitForTask(taskID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// storyboardsWithoutImage 有图片提示词但还没有分镜图片的分镜
+func (s *EpisodePipelineService) storyboardsWithoutImage(episodeID uint) ([]models.Storyboard, error) {
+	var storyboards []models.Storyboard
+	err := s.db.Preload("Characters").
+		Where("episode_id = ? AND image_prompt IS NOT NULL AND image_prompt <> '' AND (composed_image IS NULL OR composed_image = '')", episodeID).
+		Order("storyboard_number ASC").Find(&storyboards).Error
+	return storyboards, err
+}
+
+func (s *EpisodePipelineService) imagesGenerated(episode *models.Episode) (bool, error) {
+	storyboards, err := s.storyboardsWithoutImage(episode.ID)
+	return len(storyboards) == 0, err
+}
+
+// firstFramePrompts 分镜最新的首帧提示词，key 为分镜ID
+func (s *EpisodePipelineService) firstFramePrompts(storyboardIDs []uint) (map[uint]string, error) {
+	var framePrompts []models.FramePrompt
+	if err := s.db.Where("storyboard_id IN ? AND frame_type = ?", storyboardIDs, string(FrameTypeFirst)).
+		Order("updated_at ASC").Find(&framePrompts).Error; err != nil {
+		return nil, err
+	}
+	prompts := make(map[uint]string, len(framePrompts))
+	for _, fp := range framePrompts {
+		if fp.Prompt != "" {
+			prompts[fp.StoryboardID] = fp.Prompt
+		}
+	}
+	return prompts, nil
+}
+
+// runImages 只为缺少图片且没有进行中生成记录的分镜提交生成，等待全部结束后检查是否仍有缺失
+// 与批量生成一样注入角色形象图参考、说明排除的角色，提示词优先使用帧提示词阶段生成的首帧提示词，并按剧集图片并发数入队
+func (s *EpisodePipelineService) runImages(episode *models.Episode) error {
+	storyboards, err := s.storyboardsWithoutImage(episode.ID)
+	if err != nil {
+		return err
+	}
+	if len(storyboards) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(storyboards))
+	for i := range storyboards {
+		ids[i] = storyboards[i].ID
+	}
+	framePrompts, err := s.firstFramePrompts(ids)
+	if err != nil {
+		return fmt.Errorf("获取帧提示词失败: %w", err)
+	}
+
+	inFlight := []models.ImageGenerationStatus{models.ImageStatusPending, models.ImageStatusProcessing}
+	var queued []*models.ImageGeneration
+	for i := range storyboards {
+		sb := &storyboards[i]
+		var busy int64
+		s.db.Model(&models.ImageGeneration{}).Where("storyboard_id = ? AND status IN ?", sb.ID, inFlight).Count(&busy)
+		if busy > 0 {
+			continue
+		}
+		prompt, ok := framePrompts[sb.ID]
+		if !ok {
+			prompt = *sb.ImagePrompt
+		}
+		req := s.imageService.storyboardImageRequest(sb, episode.DramaID, prompt, BatchImageModeComposed)
+		frameType := string(FrameTypeFirst)
+		req.FrameType = &frameType
+		imageGen, needsQueue, err := s.imageService.createImageGeneration(req)
+		if err != nil {
+			return fmt.Errorf("分镜 %d: %w", sb.StoryboardNumber, err)
+		}
+		if needsQueue {
+			queued = append(queued, imageGen)
+		}
+	}
+	s.imageService.enqueueWithLimit(queued, s.imageService.cfg().AI.EpisodeImageConcurrency)
+
+	if err := waitUntil(func() (bool, error) {
+		var busy int64
+		err := s.db.Model(&models.ImageGeneration{}).Where("storyboard_id IN ? AND status IN ?", ids, inFlight).Count(&busy).Error
+		return busy == 0, err
+	}); err != nil {
+		return err
+	}
+	missing, err := s.storyboardsWithoutImage(episode.ID)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%d 个分镜图片生成失败", len(missing))
+	}
+	return nil
+}
+
+// storyboardsWithoutVideo 已有分镜图片但还没有视频的分镜
+func (s *EpisodePipelineService) storyboardsWithoutVideo(episodeID uint) ([]models.Storyboard, error) {
+	var storyboards []models.Storyboard
+	err := s.db.Where("episode_id = ? AND composed_image IS NOT NULL AND composed_image <> '' AND (video_url IS NULL OR video_url = '')", episodeID).
+		Order("storyboard_number ASC").Find(&storyboards).Error
+	return storyboards, err
+}
+
+func (s *EpisodePipelineService) videosGenerated(episode *models.Episode) (bool, error) {
+	storyboards, err := s.storyboardsWithoutVideo(episode.ID)
+	return len(storyboards) == 0, err
+}
+
+// runVideos 用每个分镜最新完成的图片生成视频，跳过已有进行中视频生成的分镜
+func (s *EpisodePipelineService) runVideos(episode *models.Episode) error {
+	storyboards, err := s.storyboardsWithoutVideo(episode.ID)
+	if err != nil {
+		return err
+	}
+	inFlight := []models.VideoStatus{models.VideoStatusPending, models.VideoStatusProcessing}
+	var ids []uint
+	for _, sb := range storyboards {
+		ids = append(ids, sb.ID)
+		var busy int64
+		s.db.Model(&models.VideoGeneration{}).Where("storyboard_id = ? AND status IN ?", sb.ID, inFlight).Count(&busy)
+		if busy > 0 {
+			continue
+		}
+		var imageGen models.ImageGeneration
+		if err := s.db.Where("storyboard_id = ? AND status = ?", sb.ID, models.ImageStatusCompleted).
+			Order("created_at DESC").First(&imageGen).Error; err != nil {
+			return fmt.Errorf("分镜 %d 没有已完成的图片", sb.StoryboardNumber)
+		}
+		if _, err := s.videoService.GenerateVideoFromImage(imageGen.ID); err != nil {
+			return fmt.Errorf("分镜 %d: %w", sb.StoryboardNumber, err)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := waitUntil(func() (bool, error) {
+		var busy int64
+		err := s.db.Model(&models.VideoGeneration{}).Where("storyboard_id IN ? AND status IN ?", ids, inFlight).Count(&busy).Error
+		return busy == 0, err
+	}); err != nil {
+		return err
+	}
+	missing, err := s.storyboardsWithoutVideo(episode.ID)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%d 个分镜视频生成失败", len(missing))
+	}
+	return nil
+}
+
+func (s *EpisodePipelineService) episodeFinalized(episode *models.Episode) (bool, error) {
+	var current models.Episode
+	if err := s.db.Select("id", "status", "video_url").Where("id = ?", episode.ID).First(&current).Error; err != nil {
+		return false, err
+	}
+	return current.Status == "completed" && getString(current.VideoURL) != "", nil
+}
+
+func (s *EpisodePipelineService) runFinalize(episode *models.Episode) error {
+	result, err := s.videoMergeService.FinalizeEpisode(fmt.Sprintf("%d", episode.ID), nil)
+	if err != nil {
+		return err
+	}
+	mergeID, ok := result["merge_id"].(uint)
+	if !ok {
+		return fmt.Errorf("视频合成任务创建失败")
+	}
+	return waitUntil(func() (bool, error) {
+		var merge models.VideoMerge
+		if err := s.db.Select("id", "status", "error_msg").Where("id = ?", mergeID).First(&merge).Error; err != nil {
+			return false, err
+		}
+		switch merge.Status {
+		case models.VideoMergeStatusCompleted:
+			return true, nil
+		case models.VideoMergeStatusFailed:
+			return false, fmt.Errorf("视频合成失败: %s", getString(merge.ErrorMsg))
+		}
+		return false, nil
+	})
+}
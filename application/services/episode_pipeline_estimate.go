@@ -0,0 +1,174 @@
+package services
+
+import (
+	"math"
+	"unicode/utf8"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/config"
+)
+
+const (
+	// estimatedScriptCharsPerStoryboard 还没有分镜时按剧本长度估算分镜数，每个分镜约对应的剧本字数
+	estimatedScriptCharsPerStoryboard = 120
+	// estimatedTaskConcurrency 帧提示词和视频任务同时提交后，估算耗时时假设服务商同时处理的数量
+	estimatedTaskConcurrency = 4
+)
+
+// 未配置 model_costs 时各类型单次调用的平均耗时（秒）
+var defaultCallSeconds = map[string]float64{
+	"text":  20,
+	"image": 30,
+	"video": 120,
+}
+
+// PipelineStageEstimate 单个流水线阶段的预估
+type PipelineStageEstimate struct {
+	Stage       string  `json:"stage"`
+	Done        bool    `json:"done"`         // 已完成的阶段不会再执行，费用和耗时为 0
+	ServiceType string  `json:"service_type"` // text/image/video，合成阶段为空
+	Model       string  `json:"model"`
+	Calls       int     `json:"calls"`
+	Cost        float64 `json:"cost"`
+	Seconds     float64 `json:"seconds"`
+	Estimated   bool    `json:"estimated"` // 调用次数是按剧本长度推算的，而不是按已有分镜统计
+}
+
+// EpisodePipelineEstimate 剧集流水线剩余阶段的费用和耗时预估
+type EpisodePipelineEstimate struct {
+	EpisodeID      uint                    `json:"episode_id"`
+	Scenes         int                     `json:"scenes"`
+	Storyboards    int                     `json:"storyboards"`
+	Frames         int                     `json:"frames"` // 已有首帧提示词的分镜数
+	Stages         []PipelineStageEstimate `json:"stages"`
+	TotalCost      float64                 `json:"total_cost"`
+	TotalSeconds   float64                 `json:"total_seconds"`
+	UnpricedModels []string                `json:"unpriced_models"` // model_costs 中没有配置费用的模型，费用按 0 计算
+}
+
+// EstimateEpisodePipeline 预估执行剧集流水线剩余阶段的调用次数、费用和耗时，不创建任何任务
+// 费用和单次耗时来自 ai.model_costs，结果只用于帮助用户决定执行完整流水线还是其中部分阶段
+func (s *EpisodePipelineService) EstimateEpisodePipeline(episodeID string) (*EpisodePipelineEstimate, error) {
+	var episode models.Episode
+	if err := s.db.Where("id = ?", episodeID).First(&episode).Error; err != nil {
+		return nil, ErrEpisodeNotFound
+	}
+
+	estimate := &EpisodePipelineEstimate{EpisodeID: episode.ID, UnpricedModels: []string{}}
+
+	var scenes, storyboards, frames, withoutImage, withoutVideo int64
+	s.db.Model(&models.Scene{}).Where("episode_id = ?", episode.ID).Count(&scenes)
+	s.db.Model(&models.Storyboard{}).Where("episode_id = ?", episode.ID).Count(&storyboards)
+	s.db.Model(&models.FramePrompt{}).
+		Where("frame_type = ? AND storyboard_id IN (?)", string(FrameTypeFirst),
+			s.db.Model(&models.Storyboard{}).Select("id").Where("episode_id = ?", episode.ID)).
+		Distinct("storyboard_id").Count(&frames)
+	s.db.Model(&models.Storyboard{}).
+		Where("episode_id = ? AND (composed_image IS NULL OR composed_image = '')", episode.ID).Count(&withoutImage)
+	s.db.Model(&models.Storyboard{}).
+		Where("episode_id = ? AND (video_url IS NULL OR video_url = '')", episode.ID).Count(&withoutVideo)
+	estimate.Scenes = int(scenes)
+	estimate.Storyboards = int(storyboards)
+	estimate.Frames = int(frames)
+
+	// 还没有分镜时，后续阶段的调用次数按剧本长度推算
+	estimated := storyboards == 0
+	perStoryboard := func(count int64) int {
+		if estimated {
+			return s.estimateStoryboardCount(&episode)
+		}
+		return int(count)
+	}
+
+	calls := map[string]int{
+		models.PipelineStageExtract:    1,
+		models.PipelineStageStoryboard: 1,
+		models.PipelineStageFrames:     perStoryboard(storyboards - frames),
+		models.PipelineStageImages:     perStoryboard(withoutImage),
+		models.PipelineStageVideos:     perStoryboard(withoutVideo),
+	}
+	serviceTypes := map[string]string{
+		models.PipelineStageExtract:    "text",
+		models.PipelineStageStoryboard: "text",
+		models.PipelineStageFrames:     "text",
+		models.PipelineStageImages:     "image",
+		models.PipelineStageVideos:     "video",
+	}
+
+	cfg := config.CurrentOr(s.config)
+	unpriced := make(map[string]bool)
+	for _, stage := range s.stages() {
+		done, err := stage.done(&episode)
+		if err != nil {
+			return nil, err
+		}
+		item := PipelineStageEstimate{Stage: stage.name, Done: done, ServiceType: serviceTypes[stage.name]}
+		if done || item.ServiceType == "" {
+			estimate.Stages = append(estimate.Stages, item)
+			continue
+		}
+
+		item.Model = s.defaultModel(item.ServiceType)
+		item.Calls = calls[stage.name]
+		item.Estimated = estimated && stage.name != models.PipelineStageExtract && stage.name != models.PipelineStageStoryboard
+		cost, priced := lookupModelCost(cfg, item.ServiceType, item.Model)
+		if !priced && item.Model != "" && !unpriced[item.Model] {
+			unpriced[item.Model] = true
+			estimate.UnpricedModels = append(estimate.UnpricedModels, item.Model)
+		}
+		item.Cost = cost.PerCall * float64(item.Calls)
+		item.Seconds = cost.AvgSeconds * math.Ceil(float64(item.Calls)/float64(stageConcurrency(cfg, stage.name)))
+
+		estimate.TotalCost += item.Cost
+		estimate.TotalSeconds += item.Seconds
+		estimate.Stages = append(estimate.Stages, item)
+	}
+	return estimate, nil
+}
+
+// estimateStoryboardCount 按剧本长度推算分镜数，不超过每集分镜上限
+func (s *EpisodePipelineService) estimateStoryboardCount(episode *models.Episode) int {
+	count := utf8.RuneCountInString(getString(episode.ScriptContent)) / estimatedScriptCharsPerStoryboard
+	if count < 1 {
+		count = 1
+	}
+	if limit := config.CurrentOr(s.config).Limits.StoryboardsPerEpisode(); limit > 0 && count > limit {
+		count = limit
+	}
+	return count
+}
+
+// defaultModel 该类型默认配置的第一个模型，没有激活配置时返回空字符串
+func (s *EpisodePipelineService) defaultModel(serviceType string) string {
+	cfg, err := s.aiService.GetDefaultConfig(serviceType)
+	if err != nil || len(cfg.Model) == 0 {
+		return ""
+	}
+	return cfg.Model[0]
+}
+
+// lookupModelCost 先按模型名、再按服务类型查找 model_costs，耗时未配置时使用内置值；第二个返回值表示是否配置了费用
+func lookupModelCost(cfg *config.Config, serviceType, model string) (config.ModelCost, bool) {
+	cost, ok := cfg.AI.ModelCosts[model]
+	if !ok || model == "" {
+		cost, ok = cfg.AI.ModelCosts[serviceType]
+	}
+	if cost.AvgSeconds <= 0 {
+		cost.AvgSeconds = defaultCallSeconds[serviceType]
+	}
+	return cost, ok
+}
+
+// stageConcurrency 估算耗时时阶段内同时执行的调用数：图片受 image_workers 限制，提取和分镜是单次调用
+func stageConcurrency(cfg *config.Config, stage string) int {
+	switch stage {
+	case models.PipelineStageImages:
+		if cfg.AI.ImageWorkers > 0 {
+			return cfg.AI.ImageWorkers
+		}
+		return defaultImageWorkers
+	case models.PipelineStageFrames, models.PipelineStageVideos:
+		return estimatedTaskConcurrency
+	}
+	return 1
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/config"
+)
+
+// RenderPlan 供外部渲染服务使用的剧集渲染计划
+type RenderPlan struct {
+	EpisodeID     uint             `json:"episode_id"`
+	DramaID       uint             `json:"drama_id"`
+	EpisodeNumber int              `json:"episode_number"`
+	Title         string           `json:"title"`
+	TotalDuration int              `json:"total_duration"`
+	Shots         []RenderPlanShot `json:"shots"`
+}
+
+// RenderPlanShot 单个镜头的完整渲染数据
+type RenderPlanShot struct {
+	StoryboardID     uint                  `json:"storyboard_id"`
+	StoryboardNumber int                   `json:"storyboard_number"`
+	Title            string                `json:"title"`
+	Duration         int                   `json:"duration"`
+	StartTime        int                   `json:"start_time"` // 在剧集中的起始秒数
+	VideoPrompt      string                `json:"video_prompt"`
+	Dialogue         string                `json:"dialogue"`
+	BgmPrompt        string                `json:"bgm_prompt"`
+	SoundEffect      string                `json:"sound_effect"`
+	ComposedImage    string                `json:"composed_image"`
+	BackgroundImage  string                `json:"background_image"`
+	VideoURL         string                `json:"video_url"`
+	Frames           []RenderPlanFrame     `json:"frames"`
+	Characters       []RenderPlanCharacter `json:"characters"`
+}
+
+// RenderPlanFrame 帧提示词及其最新生成的图片
+type RenderPlanFrame struct {
+	FrameType string `json:"frame_type"`
+	Prompt    string `json:"prompt"`
+	Layout    string `json:"layout,omitempty"`
+	ImageURL  string `json:"image_url"`
+}
+
+// RenderPlanCharacter 镜头中出现的角色
+type RenderPlanCharacter struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	ImageURL string `json:"image_url"`
+}
+
+// ExportEpisodeRenderPlan 按分镜顺序汇总剧集的视频提示词、帧提示词、图片和角色，资源地址均已解析为可访问URL
+func (s *StoryboardService) ExportEpisodeRenderPlan(episodeID string) (*RenderPlan, error) {
+	var episode models.Episode
+	if err := s.db.Where("id = ?", episodeID).First(&episode).Error; err != nil {
+		return nil, ErrEpisodeNotFound
+	}
+
+	var storyboards []models.Storyboard
+	if err := s.db.Where("episode_id = ?", episode.ID).
+		Preload("Characters").
+		Preload("Background").
+		Order("storyboard_number ASC").
+		Find(&storyboards).Error; err != nil {
+		return nil, fmt.Errorf("获取分镜失败: %w", err)
+	}
+
+	storyboardIDs := make([]uint, len(storyboards))
+	for i, sb := range storyboards {
+		storyboardIDs[i] = sb.ID
+	}
+
+	var framePrompts []models.FramePrompt
+	if len(storyboardIDs) > 0 {
+		if err := s.db.Where("storyboard_id IN ?", storyboardIDs).Order("id ASC").Find(&framePrompts).Error; err != nil {
+			return nil, fmt.Errorf("获取帧提示词失败: %w", err)
+		}
+	}
+	framesByStoryboard := make(map[uint][]models.FramePrompt)
+	for _, fp := range framePrompts {
+		framesByStoryboard[fp.StoryboardID] = append(framesByStoryboard[fp.StoryboardID], fp)
+	}
+
+	// 每个分镜每种帧类型取最新一张已完成的图片
+	var imageGens []models.ImageGeneration
+	if len(storyboardIDs) > 0 {
+		if err := s.db.Where("storyboard_id IN ? AND status = ? AND preview = ?", storyboardIDs, models.ImageStatusCompleted, false).
+			Order("id DESC").
+			Find(&imageGens).Error; err != nil {
+			return nil, fmt.Errorf("获取分镜图片失败: %w", err)
+		}
+	}
+	frameImages := make(map[string]string)
+	for _, ig := range imageGens {
+		frameType := ""
+		if ig.FrameType != nil {
+			frameType = *ig.FrameType
+		}
+		key := fmt.Sprintf("%d:%s", *ig.StoryboardID, frameType)
+		if _, ok := frameImages[key]; !ok {
+			frameImages[key] = s.resolveAssetURL(ig.LocalPath, ig.ImageURL)
+		}
+	}
+
+	plan := &RenderPlan{
+		EpisodeID:     episode.ID,
+		DramaID:       episode.DramaID,
+		EpisodeNumber: episode.EpisodeNum,
+		Title:         episode.Title,
+		Shots:         make([]RenderPlanShot, 0, len(storyboards)),
+	}
+
+	for _, sb := range storyboards {
+		shot := RenderPlanShot{
+			StoryboardID:     sb.ID,
+			StoryboardNumber: sb.StoryboardNumber,
+			Title:            getString(sb.Title),
+			Duration:         sb.Duration,
+			StartTime:        plan.TotalDuration,
+			VideoPrompt:      getString(sb.VideoPrompt),
+			Dialogue:         getString(sb.Dialogue),
+			BgmPrompt:        getString(sb.BgmPrompt),
+			SoundEffect:      getString(sb.SoundEffect),
+			ComposedImage:    s.resolveAssetURL(nil, sb.ComposedImage),
+			VideoURL:         s.resolveAssetURL(nil, sb.VideoURL),
+			Frames:           []RenderPlanFrame{},
+			Characters:       []RenderPlanCharacter{},
+		}
+		if sb.Background != nil {
+			shot.BackgroundImage = s.resolveAssetURL(sb.Background.LocalPath, sb.Background.ImageURL)
+		}
+
+		for _, fp := range framesByStoryboard[sb.ID] {
+			frame := RenderPlanFrame{
+				FrameType: fp.FrameType,
+				Prompt:    fp.Prompt,
+				ImageURL:  frameImages[fmt.Sprintf("%d:%s", sb.ID, fp.FrameType)],
+			}
+			if fp.Layout != nil {
+				frame.Layout = *fp.Layout
+			}
+			shot.Frames = append(shot.Frames, frame)
+		}
+
+		for _, char := range sb.Characters {
+			shot.Characters = append(shot.Characters, RenderPlanCharacter{
+				ID:       char.ID,
+				Name:     char.Name,
+				ImageURL: s.resolveAssetURL(char.LocalPath, char.ImageURL),
+			})
+		}
+
+		plan.TotalDuration += sb.Duration
+		plan.Shots = append(plan.Shots, shot)
+	}
+
+	return plan, nil
+}
+
+// resolveAssetURL 优先使用本地缓存地址，其次使用原始URL；相对路径会拼接存储访问前缀
+func (s *StoryboardService) resolveAssetURL(localPath, url *string) string {
+	if localPath != nil && *localPath != "" {
+		return s.joinStorageURL(*localPath)
+	}
+	if url == nil || *url == "" {
+		return ""
+	}
+	if strings.HasPrefix(*url, "http://") || strings.HasPrefix(*url, "https://") || strings.HasPrefix(*url, "data:") {
+		return *url
+	}
+	return s.joinStorageURL(*url)
+}
+
+func (s *StoryboardService) joinStorageURL(path string) string {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	baseURL := strings.TrimSuffix(config.CurrentOr(s.config).Storage.BaseURL, "/")
+	return baseURL + "/" + strings.TrimPrefix(path, "/")
+}
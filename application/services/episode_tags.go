@@ -0,0 +1,118 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"gorm.io/gorm"
+)
+
+// maxEpisodeTags 单集最多保存的标签数
+const maxEpisodeTags = 20
+
+// UpdateEpisodeRequest 更新剧集基本信息，未提供的字段保持不变；tags 传空数组表示清空标签
+type UpdateEpisodeRequest struct {
+	Title       *string   `json:"title"`
+	Description *string   `json:"description"`
+	Tags        *[]string `json:"tags"`
+}
+
+// normalizeEpisodeTags 去除首尾空白、空标签和重复标签（忽略大小写），保留首次出现的写法和顺序
+func normalizeEpisodeTags(tags []string) []string {
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// hasEpisodeTag 判断剧集是否带有指定标签（忽略大小写）
+func hasEpisodeTag(episode *models.Episode, tag string) bool {
+	for _, t := range episode.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListEpisodes 按集数返回剧本的剧集（不预加载关联数据），tag 不为空时只返回带该标签的剧集
+func (s *DramaService) ListEpisodes(dramaID string, tag string) ([]models.Episode, error) {
+	var drama models.Drama
+	if err := s.db.Select("id").Where("id = ?", dramaID).First(&drama).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDramaNotFound
+		}
+		return nil, err
+	}
+
+	var episodes []models.Episode
+	if err := s.db.Where("drama_id = ?", drama.ID).Order("episode_number ASC").Find(&episodes).Error; err != nil {
+		return nil, fmt.Errorf("获取剧集失败: %w", err)
+	}
+
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return episodes, nil
+	}
+	filtered := make([]models.Episode, 0, len(episodes))
+	for i := range episodes {
+		if hasEpisodeTag(&episodes[i], tag) {
+			filtered = append(filtered, episodes[i])
+		}
+	}
+	return filtered, nil
+}
+
+// UpdateEpisode 更新剧集标题、简介和标签
+func (s *DramaService) UpdateEpisode(episodeID string, req *UpdateEpisodeRequest) (*models.Episode, error) {
+	var episode models.Episode
+	if err := s.db.Where("id = ?", episodeID).First(&episode).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEpisodeNotFound
+		}
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			return nil, &ServiceError{Kind: ErrInvalidInput, Message: "剧集标题不能为空"}
+		}
+		updates["title"] = title
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Tags != nil {
+		tags := normalizeEpisodeTags(*req.Tags)
+		if len(tags) > maxEpisodeTags {
+			return nil, &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf("每集最多 %d 个标签", maxEpisodeTags)}
+		}
+		// map 更新不经过 serializer，标签单独按字段更新
+		if err := s.db.Model(&episode).Select("tags").Updates(&models.Episode{Tags: tags}).Error; err != nil {
+			return nil, fmt.Errorf("更新剧集标签失败: %w", err)
+		}
+	}
+	if len(updates) > 0 {
+		if err := s.db.Model(&episode).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("更新剧集失败: %w", err)
+		}
+	}
+
+	if err := s.db.Where("id = ?", episode.ID).First(&episode).Error; err != nil {
+		return nil, err
+	}
+	s.log.Infow("Episode updated", "episode_id", episode.ID, "tags", episode.Tags)
+	return &episode, nil
+}
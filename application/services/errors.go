@@ -0,0 +1,43 @@
+package services
+
+import "errors"
+
+// 服务层错误分类，handler 通过 errors.Is 映射为对应的 HTTP 状态码
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrInvalidInput = errors.New("invalid input")
+	ErrForbidden    = errors.New("forbidden")
+	ErrConflict     = errors.New("conflict")
+)
+
+// ServiceError 归属于某个分类的具体错误，Error() 保持原有的错误消息
+type ServiceError struct {
+	Kind    error
+	Message string
+}
+
+func (e *ServiceError) Error() string {
+	return e.Message
+}
+
+// Unwrap 使 errors.Is(err, ErrNotFound) 等分类判断生效
+func (e *ServiceError) Unwrap() error {
+	return e.Kind
+}
+
+// 具体的服务层错误
+var (
+	ErrDramaNotFound           = &ServiceError{Kind: ErrNotFound, Message: "drama not found"}
+	ErrEpisodeNotFound         = &ServiceError{Kind: ErrNotFound, Message: "episode not found"}
+	ErrStoryboardNotFound      = &ServiceError{Kind: ErrNotFound, Message: "storyboard not found"}
+	ErrSceneNotFound           = &ServiceError{Kind: ErrNotFound, Message: "scene not found"}
+	ErrCharacterNotFound       = &ServiceError{Kind: ErrNotFound, Message: "character not found"}
+	ErrLibraryItemNotFound     = &ServiceError{Kind: ErrNotFound, Message: "library item not found"}
+	ErrConfigNotFound          = &ServiceError{Kind: ErrNotFound, Message: "config not found"}
+	ErrImageGenerationNotFound = &ServiceError{Kind: ErrNotFound, Message: "image generation not found"}
+	ErrAssetNotFound           = &ServiceError{Kind: ErrNotFound, Message: "asset not found"}
+	ErrUnauthorized            = &ServiceError{Kind: ErrForbidden, Message: "unauthorized"}
+	ErrInvalidFrameType        = &ServiceError{Kind: ErrInvalidInput, Message: "invalid frame_type"}
+	ErrCharacterHasNoImage     = &ServiceError{Kind: ErrInvalidInput, Message: "character has no image"}
+	ErrGenerationInProgress    = &ServiceError{Kind: ErrConflict, Message: "storyboard generation already in progress"}
+)
@@ -0,0 +1,64 @@
+package services
+
+import "github.com/drama-generator/backend/domain/models"
+
+// validFramePromptType 校验帧类型过滤参数，空字符串表示不过滤
+func validFramePromptType(frameType string) bool {
+	switch frameType {
+	case "", models.FrameTypeFirst, models.FrameTypeKey, models.FrameTypeLast, models.FrameTypePanel, models.FrameTypeAction:
+		return true
+	}
+	return false
+}
+
+// ListFramePrompts 分页查询镜头的帧提示词，可按帧类型过滤
+func (s *FramePromptService) ListFramePrompts(storyboardID string, frameType string, page, pageSize int) ([]models.FramePrompt, int64, error) {
+	if !validFramePromptType(frameType) {
+		return nil, 0, ErrInvalidFrameType
+	}
+
+	query := s.db.Model(&models.FramePrompt{}).Where("storyboard_id = ?", storyboardID)
+	if frameType != "" {
+		query = query.Where("frame_type = ?", frameType)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var framePrompts []models.FramePrompt
+	offset := (page - 1) * pageSize
+	if err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&framePrompts).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return framePrompts, total, nil
+}
+
+// ListFramePromptsForEpisode 查询整集所有镜头的帧提示词，按镜头编号排序，可按帧类型过滤
+func (s *FramePromptService) ListFramePromptsForEpisode(episodeID string, frameType string) ([]models.FramePrompt, error) {
+	if !validFramePromptType(frameType) {
+		return nil, ErrInvalidFrameType
+	}
+
+	var episode models.Episode
+	if err := s.db.Where("id = ?", episodeID).First(&episode).Error; err != nil {
+		return nil, ErrEpisodeNotFound
+	}
+
+	query := s.db.Model(&models.FramePrompt{}).
+		Joins("JOIN storyboards ON storyboards.id = frame_prompts.storyboard_id").
+		Where("storyboards.episode_id = ? AND storyboards.deleted_at IS NULL", episode.ID)
+	if frameType != "" {
+		query = query.Where("frame_prompts.frame_type = ?", frameType)
+	}
+
+	var framePrompts []models.FramePrompt
+	if err := query.Order("storyboards.storyboard_number ASC, frame_prompts.id ASC").
+		Find(&framePrompts).Error; err != nil {
+		return nil, err
+	}
+
+	return framePrompts, nil
+}
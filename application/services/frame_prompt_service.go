@@ -45,12 +45,41 @@ const (
 
 // GenerateFramePromptRequest 生成帧提示词请求
 type GenerateFramePromptRequest struct {
-	StoryboardID string    `json:"storyboard_id"`
-	FrameType    FrameType `json:"frame_type"`
+	StoryboardID string      `json:"storyboard_id"`
+	FrameType    FrameType   `json:"frame_type"`
+	FrameTypes   []FrameType `json:"frame_types,omitempty"` // 一次生成多种帧类型，非空时优先于 FrameType
 	// 可选参数
 	PanelCount int `json:"panel_count,omitempty"` // 分镜板格数，默认3
 }
 
+// requestedFrameTypes 返回本次需要生成的帧类型（去重，保持请求顺序）
+func (req GenerateFramePromptRequest) requestedFrameTypes() []FrameType {
+	if len(req.FrameTypes) == 0 {
+		if req.FrameType == "" {
+			return nil
+		}
+		return []FrameType{req.FrameType}
+	}
+	seen := make(map[FrameType]bool, len(req.FrameTypes))
+	types := make([]FrameType, 0, len(req.FrameTypes))
+	for _, ft := range req.FrameTypes {
+		if !seen[ft] {
+			seen[ft] = true
+			types = append(types, ft)
+		}
+	}
+	return types
+}
+
+// isSupportedFrameType 是否为支持的帧类型
+func isSupportedFrameType(ft FrameType) bool {
+	switch ft {
+	case FrameTypeFirst, FrameTypeKey, FrameTypeLast, FrameTypePanel, FrameTypeAction:
+		return true
+	}
+	return false
+}
+
 // FramePromptResponse 帧提示词响应
 type FramePromptResponse struct {
 	FrameType   FrameType          `json:"frame_type"`
@@ -78,6 +107,16 @@ func (s *FramePromptService) GenerateFramePrompt(req GenerateFramePromptRequest,
 		return "", fmt.Errorf("storyboard not found: %w", err)
 	}
 
+	frameTypes := req.requestedFrameTypes()
+	if len(frameTypes) == 0 {
+		return "", &ServiceError{Kind: ErrInvalidInput, Message: "请指定帧类型"}
+	}
+	for _, ft := range frameTypes {
+		if !isSupportedFrameType(ft) {
+			return "", &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf("不支持的帧类型: %s", ft)}
+		}
+	}
+
 	// 创建任务
 	task, err := s.taskService.CreateTask("frame_prompt_generation", req.StoryboardID)
 	if err != nil {
@@ -88,7 +127,7 @@ func (s *FramePromptService) GenerateFramePrompt(req GenerateFramePromptRequest,
 	// 异步处理帧提示词生成
 	go s.processFramePromptGeneration(task.ID, req, model)
 
-	s.log.Infow("Frame prompt generation task created", "task_id", task.ID, "storyboard_id", req.StoryboardID, "frame_type", req.FrameType)
+	s.log.Infow("Frame prompt generation task created", "task_id", task.ID, "storyboard_id", req.StoryboardID, "frame_types", frameTypes)
 	return task.ID, nil
 }
 
@@ -121,25 +160,61 @@ func (s *FramePromptService) processFramePromptGeneration(taskID string, req Gen
 		s.log.Warnw("Failed to load episode and drama", "error", err, "episode_id", storyboard.EpisodeID)
 	}
 	dramaStyle := episode.Drama.Style
+	storyboard.Episode = episode
+
+	frameTypes := req.requestedFrameTypes()
+	responses := make([]*FramePromptResponse, 0, len(frameTypes))
+	for i, frameType := range frameTypes {
+		// 多个帧类型时按完成数量推进子进度
+		progress := i * 100 / len(frameTypes)
+		s.taskService.UpdateTaskStatus(taskID, "processing", progress,
+			fmt.Sprintf("正在生成帧提示词 (%d/%d): %s", i+1, len(frameTypes), frameType))
+
+		response := s.generateFrameTypePrompt(storyboard, scene, dramaStyle, model, frameType, req.PanelCount)
+		if response == nil {
+			s.log.Errorw("Unsupported frame type during frame prompt generation", "frame_type", frameType, "task_id", taskID)
+			s.taskService.UpdateTaskStatus(taskID, "failed", 0, "不支持的帧类型")
+			return
+		}
+		responses = append(responses, response)
+	}
+
+	// 更新任务状态为完成
+	result := map[string]interface{}{
+		"responses":     responses,
+		"storyboard_id": req.StoryboardID,
+		"frame_types":   frameTypes,
+	}
+	if len(responses) == 1 {
+		// 兼容单帧类型请求的返回结构
+		result["response"] = responses[0]
+		result["frame_type"] = string(responses[0].FrameType)
+	}
+	s.taskService.UpdateTaskResult(taskID, result)
+
+	s.log.Infow("Frame prompt generation completed", "task_id", taskID, "storyboard_id", req.StoryboardID, "frame_types", frameTypes)
+}
 
+// generateFrameTypePrompt 生成并保存单个帧类型的提示词，不支持的类型返回 nil
+func (s *FramePromptService) generateFrameTypePrompt(storyboard models.Storyboard, scene *models.Scene, dramaStyle, model string, frameType FrameType, panelCount int) *FramePromptResponse {
+	storyboardID := fmt.Sprintf("%d", storyboard.ID)
 	response := &FramePromptResponse{
-		FrameType: req.FrameType,
+		FrameType: frameType,
 	}
 
-	// 生成提示词
-	switch req.FrameType {
+	switch frameType {
 	case FrameTypeFirst:
 		response.SingleFrame = s.generateFirstFrame(storyboard, scene, dramaStyle, model)
 		// 保存单帧提示词
-		s.saveFramePrompt(req.StoryboardID, string(req.FrameType), response.SingleFrame.Prompt, response.SingleFrame.Description, "")
+		s.saveFramePrompt(storyboardID, string(frameType), response.SingleFrame.Prompt, response.SingleFrame.Description, "")
 	case FrameTypeKey:
 		response.SingleFrame = s.generateKeyFrame(storyboard, scene, dramaStyle, model)
-		s.saveFramePrompt(req.StoryboardID, string(req.FrameType), response.SingleFrame.Prompt, response.SingleFrame.Description, "")
+		s.saveFramePrompt(storyboardID, string(frameType), response.SingleFrame.Prompt, response.SingleFrame.Description, "")
 	case FrameTypeLast:
 		response.SingleFrame = s.generateLastFrame(storyboard, scene, dramaStyle, model)
-		s.saveFramePrompt(req.StoryboardID, string(req.FrameType), response.SingleFrame.Prompt, response.SingleFrame.Description, "")
+		s.saveFramePrompt(storyboardID, string(frameType), response.SingleFrame.Prompt, response.SingleFrame.Description, "")
 	case FrameTypePanel:
-		count := req.PanelCount
+		count := panelCount
 		if count == 0 {
 			count = 3
 		}
@@ -150,7 +225,7 @@ func (s *FramePromptService) processFramePromptGeneration(taskID string, req Gen
 			prompts = append(prompts, frame.Prompt)
 		}
 		combinedPrompt := strings.Join(prompts, "\n---\n")
-		s.saveFramePrompt(req.StoryboardID, string(req.FrameType), combinedPrompt, "分镜板组合提示词", response.MultiFrame.Layout)
+		s.saveFramePrompt(storyboardID, string(frameType), combinedPrompt, "分镜板组合提示词", response.MultiFrame.Layout)
 	case FrameTypeAction:
 		response.MultiFrame = s.generateActionSequence(storyboard, scene, dramaStyle, model)
 		var prompts []string
@@ -158,21 +233,11 @@ func (s *FramePromptService) processFramePromptGeneration(taskID string, req Gen
 			prompts = append(prompts, frame.Prompt)
 		}
 		combinedPrompt := strings.Join(prompts, "\n---\n")
-		s.saveFramePrompt(req.StoryboardID, string(req.FrameType), combinedPrompt, "动作序列组合提示词", response.MultiFrame.Layout)
+		s.saveFramePrompt(storyboardID, string(frameType), combinedPrompt, "动作序列组合提示词", response.MultiFrame.Layout)
 	default:
-		s.log.Errorw("Unsupported frame type during frame prompt generation", "frame_type", req.FrameType, "task_id", taskID)
-		s.taskService.UpdateTaskStatus(taskID, "failed", 0, "不支持的帧类型")
-		return
+		return nil
 	}
-
-	// 更新任务状态为完成
-	s.taskService.UpdateTaskResult(taskID, map[string]interface{}{
-		"response":      response,
-		"storyboard_id": req.StoryboardID,
-		"frame_type":    string(req.FrameType),
-	})
-
-	s.log.Infow("Frame prompt generation completed", "task_id", taskID, "storyboard_id", req.StoryboardID, "frame_type", req.FrameType)
+	return response
 }
 
 // saveFramePrompt 保存帧提示词到数据库
@@ -199,6 +264,15 @@ func (s *FramePromptService) saveFramePrompt(storyboardID, frameType, prompt, de
 	}
 }
 
+// generateText 用分镜所属剧本的文本配置调用AI：剧本绑定了配置时始终使用该配置，否则按 model 查找，找不到时使用默认配置
+func (s *FramePromptService) generateText(sb models.Storyboard, model, userPrompt, systemPrompt string) (string, error) {
+	dramaID := sb.Episode.DramaID
+	if dramaID == 0 {
+		s.db.Model(&models.Episode{}).Select("drama_id").Where("id = ?", sb.EpisodeID).Scan(&dramaID)
+	}
+	return s.aiService.GenerateTextForDrama(dramaID, model, userPrompt, systemPrompt)
+}
+
 // mustParseUint 辅助函数
 func mustParseUint(s string) uint64 {
 	var result uint64
@@ -215,24 +289,12 @@ func (s *FramePromptService) generateFirstFrame(sb models.Storyboard, scene *mod
 	systemPrompt := s.promptI18n.GetFirstFramePrompt(dramaStyle)
 	userPrompt := s.promptI18n.FormatUserPrompt("frame_info", contextInfo)
 
-	// 调用AI生成（如果指定了模型则使用指定的模型）
-	var aiResponse string
-	var err error
-	if model != "" {
-		client, getErr := s.aiService.GetAIClientForModel("text", model)
-		if getErr != nil {
-			s.log.Warnw("Failed to get client for specified model, using default", "model", model, "error", getErr)
-			aiResponse, err = s.aiService.GenerateText(userPrompt, systemPrompt)
-		} else {
-			aiResponse, err = client.GenerateText(userPrompt, systemPrompt)
-		}
-	} else {
-		aiResponse, err = s.aiService.GenerateText(userPrompt, systemPrompt)
-	}
+	// 调用AI生成（剧本绑定的配置优先，否则使用指定的模型）
+	aiResponse, err := s.generateText(sb, model, userPrompt, systemPrompt)
 	if err != nil {
 		s.log.Warnw("AI generation failed, using fallback", "error", err)
 		// 降级方案：使用简单拼接
-		fallbackPrompt := s.buildFallbackPrompt(sb, scene, "first frame, static shot")
+		fallbackPrompt := s.buildFallbackPrompt(sb, scene, dramaStyle, FrameTypeFirst)
 		return &SingleFramePrompt{
 			Prompt:      fallbackPrompt,
 			Description: "镜头开始的静态画面，展示初始状态",
@@ -244,7 +306,7 @@ func (s *FramePromptService) generateFirstFrame(sb models.Storyboard, scene *mod
 	if result == nil {
 		// JSON解析失败，使用降级方案
 		s.log.Warnw("Failed to parse AI JSON response, using fallback", "storyboard_id", sb.ID, "response", aiResponse)
-		fallbackPrompt := s.buildFallbackPrompt(sb, scene, "first frame, static shot")
+		fallbackPrompt := s.buildFallbackPrompt(sb, scene, dramaStyle, FrameTypeFirst)
 		return &SingleFramePrompt{
 			Prompt:      fallbackPrompt,
 			Description: "镜头开始的静态画面，展示初始状态",
@@ -263,23 +325,11 @@ func (s *FramePromptService) generateKeyFrame(sb models.Storyboard, scene *model
 	systemPrompt := s.promptI18n.GetKeyFramePrompt(dramaStyle)
 	userPrompt := s.promptI18n.FormatUserPrompt("key_frame_info", contextInfo)
 
-	// 调用AI生成（如果指定了模型则使用指定的模型）
-	var aiResponse string
-	var err error
-	if model != "" {
-		client, getErr := s.aiService.GetAIClientForModel("text", model)
-		if getErr != nil {
-			s.log.Warnw("Failed to get client for specified model, using default", "model", model, "error", getErr)
-			aiResponse, err = s.aiService.GenerateText(userPrompt, systemPrompt)
-		} else {
-			aiResponse, err = client.GenerateText(userPrompt, systemPrompt)
-		}
-	} else {
-		aiResponse, err = s.aiService.GenerateText(userPrompt, systemPrompt)
-	}
+	// 调用AI生成（剧本绑定的配置优先，否则使用指定的模型）
+	aiResponse, err := s.generateText(sb, model, userPrompt, systemPrompt)
 	if err != nil {
 		s.log.Warnw("AI generation failed, using fallback", "error", err)
-		fallbackPrompt := s.buildFallbackPrompt(sb, scene, "key frame, dynamic action")
+		fallbackPrompt := s.buildFallbackPrompt(sb, scene, dramaStyle, FrameTypeKey)
 		return &SingleFramePrompt{
 			Prompt:      fallbackPrompt,
 			Description: "动作高潮瞬间，展示关键动作",
@@ -291,7 +341,7 @@ func (s *FramePromptService) generateKeyFrame(sb models.Storyboard, scene *model
 	if result == nil {
 		// JSON解析失败，使用降级方案
 		s.log.Warnw("Failed to parse AI JSON response, using fallback", "storyboard_id", sb.ID, "response", aiResponse)
-		fallbackPrompt := s.buildFallbackPrompt(sb, scene, "key frame, dynamic action")
+		fallbackPrompt := s.buildFallbackPrompt(sb, scene, dramaStyle, FrameTypeKey)
 		return &SingleFramePrompt{
 			Prompt:      fallbackPrompt,
 			Description: "动作高潮瞬间，展示关键动作",
@@ -310,23 +360,11 @@ func (s *FramePromptService) generateLastFrame(sb models.Storyboard, scene *mode
 	systemPrompt := s.promptI18n.GetLastFramePrompt(dramaStyle)
 	userPrompt := s.promptI18n.FormatUserPrompt("last_frame_info", contextInfo)
 
-	// 调用AI生成（如果指定了模型则使用指定的模型）
-	var aiResponse string
-	var err error
-	if model != "" {
-		client, getErr := s.aiService.GetAIClientForModel("text", model)
-		if getErr != nil {
-			s.log.Warnw("Failed to get client for specified model, using default", "model", model, "error", getErr)
-			aiResponse, err = s.aiService.GenerateText(userPrompt, systemPrompt)
-		} else {
-			aiResponse, err = client.GenerateText(userPrompt, systemPrompt)
-		}
-	} else {
-		aiResponse, err = s.aiService.GenerateText(userPrompt, systemPrompt)
-	}
+	// 调用AI生成（剧本绑定的配置优先，否则使用指定的模型）
+	aiResponse, err := s.generateText(sb, model, userPrompt, systemPrompt)
 	if err != nil {
 		s.log.Warnw("AI generation failed, using fallback", "error", err)
-		fallbackPrompt := s.buildFallbackPrompt(sb, scene, "last frame, final state")
+		fallbackPrompt := s.buildFallbackPrompt(sb, scene, dramaStyle, FrameTypeLast)
 		return &SingleFramePrompt{
 			Prompt:      fallbackPrompt,
 			Description: "镜头结束画面，展示最终状态和结果",
@@ -338,7 +376,7 @@ func (s *FramePromptService) generateLastFrame(sb models.Storyboard, scene *mode
 	if result == nil {
 		// JSON解析失败，使用降级方案
 		s.log.Warnw("Failed to parse AI JSON response, using fallback", "storyboard_id", sb.ID, "response", aiResponse)
-		fallbackPrompt := s.buildFallbackPrompt(sb, scene, "last frame, final state")
+		fallbackPrompt := s.buildFallbackPrompt(sb, scene, dramaStyle, FrameTypeLast)
 		return &SingleFramePrompt{
 			Prompt:      fallbackPrompt,
 			Description: "镜头结束画面，展示最终状态和结果",
@@ -387,25 +425,13 @@ func (s *FramePromptService) generateActionSequence(sb models.Storyboard, scene
 	systemPrompt := s.promptI18n.GetActionSequenceFramePrompt(dramaStyle)
 	userPrompt := s.promptI18n.FormatUserPrompt("frame_info", contextInfo)
 
-	// 调用AI生成（如果指定了模型则使用指定的模型）
-	var aiResponse string
-	var err error
-	if model != "" {
-		client, getErr := s.aiService.GetAIClientForModel("text", model)
-		if getErr != nil {
-			s.log.Warnw("Failed to get client for specified model, using default", "model", model, "error", getErr)
-			aiResponse, err = s.aiService.GenerateText(userPrompt, systemPrompt)
-		} else {
-			aiResponse, err = client.GenerateText(userPrompt, systemPrompt)
-		}
-	} else {
-		aiResponse, err = s.aiService.GenerateText(userPrompt, systemPrompt)
-	}
+	// 调用AI生成（剧本绑定的配置优先，否则使用指定的模型）
+	aiResponse, err := s.generateText(sb, model, userPrompt, systemPrompt)
 
 	if err != nil {
 		s.log.Warnw("AI generation failed for action sequence, using fallback", "error", err)
 		// 降级方案：使用简单拼接
-		fallbackPrompt := s.buildFallbackPrompt(sb, scene, "3x3 storyboard grid action sequence, character consistency, continuous movement progression")
+		fallbackPrompt := s.buildFallbackPrompt(sb, scene, dramaStyle, FrameTypeAction)
 		return &MultiFramePrompt{
 			Layout: "grid_3x3",
 			Frames: []SingleFramePrompt{
@@ -422,7 +448,7 @@ func (s *FramePromptService) generateActionSequence(sb models.Storyboard, scene
 	if result == nil {
 		// JSON解析失败，使用降级方案
 		s.log.Warnw("Failed to parse AI JSON response for action sequence, using fallback", "storyboard_id", sb.ID, "response", aiResponse)
-		fallbackPrompt := s.buildFallbackPrompt(sb, scene, "3x3 storyboard grid action sequence, character consistency, continuous movement progression")
+		fallbackPrompt := s.buildFallbackPrompt(sb, scene, dramaStyle, FrameTypeAction)
 		return &MultiFramePrompt{
 			Layout: "grid_3x3",
 			Frames: []SingleFramePrompt{
@@ -458,13 +484,17 @@ func (s *FramePromptService) buildStoryboardContext(sb models.Storyboard, scene
 	}
 
 	// 角色
-	if len(sb.Characters) > 0 {
+	characters := withoutExcludedCharacters(sb.Characters, sb.ExcludedCharacters)
+	if len(characters) > 0 {
 		var charNames []string
-		for _, char := range sb.Characters {
+		for _, char := range characters {
 			charNames = append(charNames, char.Name)
 		}
 		parts = append(parts, s.promptI18n.FormatUserPrompt("characters_label", strings.Join(charNames, ", ")))
 	}
+	if excluded := excludedCharacterNames(s.db, &sb); len(excluded) > 0 {
+		parts = append(parts, s.promptI18n.FormatUserPrompt("excluded_label", strings.Join(excluded, ", ")))
+	}
 
 	// 动作
 	if sb.Action != nil && *sb.Action != "" {
@@ -501,7 +531,7 @@ func (s *FramePromptService) buildStoryboardContext(sb models.Storyboard, scene
 }
 
 // buildFallbackPrompt 构建降级提示词（AI失败时使用）
-func (s *FramePromptService) buildFallbackPrompt(sb models.Storyboard, scene *models.Scene, suffix string) string {
+func (s *FramePromptService) buildFallbackPrompt(sb models.Storyboard, scene *models.Scene, dramaStyle string, frameType FrameType) string {
 	var parts []string
 
 	// 场景
@@ -510,10 +540,11 @@ func (s *FramePromptService) buildFallbackPrompt(sb models.Storyboard, scene *mo
 	}
 
 	// 角色
-	if len(sb.Characters) > 0 {
-		for _, char := range sb.Characters {
-			parts = append(parts, char.Name)
-		}
+	for _, char := range withoutExcludedCharacters(sb.Characters, sb.ExcludedCharacters) {
+		parts = append(parts, char.Name)
+	}
+	if excluded := excludedCharacterNames(s.db, &sb); len(excluded) > 0 {
+		parts = append(parts, s.promptI18n.FormatUserPrompt("excluded_label", strings.Join(excluded, ", ")))
 	}
 
 	// 氛围
@@ -521,6 +552,6 @@ func (s *FramePromptService) buildFallbackPrompt(sb models.Storyboard, scene *mo
 		parts = append(parts, *sb.Atmosphere)
 	}
 
-	parts = append(parts, "anime style", suffix)
+	parts = appendStyleAndFrame(parts, dramaStyle, frameType)
 	return strings.Join(parts, ", ")
 }
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/drama-generator/backend/domain/models"
+	"gorm.io/gorm"
+)
+
+// generationHistoryTargets 返回生成完成后会被写回图片的实体，与 applyCompletedImage 的更新分支一致
+func generationHistoryTargets(imageGen *models.ImageGeneration) map[string]uint {
+	targets := make(map[string]uint)
+	if imageGen.StoryboardID != nil {
+		targets[models.HistoryEntityStoryboard] = *imageGen.StoryboardID
+	}
+	if imageGen.SceneID != nil && imageGen.ImageType == string(models.ImageTypeScene) {
+		targets[models.HistoryEntityScene] = *imageGen.SceneID
+	}
+	if imageGen.CharacterID != nil {
+		targets[models.HistoryEntityCharacter] = *imageGen.CharacterID
+	}
+	if imageGen.PropID != nil {
+		targets[models.HistoryEntityProp] = *imageGen.PropID
+	}
+	return targets
+}
+
+// recordGenerationHistory 为生成结果写回的每个实体追加一条历史记录
+func (s *ImageGenerationService) recordGenerationHistory(imageGen *models.ImageGeneration, imageURL string, localPath *string) {
+	targets := generationHistoryTargets(imageGen)
+	if len(targets) == 0 || imageURL == "" {
+		return
+	}
+
+	records := make([]models.GenerationHistory, 0, len(targets))
+	for entityType, entityID := range targets {
+		records = append(records, models.GenerationHistory{
+			EntityType:        entityType,
+			EntityID:          entityID,
+			ImageGenerationID: imageGen.ID,
+			ImageURL:          imageURL,
+			LocalPath:         localPath,
+		})
+	}
+	if err := s.db.Create(&records).Error; err != nil {
+		s.log.Warnw("Failed to record generation history", "error", err, "image_gen_id", imageGen.ID)
+	}
+}
+
+// ListGenerationHistory 按时间倒序列出实体的图片生成历史
+func (s *ImageGenerationService) ListGenerationHistory(entityType, entityID string) ([]models.GenerationHistory, error) {
+	if !isHistoryEntityType(entityType) {
+		return nil, &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf("不支持的实体类型: %s", entityType)}
+	}
+	id, err := strconv.ParseUint(entityID, 10, 32)
+	if err != nil {
+		return nil, &ServiceError{Kind: ErrInvalidInput, Message: "无效的实体ID"}
+	}
+
+	var history []models.GenerationHistory
+	if err := s.db.Where("entity_type = ? AND entity_id = ?", entityType, uint(id)).
+		Order("created_at DESC, id DESC").Find(&history).Error; err != nil {
+		return nil, fmt.Errorf("获取生成历史失败: %w", err)
+	}
+	return history, nil
+}
+
+// RestoreGenerationHistory 将实体的图片恢复为某条历史记录中的图片
+func (s *ImageGenerationService) RestoreGenerationHistory(historyID string) (*models.GenerationHistory, error) {
+	var record models.GenerationHistory
+	if err := s.db.Where("id = ?", historyID).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &ServiceError{Kind: ErrNotFound, Message: "generation history not found"}
+		}
+		return nil, err
+	}
+
+	var err error
+	switch record.EntityType {
+	case models.HistoryEntityStoryboard:
+		err = s.db.Model(&models.Storyboard{}).Where("id = ?", record.EntityID).
+			Update("composed_image", record.ImageURL).Error
+	case models.HistoryEntityScene:
+		err = s.db.Model(&models.Scene{}).Where("id = ?", record.EntityID).Updates(map[string]interface{}{
+			"image_url":  record.ImageURL,
+			"local_path": record.LocalPath,
+			"status":     "generated",
+		}).Error
+	case models.HistoryEntityCharacter:
+		err = s.db.Model(&models.Character{}).Where("id = ?", record.EntityID).Updates(map[string]interface{}{
+			"image_url":  record.ImageURL,
+			"local_path": record.LocalPath,
+		}).Error
+		if err == nil {
+			s.restoreCharacterPortrait(record)
+		}
+	case models.HistoryEntityProp:
+		err = s.db.Model(&models.Prop{}).Where("id = ?", record.EntityID).Updates(map[string]interface{}{
+			"image_url":  record.ImageURL,
+			"local_path": record.LocalPath,
+		}).Error
+	default:
+		return nil, &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf("不支持的实体类型: %s", record.EntityType)}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("恢复图片失败: %w", err)
+	}
+
+	s.log.Infow("Generation history restored",
+		"history_id", record.ID,
+		"entity_type", record.EntityType,
+		"entity_id", record.EntityID,
+		"image_gen_id", record.ImageGenerationID)
+	return &record, nil
+}
+
+// restoreCharacterPortrait 恢复角色形象时同步恢复对应的生成参数，生成记录已删除时清空
+func (s *ImageGenerationService) restoreCharacterPortrait(record models.GenerationHistory) {
+	var imageGen models.ImageGeneration
+	if err := s.db.Where("id = ?", record.ImageGenerationID).First(&imageGen).Error; err == nil &&
+		imageGen.CharacterID != nil && imageGen.ImageType == string(models.ImageTypeCharacter) {
+		s.recordCharacterPortrait(&imageGen)
+		return
+	}
+	if err := s.db.Model(&models.Character{}).Where("id = ?", record.EntityID).
+		Update("portrait_params", nil).Error; err != nil {
+		s.log.Warnw("Failed to clear character portrait params", "error", err, "character_id", record.EntityID)
+	}
+}
+
+// isHistoryEntityType 是否为支持生成历史的实体类型
+func isHistoryEntityType(entityType string) bool {
+	switch entityType {
+	case models.HistoryEntityStoryboard, models.HistoryEntityScene, models.HistoryEntityCharacter, models.HistoryEntityProp:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"time"
+
+	"github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/metrics"
+)
+
+// 生成结果状态标签
+const (
+	metricStatusCompleted = "completed"
+	metricStatusCached    = "cached"
+	metricStatusFailed    = "failed"
+)
+
+var (
+	imageGenerationsTotal = metrics.NewCounter(
+		"drama_image_generations_total",
+		"Finished image generations by image type, provider and outcome status",
+		"image_type", "provider", "status",
+	)
+	imageGenerationsStartedTotal = metrics.NewCounter(
+		"drama_image_generations_started_total",
+		"Image generations picked up for processing by image type and provider",
+		"image_type", "provider",
+	)
+	imageGenerationFailuresTotal = metrics.NewCounter(
+		"drama_image_generation_failures_total",
+		"Failed image generations by provider and error code",
+		"provider", "error_code",
+	)
+	imageGenerationDuration = metrics.NewHistogram(
+		"drama_image_generation_duration_seconds",
+		"End-to-end image generation latency from creation to completion or failure",
+		metrics.DefaultDurationBuckets,
+		"image_type", "provider", "status",
+	)
+	tasksTotal = metrics.NewCounter(
+		"drama_tasks_total",
+		"Async tasks by task type and status",
+		"task_type", "status",
+	)
+	taskDuration = metrics.NewHistogram(
+		"drama_task_duration_seconds",
+		"Async task latency from creation to completion or failure",
+		metrics.DefaultDurationBuckets,
+		"task_type", "status",
+	)
+	_ = metrics.NewGaugeFunc(
+		"drama_image_queue_depth",
+		"Image generation jobs waiting in the shared queue",
+		func() float64 {
+			// 队列尚未创建时不在此处创建，避免采集指标时启动工作协程
+			q := sharedImageQueue.Load()
+			if q == nil {
+				return 0
+			}
+			return float64(q.depth())
+		},
+	)
+)
+
+// recordImageGenerationStarted 记录图片生成开始处理
+func recordImageGenerationStarted(imageGen *models.ImageGeneration) {
+	imageGenerationsStartedTotal.Inc(imageGen.ImageType, imageGen.Provider)
+}
+
+// recordImageGenerationOutcome 记录图片生成的最终结果及从创建到结束的耗时，失败时按错误代码计数
+func recordImageGenerationOutcome(imageGen *models.ImageGeneration, status, errorCode string) {
+	imageGenerationsTotal.Inc(imageGen.ImageType, imageGen.Provider, status)
+	if !imageGen.CreatedAt.IsZero() {
+		imageGenerationDuration.Observe(time.Since(imageGen.CreatedAt).Seconds(), imageGen.ImageType, imageGen.Provider, status)
+	}
+	if status == metricStatusFailed {
+		if errorCode == "" {
+			errorCode = "unknown"
+		}
+		imageGenerationFailuresTotal.Inc(imageGen.Provider, errorCode)
+	}
+}
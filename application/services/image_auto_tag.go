@@ -0,0 +1,90 @@
+package services
+
+import (
+	"strings"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/ai"
+	"github.com/drama-generator/backend/pkg/utils"
+)
+
+// maxContentTagsPerGroup 每类标签最多保存的数量
+const maxContentTagsPerGroup = 10
+
+// normalizeContentTags 转为小写、去除空白和重复，并限制每类数量
+func normalizeContentTags(tags []string) []string {
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(strings.Trim(tag, `"`)))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+		if len(result) == maxContentTagsPerGroup {
+			break
+		}
+	}
+	return result
+}
+
+// contentTagLikeEscape 内容标签 LIKE 条件使用的转义字符，不用反斜杠以兼容 MySQL 字符串字面量
+const contentTagLikeEscape = "!"
+
+// contentTagLikeEscaper 转义标签中的 LIKE 通配符，使 % 和 _ 按字面匹配
+var contentTagLikeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// contentTagFilter 按内容标签筛选时使用的 LIKE 条件值，匹配 JSON 中完整的标签元素
+// 需配合 ESCAPE contentTagLikeEscape 使用
+func contentTagFilter(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(strings.Trim(tag, `"`)))
+	return `%"` + contentTagLikeEscaper.Replace(tag) + `"%`
+}
+
+// tagImageContent 用视觉模型为已完成的图片生成物体、情绪和色调标签，失败时只记录日志
+func (s *ImageGenerationService) tagImageContent(imageGenID uint, localPath *string, imageURL string) {
+	img := imageURL
+	if localPath != nil && *localPath != "" {
+		if dataURI, err := s.loadImageAsBase64(*localPath); err == nil {
+			img = dataURI
+		}
+	}
+	if img == "" {
+		return
+	}
+
+	client, err := s.aiService.GetVisionClient(s.cfg().AI.ImageAutoTagModel)
+	if err != nil {
+		s.log.Warnw("Image auto tag skipped: no vision client", "error", err, "id", imageGenID)
+		return
+	}
+
+	prompt := `请为这张图片生成用于检索的标签，每类不超过10个，使用简短的词语。
+
+【输出格式】只输出JSON，不要任何解释：
+{"objects": ["画面中的主要物体、人物类型和场景元素"], "mood": ["情绪氛围"], "colors": ["主色调"]}`
+
+	text, err := client.GenerateTextWithImages(prompt, "", []string{img}, ai.WithTemperature(0))
+	if err != nil {
+		s.log.Warnw("Image auto tag failed", "error", err, "id", imageGenID)
+		return
+	}
+
+	var tags models.ImageContentTags
+	if err := utils.SafeParseAIJSON(text, &tags); err != nil {
+		s.log.Warnw("Failed to parse image tags", "error", err, "id", imageGenID, "response", text[:min(300, len(text))])
+		return
+	}
+	tags.Objects = normalizeContentTags(tags.Objects)
+	tags.Mood = normalizeContentTags(tags.Mood)
+	tags.Colors = normalizeContentTags(tags.Colors)
+
+	if err := s.db.Model(&models.ImageGeneration{ID: imageGenID}).Select("content_tags").
+		Updates(&models.ImageGeneration{ContentTags: &tags}).Error; err != nil {
+		s.log.Warnw("Failed to save image tags", "error", err, "id", imageGenID)
+		return
+	}
+	s.log.Infow("Image content tagged", "id", imageGenID,
+		"objects", len(tags.Objects), "mood", len(tags.Mood), "colors", len(tags.Colors))
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+
+	models "github.com/drama-generator/backend/domain/models"
+)
+
+func storyboardAt(id uint, number int, location, time string) models.Storyboard {
+	return models.Storyboard{ID: id, StoryboardNumber: number, Location: &location, Time: &time}
+}
+
+// TestExtractUniqueBackgroundsOrder tests that backgrounds follow the first storyboard they appear in
+func TestExtractUniqueBackgroundsOrder(t *testing.T) {
+	scenes := []models.Storyboard{
+		storyboardAt(15, 5, "仓库", "夜晚"),
+		storyboardAt(11, 1, "街道", "白天"),
+		storyboardAt(14, 4, "街道", "白天"),
+		storyboardAt(12, 2, "办公室", "白天"),
+		storyboardAt(13, 3, "仓库", "夜晚"),
+		storyboardAt(16, 6, "天台", "黄昏"),
+	}
+	wantLocations := []string{"街道", "办公室", "仓库", "天台"}
+
+	s := &ImageGenerationService{}
+	for run := 0; run < 20; run++ {
+		backgrounds := s.extractUniqueBackgrounds(scenes)
+		if len(backgrounds) != len(wantLocations) {
+			t.Fatalf("run %d: got %d backgrounds, want %d", run, len(backgrounds), len(wantLocations))
+		}
+		for i, bg := range backgrounds {
+			if bg.Location != wantLocations[i] {
+				t.Fatalf("run %d: background %d = %s, want %s", run, i, bg.Location, wantLocations[i])
+			}
+		}
+	}
+
+	backgrounds := s.extractUniqueBackgrounds(scenes)
+	warehouse := backgrounds[2]
+	if warehouse.StoryboardCount != 2 || warehouse.SceneIDs[0] != 13 || warehouse.SceneIDs[1] != 15 {
+		t.Errorf("warehouse background = %+v, want storyboards 13 and 15 in order", warehouse)
+	}
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	models "github.com/drama-generator/backend/domain/models"
+)
+
+// 批量生成中单个分镜的处理结果
+const (
+	BatchImageEnqueued        = "enqueued"          // 已创建生成记录并入队
+	BatchImageSkippedNoPrompt = "skipped_no_prompt" // 分镜没有图片提示词，未生成
+	BatchImageFailed          = "failed"            // 创建生成记录失败
+)
+
+// BatchImageItem 批量生成中单个分镜的状态及原因
+type BatchImageItem struct {
+	StoryboardID      uint   `json:"storyboard_id"`
+	StoryboardNumber  int    `json:"storyboard_number"`
+	Status            string `json:"status"`
+	Reason            string `json:"reason,omitempty"`
+	ImageGenerationID *uint  `json:"image_generation_id,omitempty"`
+}
+
+// BatchImageResult 剧集批量生成图片的结果，同时写入跟踪任务的结果
+type BatchImageResult struct {
+	TaskID           string                    `json:"task_id"`
+	Total            int                       `json:"total"`
+	Enqueued         int                       `json:"enqueued"`
+	Skipped          int                       `json:"skipped"`
+	Failed           int                       `json:"failed"`
+	Items            []BatchImageItem          `json:"items"`
+	ImageGenerations []*models.ImageGeneration `json:"image_generations"`
+}
+
+// add 记录单个分镜的处理结果并更新计数
+func (r *BatchImageResult) add(sb *models.Storyboard, status, reason string, imageGen *models.ImageGeneration) {
+	item := BatchImageItem{
+		StoryboardID:     sb.ID,
+		StoryboardNumber: sb.StoryboardNumber,
+		Status:           status,
+		Reason:           reason,
+	}
+	switch status {
+	case BatchImageEnqueued:
+		r.Enqueued++
+		item.ImageGenerationID = &imageGen.ID
+		r.ImageGenerations = append(r.ImageGenerations, imageGen)
+	case BatchImageSkippedNoPrompt:
+		r.Skipped++
+	case BatchImageFailed:
+		r.Failed++
+	}
+	r.Items = append(r.Items, item)
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/ai"
+	"github.com/drama-generator/backend/pkg/utils"
+)
+
+// characterQAResult 视觉模型返回的角色出镜检查结果
+type characterQAResult struct {
+	Present []string `json:"present"`
+	Missing []string `json:"missing"`
+	Note    string   `json:"note"`
+}
+
+// characterQAEntry 角色出镜检查提示词中的应出镜角色
+type characterQAEntry struct {
+	Name       string `json:"name"`
+	Appearance string `json:"appearance"`
+}
+
+// checkCharactersInImage 用视觉模型检查分镜关联的角色是否都出现在生成的图片中，发现缺失时写入 qa_note
+func (s *ImageGenerationService) checkCharactersInImage(imageGenID uint, dramaID uint, storyboardID uint, localPath *string, imageURL string) {
+	var storyboard models.Storyboard
+	if err := s.db.Preload("Characters").Where("id = ?", storyboardID).First(&storyboard).Error; err != nil {
+		s.log.Warnw("Character QA skipped: storyboard not found", "id", imageGenID, "storyboard_id", storyboardID)
+		return
+	}
+	if len(storyboard.Characters) == 0 {
+		return
+	}
+
+	img := imageURL
+	if localPath != nil && *localPath != "" {
+		dataURI, err := s.loadImageAsBase64(*localPath)
+		if err == nil {
+			img = dataURI
+		}
+	}
+	if img == "" {
+		return
+	}
+
+	client, err := s.aiService.GetVisionClientForDrama(dramaID, s.cfg().AI.CharacterQAModel)
+	if err != nil {
+		s.log.Warnw("Character QA skipped: no vision client", "error", err, "id", imageGenID)
+		return
+	}
+
+	var charList []string
+	for _, char := range storyboard.Characters {
+		info, err := json.Marshal(characterQAEntry{Name: char.Name, Appearance: getString(char.Appearance)})
+		if err != nil {
+			s.log.Warnw("Character QA skipped: failed to encode character", "error", err, "id", imageGenID)
+			return
+		}
+		charList = append(charList, string(info))
+	}
+
+	prompt := fmt.Sprintf(`请检查图片中是否出现了以下每个角色（根据外貌描述判断）。
+
+【应出镜角色】
+[%s]
+
+【输出格式】只输出JSON，不要任何解释：
+{"present": ["出现的角色名"], "missing": ["未出现的角色名"], "note": "简要说明，如人数不符或外貌明显不一致"}`, strings.Join(charList, ",\n"))
+
+	text, err := client.GenerateTextWithImages(prompt, "", []string{img}, ai.WithTemperature(0))
+	if err != nil {
+		s.log.Warnw("Character QA failed", "error", err, "id", imageGenID)
+		return
+	}
+
+	var result characterQAResult
+	if err := utils.SafeParseAIJSON(text, &result); err != nil {
+		s.log.Warnw("Failed to parse character QA result", "error", err, "id", imageGenID, "response", text[:min(300, len(text))])
+		return
+	}
+
+	if len(result.Missing) == 0 {
+		s.log.Infow("Character QA passed", "id", imageGenID, "present", result.Present)
+		return
+	}
+
+	note := "缺少角色: " + strings.Join(result.Missing, "、")
+	if result.Note != "" {
+		note += "；" + result.Note
+	}
+	if err := s.db.Model(&models.ImageGeneration{}).Where("id = ?", imageGenID).Update("qa_note", note).Error; err != nil {
+		s.log.Warnw("Failed to save character QA note", "error", err, "id", imageGenID)
+		return
+	}
+	s.log.Warnw("Character QA found missing characters", "id", imageGenID, "storyboard_id", storyboardID, "missing", result.Missing)
+}
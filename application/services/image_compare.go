@@ -0,0 +1,84 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	models "github.com/drama-generator/backend/domain/models"
+)
+
+// ImageParamDiff 两次生成之间不同的一项参数
+type ImageParamDiff struct {
+	Field string      `json:"field"`
+	A     interface{} `json:"a"`
+	B     interface{} `json:"b"`
+}
+
+// ImageComparison 两条图片生成记录及其参数差异
+type ImageComparison struct {
+	A    *models.ImageGeneration `json:"a"`
+	B    *models.ImageGeneration `json:"b"`
+	Diff []ImageParamDiff        `json:"diff"`
+}
+
+// imageCompareFields 参与比较的生成参数，nil 指针视为未设置
+var imageCompareFields = []struct {
+	name  string
+	value func(g *models.ImageGeneration) interface{}
+}{
+	{"provider", func(g *models.ImageGeneration) interface{} { return g.Provider }},
+	{"model", func(g *models.ImageGeneration) interface{} { return g.Model }},
+	{"prompt", func(g *models.ImageGeneration) interface{} { return g.Prompt }},
+	{"negative_prompt", func(g *models.ImageGeneration) interface{} { return getString(g.NegPrompt) }},
+	{"translate_prompt_to", func(g *models.ImageGeneration) interface{} { return g.TranslatePromptTo }},
+	{"image_type", func(g *models.ImageGeneration) interface{} { return g.ImageType }},
+	{"frame_type", func(g *models.ImageGeneration) interface{} { return getString(g.FrameType) }},
+	{"size", func(g *models.ImageGeneration) interface{} { return g.Size }},
+	{"width", func(g *models.ImageGeneration) interface{} { return derefOrNil(g.Width) }},
+	{"height", func(g *models.ImageGeneration) interface{} { return derefOrNil(g.Height) }},
+	{"quality", func(g *models.ImageGeneration) interface{} { return g.Quality }},
+	{"style", func(g *models.ImageGeneration) interface{} { return getString(g.Style) }},
+	{"steps", func(g *models.ImageGeneration) interface{} { return derefOrNil(g.Steps) }},
+	{"cfg_scale", func(g *models.ImageGeneration) interface{} { return derefOrNil(g.CfgScale) }},
+	{"seed", func(g *models.ImageGeneration) interface{} { return derefOrNil(g.Seed) }},
+	{"reference_images", func(g *models.ImageGeneration) interface{} { return sortedReferenceImages(g) }},
+}
+
+// CompareImageGenerations 返回两条图片生成记录及其生成参数的差异，便于判断是哪项修改导致了结果不同
+func (s *ImageGenerationService) CompareImageGenerations(id1, id2 uint) (*ImageComparison, error) {
+	var a, b models.ImageGeneration
+	if err := s.db.Where("id = ?", id1).First(&a).Error; err != nil {
+		return nil, &ServiceError{Kind: ErrNotFound, Message: "图片生成记录不存在"}
+	}
+	if err := s.db.Where("id = ?", id2).First(&b).Error; err != nil {
+		return nil, &ServiceError{Kind: ErrNotFound, Message: "图片生成记录不存在"}
+	}
+
+	result := &ImageComparison{A: &a, B: &b, Diff: []ImageParamDiff{}}
+	for _, field := range imageCompareFields {
+		va, vb := field.value(&a), field.value(&b)
+		if !reflect.DeepEqual(va, vb) {
+			result.Diff = append(result.Diff, ImageParamDiff{Field: field.name, A: va, B: vb})
+		}
+	}
+	return result, nil
+}
+
+// derefOrNil 指针非空时返回其值，否则返回 nil
+func derefOrNil[T any](p *T) interface{} {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+// sortedReferenceImages 排序后的参考图列表，参考图顺序不同不视为差异
+func sortedReferenceImages(g *models.ImageGeneration) []string {
+	refs := []string{}
+	if len(g.ReferenceImages) > 0 {
+		_ = json.Unmarshal(g.ReferenceImages, &refs)
+	}
+	sort.Strings(refs)
+	return refs
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"gorm.io/gorm"
+)
+
+// imageExportClient 导出时下载远程图片使用的 HTTP 客户端
+var imageExportClient = &http.Client{Timeout: 60 * time.Second}
+
+// ImageExport 待导出的已完成图片，WriteZip 逐张读取并写入 ZIP，不在内存中缓存整个压缩包
+type ImageExport struct {
+	Filename string
+	Count    int
+
+	images  []models.ImageGeneration
+	service *ImageGenerationService
+}
+
+// ExportImagesZip 准备导出剧本或剧集的所有已完成图片，至少需要指定 dramaID 或 episodeID 之一
+// 按剧集导出时包含该集分镜和场景的图片
+func (s *ImageGenerationService) ExportImagesZip(dramaID *uint, episodeID *uint) (*ImageExport, error) {
+	if dramaID == nil && episodeID == nil {
+		return nil, &ServiceError{Kind: ErrInvalidInput, Message: "需要指定 drama_id 或 episode_id"}
+	}
+
+	query := s.db.Model(&models.ImageGeneration{}).Where("status = ?", models.ImageStatusCompleted)
+	filename := ""
+	if episodeID != nil {
+		var episode models.Episode
+		if err := s.db.Select("id", "drama_id", "episode_number").Where("id = ?", *episodeID).First(&episode).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, ErrEpisodeNotFound
+			}
+			return nil, fmt.Errorf("failed to find episode: %w", err)
+		}
+		if dramaID != nil && *dramaID != episode.DramaID {
+			return nil, &ServiceError{Kind: ErrInvalidInput, Message: "剧集不属于该剧本"}
+		}
+		query = query.Where("drama_id = ?", episode.DramaID).Where(
+			s.db.Where("storyboard_id IN (?)", s.db.Model(&models.Storyboard{}).Select("id").Where("episode_id = ?", episode.ID)).
+				Or("scene_id IN (?)", s.db.Model(&models.Scene{}).Select("id").Where("episode_id = ?", episode.ID)),
+		)
+		filename = fmt.Sprintf("drama_%d_episode_%d_images.zip", episode.DramaID, episode.EpisodeNum)
+	} else {
+		var drama models.Drama
+		if err := s.db.Select("id").Where("id = ?", *dramaID).First(&drama).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, ErrDramaNotFound
+			}
+			return nil, fmt.Errorf("failed to find drama: %w", err)
+		}
+		query = query.Where("drama_id = ?", drama.ID)
+		filename = fmt.Sprintf("drama_%d_images.zip", drama.ID)
+	}
+
+	var images []models.ImageGeneration
+	if err := query.Preload("Storyboard.Episode").Preload("Storyboard.Background").
+		Preload("Scene").Preload("Character").Preload("Prop").
+		Order("image_type ASC, id ASC").Find(&images).Error; err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	if len(images) == 0 {
+		return nil, &ServiceError{Kind: ErrNotFound, Message: "没有已完成的图片可导出"}
+	}
+
+	return &ImageExport{Filename: filename, Count: len(images), images: images, service: s}, nil
+}
+
+// WriteZip 将图片逐张写入 ZIP；读取失败的图片跳过，并在压缩包末尾的 missing.txt 中列出
+func (e *ImageExport) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	used := make(map[string]bool, len(e.images))
+	var missing []string
+
+	for i := range e.images {
+		img := &e.images[i]
+		name := uniqueExportName(imageExportName(img), used)
+		if err := e.writeImage(zw, name, img); err != nil {
+			e.service.log.Warnw("Failed to export image", "error", err, "id", img.ID)
+			missing = append(missing, fmt.Sprintf("%d\t%s\t%v", img.ID, name, err))
+		}
+	}
+
+	if len(missing) > 0 {
+		f, err := zw.Create("missing.txt")
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, strings.Join(missing, "\n")+"\n"); err != nil {
+			return err
+		}
+	}
+
+	e.service.log.Infow("Images exported", "file", e.Filename, "count", len(e.images), "missing", len(missing))
+	return zw.Close()
+}
+
+// writeImage 读取图片并写入 ZIP；图片本身已压缩，直接存储不再压缩
+// 先完整读取来源再创建 ZIP 条目，读取失败时不会在压缩包中留下截断的文件
+func (e *ImageExport) writeImage(zw *zip.Writer, name string, img *models.ImageGeneration) error {
+	src, err := e.openImage(img)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: img.CreatedAt})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// openImage 优先打开本地文件，本地文件不存在或没有本地路径时读取 image_url（data URI 直接解码，其余下载到临时文件）
+func (e *ImageExport) openImage(img *models.ImageGeneration) (io.ReadCloser, error) {
+	url := getString(img.ImageURL)
+	if localPath := getString(img.LocalPath); localPath != "" {
+		f, err := os.Open(e.service.localImagePath(localPath))
+		if err == nil {
+			return f, nil
+		}
+		if url == "" {
+			return nil, fmt.Errorf("open local image: %w", err)
+		}
+		e.service.log.Warnw("Local image unavailable, exporting from url", "error", err, "id", img.ID)
+	}
+
+	switch {
+	case strings.HasPrefix(url, "data:"):
+		_, data, err := splitDataURI(url)
+		if err != nil {
+			return nil, err
+		}
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, fmt.Errorf("decode data URI image: %w", err)
+		}
+		return io.NopCloser(bytes.NewReader(decoded)), nil
+	case url != "":
+		return downloadExportImage(url)
+	default:
+		return nil, fmt.Errorf("image has no local path or url")
+	}
+}
+
+// downloadExportImage 将远程图片完整下载到临时文件，关闭时删除临时文件
+func downloadExportImage(url string) (io.ReadCloser, error) {
+	resp, err := imageExportClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("download image: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download image failed with status: %d", resp.StatusCode)
+	}
+
+	tmp, err := os.CreateTemp("", "image-export-*")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, fmt.Errorf("download image: %w", err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return &tempExportFile{File: tmp}, nil
+}
+
+// tempExportFile 关闭时删除的临时文件
+type tempExportFile struct {
+	*os.File
+}
+
+func (f *tempExportFile) Close() error {
+	err := f.File.Close()
+	os.Remove(f.Name())
+	return err
+}
+
+// imageExportName 按图片类型生成压缩包内的文件名，例如 episode_01/shot_003_客厅_first_12.png
+func imageExportName(img *models.ImageGeneration) string {
+	ext := imageExportExt(img)
+	switch {
+	case img.Storyboard != nil:
+		dir := "storyboards"
+		if img.Storyboard.Episode.ID != 0 {
+			dir = fmt.Sprintf("episode_%02d", img.Storyboard.Episode.EpisodeNum)
+		}
+		parts := []string{fmt.Sprintf("shot_%03d", img.Storyboard.StoryboardNumber)}
+		if img.Storyboard.Background != nil {
+			parts = append(parts, img.Storyboard.Background.Location)
+		}
+		if frameType := getString(img.FrameType); frameType != "" {
+			parts = append(parts, frameType)
+		}
+		parts = append(parts, fmt.Sprintf("%d", img.ID))
+		return dir + "/" + sanitizeExportName(strings.Join(parts, "_")) + ext
+	case img.Scene != nil:
+		return "scenes/" + sanitizeExportName(fmt.Sprintf("%s_%s_%d", img.Scene.Location, img.Scene.Time, img.ID)) + ext
+	case img.Character != nil:
+		return "characters/" + sanitizeExportName(fmt.Sprintf("%s_%d", img.Character.Name, img.ID)) + ext
+	case img.Prop != nil:
+		return "props/" + sanitizeExportName(fmt.Sprintf("%s_%d", img.Prop.Name, img.ID)) + ext
+	default:
+		return "other/" + sanitizeExportName(fmt.Sprintf("%s_%d", img.ImageType, img.ID)) + ext
+	}
+}
+
+// imageExportExt 从本地路径、URL 或 data URI 的 MIME 类型推断扩展名，无法识别时使用 .png
+func imageExportExt(img *models.ImageGeneration) string {
+	candidates := []string{getString(img.LocalPath), getString(img.ImageURL)}
+	for _, p := range candidates {
+		if strings.HasPrefix(p, "data:") {
+			if mimeType, _, err := splitDataURI(p); err == nil {
+				switch mimeType {
+				case "image/jpeg", "image/jpg":
+					return ".jpg"
+				case "image/webp":
+					return ".webp"
+				case "image/gif":
+					return ".gif"
+				}
+			}
+			return ".png"
+		}
+		if p == "" {
+			continue
+		}
+		if i := strings.IndexAny(p, "?#"); i >= 0 {
+			p = p[:i]
+		}
+		switch ext := strings.ToLower(filepath.Ext(p)); ext {
+		case ".png", ".jpg", ".jpeg", ".webp", ".gif":
+			return ext
+		}
+	}
+	return ".png"
+}
+
+// splitDataURI 拆分 base64 data URI，返回 MIME 类型和 base64 数据
+func splitDataURI(uri string) (string, string, error) {
+	header, data, ok := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !ok || !strings.HasSuffix(header, ";base64") {
+		return "", "", fmt.Errorf("invalid data URI image")
+	}
+	return strings.TrimSuffix(header, ";base64"), data, nil
+}
+
+// sanitizeExportName 去掉文件名中不允许的字符，空白替换为下划线
+func sanitizeExportName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return -1
+		case ' ', '\t', '\n', '\r':
+			return '_'
+		}
+		return r
+	}, name)
+	return strings.Trim(name, "._")
+}
+
+// uniqueExportName 文件名重复时在扩展名前追加序号
+func uniqueExportName(name string, used map[string]bool) string {
+	unique := name
+	ext := path.Ext(name)
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s_%d%s", strings.TrimSuffix(name, ext), i, ext)
+	}
+	used[unique] = true
+	return unique
+}
@@ -3,12 +3,15 @@ package services
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	models "github.com/drama-generator/backend/domain/models"
 	"github.com/drama-generator/backend/infrastructure/storage"
@@ -29,6 +32,7 @@ type ImageGenerationService struct {
 	config          *config.Config
 	promptI18n      *PromptI18n
 	taskService     *TaskService
+	queue           *imageGenQueue
 }
 
 // truncateImageURL 截断图片 URL，避免 base64 格式的 URL 占满日志
@@ -59,9 +63,15 @@ func NewImageGenerationService(db *gorm.DB, cfg *config.Config, transferService
 		promptI18n:      NewPromptI18n(cfg),
 		log:             log,
 		taskService:     NewTaskService(db, log),
+		queue:           getImageQueue(cfg.AI.ImageWorkers, cfg.AI.DramaImageConcurrency),
 	}
 }
 
+// cfg 返回当前生效的配置快照（支持热加载）
+func (s *ImageGenerationService) cfg() *config.Config {
+	return config.CurrentOr(s.config)
+}
+
 // GetDB 获取数据库连接
 func (s *ImageGenerationService) GetDB() *gorm.DB {
 	return s.db
@@ -89,12 +99,87 @@ type GenerateImageRequest struct {
 	Height          *int     `json:"height"`
 	ImageLocalPath  *string  `json:"image_local_path"` // 本地图片路径，用于图生图
 	ReferenceImages []string `json:"reference_images"` // 参考图片URL列表
+	SkipCache       bool     `json:"skip_cache"`       // 跳过结果缓存，强制重新生成
+	Priority        int      `json:"priority"`         // 排队优先级，数值越大越先执行，默认0
+	PreviewMode     bool     `json:"preview_mode"`     // 预览模式：使用服务商最低成本的尺寸和质量，结果不写回关联实体
+	// 生成前先用文本模型将提示词翻译为指定语言（en/zh）；服务商只支持英文时自动翻译为英文
+	TranslatePromptTo string `json:"translate_prompt_to" binding:"omitempty,oneof=en zh"`
+	// SeedRange 种子范围 [起始, 结束]（含两端），设置后为范围内每个种子各生成一张图片，归入同一批次
+	SeedRange *[2]int64 `json:"seed_range"`
+
+	batchID string // 所属批次，由种子范围生成时设置
+}
+
+// NormalizeImageSize 校验尺寸参数并统一为 Size 一种表示：
+// 显式的 width/height 优先于 size，两者同时给出且不一致时返回错误
+func NormalizeImageSize(request *GenerateImageRequest) error {
+	if (request.Width == nil) != (request.Height == nil) {
+		return fmt.Errorf("width 和 height 必须同时提供")
+	}
+
+	if request.Width != nil {
+		if *request.Width <= 0 || *request.Height <= 0 {
+			return fmt.Errorf("width 和 height 必须大于0")
+		}
+		size := fmt.Sprintf("%dx%d", *request.Width, *request.Height)
+		if request.Size != "" {
+			if w, h, ok := parseImageSize(request.Size); !ok || w != *request.Width || h != *request.Height {
+				return fmt.Errorf("size(%s) 与 width/height(%s) 冲突", request.Size, size)
+			}
+		}
+		request.Size = size
+		request.Width = nil
+		request.Height = nil
+	}
+
+	return nil
+}
+
+// parseImageSize 解析 "1024x1024" 格式的尺寸
+func parseImageSize(size string) (int, int, bool) {
+	parts := strings.Split(strings.ToLower(size), "x")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	w, errW := strconv.Atoi(strings.TrimSpace(parts[0]))
+	h, errH := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if errW != nil || errH != nil || w <= 0 || h <= 0 {
+		return 0, 0, false
+	}
+	return w, h, true
 }
 
 func (s *ImageGenerationService) GenerateImage(request *GenerateImageRequest) (*models.ImageGeneration, error) {
+	if request.SeedRange != nil {
+		return s.generateSeedRange(request)
+	}
+
+	imageGen, queued, err := s.createImageGeneration(request)
+	if err != nil {
+		return nil, err
+	}
+	if queued {
+		s.enqueueImageGeneration(imageGen)
+	}
+	return imageGen, nil
+}
+
+// createImageGeneration 校验请求并创建生成记录；命中结果缓存时直接复用并返回 queued=false，
+// 否则返回 queued=true，由调用方负责放入队列
+func (s *ImageGenerationService) createImageGeneration(request *GenerateImageRequest) (*models.ImageGeneration, bool, error) {
+	if err := NormalizeImageSize(request); err != nil {
+		return nil, false, err
+	}
+
+	request.Prompt = utils.SanitizePrompt(request.Prompt)
+	sanitizeStrings(request.NegativePrompt)
+	if request.Prompt == "" {
+		return nil, false, &ServiceError{Kind: ErrInvalidInput, Message: "提示词不能为空"}
+	}
+
 	var drama models.Drama
 	if err := s.db.Where("id = ? ", request.DramaID).First(&drama).Error; err != nil {
-		return nil, fmt.Errorf("drama not found")
+		return nil, false, ErrDramaNotFound
 	}
 	// 注意：SceneID可能指向Scene或Storyboard表，调用方已经做过权限验证，这里不再重复验证
 
@@ -112,7 +197,7 @@ func (s *ImageGenerationService) GenerateImage(request *GenerateImageRequest) (*
 	// 转换DramaID
 	dramaIDParsed, err := strconv.ParseUint(request.DramaID, 10, 32)
 	if err != nil {
-		return nil, fmt.Errorf("invalid drama ID")
+		return nil, false, fmt.Errorf("invalid drama ID")
 	}
 
 	// 设置默认图片类型
@@ -121,6 +206,8 @@ func (s *ImageGenerationService) GenerateImage(request *GenerateImageRequest) (*
 		imageType = string(models.ImageTypeStoryboard)
 	}
 
+	inputHash := imageInputHash(request, provider, &drama)
+
 	imageGen := &models.ImageGeneration{
 		StoryboardID:    request.StoryboardID,
 		DramaID:         uint(dramaIDParsed),
@@ -143,16 +230,33 @@ func (s *ImageGenerationService) GenerateImage(request *GenerateImageRequest) (*
 		Width:           request.Width,
 		Height:          request.Height,
 		LocalPath:       request.ImageLocalPath,
+		InputHash:       &inputHash,
+		Priority:        request.Priority,
+		Preview:         request.PreviewMode,
 		Status:          models.ImageStatusPending,
+
+		TranslatePromptTo: request.TranslatePromptTo,
+	}
+	if request.batchID != "" {
+		imageGen.BatchID = &request.batchID
 	}
 
 	if err := s.db.Create(imageGen).Error; err != nil {
-		return nil, fmt.Errorf("failed to create record: %w", err)
+		return nil, false, fmt.Errorf("failed to create record: %w", err)
 	}
 
-	go s.ProcessImageGeneration(imageGen.ID)
+	// 输入完全相同的已完成结果直接复用，不再调用服务商；预览不复用正式结果
+	if s.cfg().AI.ImageResultCache && !request.SkipCache && !request.PreviewMode {
+		if cached := s.findCachedImageGeneration(imageGen.DramaID, inputHash, imageGen.ID); cached != nil {
+			s.reuseCachedImageGeneration(imageGen.ID, cached)
+			if err := s.db.First(imageGen, imageGen.ID).Error; err != nil {
+				return nil, false, fmt.Errorf("failed to reload record: %w", err)
+			}
+			return imageGen, false, nil
+		}
+	}
 
-	return imageGen, nil
+	return imageGen, true, nil
 }
 
 func (s *ImageGenerationService) ProcessImageGeneration(imageGenID uint) {
@@ -162,6 +266,10 @@ func (s *ImageGenerationService) ProcessImageGeneration(imageGenID uint) {
 		s.log.Errorw("Failed to load image generation", "error", err, "id", imageGenID)
 		return
 	}
+	if imageGen.Status == models.ImageStatusCancelled {
+		s.log.Infow("Skipping cancelled image generation", "id", imageGenID)
+		return
+	}
 
 	// 获取drama的style信息
 	var drama models.Drama
@@ -170,9 +278,10 @@ func (s *ImageGenerationService) ProcessImageGeneration(imageGenID uint) {
 	}
 
 	s.db.Model(&imageGen).Update("status", models.ImageStatusProcessing)
+	recordImageGenerationStarted(&imageGen)
 
 	// 如果关联了background，同步更新background为generating状态
-	if imageGen.StoryboardID != nil {
+	if imageGen.StoryboardID != nil && !imageGen.Preview {
 		if err := s.db.Model(&models.Scene{}).Where("id = ?", *imageGen.StoryboardID).Update("status", "generating").Error; err != nil {
 			s.log.Warnw("Failed to update background status to generating", "scene_id", *imageGen.StoryboardID, "error", err)
 		} else {
@@ -180,7 +289,7 @@ func (s *ImageGenerationService) ProcessImageGeneration(imageGenID uint) {
 		}
 	}
 
-	client, err := s.getImageClientWithModel(imageGen.Provider, imageGen.Model)
+	client, aiConfig, err := s.getImageClientWithModel(imageGen.Provider, imageGen.Model, imageGen.DramaID, imageStickyKey(imageGenID))
 	if err != nil {
 		s.log.Errorw("Failed to get image client", "error", err, "provider", imageGen.Provider, "model", imageGen.Model)
 		s.updateImageGenError(imageGenID, err.Error())
@@ -199,27 +308,45 @@ func (s *ImageGenerationService) ProcessImageGeneration(imageGenID uint) {
 	}
 
 	// 如果有 local_path，添加到参考图片列表的开头
-	if imageGen.LocalPath != nil && *imageGen.LocalPath != "" {
+	hasSource := imageGen.LocalPath != nil && *imageGen.LocalPath != ""
+	if hasSource {
 		referenceImagePaths = append([]string{*imageGen.LocalPath}, referenceImagePaths...)
 	}
 
-	// 将所有参考图片路径转换为 base64（如果是本地路径）或保持原样（如果是 URL）
-	var referenceImages []string
+	// 预检远程参考图，剔除已失效的地址，避免单张失效导致整次生成失败
+	var remoteReferences []string
+	for _, imgPath := range referenceImagePaths {
+		if strings.HasPrefix(imgPath, "http://") || strings.HasPrefix(imgPath, "https://") {
+			remoteReferences = append(remoteReferences, imgPath)
+		}
+	}
+	reachableReferences := make(map[string]bool)
+	for _, url := range s.filterReachableReferences(imageGenID, remoteReferences) {
+		reachableReferences[url] = true
+	}
+
+	// 将所有参考图片路径转换为 base64（如果是本地路径）或保持原样（如果是 URL），无法使用的参考图直接剔除
+	var usablePaths []string
+	loadedReferences := make(map[string]string)
 	for _, imgPath := range referenceImagePaths {
 		// 判断是否为 HTTP/HTTPS URL
 		if strings.HasPrefix(imgPath, "http://") || strings.HasPrefix(imgPath, "https://") {
 			// 保持 URL 原样
-			referenceImages = append(referenceImages, imgPath)
+			if reachableReferences[imgPath] {
+				usablePaths = append(usablePaths, imgPath)
+				loadedReferences[imgPath] = imgPath
+			}
 		} else {
-			// 视为本地路径，转换为 base64
-			base64Image, err := s.loadImageAsBase64(imgPath)
+			// 视为本地路径，转换为 base64（超过大小限制时先缩小）
+			base64Image, err := s.loadReferenceAsBase64(imageGenID, imgPath)
 			if err != nil {
 				s.log.Warnw("Failed to load local image as base64",
 					"error", err,
 					"id", imageGenID,
 					"local_path", imgPath)
 			} else {
-				referenceImages = append(referenceImages, base64Image)
+				usablePaths = append(usablePaths, imgPath)
+				loadedReferences[imgPath] = base64Image
 				s.log.Infow("Loaded local image for generation",
 					"id", imageGenID,
 					"local_path", imgPath)
@@ -227,29 +354,86 @@ func (s *ImageGenerationService) ProcessImageGeneration(imageGenID uint) {
 		}
 	}
 
+	if len(referenceImagePaths) > 0 && len(usablePaths) == 0 {
+		if s.cfg().AI.RequireReferenceImages {
+			s.updateImageGenError(imageGenID, "所有参考图片均无法访问")
+			return
+		}
+		s.log.Warnw("All reference images unavailable, generating without references", "id", imageGenID)
+	} else if len(usablePaths) < len(referenceImagePaths) {
+		s.log.Warnw("Some reference images were dropped",
+			"id", imageGenID,
+			"requested", len(referenceImagePaths),
+			"usable", len(usablePaths))
+	}
+
+	// 先剔除失效的参考图再按上限裁剪，避免失效的参考图占用名额而挤掉可用的参考图
+	sourceUsable := hasSource && len(usablePaths) > 0 && usablePaths[0] == referenceImagePaths[0]
+	var referenceImages []string
+	for _, imgPath := range s.capReferenceImages(&imageGen, usablePaths, sourceUsable) {
+		referenceImages = append(referenceImages, loadedReferences[imgPath])
+	}
+
 	s.log.Infow("Starting image generation", "id", imageGenID, "prompt", imageGen.Prompt, "provider", imageGen.Provider)
 
+	// 只发送服务商支持的参数，避免不支持的参数导致请求被拒绝
+	caps := image.GetCapabilitiesForClient(client)
+	if imageGen.Preview {
+		s.applyPreviewSettings(&imageGen, caps)
+	}
+	dropOption := func(option string) {
+		s.log.Debugw("Dropping image option unsupported by provider", "id", imageGenID, "option", option, "provider", imageGen.Provider)
+	}
+
 	var opts []image.ImageOption
 	if imageGen.NegPrompt != nil && *imageGen.NegPrompt != "" {
-		opts = append(opts, image.WithNegativePrompt(*imageGen.NegPrompt))
+		if caps.NegativePrompt {
+			opts = append(opts, image.WithNegativePrompt(*imageGen.NegPrompt))
+		} else {
+			dropOption("negative_prompt")
+		}
 	}
 	if imageGen.Size != "" {
-		opts = append(opts, image.WithSize(imageGen.Size))
+		if caps.Size {
+			opts = append(opts, image.WithSize(imageGen.Size))
+		} else {
+			dropOption("size")
+		}
 	}
 	if imageGen.Quality != "" {
-		opts = append(opts, image.WithQuality(imageGen.Quality))
+		if caps.Quality {
+			opts = append(opts, image.WithQuality(imageGen.Quality))
+		} else {
+			dropOption("quality")
+		}
 	}
 	if imageGen.Style != nil && *imageGen.Style != "" {
-		opts = append(opts, image.WithStyle(*imageGen.Style))
+		if caps.Style {
+			opts = append(opts, image.WithStyle(*imageGen.Style))
+		} else {
+			dropOption("style")
+		}
 	}
 	if imageGen.Steps != nil {
-		opts = append(opts, image.WithSteps(*imageGen.Steps))
+		if caps.Steps {
+			opts = append(opts, image.WithSteps(*imageGen.Steps))
+		} else {
+			dropOption("steps")
+		}
 	}
 	if imageGen.CfgScale != nil {
-		opts = append(opts, image.WithCfgScale(*imageGen.CfgScale))
+		if caps.CfgScale {
+			opts = append(opts, image.WithCfgScale(*imageGen.CfgScale))
+		} else {
+			dropOption("cfg_scale")
+		}
 	}
 	if imageGen.Seed != nil {
-		opts = append(opts, image.WithSeed(*imageGen.Seed))
+		if caps.Seed {
+			opts = append(opts, image.WithSeed(*imageGen.Seed))
+		} else {
+			dropOption("seed")
+		}
 	}
 	if imageGen.Model != "" {
 		opts = append(opts, image.WithModel(imageGen.Model))
@@ -259,18 +443,23 @@ func (s *ImageGenerationService) ProcessImageGeneration(imageGenID uint) {
 	}
 	// 添加参考图片
 	if len(referenceImages) > 0 {
-		opts = append(opts, image.WithReferenceImages(referenceImages))
+		if caps.ReferenceImages {
+			opts = append(opts, image.WithReferenceImages(referenceImages), image.WithReferenceLimits(s.referenceLimits(imageGenID)))
+		} else {
+			dropOption("reference_images")
+		}
 	}
 
-	// 构建完整的提示词：风格提示词 + 用户提示词
-	prompt := imageGen.Prompt
+	// 构建完整的提示词：风格提示词 + 用户提示词 + 比例/参考图说明
+	// 风格和说明部分在超长截断时完整保留，只裁剪用户提示词
+	var promptPrefix, promptSuffix string
 
 	// 如果drama有风格设置，添加风格提示词
 	if drama.Style != "" && drama.Style != "realistic" {
-		stylePrompt := s.promptI18n.GetStylePrompt(drama.Style)
+		stylePrompt := s.promptI18n.WithLanguage(drama.Language).GetStylePrompt(drama.Style)
 		if stylePrompt != "" {
 			// 将风格提示词作为系统级约束添加到提示词前面
-			prompt = stylePrompt + "\n\n" + prompt
+			promptPrefix = stylePrompt + "\n\n"
 			s.log.Infow("Added style prompt to image generation",
 				"id", imageGenID,
 				"style", drama.Style,
@@ -278,21 +467,44 @@ func (s *ImageGenerationService) ProcessImageGeneration(imageGenID uint) {
 		}
 	}
 
-	prompt += ", imageRatio:" + imageRatio
+	promptSuffix = ", imageRatio:" + imageRatio
 
 	// 如果有参考图，在提示词末尾添加参考图一致性说明
 	if len(referenceImages) > 0 {
-		prompt += "\n\n**重要：**\n**必须严格**遵守参考图内的内容元素，保持场景和角色的**一致性**"
+		promptSuffix += "\n\n**重要：**\n**必须严格**遵守参考图内的内容元素，保持场景和角色的**一致性**"
 		s.log.Infow("Added reference image consistency instruction to prompt",
 			"id", imageGenID,
 			"reference_count", len(referenceImages))
 	}
+
+	maxPromptLength := caps.MaxPromptLength
+	if limit, ok := s.cfg().AI.ImagePromptLimits[image.ProviderNameForClient(client)]; ok {
+		maxPromptLength = limit
+	}
+	userPrompt := s.translatedImagePrompt(&imageGen, caps)
+	prompt, truncated := image.TruncatePrompt(promptPrefix, userPrompt, promptSuffix, maxPromptLength)
+	if truncated {
+		s.log.Warnw("Image prompt truncated to provider limit",
+			"id", imageGenID,
+			"max_length", maxPromptLength,
+			"original_length", utf8.RuneCountInString(promptPrefix+userPrompt+promptSuffix),
+			"truncated_length", utf8.RuneCountInString(prompt))
+	}
+
+	callStart := time.Now()
 	result, err := client.GenerateImage(prompt, opts...)
+	observeStage(metricTaskImage, StageProviderCall, imageGen.Provider, callStart)
 	if err != nil {
 		s.log.Errorw("Image generation API call failed", "error", err, "id", imageGenID, "prompt", imageGen.Prompt)
+		s.saveRawResponse(imageGenID, image.RawResponseFromError(err), aiConfig)
+		if errors.Is(err, image.ErrContentPolicy) {
+			s.updateImageGenErrorWithCode(imageGenID, models.ImageErrorContentPolicy, err.Error())
+			return
+		}
 		s.updateImageGenError(imageGenID, err.Error())
 		return
 	}
+	s.saveRawResponse(imageGenID, result.RawResponse, aiConfig)
 
 	s.log.Infow("Image generation API call completed", "id", imageGenID, "completed", result.Completed, "has_url", result.ImageURL != "")
 
@@ -301,16 +513,23 @@ func (s *ImageGenerationService) ProcessImageGeneration(imageGenID uint) {
 			"status":  models.ImageStatusProcessing,
 			"task_id": result.TaskID,
 		})
-		go s.pollTaskStatus(imageGenID, client, result.TaskID)
+		go s.pollTaskStatus(imageGenID, client, result.TaskID, imageGen.Provider)
 		return
 	}
 
 	s.completeImageGeneration(imageGenID, result)
 }
 
-func (s *ImageGenerationService) pollTaskStatus(imageGenID uint, client image.ImageClient, taskID string) {
+func (s *ImageGenerationService) pollTaskStatus(imageGenID uint, client image.ImageClient, taskID string, provider string) {
+	if _, polling := activeImagePolls.LoadOrStore(imageGenID, struct{}{}); polling {
+		s.log.Infow("Image generation already being polled", "id", imageGenID, "task_id", taskID)
+		return
+	}
+	defer activeImagePolls.Delete(imageGenID)
+
 	maxAttempts := 60
 	pollInterval := 5 * time.Second
+	pollStart := time.Now()
 
 	for i := 0; i < maxAttempts; i++ {
 		time.Sleep(pollInterval)
@@ -322,16 +541,20 @@ func (s *ImageGenerationService) pollTaskStatus(imageGenID uint, client image.Im
 		}
 
 		if result.Completed {
+			observeStage(metricTaskImage, StagePoll, provider, pollStart)
 			s.completeImageGeneration(imageGenID, result)
 			return
 		}
 
 		if result.Error != "" {
+			observeStage(metricTaskImage, StagePoll, provider, pollStart)
 			s.updateImageGenError(imageGenID, result.Error)
 			return
 		}
 	}
 
+	observeStage(metricTaskImage, StagePoll, provider, pollStart)
+
 	s.updateImageGenError(imageGenID, "timeout: image generation took too long")
 }
 
@@ -340,10 +563,35 @@ func (s *ImageGenerationService) completeImageGeneration(imageGenID uint, result
 
 	// 下载图片到本地存储并保存相对路径到数据库
 	var localPath *string
-	if s.localStorage != nil && result.ImageURL != "" &&
-		(strings.HasPrefix(result.ImageURL, "http://") || strings.HasPrefix(result.ImageURL, "https://")) {
-		downloadResult, err := s.localStorage.DownloadFromURLWithPath(result.ImageURL, "images")
+	var originalRelPath *string
+	watermarkedURL := ""
+	cacheFailed := false
+	// 预览图只返回服务商地址，不下载到本地
+	var record models.ImageGeneration
+	s.db.Select("id", "drama_id", "storyboard_id", "scene_id", "image_type", "provider", "preview").First(&record, imageGenID)
+
+	// data URI 结果同样保存到本地，以便经过空白检测、转码和水印
+	isDataURI := strings.HasPrefix(result.ImageURL, "data:")
+	var masterPath *string
+	if s.localStorage != nil && result.ImageURL != "" && !record.Preview &&
+		(strings.HasPrefix(result.ImageURL, "http://") || strings.HasPrefix(result.ImageURL, "https://") || isDataURI) {
+		storageCfg := s.cfg().Storage
+		backoff := time.Duration(storageCfg.DownloadBackoff) * time.Second
+		if backoff <= 0 {
+			backoff = 2 * time.Second
+		}
+		category := s.imageStorageCategory(&record, storageCfg.ImagePathTemplate, now)
+		downloadStart := time.Now()
+		var downloadResult *storage.DownloadResult
+		var err error
+		if isDataURI {
+			downloadResult, err = s.localStorage.SaveDataURI(result.ImageURL, category)
+		} else {
+			downloadResult, err = s.localStorage.DownloadImageWithRetry(result.ImageURL, category, storageCfg.DownloadRetries, backoff)
+		}
+		observeStage(metricTaskImage, StageDownload, record.Provider, downloadStart)
 		if err != nil {
+			cacheFailed = storageCfg.MarkCacheFailed
 			errStr := err.Error()
 			if len(errStr) > 200 {
 				errStr = errStr[:200] + "..."
@@ -353,11 +601,30 @@ func (s *ImageGenerationService) completeImageGeneration(imageGenID uint, result
 				"id", imageGenID,
 				"original_url", truncateImageURL(result.ImageURL))
 		} else {
-			localPath = &downloadResult.RelativePath
 			s.log.Infow("Image downloaded to local storage",
 				"id", imageGenID,
 				"original_url", truncateImageURL(result.ImageURL),
 				"local_path", downloadResult.RelativePath)
+
+			if s.isBlankImage(imageGenID, downloadResult.AbsolutePath) {
+				os.Remove(downloadResult.AbsolutePath)
+				s.updateImageGenErrorWithCode(imageGenID, models.ImageErrorBlankOutput, "生成的图片为纯色或空白图片，请重试")
+				return
+			}
+
+			masterPath = s.transcodeDownloadedImage(imageGenID, downloadResult)
+			localPath = &downloadResult.RelativePath
+
+			if s.watermarkEnabled(imageGenID) {
+				originalPath, err := s.applyWatermark(downloadResult)
+				if err != nil {
+					s.log.Errorw("Failed to apply watermark", "error", err, "id", imageGenID)
+				} else {
+					// 对外只暴露加水印后的本地地址，原始地址不再返回
+					watermarkedURL = downloadResult.URL
+					originalRelPath = &originalPath
+				}
+			}
 		}
 	}
 
@@ -366,8 +633,18 @@ func (s *ImageGenerationService) completeImageGeneration(imageGenID uint, result
 		"status":       models.ImageStatusCompleted,
 		"image_url":    result.ImageURL,
 		"local_path":   localPath,
+		"cache_failed": cacheFailed,
+		"error_code":   nil,
 		"completed_at": now,
 	}
+	if masterPath != nil {
+		updates["master_path"] = *masterPath
+	}
+	if watermarkedURL != "" {
+		result.ImageURL = watermarkedURL
+		updates["image_url"] = watermarkedURL
+		updates["original_path"] = originalRelPath
+	}
 
 	if result.Width > 0 {
 		updates["width"] = result.Width
@@ -376,16 +653,34 @@ func (s *ImageGenerationService) completeImageGeneration(imageGenID uint, result
 		updates["height"] = result.Height
 	}
 
+	s.applyCompletedImage(imageGenID, updates, result.ImageURL, localPath)
+	s.saveSiblingImages(imageGenID, result)
+}
+
+// applyCompletedImage 写入完成状态并把图片同步到关联的分镜、场景、角色和道具
+func (s *ImageGenerationService) applyCompletedImage(imageGenID uint, updates map[string]interface{}, imageURL string, localPath *string) {
 	// 更新image_generation记录
 	var imageGen models.ImageGeneration
 	if err := s.db.Where("id = ?", imageGenID).First(&imageGen).Error; err != nil {
 		s.log.Errorw("Failed to load image generation", "error", err, "id", imageGenID)
 		return
 	}
+	// 已被新任务替代的生成结果不再写回关联实体
+	if imageGen.Status == models.ImageStatusCancelled {
+		s.log.Infow("Discarding result of cancelled image generation", "id", imageGenID)
+		return
+	}
 
-	// 使用 Updates 更新基本字段
-	if err := s.db.Model(&models.ImageGeneration{}).Where("id = ?", imageGenID).Updates(updates).Error; err != nil {
-		s.log.Errorw("Failed to update image generation", "error", err, "id", imageGenID)
+	// 使用 Updates 更新基本字段；读取后才被取消的记录不会匹配，结果同样丢弃
+	result := s.db.Model(&models.ImageGeneration{}).
+		Where("id = ? AND status != ?", imageGenID, models.ImageStatusCancelled).
+		Updates(updates)
+	if result.Error != nil {
+		s.log.Errorw("Failed to update image generation", "error", result.Error, "id", imageGenID)
+		return
+	}
+	if result.RowsAffected == 0 {
+		s.log.Infow("Discarding result of cancelled image generation", "id", imageGenID)
 		return
 	}
 
@@ -395,15 +690,25 @@ func (s *ImageGenerationService) completeImageGeneration(imageGenID uint, result
 	}
 
 	s.log.Infow("Image generation completed", "id", imageGenID)
+	if _, cached := updates["cached_from_id"]; cached {
+		recordImageGenerationOutcome(&imageGen, metricStatusCached, "")
+	} else {
+		recordImageGenerationOutcome(&imageGen, metricStatusCompleted, "")
+	}
+
+	// 预览图不写回关联实体，也不计入生成历史
+	if imageGen.Preview {
+		return
+	}
 
 	// 如果关联了storyboard，同步更新storyboard的composed_image
 	if imageGen.StoryboardID != nil {
-		if err := s.db.Model(&models.Storyboard{}).Where("id = ?", *imageGen.StoryboardID).Update("composed_image", result.ImageURL).Error; err != nil {
+		if err := s.db.Model(&models.Storyboard{}).Where("id = ?", *imageGen.StoryboardID).Update("composed_image", imageURL).Error; err != nil {
 			s.log.Errorw("Failed to update storyboard composed_image", "error", err, "storyboard_id", *imageGen.StoryboardID)
 		} else {
 			s.log.Infow("Storyboard updated with composed image",
 				"storyboard_id", *imageGen.StoryboardID,
-				"composed_image", truncateImageURL(result.ImageURL))
+				"composed_image", truncateImageURL(imageURL))
 		}
 	}
 
@@ -411,7 +716,7 @@ func (s *ImageGenerationService) completeImageGeneration(imageGenID uint, result
 	if imageGen.SceneID != nil && imageGen.ImageType == string(models.ImageTypeScene) {
 		sceneUpdates := map[string]interface{}{
 			"status":    "generated",
-			"image_url": result.ImageURL,
+			"image_url": imageURL,
 		}
 		if localPath != nil {
 			sceneUpdates["local_path"] = localPath
@@ -421,7 +726,7 @@ func (s *ImageGenerationService) completeImageGeneration(imageGenID uint, result
 		} else {
 			s.log.Infow("Scene updated with generated image",
 				"scene_id", *imageGen.SceneID,
-				"image_url", truncateImageURL(result.ImageURL),
+				"image_url", truncateImageURL(imageURL),
 				"local_path", localPath)
 		}
 	}
@@ -429,7 +734,7 @@ func (s *ImageGenerationService) completeImageGeneration(imageGenID uint, result
 	// 如果关联了角色，同步更新角色的image_url和local_path
 	if imageGen.CharacterID != nil {
 		characterUpdates := map[string]interface{}{
-			"image_url": result.ImageURL,
+			"image_url": imageURL,
 		}
 		if localPath != nil {
 			characterUpdates["local_path"] = localPath
@@ -439,15 +744,18 @@ func (s *ImageGenerationService) completeImageGeneration(imageGenID uint, result
 		} else {
 			s.log.Infow("Character updated with generated image",
 				"character_id", *imageGen.CharacterID,
-				"image_url", truncateImageURL(result.ImageURL),
+				"image_url", truncateImageURL(imageURL),
 				"local_path", localPath)
 		}
+		if imageGen.ImageType == string(models.ImageTypeCharacter) {
+			s.recordCharacterPortrait(&imageGen)
+		}
 	}
 
 	// 如果关联了道具，同步更新道具的image_url和local_path
 	if imageGen.PropID != nil {
 		propUpdates := map[string]interface{}{
-			"image_url": result.ImageURL,
+			"image_url": imageURL,
 		}
 		if localPath != nil {
 			propUpdates["local_path"] = localPath
@@ -457,29 +765,132 @@ func (s *ImageGenerationService) completeImageGeneration(imageGenID uint, result
 		} else {
 			s.log.Infow("Prop updated with generated image",
 				"prop_id", *imageGen.PropID,
-				"image_url", truncateImageURL(result.ImageURL),
+				"image_url", truncateImageURL(imageURL),
 				"local_path", localPath)
 		}
 	}
+
+	s.recordGenerationHistory(&imageGen, imageURL, localPath)
+
+	// 分镜图片可选的角色出镜检查，异步执行不影响生成结果
+	if imageGen.StoryboardID != nil && s.cfg().AI.CharacterQA {
+		go s.checkCharactersInImage(imageGenID, imageGen.DramaID, *imageGen.StoryboardID, localPath, imageURL)
+	}
+	// 可选的内容标签，用于图片库按画面内容筛选
+	if s.cfg().AI.ImageAutoTag {
+		go s.tagImageContent(imageGenID, localPath, imageURL)
+	}
+}
+
+// saveRawResponse 开启 capture_raw_response 时保存脱敏后的服务商原始响应
+func (s *ImageGenerationService) saveRawResponse(imageGenID uint, raw string, aiConfig *models.AIServiceConfig) {
+	if !s.cfg().AI.CaptureRawResponse || raw == "" {
+		return
+	}
+	redacted := image.RedactRawResponse(raw, configSecrets(aiConfig)...)
+	if err := s.db.Model(&models.ImageGeneration{}).Where("id = ?", imageGenID).
+		Update("raw_response", redacted).Error; err != nil {
+		s.log.Warnw("Failed to save raw response", "error", err, "id", imageGenID)
+	}
+}
+
+// configSecrets 返回配置中需要从原始响应里遮盖的密钥：API Key 和名称像凭证的附加请求头（如 api-key、Authorization）的值
+func configSecrets(aiConfig *models.AIServiceConfig) []string {
+	if aiConfig == nil {
+		return nil
+	}
+	secrets := []string{aiConfig.APIKey}
+	for name, value := range aiConfig.ExtraHeaders {
+		lower := strings.ToLower(name)
+		if strings.Contains(lower, "key") || strings.Contains(lower, "token") ||
+			strings.Contains(lower, "secret") || strings.Contains(lower, "auth") {
+			secrets = append(secrets, value)
+		}
+	}
+	return secrets
+}
+
+// 空白图检测默认阈值，配置为 0 时使用
+const (
+	defaultBlankImageStdDev  = 2.0
+	defaultBlankImageEntropy = 1.0
+)
+
+// isBlankImage 检测下载后的图片是否为纯色或近乎空白（服务商软失败时常见）
+func (s *ImageGenerationService) isBlankImage(imageGenID uint, path string) bool {
+	aiCfg := s.cfg().AI
+	minStdDev := aiCfg.BlankImageStdDev
+	if minStdDev == 0 {
+		minStdDev = defaultBlankImageStdDev
+	}
+	minEntropy := aiCfg.BlankImageEntropy
+	if minEntropy == 0 {
+		minEntropy = defaultBlankImageEntropy
+	}
+	if minStdDev < 0 && minEntropy < 0 {
+		return false
+	}
+
+	stats, err := image.AnalyzeImage(path)
+	if err != nil {
+		// WebP 等标准库无法解码的格式跳过检测
+		s.log.Debugw("Skip blank image check", "id", imageGenID, "error", err)
+		return false
+	}
+
+	if stats.IsBlank(minStdDev, minEntropy) {
+		s.log.Warnw("Generated image looks blank",
+			"id", imageGenID,
+			"std_dev", stats.StdDev,
+			"entropy", stats.Entropy,
+			"min_std_dev", minStdDev,
+			"min_entropy", minEntropy)
+		return true
+	}
+	return false
 }
 
 func (s *ImageGenerationService) updateImageGenError(imageGenID uint, errorMsg string) {
+	s.updateImageGenErrorWithCode(imageGenID, "", errorMsg)
+}
+
+// updateImageGenErrorWithCode 标记生成失败并记录失败原因代码，便于前端按原因重试
+func (s *ImageGenerationService) updateImageGenErrorWithCode(imageGenID uint, errorCode string, errorMsg string) {
 	// 先获取image_generation记录
 	var imageGen models.ImageGeneration
 	if err := s.db.Where("id = ?", imageGenID).First(&imageGen).Error; err != nil {
 		s.log.Errorw("Failed to load image generation", "error", err, "id", imageGenID)
 		return
 	}
+	if imageGen.Status == models.ImageStatusCancelled {
+		return
+	}
 
 	// 更新image_generation状态
-	s.db.Model(&models.ImageGeneration{}).Where("id = ?", imageGenID).Updates(map[string]interface{}{
-		"status":    models.ImageStatusFailed,
-		"error_msg": errorMsg,
-	})
-	s.log.Errorw("Image generation failed", "id", imageGenID, "error", errorMsg)
+	var code *string
+	if errorCode != "" {
+		code = &errorCode
+	}
+	result := s.db.Model(&models.ImageGeneration{}).
+		Where("id = ? AND status != ?", imageGenID, models.ImageStatusCancelled).
+		Updates(map[string]interface{}{
+			"status":     models.ImageStatusFailed,
+			"error_msg":  errorMsg,
+			"error_code": code,
+		})
+	if result.Error != nil {
+		s.log.Errorw("Failed to update image generation error", "error", result.Error, "id", imageGenID)
+		return
+	}
+	// 读取后才被取消的记录保持已取消状态，不再标记场景失败
+	if result.RowsAffected == 0 {
+		return
+	}
+	s.log.Errorw("Image generation failed", "id", imageGenID, "error", errorMsg, "error_code", errorCode)
+	recordImageGenerationOutcome(&imageGen, metricStatusFailed, errorCode)
 
 	// 如果关联了scene，同步更新scene为失败状态
-	if imageGen.SceneID != nil {
+	if imageGen.SceneID != nil && !imageGen.Preview {
 		s.db.Model(&models.Scene{}).Where("id = ?", *imageGen.SceneID).Update("status", "failed")
 		s.log.Warnw("Scene marked as failed", "scene_id", *imageGen.SceneID)
 	}
@@ -491,98 +902,159 @@ func (s *ImageGenerationService) getImageClient(provider string) (image.ImageCli
 		return nil, fmt.Errorf("no image AI config found: %w", err)
 	}
 
-	// 使用第一个模型
-	model := ""
-	if len(config.Model) > 0 {
-		model = config.Model[0]
-	}
-
 	// 使用配置中的 provider，如果没有则使用传入的 provider
 	actualProvider := config.Provider
 	if actualProvider == "" {
 		actualProvider = provider
 	}
 
+	// 使用服务商的默认模型或配置中的第一个模型
+	model, err := resolveProviderModel(config, actualProvider, "")
+	if err != nil {
+		return nil, err
+	}
+
 	// 根据 provider 自动设置默认端点
 	var endpoint string
 	var queryEndpoint string
+	var client image.ImageClient
 
-	switch actualProvider {
-	case "openai", "dalle":
-		endpoint = "/images/generations"
-		return image.NewOpenAIImageClient(config.BaseURL, config.APIKey, model, endpoint), nil
-	case "chatfire":
-		endpoint = "/images/generations"
-		return image.NewOpenAIImageClient(config.BaseURL, config.APIKey, model, endpoint), nil
-	case "volcengine", "volces", "doubao":
+	switch s.resolveImageProvider(actualProvider) {
+	case "volcengine":
 		endpoint = "/images/generations"
 		queryEndpoint = ""
-		return image.NewVolcEngineImageClient(config.BaseURL, config.APIKey, model, endpoint, queryEndpoint), nil
-	case "gemini", "google":
+		client = image.NewVolcEngineImageClient(config.BaseURL, config.APIKey, model, endpoint, queryEndpoint)
+	case "gemini":
 		endpoint = "/v1beta/models/{model}:generateContent"
-		return image.NewGeminiImageClient(config.BaseURL, config.APIKey, model, endpoint), nil
+		client = image.NewGeminiImageClient(config.BaseURL, config.APIKey, model, endpoint)
 	default:
 		endpoint = "/images/generations"
-		return image.NewOpenAIImageClient(config.BaseURL, config.APIKey, model, endpoint), nil
+		client = image.NewOpenAIImageClient(config.BaseURL, config.APIKey, model, endpoint)
 	}
+	applyExtraHeaders(client, config.ExtraHeaders)
+	applySafetySettings(client, config.SafetySettings)
+
+	return client, nil
 }
 
 // getImageClientWithModel 根据模型名称获取图片客户端
-func (s *ImageGenerationService) getImageClientWithModel(provider string, modelName string) (image.ImageClient, error) {
-	var config *models.AIServiceConfig
-	var err error
+// stickyKey 用于加权选择模式，同一生成记录的提交和恢复轮询始终使用同一配置
+func (s *ImageGenerationService) getImageClientWithModel(provider string, modelName string, dramaID uint, stickyKey string) (image.ImageClient, *models.AIServiceConfig, error) {
+	// 剧本绑定了图片配置时始终使用该配置
+	config, err := s.aiService.dramaPinnedConfig("image", dramaID)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	// 如果指定了模型，尝试获取对应的配置
-	if modelName != "" {
+	if config != nil {
+		modelName = pinnedModel(config, modelName)
+	} else if modelName != "" {
+		// 如果指定了模型，尝试获取对应的配置
 		config, err = s.aiService.GetConfigForModel("image", modelName)
 		if err != nil {
 			s.log.Warnw("Failed to get config for model, using default", "model", modelName, "error", err)
-			config, err = s.aiService.GetDefaultConfig("image")
+			config, err = s.aiService.GetStickyConfig("image", stickyKey)
 			if err != nil {
-				return nil, fmt.Errorf("no image AI config found: %w", err)
+				return nil, nil, fmt.Errorf("no image AI config found: %w", err)
 			}
 		}
 	} else {
-		config, err = s.aiService.GetDefaultConfig("image")
+		config, err = s.aiService.GetStickyConfig("image", stickyKey)
 		if err != nil {
-			return nil, fmt.Errorf("no image AI config found: %w", err)
+			return nil, nil, fmt.Errorf("no image AI config found: %w", err)
 		}
 	}
 
-	// 使用指定的模型或配置中的第一个模型
-	model := modelName
-	if model == "" && len(config.Model) > 0 {
-		model = config.Model[0]
-	}
-
 	// 使用配置中的 provider，如果没有则使用传入的 provider
 	actualProvider := config.Provider
 	if actualProvider == "" {
 		actualProvider = provider
 	}
 
+	// 使用指定的模型（需在配置的模型列表中），否则使用服务商的默认模型或配置中的第一个模型
+	model, err := resolveProviderModel(config, actualProvider, modelName)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// 根据 provider 自动设置默认端点
 	var endpoint string
 	var queryEndpoint string
+	var client image.ImageClient
 
-	switch actualProvider {
-	case "openai", "dalle":
-		endpoint = "/images/generations"
-		return image.NewOpenAIImageClient(config.BaseURL, config.APIKey, model, endpoint), nil
-	case "chatfire":
-		endpoint = "/images/generations"
-		return image.NewOpenAIImageClient(config.BaseURL, config.APIKey, model, endpoint), nil
-	case "volcengine", "volces", "doubao":
+	switch s.resolveImageProvider(actualProvider) {
+	case "volcengine":
 		endpoint = "/images/generations"
 		queryEndpoint = ""
-		return image.NewVolcEngineImageClient(config.BaseURL, config.APIKey, model, endpoint, queryEndpoint), nil
-	case "gemini", "google":
+		client = image.NewVolcEngineImageClient(config.BaseURL, config.APIKey, model, endpoint, queryEndpoint)
+	case "gemini":
 		endpoint = "/v1beta/models/{model}:generateContent"
-		return image.NewGeminiImageClient(config.BaseURL, config.APIKey, model, endpoint), nil
+		client = image.NewGeminiImageClient(config.BaseURL, config.APIKey, model, endpoint)
 	default:
 		endpoint = "/images/generations"
-		return image.NewOpenAIImageClient(config.BaseURL, config.APIKey, model, endpoint), nil
+		client = image.NewOpenAIImageClient(config.BaseURL, config.APIKey, model, endpoint)
+	}
+	applyExtraHeaders(client, config.ExtraHeaders)
+	applySafetySettings(client, config.SafetySettings)
+
+	return client, config, nil
+}
+
+// imageStickyKey 加权选择模式下固定图片生成记录所用配置的 key
+func imageStickyKey(imageGenID uint) string {
+	return fmt.Sprintf("image:%d", imageGenID)
+}
+
+const defaultImageMaxRetries = 3
+
+// imageMaxRetries 单条图片生成最多重试次数
+func (s *ImageGenerationService) imageMaxRetries() int {
+	if maxRetries := s.cfg().AI.ImageMaxRetries; maxRetries > 0 {
+		return maxRetries
+	}
+	return defaultImageMaxRetries
+}
+
+// RetryImageGeneration 重试失败的图片生成，超过最大重试次数时拒绝
+func (s *ImageGenerationService) RetryImageGeneration(imageGenID uint) (*models.ImageGeneration, error) {
+	var imageGen models.ImageGeneration
+	if err := s.db.Where("id = ?", imageGenID).First(&imageGen).Error; err != nil {
+		return nil, ErrImageGenerationNotFound
+	}
+	if imageGen.Status != models.ImageStatusFailed {
+		return nil, fmt.Errorf("只能重试失败的图片生成")
+	}
+
+	maxRetries := s.imageMaxRetries()
+	if imageGen.RetryCount >= maxRetries {
+		return nil, fmt.Errorf("已达到最大重试次数(%d)，请修改提示词后重新生成", maxRetries)
+	}
+
+	// 条件更新，避免并发重试重复计数
+	result := s.db.Model(&models.ImageGeneration{}).
+		Where("id = ? AND status = ? AND retry_count = ?", imageGenID, models.ImageStatusFailed, imageGen.RetryCount).
+		Updates(map[string]interface{}{
+			"status":      models.ImageStatusPending,
+			"retry_count": gorm.Expr("retry_count + 1"),
+			"error_msg":   nil,
+			"error_code":  nil,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update record: %w", result.Error)
 	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("该图片生成正在重试中")
+	}
+
+	imageGen.Status = models.ImageStatusPending
+	imageGen.RetryCount++
+	imageGen.ErrorMsg = nil
+	imageGen.ErrorCode = nil
+
+	s.log.Infow("Retrying image generation", "id", imageGenID, "retry_count", imageGen.RetryCount, "max_retries", maxRetries)
+	s.enqueueImageGeneration(&imageGen)
+
+	return &imageGen, nil
 }
 
 func (s *ImageGenerationService) GetImageGeneration(imageGenID uint) (*models.ImageGeneration, error) {
@@ -590,10 +1062,12 @@ func (s *ImageGenerationService) GetImageGeneration(imageGenID uint) (*models.Im
 	if err := s.db.Where("id = ? ", imageGenID).First(&imageGen).Error; err != nil {
 		return nil, err
 	}
+	s.fillQueuePositions([]*models.ImageGeneration{&imageGen})
 	return &imageGen, nil
 }
 
-func (s *ImageGenerationService) ListImageGenerations(dramaID *uint, sceneID *uint, storyboardID *uint, frameType string, status string, page, pageSize int) ([]models.ImageGeneration, int64, error) {
+// ListImageGenerations 分页查询图片生成记录，tag 不为空时只返回内容标签中包含该标签的记录
+func (s *ImageGenerationService) ListImageGenerations(dramaID *uint, sceneID *uint, storyboardID *uint, frameType string, status string, tag string, page, pageSize int) ([]models.ImageGeneration, int64, error) {
 	query := s.db.Model(&models.ImageGeneration{})
 
 	if dramaID != nil {
@@ -616,6 +1090,10 @@ func (s *ImageGenerationService) ListImageGenerations(dramaID *uint, sceneID *ui
 		query = query.Where("status = ?", status)
 	}
 
+	if strings.TrimSpace(tag) != "" {
+		query = query.Where("content_tags LIKE ? ESCAPE '"+contentTagLikeEscape+"'", contentTagFilter(tag))
+	}
+
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -636,7 +1114,7 @@ func (s *ImageGenerationService) DeleteImageGeneration(imageGenID uint) error {
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("image generation not found")
+		return ErrImageGenerationNotFound
 	}
 	return nil
 }
@@ -655,13 +1133,13 @@ func (s *ImageGenerationService) CreateImageFromUpload(req *UploadImageRequest)
 	// 验证storyboard存在
 	var storyboard models.Storyboard
 	if err := s.db.First(&storyboard, req.StoryboardID).Error; err != nil {
-		return nil, fmt.Errorf("storyboard not found")
+		return nil, ErrStoryboardNotFound
 	}
 
 	// 验证drama存在
 	var drama models.Drama
 	if err := s.db.First(&drama, req.DramaID).Error; err != nil {
-		return nil, fmt.Errorf("drama not found")
+		return nil, ErrDramaNotFound
 	}
 
 	prompt := req.Prompt
@@ -705,7 +1183,7 @@ func (s *ImageGenerationService) GenerateImagesForScene(sceneID string) ([]*mode
 
 	var scene models.Scene
 	if err := s.db.Where("id = ?", sceneIDUint).First(&scene).Error; err != nil {
-		return nil, fmt.Errorf("scene not found")
+		return nil, ErrSceneNotFound
 	}
 
 	// 构建场景图片生成提示词
@@ -741,14 +1219,30 @@ type BackgroundInfo struct {
 	StoryboardCount   int    `json:"scene_count"`
 }
 
-func (s *ImageGenerationService) BatchGenerateImagesForEpisode(episodeID string) ([]*models.ImageGeneration, error) {
+// 批量生成图片的模式
+const (
+	BatchImageModeBackground = "background" // 仅生成背景（默认）
+	BatchImageModeComposed   = "composed"   // 以角色形象图为参考，生成包含角色的完整画面
+)
+
+// BatchGenerateImagesForEpisode 为剧集的所有分镜批量生成图片，mode 为空时按背景模式处理
+// concurrency 为本批最多同时生成的图片数，0 使用配置 ai.episode_image_concurrency，仍受全局 image_workers 限制
+// 返回每个分镜的处理状态（入队/无提示词跳过/失败）及原因，并写入跟踪任务的结果
+func (s *ImageGenerationService) BatchGenerateImagesForEpisode(episodeID string, mode string, concurrency int) (*BatchImageResult, error) {
+	if concurrency < 0 {
+		return nil, &ServiceError{Kind: ErrInvalidInput, Message: "concurrency 不能为负数"}
+	}
+	if concurrency == 0 {
+		concurrency = s.cfg().AI.EpisodeImageConcurrency
+	}
+
 	var ep models.Episode
 	if err := s.db.Preload("Drama").Where("id = ?", episodeID).First(&ep).Error; err != nil {
-		return nil, fmt.Errorf("episode not found")
+		return nil, ErrEpisodeNotFound
 	}
 	// 从数据库读取已保存的场景
 	var scenes []models.Storyboard
-	if err := s.db.Where("episode_id = ?", episodeID).Find(&scenes).Error; err != nil {
+	if err := s.db.Preload("Characters").Where("episode_id = ?", episodeID).Find(&scenes).Error; err != nil {
 		return nil, fmt.Errorf("failed to get scenes: %w", err)
 	}
 
@@ -757,30 +1251,40 @@ func (s *ImageGenerationService) BatchGenerateImagesForEpisode(episodeID string)
 		"episode_id", episodeID,
 		"background_count", len(backgrounds))
 
-	// 为每个背景生成图片
-	var results []*models.ImageGeneration
+	task, err := s.taskService.CreateTask("episode_batch_image", episodeID)
+	if err != nil {
+		s.log.Errorw("Failed to create task", "error", err)
+		return nil, fmt.Errorf("创建任务失败: %w", err)
+	}
+
+	// 为每个背景生成图片，全部创建记录后按并发限制入队
+	result := &BatchImageResult{
+		TaskID:           task.ID,
+		Total:            len(scenes),
+		Items:            make([]BatchImageItem, 0, len(scenes)),
+		ImageGenerations: make([]*models.ImageGeneration, 0, len(scenes)),
+	}
+	var queued []*models.ImageGeneration
 	for _, bg := range scenes {
 		if bg.ImagePrompt == nil || *bg.ImagePrompt == "" {
 			s.log.Warnw("Background has no prompt, skipping", "scene_id", bg.ID)
+			result.add(&bg, BatchImageSkippedNoPrompt, "分镜没有图片提示词", nil)
 			continue
 		}
 
 		// 更新背景状态为处理中
 		s.db.Model(bg).Update("status", "generating")
 
-		req := &GenerateImageRequest{
-			StoryboardID: &bg.ID,
-			DramaID:      fmt.Sprintf("%d", ep.DramaID),
-			Prompt:       *bg.ImagePrompt,
-		}
+		req := s.storyboardImageRequest(&bg, ep.DramaID, *bg.ImagePrompt, mode)
 
-		imageGen, err := s.GenerateImage(req)
+		imageGen, needsQueue, err := s.createImageGeneration(req)
 		if err != nil {
 			s.log.Errorw("Failed to generate image for background",
 				"scene_id", bg.ID,
 				"location", bg.Location,
 				"error", err)
 			s.db.Model(bg).Update("status", "failed")
+			result.add(&bg, BatchImageFailed, err.Error(), nil)
 			continue
 		}
 
@@ -790,17 +1294,50 @@ func (s *ImageGenerationService) BatchGenerateImagesForEpisode(episodeID string)
 			"location", bg.Location,
 			"time", bg.Time)
 
-		results = append(results, imageGen)
+		result.add(&bg, BatchImageEnqueued, "", imageGen)
+		if needsQueue {
+			queued = append(queued, imageGen)
+		}
+	}
+
+	s.enqueueWithLimit(queued, concurrency)
+	s.log.Infow("Episode batch image generation queued",
+		"episode_id", episodeID,
+		"queued", len(queued),
+		"skipped", result.Skipped,
+		"failed", result.Failed,
+		"concurrency", concurrency)
+
+	if err := s.taskService.UpdateTaskResult(task.ID, result); err != nil {
+		s.log.Errorw("Failed to update task result", "error", err, "task_id", task.ID)
 	}
 
-	return results, nil
+	return result, nil
+}
+
+// storyboardImageRequest 构建分镜图片的生成请求：composed 模式以未被排除的角色形象图为参考，并在提示词中说明排除的角色
+// sb 需预加载 Characters
+func (s *ImageGenerationService) storyboardImageRequest(sb *models.Storyboard, dramaID uint, prompt string, mode string) *GenerateImageRequest {
+	req := &GenerateImageRequest{
+		StoryboardID: &sb.ID,
+		DramaID:      fmt.Sprintf("%d", dramaID),
+		Prompt:       prompt,
+	}
+	if mode == BatchImageModeComposed {
+		s.applyCharacterPortraits(req, withoutExcludedCharacters(sb.Characters, sb.ExcludedCharacters))
+	}
+	if excluded := excludedCharacterNames(s.db, sb); len(excluded) > 0 {
+		label := s.promptI18nForDrama(dramaID).FormatUserPrompt("excluded_label", strings.Join(excluded, ", "))
+		req.Prompt = req.Prompt + "\n" + label
+	}
+	return req
 }
 
 // GetScencesForEpisode 获取项目的场景列表（项目级）
 func (s *ImageGenerationService) GetScencesForEpisode(episodeID string) ([]*models.Scene, error) {
 	var episode models.Episode
 	if err := s.db.Preload("Drama").Where("id = ?", episodeID).First(&episode).Error; err != nil {
-		return nil, fmt.Errorf("episode not found")
+		return nil, ErrEpisodeNotFound
 	}
 
 	// 场景是项目级的，通过drama_id查询
@@ -809,14 +1346,69 @@ func (s *ImageGenerationService) GetScencesForEpisode(episodeID string) ([]*mode
 		return nil, fmt.Errorf("failed to load scenes: %w", err)
 	}
 
+	s.fillSceneGenerationStatus(scenes)
+
 	return scenes, nil
 }
 
+// fillSceneGenerationStatus 根据最新的图片生成记录填充场景的生成状态（pending/generating/generated/failed）和最新图片
+func (s *ImageGenerationService) fillSceneGenerationStatus(scenes []*models.Scene) {
+	if len(scenes) == 0 {
+		return
+	}
+
+	sceneIDs := make([]uint, len(scenes))
+	for i, scene := range scenes {
+		sceneIDs[i] = scene.ID
+	}
+
+	var imageGens []models.ImageGeneration
+	if err := s.db.Where("scene_id IN ? AND preview = ?", sceneIDs, false).Order("created_at DESC").Find(&imageGens).Error; err != nil {
+		s.log.Warnw("Failed to load scene image generations", "error", err)
+		return
+	}
+
+	// 每个场景只取最新的一条记录
+	latest := make(map[uint]*models.ImageGeneration)
+	for i := range imageGens {
+		if _, exists := latest[*imageGens[i].SceneID]; !exists {
+			latest[*imageGens[i].SceneID] = &imageGens[i]
+		}
+	}
+
+	for _, scene := range scenes {
+		status := "pending"
+		if scene.ImageURL != nil && *scene.ImageURL != "" {
+			status = "generated"
+			scene.LatestImageURL = scene.ImageURL
+		}
+
+		if imageGen, ok := latest[scene.ID]; ok {
+			switch imageGen.Status {
+			case models.ImageStatusPending, models.ImageStatusProcessing:
+				status = "generating"
+			case models.ImageStatusCompleted:
+				status = "generated"
+				if imageGen.ImageURL != nil {
+					scene.LatestImageURL = imageGen.ImageURL
+				}
+			case models.ImageStatusFailed:
+				status = "failed"
+				scene.ImageGenerationError = imageGen.ErrorMsg
+			}
+		}
+
+		scene.ImageGenerationStatus = &status
+	}
+}
+
 // ExtractBackgroundsForEpisode 从剧本内容中提取场景并保存到项目级别数据库
-func (s *ImageGenerationService) ExtractBackgroundsForEpisode(episodeID string, model string, style string) (string, error) {
+// dedupMode 为 SceneDedupEmbedding 时，会对提取结果按语义相似度合并近似场景；
+// saveMode 为 SceneExtractMerge 时与现有场景合并，保留用户手动修改过的字段
+func (s *ImageGenerationService) ExtractBackgroundsForEpisode(episodeID string, model string, style string, dedupMode string, saveMode string) (string, error) {
 	var episode models.Episode
 	if err := s.db.Preload("Storyboards").First(&episode, episodeID).Error; err != nil {
-		return "", fmt.Errorf("episode not found")
+		return "", ErrEpisodeNotFound
 	}
 
 	// 如果没有剧本内容，无法提取场景
@@ -832,14 +1424,14 @@ func (s *ImageGenerationService) ExtractBackgroundsForEpisode(episodeID string,
 	}
 
 	// 异步处理场景提取
-	go s.processBackgroundExtraction(task.ID, episodeID, model, style)
+	go s.processBackgroundExtraction(task.ID, episodeID, model, style, dedupMode, saveMode)
 
 	s.log.Infow("Background extraction task created", "task_id", task.ID, "episode_id", episodeID)
 	return task.ID, nil
 }
 
 // processBackgroundExtraction 异步处理场景提取
-func (s *ImageGenerationService) processBackgroundExtraction(taskID string, episodeID string, model string, style string) {
+func (s *ImageGenerationService) processBackgroundExtraction(taskID string, episodeID string, model string, style string, dedupMode string, saveMode string) {
 	// 更新任务状态为处理中
 	s.taskService.UpdateTaskStatus(taskID, "processing", 0, "正在提取场景信息...")
 
@@ -863,7 +1455,33 @@ func (s *ImageGenerationService) processBackgroundExtraction(taskID string, epis
 	backgroundsInfo, err := s.extractBackgroundsFromScript(*episode.ScriptContent, dramaID, model, style)
 	if err != nil {
 		s.log.Errorw("Failed to extract backgrounds from script", "error", err, "task_id", taskID)
-		s.taskService.UpdateTaskStatus(taskID, "failed", 0, "AI提取场景失败: "+err.Error())
+		s.taskService.UpdateTaskError(taskID, fmt.Errorf("AI提取场景失败: %w", err))
+		return
+	}
+
+	// 剧本过于简略时AI可能一个场景都提取不到，此时使用根据标题和简介生成的通用场景
+	// 合并模式下剧集已有场景时保留原场景即可，不再补充通用场景
+	fallbackUsed := false
+	if len(backgroundsInfo) == 0 && (saveMode != SceneExtractMerge || !s.episodeHasScenes(episode.ID)) {
+		backgroundsInfo = []BackgroundInfo{fallbackBackground(&episode, s.promptI18nForDrama(dramaID), s.sceneFallbackStyle(dramaID, style))}
+		fallbackUsed = true
+		s.log.Warnw("AI extracted no backgrounds, using fallback scene from episode title",
+			"episode_id", episodeID,
+			"location", backgroundsInfo[0].Location,
+			"task_id", taskID)
+	}
+
+	// 可选：按语义相似度合并近似场景，失败时保留原结果
+	if dedupMode == SceneDedupEmbedding {
+		if deduped, err := s.dedupBackgroundsByEmbedding(backgroundsInfo); err != nil {
+			s.log.Warnw("Embedding dedup failed, keeping original backgrounds", "error", err, "task_id", taskID)
+		} else {
+			backgroundsInfo = deduped
+		}
+	}
+
+	if saveMode == SceneExtractMerge {
+		s.mergeExtractedBackgrounds(taskID, &episode, backgroundsInfo)
 		return
 	}
 
@@ -887,8 +1505,9 @@ func (s *ImageGenerationService) processBackgroundExtraction(taskID string, epis
 				Location:        bgInfo.Location,
 				Time:            bgInfo.Time,
 				Prompt:          bgInfo.Prompt,
-				StoryboardCount: 1, // 默认为1
+				StoryboardCount: max(bgInfo.StoryboardCount, 1), // 默认为1
 				Status:          "pending",
+				ExtractKey:      sceneExtractKey(bgInfo.Location, bgInfo.Time),
 			}
 			if err := tx.Create(scene).Error; err != nil {
 				return err
@@ -917,6 +1536,7 @@ func (s *ImageGenerationService) processBackgroundExtraction(taskID string, epis
 		"count":      len(scenes),
 		"episode_id": episodeID,
 		"drama_id":   dramaID,
+		"fallback":   fallbackUsed,
 	}
 	s.taskService.UpdateTaskResult(taskID, resultData)
 
@@ -927,36 +1547,51 @@ func (s *ImageGenerationService) processBackgroundExtraction(taskID string, epis
 		"unique_scenes", len(scenes))
 }
 
+// parseBackgroundsText 解析AI返回的场景JSON，支持数组格式和 {"backgrounds": [...]} 对象格式
+func parseBackgroundsText(text string) ([]BackgroundInfo, error) {
+	var backgrounds []BackgroundInfo
+	if err := utils.SafeParseAIJSON(text, &backgrounds); err == nil {
+		return backgrounds, nil
+	}
+
+	var result struct {
+		Backgrounds []BackgroundInfo `json:"backgrounds"`
+	}
+	if err := utils.SafeParseAIJSON(text, &result); err != nil {
+		return nil, err
+	}
+	return result.Backgrounds, nil
+}
+
+// promptI18nForDrama 按剧本的语言设置获取提示词工具
+func (s *ImageGenerationService) promptI18nForDrama(dramaID uint) *PromptI18n {
+	var drama models.Drama
+	if err := s.db.Select("id", "language").Where("id = ?", dramaID).First(&drama).Error; err != nil {
+		return s.promptI18n
+	}
+	return s.promptI18n.WithLanguage(drama.Language)
+}
+
 // extractBackgroundsFromScript 从剧本内容中使用AI提取场景信息
 func (s *ImageGenerationService) extractBackgroundsFromScript(scriptContent string, dramaID uint, model string, style string) ([]BackgroundInfo, error) {
 	if scriptContent == "" {
 		return []BackgroundInfo{}, nil
 	}
 
-	// 获取AI客户端（如果指定了模型则使用指定的模型）
-	var client ai.AIClient
-	var err error
-	if model != "" {
-		s.log.Infow("Using specified model for background extraction", "model", model)
-		client, err = s.aiService.GetAIClientForModel("text", model)
-		if err != nil {
-			s.log.Warnw("Failed to get client for specified model, using default", "model", model, "error", err)
-			client, err = s.aiService.GetAIClient("text")
-		}
-	} else {
-		client, err = s.aiService.GetAIClient("text")
-	}
+	// 获取AI客户端（剧本绑定了配置时使用绑定的配置，否则如果指定了模型则使用指定的模型）
+	client, _, err := s.aiService.GetAIClientForDrama("text", dramaID, model)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get AI client: %w", err)
 	}
 
-	// 使用国际化提示词
-	systemPrompt := s.promptI18n.GetSceneExtractionPrompt(style)
-	contentLabel := s.promptI18n.FormatUserPrompt("script_content_label")
+	// 使用国际化提示词（剧本可覆盖全局语言）
+	i18n := s.promptI18nForDrama(dramaID)
+	systemPrompt := i18n.GetSceneExtractionPrompt(style)
+	contentLabel := i18n.FormatUserPrompt("script_content_label")
 
 	// 根据语言构建不同的格式说明
 	var formatInstructions string
-	if s.promptI18n.IsEnglish() {
+	if i18n.IsEnglish() {
 		formatInstructions = `[Output JSON Format]
 {
   "backgrounds": [
@@ -1043,7 +1678,7 @@ Please strictly follow the JSON format and ensure all fields use English.`
 
 	// 打印完整提示词用于调试
 	s.log.Infow("=== AI Prompt for Background Extraction (extractBackgroundsFromScript) ===",
-		"language", s.promptI18n.GetLanguage(),
+		"language", i18n.GetLanguage(),
 		"prompt_length", len(prompt),
 		"full_prompt", prompt)
 
@@ -1058,23 +1693,11 @@ Please strictly follow the JSON format and ensure all fields use English.`
 		"response_length", len(response),
 		"raw_response", response)
 
-	// 解析AI返回的JSON
-	var backgrounds []BackgroundInfo
-
-	// 先尝试解析为数组格式
-	if err := utils.SafeParseAIJSON(response, &backgrounds); err == nil {
-		s.log.Infow("Parsed backgrounds as array format", "count", len(backgrounds))
-	} else {
-		// 尝试解析为对象格式
-		var result struct {
-			Backgrounds []BackgroundInfo `json:"backgrounds"`
-		}
-		if err := utils.SafeParseAIJSON(response, &result); err != nil {
-			s.log.Errorw("Failed to parse AI response in both formats", "error", err, "response", response[:min(len(response), 500)])
-			return nil, fmt.Errorf("解析AI响应失败: %w", err)
-		}
-		backgrounds = result.Backgrounds
-		s.log.Infow("Parsed backgrounds as object format", "count", len(backgrounds))
+	// 解析AI返回的JSON，无法解析时请求AI修复
+	backgrounds, err := repairStructured(s.aiService, client, i18n, response, formatInstructions, parseBackgroundsText, ai.WithTemperature(0.7))
+	if err != nil {
+		s.log.Errorw("Failed to parse AI response in both formats", "error", err, "response", response[:min(len(response), 500)])
+		return nil, fmt.Errorf("解析AI响应失败: %w", err)
 	}
 
 	s.log.Infow("Extracted backgrounds from script",
@@ -1085,7 +1708,7 @@ Please strictly follow the JSON format and ensure all fields use English.`
 }
 
 // extractBackgroundsWithAI 使用AI智能分析场景并提取唯一背景
-func (s *ImageGenerationService) extractBackgroundsWithAI(storyboards []models.Storyboard, style string) ([]BackgroundInfo, error) {
+func (s *ImageGenerationService) extractBackgroundsWithAI(dramaID uint, storyboards []models.Storyboard, style string) ([]BackgroundInfo, error) {
 	if len(storyboards) == 0 {
 		return []BackgroundInfo{}, nil
 	}
@@ -1208,7 +1831,7 @@ Please strictly follow the JSON format and ensure:
 		"full_prompt", prompt)
 
 	// 调用AI服务
-	text, err := s.aiService.GenerateText(prompt, "")
+	text, err := s.aiService.GenerateTextForDrama(dramaID, "", prompt, "")
 	if err != nil {
 		return nil, fmt.Errorf("AI analysis failed: %w", err)
 	}
@@ -1268,9 +1891,20 @@ Please strictly follow the JSON format and ensure:
 
 // extractUniqueBackgrounds 从分镜头中提取唯一背景（代码逻辑，作为AI提取的备份）
 func (s *ImageGenerationService) extractUniqueBackgrounds(scenes []models.Storyboard) []BackgroundInfo {
+	// 按分镜编号遍历，保证结果顺序稳定
+	sorted := make([]models.Storyboard, len(scenes))
+	copy(sorted, scenes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].StoryboardNumber != sorted[j].StoryboardNumber {
+			return sorted[i].StoryboardNumber < sorted[j].StoryboardNumber
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
 	backgroundMap := make(map[string]*BackgroundInfo)
+	var order []string
 
-	for _, scene := range scenes {
+	for _, scene := range sorted {
 		if scene.Location == nil || scene.Time == nil {
 			continue
 		}
@@ -1281,6 +1915,7 @@ func (s *ImageGenerationService) extractUniqueBackgrounds(scenes []models.Storyb
 		if bg, exists := backgroundMap[key]; exists {
 			// 背景已存在，添加scene ID
 			bg.SceneIDs = append(bg.SceneIDs, scene.ID)
+			bg.StoryboardNumbers = append(bg.StoryboardNumbers, scene.StoryboardNumber)
 			bg.StoryboardCount++
 		} else {
 			// 新背景 - 使用ImagePrompt构建背景提示词
@@ -1289,19 +1924,21 @@ func (s *ImageGenerationService) extractUniqueBackgrounds(scenes []models.Storyb
 				prompt = *scene.ImagePrompt
 			}
 			backgroundMap[key] = &BackgroundInfo{
-				Location:        *scene.Location,
-				Time:            *scene.Time,
-				Prompt:          prompt,
-				SceneIDs:        []uint{scene.ID},
-				StoryboardCount: 1,
+				Location:          *scene.Location,
+				Time:              *scene.Time,
+				Prompt:            prompt,
+				StoryboardNumbers: []int{scene.StoryboardNumber},
+				SceneIDs:          []uint{scene.ID},
+				StoryboardCount:   1,
 			}
+			order = append(order, key)
 		}
 	}
 
-	// 转换为切片
-	var backgrounds []BackgroundInfo
-	for _, bg := range backgroundMap {
-		backgrounds = append(backgrounds, *bg)
+	// 按首次出现的分镜顺序转换为切片
+	backgrounds := make([]BackgroundInfo, 0, len(order))
+	for _, key := range order {
+		backgrounds = append(backgrounds, *backgroundMap[key])
 	}
 
 	return backgrounds
@@ -1309,23 +1946,39 @@ func (s *ImageGenerationService) extractUniqueBackgrounds(scenes []models.Storyb
 
 // loadImageAsBase64 读取本地图片文件并转换为 base64 格式的 data URI
 func (s *ImageGenerationService) loadImageAsBase64(localPath string) (string, error) {
-	// 构建完整的文件路径
-	var fullPath string
+	fileData, mimeType, err := s.readLocalImage(localPath)
+	if err != nil {
+		return "", err
+	}
+
+	// 转换为 base64
+	base64Data := base64.StdEncoding.EncodeToString(fileData)
+
+	// 构建 data URI
+	dataURI := fmt.Sprintf("data:%s;base64,%s", mimeType, base64Data)
+
+	return dataURI, nil
+}
+
+// localImagePath 返回本地图片的完整路径，相对路径拼接存储根目录
+func (s *ImageGenerationService) localImagePath(localPath string) string {
 	if filepath.IsAbs(localPath) {
-		fullPath = localPath
-	} else {
-		// 如果是相对路径，拼接存储根目录
-		if s.localStorage != nil {
-			fullPath = s.localStorage.GetAbsolutePath(localPath)
-		} else {
-			fullPath = filepath.Join(s.config.Storage.LocalPath, localPath)
-		}
+		return localPath
+	}
+	if s.localStorage != nil {
+		return s.localStorage.GetAbsolutePath(localPath)
 	}
+	return filepath.Join(s.config.Storage.LocalPath, localPath)
+}
+
+// readLocalImage 读取本地图片文件，按扩展名返回 MIME 类型
+func (s *ImageGenerationService) readLocalImage(localPath string) ([]byte, string, error) {
+	fullPath := s.localImagePath(localPath)
 
 	// 读取文件
 	fileData, err := os.ReadFile(fullPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to read image file: %w", err)
+		return nil, "", fmt.Errorf("failed to read image file: %w", err)
 	}
 
 	// 根据文件扩展名确定 MIME 类型
@@ -1342,11 +1995,34 @@ func (s *ImageGenerationService) loadImageAsBase64(localPath string) (string, er
 		mimeType = "image/webp"
 	}
 
-	// 转换为 base64
-	base64Data := base64.StdEncoding.EncodeToString(fileData)
+	return fileData, mimeType, nil
+}
 
-	// 构建 data URI
-	dataURI := fmt.Sprintf("data:%s;base64,%s", mimeType, base64Data)
+// applyCharacterPortraits 将分镜中已有形象图的角色作为参考图注入请求，并在提示词中补充角色信息
+func (s *ImageGenerationService) applyCharacterPortraits(req *GenerateImageRequest, characters []models.Character) {
+	var characterDescs []string
+	for _, char := range characters {
+		var portrait string
+		if char.LocalPath != nil && *char.LocalPath != "" {
+			portrait = *char.LocalPath
+		} else if char.ImageURL != nil && *char.ImageURL != "" {
+			portrait = *char.ImageURL
+		} else {
+			continue
+		}
+		req.ReferenceImages = append(req.ReferenceImages, portrait)
 
-	return dataURI, nil
+		desc := char.Name
+		if char.Appearance != nil && *char.Appearance != "" {
+			desc = fmt.Sprintf("%s（%s）", char.Name, *char.Appearance)
+		}
+		characterDescs = append(characterDescs, desc)
+	}
+
+	if len(characterDescs) == 0 {
+		return
+	}
+
+	req.ImageType = string(models.ImageTypeStoryboard)
+	req.Prompt = fmt.Sprintf("%s，画面中的角色：%s，角色外貌与参考图保持一致", req.Prompt, strings.Join(characterDescs, "、"))
 }
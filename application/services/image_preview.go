@@ -0,0 +1,27 @@
+package services
+
+import (
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/image"
+)
+
+// applyPreviewSettings 预览模式下改用服务商最低成本的尺寸和质量，并去掉显式宽高和步数
+// 实际使用的参数写回记录，便于确认预览图的生成条件
+func (s *ImageGenerationService) applyPreviewSettings(imageGen *models.ImageGeneration, caps image.Capabilities) {
+	imageGen.Size = caps.PreviewSize
+	imageGen.Quality = caps.PreviewQuality
+	imageGen.Width = nil
+	imageGen.Height = nil
+	imageGen.Steps = nil
+
+	if err := s.db.Model(&models.ImageGeneration{}).Where("id = ?", imageGen.ID).Updates(map[string]interface{}{
+		"size":    imageGen.Size,
+		"quality": imageGen.Quality,
+		"width":   nil,
+		"height":  nil,
+		"steps":   nil,
+	}).Error; err != nil {
+		s.log.Warnw("Failed to save preview settings", "error", err, "id", imageGen.ID)
+	}
+	s.log.Infow("Using preview settings", "id", imageGen.ID, "size", imageGen.Size, "quality", imageGen.Quality)
+}
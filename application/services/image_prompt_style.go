@@ -0,0 +1,45 @@
+package services
+
+import (
+	"github.com/drama-generator/backend/pkg/config"
+)
+
+// defaultFrameSuffixes 各帧类型图片提示词的内置后缀，可通过 style.frame_suffixes 覆盖
+var defaultFrameSuffixes = map[FrameType]string{
+	FrameTypeFirst:  "first frame, static shot",
+	FrameTypeKey:    "key frame, dynamic action",
+	FrameTypeLast:   "last frame, final state",
+	FrameTypeAction: "3x3 storyboard grid action sequence, character consistency, continuous movement progression",
+}
+
+// frameSuffix 获取帧类型的提示词后缀，配置优先
+func frameSuffix(frameType FrameType) string {
+	if cfg := config.Current(); cfg != nil {
+		if suffix, ok := cfg.Style.FrameSuffixes[string(frameType)]; ok {
+			return suffix
+		}
+	}
+	return defaultFrameSuffixes[frameType]
+}
+
+// resolveImageStyle 返回图片提示词使用的风格：剧本风格优先，其次是 style.default_style
+func resolveImageStyle(dramaStyle string) string {
+	if dramaStyle != "" {
+		return dramaStyle
+	}
+	if cfg := config.Current(); cfg != nil {
+		return cfg.Style.DefaultStyle
+	}
+	return ""
+}
+
+// appendStyleAndFrame 在提示词片段末尾追加风格和帧类型后缀，两者为空时跳过
+func appendStyleAndFrame(parts []string, dramaStyle string, frameType FrameType) []string {
+	if style := resolveImageStyle(dramaStyle); style != "" {
+		parts = append(parts, style+" style")
+	}
+	if suffix := frameSuffix(frameType); suffix != "" {
+		parts = append(parts, suffix)
+	}
+	return parts
+}
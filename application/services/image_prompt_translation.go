@@ -0,0 +1,89 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/ai"
+	"github.com/drama-generator/backend/pkg/image"
+)
+
+// promptLanguageNames 翻译目标语言代码对应的语言名称
+var promptLanguageNames = map[string]string{
+	"en": "English",
+	"zh": "Simplified Chinese",
+}
+
+// containsHan 文本是否包含汉字
+func containsHan(text string) bool {
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// promptTranslationTarget 提示词需要翻译到的语言，不需要翻译时返回空
+// 显式指定的目标语言优先；服务商只支持英文且提示词含中文时自动翻译为英文
+func promptTranslationTarget(imageGen *models.ImageGeneration, englishOnly bool) string {
+	if imageGen.TranslatePromptTo != "" {
+		return imageGen.TranslatePromptTo
+	}
+	if englishOnly && containsHan(imageGen.Prompt) {
+		return "en"
+	}
+	return ""
+}
+
+// translatedImagePrompt 返回实际发送给服务商的用户提示词，需要翻译时调用文本模型翻译并保存译文
+// 已有译文（如重试）时直接复用；翻译失败时使用原始提示词继续生成
+func (s *ImageGenerationService) translatedImagePrompt(imageGen *models.ImageGeneration, caps image.Capabilities) string {
+	target := promptTranslationTarget(imageGen, caps.EnglishOnly || s.ProviderCapabilities(imageGen.Provider).EnglishOnly)
+	if target == "" {
+		return imageGen.Prompt
+	}
+	if imageGen.TranslatedPrompt != nil && *imageGen.TranslatedPrompt != "" && imageGen.TranslatePromptTo == target {
+		return *imageGen.TranslatedPrompt
+	}
+
+	translated, err := s.translatePrompt(imageGen.DramaID, imageGen.Prompt, target)
+	if err != nil {
+		s.log.Warnw("Failed to translate image prompt, using original", "error", err, "id", imageGen.ID, "target", target)
+		return imageGen.Prompt
+	}
+
+	imageGen.TranslatePromptTo = target
+	imageGen.TranslatedPrompt = &translated
+	if err := s.db.Model(&models.ImageGeneration{}).Where("id = ?", imageGen.ID).Updates(map[string]interface{}{
+		"translate_prompt_to": target,
+		"translated_prompt":   translated,
+	}).Error; err != nil {
+		s.log.Warnw("Failed to save translated prompt", "error", err, "id", imageGen.ID)
+	}
+	s.log.Infow("Image prompt translated", "id", imageGen.ID, "target", target, "translated_prompt", translated)
+	return translated
+}
+
+// translatePrompt 使用剧本的文本模型翻译图片提示词
+func (s *ImageGenerationService) translatePrompt(dramaID uint, prompt, target string) (string, error) {
+	client, _, err := s.aiService.GetAIClientForDrama("text", dramaID, "")
+	if err != nil {
+		return "", err
+	}
+
+	systemPrompt := "You translate prompts for image generation models. Translate the user's prompt into " +
+		promptLanguageNames[target] +
+		". Keep every visual detail, names and formatting. Output only the translated prompt without explanations or quotes."
+	text, err := client.GenerateText(prompt, systemPrompt, ai.WithTemperature(0.2))
+	if err != nil {
+		return "", err
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", errors.New("翻译结果为空")
+	}
+	return text, nil
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/config"
+)
+
+const defaultImageWorkers = 4
+
+// defaultDramaImageConcurrency 未配置时单个剧本最多同时占用的任务数，工作协程不足时降为工作协程数 - 1
+const defaultDramaImageConcurrency = 10
+
+// 批量并发限制下等待异步任务（服务商返回 task_id 后轮询）结束的检查间隔和上限
+const (
+	batchSlotPollInterval = 3 * time.Second
+	batchSlotMaxWait      = 10 * time.Minute
+)
+
+// imageGenJob 队列中等待执行的图片生成任务
+type imageGenJob struct {
+	imageGenID uint
+	dramaID    uint
+	priority   int
+	seq        uint64 // 入队序号，同优先级按先进先出
+	run        func()
+	// settle 不为 nil 时在 run 返回后于后台调用，返回后才释放剧本名额（用于等待异步服务商轮询结束）
+	settle func()
+}
+
+// imageGenJobHeap 按优先级从高到低、同优先级按入队顺序排列
+type imageGenJobHeap []imageGenJob
+
+func (h imageGenJobHeap) Len() int { return len(h) }
+func (h imageGenJobHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h imageGenJobHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *imageGenJobHeap) Push(x interface{}) { *h = append(*h, x.(imageGenJob)) }
+func (h *imageGenJobHeap) Pop() interface{} {
+	old := *h
+	job := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return job
+}
+
+// imageGenQueue 全局图片生成优先级队列，限制同时调用服务商的任务数
+// 单个剧本执行中的任务达到剧本并发上限后，它的其余任务留在队列中，工作协程先执行其他剧本的任务
+type imageGenQueue struct {
+	mu         sync.Mutex
+	cond       *sync.Cond
+	jobs       imageGenJobHeap
+	nextSeq    uint64
+	workers    int
+	dramaLimit int          // 创建时的剧本并发上限，配置热更新后以 ai.drama_image_concurrency 为准
+	inFlight   map[uint]int // 各剧本正在执行（含等待异步结果）的任务数
+}
+
+var (
+	sharedImageQueue     atomic.Pointer[imageGenQueue] // 原子指针，指标采集等不经过 getImageQueue 的读取方也不会产生数据竞争
+	sharedImageQueueOnce sync.Once
+)
+
+// getImageQueue 获取全局队列，首次调用时按 workers 启动工作协程（多个服务实例共享同一个池）
+// dramaLimit 为单个剧本同时执行的任务上限，0 使用默认值，负数不限制
+func getImageQueue(workers int, dramaLimit int) *imageGenQueue {
+	sharedImageQueueOnce.Do(func() {
+		if workers <= 0 {
+			workers = defaultImageWorkers
+		}
+		q := &imageGenQueue{workers: workers, dramaLimit: dramaLimit, inFlight: make(map[uint]int)}
+		q.cond = sync.NewCond(&q.mu)
+		for i := 0; i < workers; i++ {
+			go q.worker()
+		}
+		sharedImageQueue.Store(q)
+	})
+	return sharedImageQueue.Load()
+}
+
+func (q *imageGenQueue) push(job imageGenJob) {
+	q.mu.Lock()
+	job.seq = q.nextSeq
+	q.nextSeq++
+	heap.Push(&q.jobs, job)
+	q.mu.Unlock()
+	q.cond.Signal()
+}
+
+// currentDramaLimit 单个剧本同时执行的任务上限，未配置时为 defaultDramaImageConcurrency 与工作协程数 - 1（至少为 1）中的较小值，
+// 保证总有工作协程留给其他剧本；负数表示不限制
+func (q *imageGenQueue) currentDramaLimit() int {
+	limit := q.dramaLimit
+	if cfg := config.Current(); cfg != nil {
+		limit = cfg.AI.DramaImageConcurrency
+	}
+	if limit == 0 {
+		limit = min(defaultDramaImageConcurrency, max(1, q.workers-1))
+	}
+	return limit
+}
+
+// pop 取出优先级最高且所属剧本未达到并发上限的任务，没有可执行任务时等待
+func (q *imageGenQueue) pop() imageGenJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for {
+		if job, ok := q.takeRunnable(); ok {
+			q.inFlight[job.dramaID]++
+			return job
+		}
+		q.cond.Wait()
+	}
+}
+
+// takeRunnable 按优先级查找第一个可执行的任务，跳过的任务放回队列并保持原有顺序；调用方需持有锁
+func (q *imageGenQueue) takeRunnable() (imageGenJob, bool) {
+	var skipped []imageGenJob
+	defer func() {
+		for _, job := range skipped {
+			heap.Push(&q.jobs, job)
+		}
+	}()
+	limit := q.currentDramaLimit()
+	for len(q.jobs) > 0 {
+		job := heap.Pop(&q.jobs).(imageGenJob)
+		if limit < 0 || job.dramaID == 0 || q.inFlight[job.dramaID] < limit {
+			return job, true
+		}
+		skipped = append(skipped, job)
+	}
+	return imageGenJob{}, false
+}
+
+// done 任务执行结束，释放所属剧本的名额并唤醒等待的工作协程
+func (q *imageGenQueue) done(job imageGenJob) {
+	q.mu.Lock()
+	if q.inFlight[job.dramaID]--; q.inFlight[job.dramaID] <= 0 {
+		delete(q.inFlight, job.dramaID)
+	}
+	q.mu.Unlock()
+	q.cond.Broadcast()
+}
+
+// depth 当前排队等待的任务数
+func (q *imageGenQueue) depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.jobs)
+}
+
+// positions 按优先级和入队顺序返回队列中各生成记录的排队位置（从 1 开始）
+func (q *imageGenQueue) positions() map[uint]int {
+	q.mu.Lock()
+	jobs := make(imageGenJobHeap, len(q.jobs))
+	copy(jobs, q.jobs)
+	q.mu.Unlock()
+
+	sort.Slice(jobs, jobs.Less)
+	positions := make(map[uint]int, len(jobs))
+	for i, job := range jobs {
+		positions[job.imageGenID] = i + 1
+	}
+	return positions
+}
+
+func (q *imageGenQueue) worker() {
+	for {
+		job := q.pop()
+		job.run()
+		if job.settle == nil {
+			q.done(job)
+			continue
+		}
+		// 异步服务商的结果在轮询协程中返回，工作协程继续处理其他任务，剧本名额保留到生成结束
+		go func(job imageGenJob) {
+			job.settle()
+			q.done(job)
+		}(job)
+	}
+}
+
+// enqueueImageGeneration 将图片生成任务放入全局队列，优先级高的先执行，同优先级按提交顺序执行
+func (s *ImageGenerationService) enqueueImageGeneration(imageGen *models.ImageGeneration) {
+	imageGenID := imageGen.ID
+	s.queue.push(imageGenJob{
+		imageGenID: imageGenID,
+		dramaID:    imageGen.DramaID,
+		priority:   imageGen.Priority,
+		run:        func() { s.ProcessImageGeneration(imageGenID) },
+		settle:     func() { s.waitImageGenerationSettled(imageGenID) },
+	})
+}
+
+// enqueueWithLimit 按顺序将一批生成记录放入全局队列，同一批同时排队或执行的任务不超过 limit，
+// limit<=0 时全部直接入队，只受全局工作协程数限制
+func (s *ImageGenerationService) enqueueWithLimit(imageGens []*models.ImageGeneration, limit int) {
+	if limit <= 0 || limit >= len(imageGens) {
+		for _, imageGen := range imageGens {
+			s.enqueueImageGeneration(imageGen)
+		}
+		return
+	}
+
+	slots := make(chan struct{}, limit)
+	go func() {
+		for _, imageGen := range imageGens {
+			slots <- struct{}{}
+			imageGenID := imageGen.ID
+			s.queue.push(imageGenJob{
+				imageGenID: imageGenID,
+				dramaID:    imageGen.DramaID,
+				priority:   imageGen.Priority,
+				run:        func() { s.ProcessImageGeneration(imageGenID) },
+				// 异步服务商在轮询协程中完成，等记录结束后再释放名额，不占用工作协程
+				settle: func() {
+					s.waitImageGenerationSettled(imageGenID)
+					<-slots
+				},
+			})
+		}
+	}()
+}
+
+// waitImageGenerationSettled 等待生成记录离开 pending/processing 状态，超过 batchSlotMaxWait 后放弃等待
+func (s *ImageGenerationService) waitImageGenerationSettled(imageGenID uint) {
+	deadline := time.Now().Add(batchSlotMaxWait)
+	for {
+		var imageGen models.ImageGeneration
+		if err := s.db.Select("id", "status").First(&imageGen, imageGenID).Error; err != nil ||
+			(imageGen.Status != models.ImageStatusPending && imageGen.Status != models.ImageStatusProcessing) {
+			return
+		}
+		if time.Now().After(deadline) {
+			s.log.Warnw("Image generation still running, releasing batch slot", "image_gen_id", imageGenID)
+			return
+		}
+		time.Sleep(batchSlotPollInterval)
+	}
+}
+
+// QueueDepth 返回当前排队等待的图片生成数
+func (s *ImageGenerationService) QueueDepth() int {
+	return s.queue.depth()
+}
+
+// fillQueuePositions 按内存队列的出队顺序为 pending 状态的记录填充排队位置（从 1 开始）
+// 不在队列中的记录（服务重启前遗留的 pending 记录、批量生成中等待名额尚未入队的记录）不返回排队位置
+func (s *ImageGenerationService) fillQueuePositions(imageGens []*models.ImageGeneration) {
+	var positions map[uint]int
+	for _, imageGen := range imageGens {
+		if imageGen.Status != models.ImageStatusPending {
+			continue
+		}
+		if positions == nil {
+			positions = s.queue.positions()
+		}
+		if position, ok := positions[imageGen.ID]; ok {
+			imageGen.QueuePosition = &position
+		}
+	}
+}
+
+// GetImageGenerationStatuses 批量查询图片生成状态，pending 记录附带排队位置
+func (s *ImageGenerationService) GetImageGenerationStatuses(ids []uint) ([]*models.ImageGeneration, error) {
+	var imageGens []*models.ImageGeneration
+	if err := s.db.Select("id", "status", "priority", "image_url", "local_path", "error_msg", "error_code", "created_at", "completed_at").
+		Where("id IN ?", ids).
+		Order("id ASC").
+		Find(&imageGens).Error; err != nil {
+		return nil, err
+	}
+	s.fillQueuePositions(imageGens)
+	return imageGens, nil
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	models "github.com/drama-generator/backend/domain/models"
+)
+
+// 参考图保留优先级，数值越大越优先保留
+const (
+	referencePrioritySource    = 3 // 记录自身的 local_path（基于原图修改时的底图）
+	referencePriorityKey       = 2 // 有台词或主要角色的形象图
+	referencePriorityStyle     = 1 // 非角色形象的参考图，如风格参考
+	referencePriorityCharacter = 0 // 其他角色的形象图
+)
+
+// referenceCandidate 待发送的参考图及其来源
+type referenceCandidate struct {
+	path      string
+	character string // 对应的角色名，非角色形象图为空
+	priority  int
+}
+
+// selectReferenceImages 参考图超过上限时按优先级保留，同优先级保留靠前的；返回值保持原有顺序
+func selectReferenceImages(candidates []referenceCandidate, limit int) (kept, dropped []referenceCandidate) {
+	if limit <= 0 || len(candidates) <= limit {
+		return candidates, nil
+	}
+
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return candidates[order[a]].priority > candidates[order[b]].priority
+	})
+	keep := make(map[int]bool, limit)
+	for _, i := range order[:limit] {
+		keep[i] = true
+	}
+
+	for i, c := range candidates {
+		if keep[i] {
+			kept = append(kept, c)
+		} else {
+			dropped = append(dropped, c)
+		}
+	}
+	return kept, dropped
+}
+
+// capReferenceImages 按 max_reference_images 裁剪参考图，避免镜头角色较多时超出服务商的参考图数量限制
+// hasSource 表示 paths[0] 是记录自身的底图
+func (s *ImageGenerationService) capReferenceImages(imageGen *models.ImageGeneration, paths []string, hasSource bool) []string {
+	limit := s.cfg().AI.MaxReferenceImages
+	if limit <= 0 || len(paths) <= limit {
+		return paths
+	}
+
+	candidates := s.referenceCandidates(imageGen, paths, hasSource)
+	kept, dropped := selectReferenceImages(candidates, limit)
+
+	droppedDesc := make([]string, 0, len(dropped))
+	for _, c := range dropped {
+		if c.character != "" {
+			droppedDesc = append(droppedDesc, c.character)
+		} else {
+			droppedDesc = append(droppedDesc, truncateImageURL(c.path))
+		}
+	}
+	s.log.Warnw("Too many reference images, dropped lower priority references",
+		"id", imageGen.ID,
+		"limit", limit,
+		"requested", len(paths),
+		"dropped", droppedDesc)
+
+	result := make([]string, 0, len(kept))
+	for _, c := range kept {
+		result = append(result, c.path)
+	}
+	return result
+}
+
+// referenceCandidates 根据分镜的角色、台词为参考图标注优先级
+func (s *ImageGenerationService) referenceCandidates(imageGen *models.ImageGeneration, paths []string, hasSource bool) []referenceCandidate {
+	portraits := make(map[string]models.Character)
+	speakers := make(map[string]bool)
+	dialogue := ""
+	if imageGen.StoryboardID != nil {
+		var storyboard models.Storyboard
+		if err := s.db.Preload("Characters").Where("id = ?", *imageGen.StoryboardID).First(&storyboard).Error; err == nil {
+			for _, char := range storyboard.Characters {
+				if char.LocalPath != nil && *char.LocalPath != "" {
+					portraits[*char.LocalPath] = char
+				}
+				if char.ImageURL != nil && *char.ImageURL != "" {
+					portraits[*char.ImageURL] = char
+				}
+			}
+			for _, line := range storyboard.DialogueLines {
+				if line.Speaker != "" {
+					speakers[line.Speaker] = true
+				}
+			}
+			dialogue = getString(storyboard.Dialogue)
+		}
+	}
+
+	candidates := make([]referenceCandidate, 0, len(paths))
+	for i, path := range paths {
+		c := referenceCandidate{path: path, priority: referencePriorityStyle}
+		if i == 0 && hasSource {
+			c.priority = referencePrioritySource
+		} else if char, ok := portraits[path]; ok {
+			c.character = char.Name
+			c.priority = referencePriorityCharacter
+			if speakers[char.Name] || (dialogue != "" && strings.Contains(dialogue, char.Name)) || getString(char.Role) == "main" {
+				c.priority = referencePriorityKey
+			}
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates
+}
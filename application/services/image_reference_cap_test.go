@@ -0,0 +1,48 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectReferenceImages(t *testing.T) {
+	candidates := []referenceCandidate{
+		{path: "source.png", priority: referencePrioritySource},
+		{path: "extra.png", character: "路人", priority: referencePriorityCharacter},
+		{path: "lead.png", character: "陈峥", priority: referencePriorityKey},
+		{path: "style.png", priority: referencePriorityStyle},
+		{path: "speaker.png", character: "李芳", priority: referencePriorityKey},
+	}
+
+	paths := func(cs []referenceCandidate) []string {
+		var result []string
+		for _, c := range cs {
+			result = append(result, c.path)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name        string
+		limit       int
+		wantKept    []string
+		wantDropped []string
+	}{
+		{"no limit", 0, paths(candidates), nil},
+		{"under limit", 5, paths(candidates), nil},
+		{"drop minor character", 4, []string{"source.png", "lead.png", "style.png", "speaker.png"}, []string{"extra.png"}},
+		{"drop style after characters", 3, []string{"source.png", "lead.png", "speaker.png"}, []string{"extra.png", "style.png"}},
+		{"same priority keeps earlier", 2, []string{"source.png", "lead.png"}, []string{"extra.png", "style.png", "speaker.png"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, dropped := selectReferenceImages(candidates, tt.limit)
+			if got := paths(kept); !reflect.DeepEqual(got, tt.wantKept) {
+				t.Errorf("kept = %v, want %v", got, tt.wantKept)
+			}
+			if got := paths(dropped); !reflect.DeepEqual(got, tt.wantDropped) {
+				t.Errorf("dropped = %v, want %v", got, tt.wantDropped)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// referenceCheckClient 参考图预检使用的 HTTP 客户端，超时较短以免拖慢生成
+var referenceCheckClient = &http.Client{Timeout: 5 * time.Second}
+
+// filterReachableReferences 并发检查参考图 URL 是否可访问，剔除失效的地址并保持原有顺序
+func (s *ImageGenerationService) filterReachableReferences(imageGenID uint, urls []string) []string {
+	if len(urls) == 0 {
+		return urls
+	}
+
+	reachable := make([]bool, len(urls))
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			reachable[i] = isReferenceReachable(url)
+		}(i, url)
+	}
+	wg.Wait()
+
+	var result []string
+	for i, url := range urls {
+		if !reachable[i] {
+			s.log.Warnw("Dropping unreachable reference image",
+				"id", imageGenID,
+				"url", truncateImageURL(url))
+			continue
+		}
+		result = append(result, url)
+	}
+	return result
+}
+
+// isReferenceReachable 先发 HEAD 请求，服务器不支持 HEAD 时退回只取首字节的 GET
+func isReferenceReachable(url string) bool {
+	resp, err := referenceCheckClient.Head(url)
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode < 400 {
+			return true
+		}
+		if resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusForbidden {
+			return false
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Range", "bytes=0-0")
+	resp, err = referenceCheckClient.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode < 400
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/drama-generator/backend/pkg/image"
+)
+
+// referenceLimits 按 ai.reference_max_kb / ai.reference_fetch_timeout 生成内联参考图的限制，缩小参考图时记录日志
+func (s *ImageGenerationService) referenceLimits(imageGenID uint) image.ReferenceLimits {
+	cfg := s.cfg().AI
+	limits := image.ReferenceLimits{
+		Timeout: time.Duration(cfg.ReferenceFetchTimeout) * time.Second,
+		OnDownscale: func(ref string, originalBytes, newBytes int) {
+			s.log.Infow("Reference image downscaled before inlining",
+				"id", imageGenID,
+				"reference", truncateImageURL(ref),
+				"original_bytes", originalBytes,
+				"bytes", newBytes)
+		},
+	}
+	switch {
+	case cfg.ReferenceMaxKB > 0:
+		limits.MaxBytes = int64(cfg.ReferenceMaxKB) * 1024
+	case cfg.ReferenceMaxKB < 0:
+		limits.MaxBytes = -1
+	}
+	return limits
+}
+
+// loadReferenceAsBase64 读取本地参考图并转换为 data URI，超过大小限制时先缩小，无法缩小到限制内时返回错误
+func (s *ImageGenerationService) loadReferenceAsBase64(imageGenID uint, localPath string) (string, error) {
+	data, mimeType, err := s.readLocalImage(localPath)
+	if err != nil {
+		return "", err
+	}
+	data, mimeType, err = image.FitReference(localPath, data, mimeType, s.referenceLimits(imageGenID))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(data)), nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/drama-generator/backend/domain/models"
+)
+
+// imageCacheKey 参与结果复用判断的生成输入，参考图按排序后的集合比较
+type imageCacheKey struct {
+	Provider        string   `json:"provider"`
+	Model           string   `json:"model"`
+	ImageType       string   `json:"image_type"`
+	FrameType       string   `json:"frame_type"`
+	Prompt          string   `json:"prompt"`
+	NegativePrompt  string   `json:"negative_prompt"`
+	Size            string   `json:"size"`
+	Quality         string   `json:"quality"`
+	Style           string   `json:"style"`
+	Steps           *int     `json:"steps"`
+	CfgScale        *float64 `json:"cfg_scale"`
+	Seed            *int64   `json:"seed"`
+	ImageLocalPath  string   `json:"image_local_path"`
+	ReferenceImages []string `json:"reference_images"`
+	DramaStyle      string   `json:"drama_style"`
+	DramaLanguage   string   `json:"drama_language"`
+	TranslateTo     string   `json:"translate_to,omitempty"`
+}
+
+// imageInputHash 计算生成输入的哈希；剧本风格和语言会影响最终提示词，一并计入
+func imageInputHash(req *GenerateImageRequest, provider string, drama *models.Drama) string {
+	refs := append([]string(nil), req.ReferenceImages...)
+	sort.Strings(refs)
+
+	key := imageCacheKey{
+		Provider:        provider,
+		Model:           req.Model,
+		ImageType:       req.ImageType,
+		FrameType:       getString(req.FrameType),
+		Prompt:          req.Prompt,
+		NegativePrompt:  getString(req.NegativePrompt),
+		Size:            req.Size,
+		Quality:         req.Quality,
+		Style:           getString(req.Style),
+		Steps:           req.Steps,
+		CfgScale:        req.CfgScale,
+		Seed:            req.Seed,
+		ImageLocalPath:  getString(req.ImageLocalPath),
+		ReferenceImages: refs,
+		DramaStyle:      drama.Style,
+		DramaLanguage:   drama.Language,
+		TranslateTo:     req.TranslatePromptTo,
+	}
+
+	data, _ := json.Marshal(key)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// findCachedImageGeneration 查找同一剧本中输入相同的最近一条已完成生成
+func (s *ImageGenerationService) findCachedImageGeneration(dramaID uint, inputHash string, excludeID uint) *models.ImageGeneration {
+	var cached models.ImageGeneration
+	err := s.db.Where("drama_id = ? AND input_hash = ? AND status = ? AND image_url IS NOT NULL AND image_url <> '' AND id <> ? AND preview = ?",
+		dramaID, inputHash, models.ImageStatusCompleted, excludeID, false).
+		Order("id DESC").
+		First(&cached).Error
+	if err != nil {
+		return nil
+	}
+	return &cached
+}
+
+// reuseCachedImageGeneration 将已完成生成的结果复制到新记录，并同步到关联实体
+func (s *ImageGenerationService) reuseCachedImageGeneration(imageGenID uint, cached *models.ImageGeneration) {
+	updates := map[string]interface{}{
+		"status":         models.ImageStatusCompleted,
+		"image_url":      *cached.ImageURL,
+		"local_path":     cached.LocalPath,
+		"original_path":  cached.OriginalPath,
+		"cache_failed":   cached.CacheFailed,
+		"cached_from_id": cached.ID,
+		"completed_at":   time.Now(),
+	}
+	if cached.Width != nil {
+		updates["width"] = *cached.Width
+	}
+	if cached.Height != nil {
+		updates["height"] = *cached.Height
+	}
+
+	s.log.Infow("Reusing cached image generation", "id", imageGenID, "cached_from_id", cached.ID)
+	s.applyCompletedImage(imageGenID, updates, *cached.ImageURL, cached.LocalPath)
+}
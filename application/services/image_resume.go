@@ -0,0 +1,57 @@
+package services
+
+import (
+	"sync"
+
+	models "github.com/drama-generator/backend/domain/models"
+)
+
+// activeImagePolls 正在轮询服务商任务的图片生成记录，避免同一任务被重复轮询
+var activeImagePolls sync.Map
+
+// ResumeImagePolling 按记录中保存的服务商 task_id 重新开始轮询，用于轮询协程中断（服务重启、超时）后找回已付费的异步任务
+// 只恢复处理中或失败且有 task_id 的记录，不会重新提交生成
+func (s *ImageGenerationService) ResumeImagePolling(imageGenID uint) (*models.ImageGeneration, error) {
+	var imageGen models.ImageGeneration
+	if err := s.db.Where("id = ?", imageGenID).First(&imageGen).Error; err != nil {
+		return nil, ErrImageGenerationNotFound
+	}
+	if imageGen.TaskID == nil || *imageGen.TaskID == "" {
+		return nil, &ServiceError{Kind: ErrInvalidInput, Message: "该图片生成没有服务商任务ID，无法恢复"}
+	}
+	if imageGen.Status != models.ImageStatusProcessing && imageGen.Status != models.ImageStatusFailed {
+		return nil, &ServiceError{Kind: ErrInvalidInput, Message: "只能恢复处理中或失败的图片生成"}
+	}
+	if _, polling := activeImagePolls.Load(imageGenID); polling {
+		return nil, &ServiceError{Kind: ErrConflict, Message: "该图片生成正在轮询中"}
+	}
+
+	client, _, err := s.getImageClientWithModel(imageGen.Provider, imageGen.Model, imageGen.DramaID, imageStickyKey(imageGen.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(&models.ImageGeneration{}).Where("id = ?", imageGenID).Updates(map[string]interface{}{
+		"status":     models.ImageStatusProcessing,
+		"error_msg":  nil,
+		"error_code": nil,
+	}).Error; err != nil {
+		return nil, err
+	}
+	imageGen.Status = models.ImageStatusProcessing
+	imageGen.ErrorMsg = nil
+	imageGen.ErrorCode = nil
+
+	s.log.Infow("Resuming image generation polling", "id", imageGenID, "task_id", *imageGen.TaskID, "provider", imageGen.Provider)
+	// 经全局队列恢复，轮询占用剧本并发名额直到记录结束，避免大批量恢复绕过剧本并发上限
+	taskID, provider := *imageGen.TaskID, imageGen.Provider
+	s.queue.push(imageGenJob{
+		imageGenID: imageGenID,
+		dramaID:    imageGen.DramaID,
+		priority:   imageGen.Priority,
+		run:        func() { go s.pollTaskStatus(imageGenID, client, taskID, provider) },
+		settle:     func() { s.waitImageGenerationSettled(imageGenID) },
+	})
+
+	return &imageGen, nil
+}
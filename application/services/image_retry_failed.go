@@ -0,0 +1,83 @@
+package services
+
+import (
+	"fmt"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+// RetryFailedImages 将剧本下所有未达到重试上限的失败图片生成重新放入队列（异步），返回任务ID和待重试数量
+func (s *ImageGenerationService) RetryFailedImages(dramaID uint) (string, int, error) {
+	var drama models.Drama
+	if err := s.db.Select("id").Where("id = ?", dramaID).First(&drama).Error; err != nil {
+		return "", 0, ErrDramaNotFound
+	}
+
+	var imageGenIDs []uint
+	if err := s.db.Model(&models.ImageGeneration{}).
+		Where("drama_id = ? AND status = ? AND retry_count < ?", dramaID, models.ImageStatusFailed, s.imageMaxRetries()).
+		Order("id ASC").
+		Pluck("id", &imageGenIDs).Error; err != nil {
+		return "", 0, fmt.Errorf("获取失败的图片生成记录失败: %w", err)
+	}
+	if len(imageGenIDs) == 0 {
+		return "", 0, &ServiceError{Kind: ErrInvalidInput, Message: "没有可以重试的失败图片"}
+	}
+
+	task, err := s.taskService.CreateTask("image_retry_failed", fmt.Sprintf("%d", dramaID))
+	if err != nil {
+		s.log.Errorw("Failed to create task", "error", err)
+		return "", 0, fmt.Errorf("创建任务失败: %w", err)
+	}
+
+	s.log.Infow("Retrying failed images asynchronously",
+		"task_id", task.ID,
+		"drama_id", dramaID,
+		"count", len(imageGenIDs))
+
+	go s.processRetryFailedImages(task.ID, imageGenIDs)
+
+	return task.ID, len(imageGenIDs), nil
+}
+
+// processRetryFailedImages 逐条重试，实际调用由全局队列按并发上限执行；已被其他请求重试的记录跳过
+func (s *ImageGenerationService) processRetryFailedImages(taskID string, imageGenIDs []uint) {
+	if err := s.taskService.UpdateTaskStatus(taskID, "processing", 10, "正在重新提交失败的图片..."); err != nil {
+		s.log.Errorw("Failed to update task status", "error", err, "task_id", taskID)
+		return
+	}
+
+	var requeued []uint
+	skipped := make(map[uint]string)
+	for i, imageGenID := range imageGenIDs {
+		if _, err := s.RetryImageGeneration(imageGenID); err != nil {
+			skipped[imageGenID] = err.Error()
+		} else {
+			requeued = append(requeued, imageGenID)
+		}
+
+		if (i+1)%10 == 0 || i == len(imageGenIDs)-1 {
+			progress := 10 + (i+1)*80/len(imageGenIDs)
+			if err := s.taskService.UpdateTaskStatus(taskID, "processing", progress,
+				fmt.Sprintf("已提交 %d/%d 张图片", i+1, len(imageGenIDs))); err != nil {
+				s.log.Warnw("Failed to update task progress", "error", err, "task_id", taskID)
+			}
+		}
+	}
+
+	s.log.Infow("Failed images requeued",
+		"task_id", taskID,
+		"requeued", len(requeued),
+		"skipped", len(skipped))
+
+	if err := s.taskService.UpdateTaskResult(taskID, gin.H{
+		"requeued":             len(requeued),
+		"skipped":              len(skipped),
+		"total":                len(imageGenIDs),
+		"image_generation_ids": requeued,
+		"skipped_reasons":      skipped,
+	}); err != nil {
+		s.log.Errorw("Failed to update task result", "error", err, "task_id", taskID)
+	}
+}
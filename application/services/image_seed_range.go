@@ -0,0 +1,95 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/google/uuid"
+)
+
+// maxSeedRangeImages 一个种子范围最多生成的图片数
+const maxSeedRangeImages = 16
+
+// generateSeedRange 用同一提示词为范围内的每个种子各创建一条生成记录，所有记录共用一个 batch_id
+// 返回第一条记录，可通过其 batch_id 查询整个批次
+func (s *ImageGenerationService) generateSeedRange(request *GenerateImageRequest) (*models.ImageGeneration, error) {
+	start, end := request.SeedRange[0], request.SeedRange[1]
+	if request.Seed != nil {
+		return nil, &ServiceError{Kind: ErrInvalidInput, Message: "seed 和 seed_range 不能同时使用"}
+	}
+	if start < 0 || end < start {
+		return nil, &ServiceError{Kind: ErrInvalidInput, Message: "seed_range 需为 [起始, 结束]，且 0 <= 起始 <= 结束"}
+	}
+	if end-start >= maxSeedRangeImages {
+		return nil, &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf("seed_range 最多包含 %d 个种子", maxSeedRangeImages)}
+	}
+	// 服务商不支持种子时各次生成的参数完全相同，既无法复现也是重复计费
+	if provider := s.imageProviderFor(request); !s.ProviderCapabilities(provider).Seed {
+		return nil, &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf("图片服务商 %s 不支持 seed，无法使用 seed_range", provider)}
+	}
+
+	batchID := uuid.New().String()
+	var first *models.ImageGeneration
+	for seed := start; seed <= end; seed++ {
+		req := *request
+		req.Seed = &seed
+		req.SeedRange = nil
+		req.batchID = batchID
+
+		imageGen, queued, err := s.createImageGeneration(&req)
+		if err != nil {
+			if first == nil {
+				return nil, err
+			}
+			s.log.Errorw("Failed to create seed range image generation", "error", err, "batch_id", batchID, "seed", seed)
+			break
+		}
+		if queued {
+			s.enqueueImageGeneration(imageGen)
+		}
+		if first == nil {
+			first = imageGen
+		}
+	}
+
+	s.log.Infow("Seed range image generations created", "batch_id", batchID, "from", start, "to", end)
+	return first, nil
+}
+
+// imageProviderFor 按生成时的顺序确定请求实际使用的图片服务商：剧本绑定的配置、指定模型所在的配置、默认配置
+func (s *ImageGenerationService) imageProviderFor(request *GenerateImageRequest) string {
+	dramaID, _ := strconv.ParseUint(request.DramaID, 10, 32)
+	config, err := s.aiService.dramaPinnedConfig("image", uint(dramaID))
+	if err == nil && config == nil && request.Model != "" {
+		config, err = s.aiService.GetConfigForModel("image", request.Model)
+	}
+	if config == nil {
+		config, err = s.aiService.GetDefaultConfig("image")
+	}
+	if err == nil && config != nil && config.Provider != "" {
+		return config.Provider
+	}
+	if request.Provider != "" {
+		return request.Provider
+	}
+	return "openai"
+}
+
+// ListImageBatch 查询同一批次的图片生成记录（种子范围或服务商一次返回的多张图片），按种子排序
+func (s *ImageGenerationService) ListImageBatch(batchID string) ([]models.ImageGeneration, error) {
+	var images []models.ImageGeneration
+	if err := s.db.Where("batch_id = ?", batchID).Order("seed ASC, id ASC").Find(&images).Error; err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		return nil, &ServiceError{Kind: ErrNotFound, Message: "批次不存在"}
+	}
+
+	ptrs := make([]*models.ImageGeneration, len(images))
+	for i := range images {
+		ptrs[i] = &images[i]
+	}
+	s.fillQueuePositions(ptrs)
+	return images, nil
+}
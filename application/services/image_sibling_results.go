@@ -0,0 +1,133 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/infrastructure/storage"
+	"github.com/drama-generator/backend/pkg/image"
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// saveSiblingImages 服务商一次返回多张图片时，为第一张之外的图片创建同批次的生成记录
+// 原记录保留第一张并照常写回分镜/场景等关联实体，其余图片只作为候选保存，不覆盖关联实体
+func (s *ImageGenerationService) saveSiblingImages(imageGenID uint, result *image.ImageResult) {
+	if len(result.ImageURLs) < 2 {
+		return
+	}
+
+	var primary models.ImageGeneration
+	if err := s.db.Where("id = ?", imageGenID).First(&primary).Error; err != nil {
+		s.log.Errorw("Failed to load image generation for sibling results", "error", err, "id", imageGenID)
+		return
+	}
+	if primary.Status != models.ImageStatusCompleted {
+		return
+	}
+
+	// 已属于某个批次（如种子范围）时，额外的图片归入同一批次
+	batchID := getString(primary.BatchID)
+	if batchID == "" {
+		batchID = uuid.New().String()
+		if err := s.db.Model(&models.ImageGeneration{}).Where("id = ?", imageGenID).Update("batch_id", batchID).Error; err != nil {
+			s.log.Errorw("Failed to set image batch id", "error", err, "id", imageGenID)
+			return
+		}
+	}
+
+	now := time.Now()
+	for _, url := range result.ImageURLs[1:] {
+		sibling := models.ImageGeneration{
+			StoryboardID:      primary.StoryboardID,
+			DramaID:           primary.DramaID,
+			SceneID:           primary.SceneID,
+			CharacterID:       primary.CharacterID,
+			PropID:            primary.PropID,
+			ImageType:         primary.ImageType,
+			FrameType:         primary.FrameType,
+			Provider:          primary.Provider,
+			Prompt:            primary.Prompt,
+			NegPrompt:         primary.NegPrompt,
+			Model:             primary.Model,
+			Size:              primary.Size,
+			Quality:           primary.Quality,
+			Style:             primary.Style,
+			Steps:             primary.Steps,
+			CfgScale:          primary.CfgScale,
+			Seed:              primary.Seed,
+			ImageURL:          &url,
+			Status:            models.ImageStatusCompleted,
+			Preview:           primary.Preview,
+			Width:             primary.Width,
+			Height:            primary.Height,
+			ReferenceImages:   primary.ReferenceImages,
+			TranslatePromptTo: primary.TranslatePromptTo,
+			TranslatedPrompt:  primary.TranslatedPrompt,
+			BatchID:           &batchID,
+			CompletedAt:       &now,
+		}
+		if err := s.db.Omit(clause.Associations).Create(&sibling).Error; err != nil {
+			s.log.Errorw("Failed to create sibling image generation", "error", err, "id", imageGenID)
+			continue
+		}
+		s.cacheSiblingImage(&sibling, now)
+	}
+
+	s.log.Infow("Saved extra images returned in one response",
+		"id", imageGenID,
+		"batch_id", batchID,
+		"extra", len(result.ImageURLs)-1)
+}
+
+// cacheSiblingImage 将同批次的额外图片下载（data URI 直接解码）到本地存储，与原记录使用相同的目录、转码和水印规则
+func (s *ImageGenerationService) cacheSiblingImage(sibling *models.ImageGeneration, at time.Time) {
+	url := getString(sibling.ImageURL)
+	isDataURI := strings.HasPrefix(url, "data:")
+	if s.localStorage == nil || sibling.Preview ||
+		!(strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") || isDataURI) {
+		return
+	}
+
+	storageCfg := s.cfg().Storage
+	backoff := time.Duration(storageCfg.DownloadBackoff) * time.Second
+	if backoff <= 0 {
+		backoff = 2 * time.Second
+	}
+	category := s.imageStorageCategory(sibling, storageCfg.ImagePathTemplate, at)
+	var downloadResult *storage.DownloadResult
+	var err error
+	if isDataURI {
+		downloadResult, err = s.localStorage.SaveDataURI(url, category)
+	} else {
+		downloadResult, err = s.localStorage.DownloadImageWithRetry(url, category, storageCfg.DownloadRetries, backoff)
+	}
+	if err != nil {
+		s.log.Warnw("Failed to download sibling image to local storage", "error", err, "id", sibling.ID)
+		if storageCfg.MarkCacheFailed {
+			s.db.Model(&models.ImageGeneration{}).Where("id = ?", sibling.ID).Update("cache_failed", true)
+		}
+		return
+	}
+
+	masterPath := s.transcodeDownloadedImage(sibling.ID, downloadResult)
+	updates := map[string]interface{}{
+		"local_path": downloadResult.RelativePath,
+	}
+	if masterPath != nil {
+		updates["master_path"] = *masterPath
+	}
+	if s.watermarkEnabled(sibling.ID) {
+		originalPath, err := s.applyWatermark(downloadResult)
+		if err != nil {
+			s.log.Errorw("Failed to apply watermark", "error", err, "id", sibling.ID)
+		} else {
+			updates["image_url"] = downloadResult.URL
+			updates["original_path"] = originalPath
+		}
+	}
+	if err := s.db.Model(&models.ImageGeneration{}).Where("id = ?", sibling.ID).Updates(updates).Error; err != nil {
+		s.log.Errorw("Failed to update sibling image local path", "error", err, "id", sibling.ID)
+	}
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	models "github.com/drama-generator/backend/domain/models"
+)
+
+// defaultImageCategory 未配置目录模板时生成图片的缓存目录
+const defaultImageCategory = "images"
+
+// noEpisodeDir 图片不属于任何剧集（角色、道具等剧本级图片）时 {episode_id} 的取值
+const noEpisodeDir = "shared"
+
+// imageStorageCategory 按 storage.image_path_template 计算图片缓存目录（相对存储根目录）
+// 模板展开后不是安全的相对路径时回退到 images
+func (s *ImageGenerationService) imageStorageCategory(imageGen *models.ImageGeneration, template string, at time.Time) string {
+	if strings.TrimSpace(template) == "" {
+		return defaultImageCategory
+	}
+
+	episodeDir := noEpisodeDir
+	if episodeID := s.imageEpisodeID(imageGen); episodeID != 0 {
+		episodeDir = fmt.Sprintf("%d", episodeID)
+	}
+	imageType := imageGen.ImageType
+	if imageType == "" {
+		imageType = string(models.ImageTypeStoryboard)
+	}
+
+	category := strings.NewReplacer(
+		"{drama_id}", fmt.Sprintf("%d", imageGen.DramaID),
+		"{episode_id}", episodeDir,
+		"{image_type}", imageType,
+		"{yyyy-mm}", at.Format("2006-01"),
+		"{yyyy}", at.Format("2006"),
+		"{mm}", at.Format("01"),
+		"{dd}", at.Format("02"),
+	).Replace(strings.TrimSpace(template))
+	category = path.Clean(strings.Trim(category, "/"))
+
+	if category == "." || category == ".." || strings.HasPrefix(category, "../") || strings.ContainsAny(category, "{}\\") {
+		s.log.Warnw("Invalid image path template, using default", "template", template, "expanded", category)
+		return defaultImageCategory
+	}
+	return category
+}
+
+// imageEpisodeID 图片所属的剧集：分镜图取分镜的剧集，场景图取场景的剧集，其他返回 0
+func (s *ImageGenerationService) imageEpisodeID(imageGen *models.ImageGeneration) uint {
+	var episodeID *uint
+	switch {
+	case imageGen.StoryboardID != nil && imageGen.ImageType != string(models.ImageTypeScene):
+		s.db.Model(&models.Storyboard{}).Where("id = ?", *imageGen.StoryboardID).Select("episode_id").Scan(&episodeID)
+	case imageGen.SceneID != nil:
+		s.db.Model(&models.Scene{}).Where("id = ?", *imageGen.SceneID).Select("episode_id").Scan(&episodeID)
+	}
+	if episodeID == nil {
+		return 0
+	}
+	return *episodeID
+}
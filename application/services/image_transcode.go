@@ -0,0 +1,89 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/drama-generator/backend/infrastructure/storage"
+	"github.com/drama-generator/backend/pkg/image"
+)
+
+// transcodeDownloadedImage 按存储配置将下载的图片转码为目标格式，成功时就地更新 download 的路径和URL
+// 已是目标格式或转码失败时保持原文件不变；keep_original 开启时返回作为母版保留的原文件相对路径，否则返回 nil
+func (s *ImageGenerationService) transcodeDownloadedImage(imageGenID uint, download *storage.DownloadResult) *string {
+	cfg := s.cfg().Storage
+	if cfg.ImageFormat == "" {
+		return nil
+	}
+	format := image.NormalizeTranscodeFormat(cfg.ImageFormat)
+	if format == "" {
+		s.log.Warnw("Unsupported image_format in storage config, skipping transcode", "image_format", cfg.ImageFormat)
+		return nil
+	}
+
+	current, err := image.DetectFormat(download.AbsolutePath)
+	if err != nil {
+		s.log.Warnw("Failed to detect downloaded image format", "error", err, "id", imageGenID)
+		return nil
+	}
+	if current == format {
+		return nil
+	}
+
+	ext := image.TranscodeExt(format)
+	relBase := strings.TrimSuffix(download.RelativePath, filepath.Ext(download.RelativePath))
+	absBase := strings.TrimSuffix(download.AbsolutePath, filepath.Ext(download.AbsolutePath))
+	relPath, absPath := relBase+ext, absBase+ext
+	// URL 扩展名与实际格式不符（如 .jpg 地址返回 PNG）时输出路径与原文件相同，先写临时文件再替换，避免覆盖输入
+	collides := absPath == download.AbsolutePath
+	dstPath := absPath
+	if collides {
+		dstPath = absPath + ".tmp"
+	}
+	if err := image.TranscodeImage(download.AbsolutePath, dstPath, format, cfg.ImageQuality); err != nil {
+		s.log.Warnw("Failed to transcode image", "error", err, "id", imageGenID, "format", format)
+		return nil
+	}
+
+	var masterPath *string
+	masterAbs := download.AbsolutePath
+	if cfg.KeepOriginal {
+		master := download.RelativePath
+		if collides {
+			// 原文件改用实际格式的扩展名保存为母版，给转码结果让出位置
+			master = relBase + "_master" + image.TranscodeExt(current)
+			masterAbs = absBase + "_master" + image.TranscodeExt(current)
+			if err := os.Rename(download.AbsolutePath, masterAbs); err != nil {
+				s.log.Warnw("Failed to keep original image", "error", err, "id", imageGenID)
+				master = ""
+			}
+		}
+		if master != "" {
+			masterPath = &master
+		}
+	} else if !collides {
+		os.Remove(download.AbsolutePath)
+	}
+	if collides {
+		if err := os.Rename(dstPath, absPath); err != nil {
+			os.Remove(dstPath)
+			if masterPath != nil {
+				os.Rename(masterAbs, absPath)
+			}
+			s.log.Warnw("Failed to replace image with transcoded file", "error", err, "id", imageGenID)
+			return nil
+		}
+	}
+	download.RelativePath = relPath
+	download.AbsolutePath = absPath
+	download.URL = s.localStorage.GetURL(filepath.ToSlash(relPath))
+
+	s.log.Infow("Image transcoded",
+		"id", imageGenID,
+		"from", current,
+		"to", format,
+		"local_path", relPath,
+		"master_path", getString(masterPath))
+	return masterPath
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/infrastructure/storage"
+	"github.com/drama-generator/backend/pkg/image"
+)
+
+const defaultWatermarkPrivatePath = "./data/private"
+
+// watermarkEnabled 全局开启水印且图片所属剧本设置了水印标记时返回 true
+func (s *ImageGenerationService) watermarkEnabled(imageGenID uint) bool {
+	cfg := s.cfg().Watermark
+	if !cfg.Enabled || (cfg.Text == "" && cfg.LogoPath == "") {
+		return false
+	}
+
+	var drama models.Drama
+	if err := s.db.Model(&models.Drama{}).
+		Joins("INNER JOIN image_generations ON image_generations.drama_id = dramas.id").
+		Where("image_generations.id = ?", imageGenID).
+		Select("dramas.id", "dramas.watermark").
+		First(&drama).Error; err != nil {
+		return false
+	}
+	return drama.Watermark
+}
+
+// applyWatermark 将无水印原图移入私有目录，并在原存储位置写入加水印的版本，返回原图路径
+func (s *ImageGenerationService) applyWatermark(download *storage.DownloadResult) (string, error) {
+	cfg := s.cfg().Watermark
+	privateRoot := cfg.PrivatePath
+	if privateRoot == "" {
+		privateRoot = defaultWatermarkPrivatePath
+	}
+
+	originalPath := filepath.Join(privateRoot, download.RelativePath)
+	if err := os.MkdirAll(filepath.Dir(originalPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create private directory: %w", err)
+	}
+	if err := os.Rename(download.AbsolutePath, originalPath); err != nil {
+		return "", fmt.Errorf("failed to move original image: %w", err)
+	}
+
+	err := image.ApplyWatermark(originalPath, download.AbsolutePath, image.WatermarkOptions{
+		Text:     cfg.Text,
+		LogoPath: cfg.LogoPath,
+		Opacity:  cfg.Opacity,
+		Position: cfg.Position,
+	})
+	if err != nil {
+		// 加水印失败时恢复原图，保证本地缓存可用
+		os.Remove(download.AbsolutePath)
+		if renameErr := os.Rename(originalPath, download.AbsolutePath); renameErr != nil {
+			s.log.Errorw("Failed to restore original image", "error", renameErr, "path", originalPath)
+		}
+		return "", err
+	}
+
+	s.log.Infow("Watermark applied", "local_path", download.RelativePath, "original_path", originalPath)
+	return originalPath, nil
+}
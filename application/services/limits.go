@@ -0,0 +1,45 @@
+package services
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/drama-generator/backend/pkg/config"
+)
+
+// LimitExceededError 输入超出配置的规模上限，handler 应返回 400
+type LimitExceededError struct {
+	Resource string
+	Limit    int
+	Actual   int
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("%s超出上限：当前 %d，最多 %d", e.Resource, e.Actual, e.Limit)
+}
+
+// Unwrap 规模超限归类为无效输入
+func (e *LimitExceededError) Unwrap() error {
+	return ErrInvalidInput
+}
+
+// currentLimits 读取当前生效的规模上限，未加载配置时使用默认值
+func currentLimits() config.LimitsConfig {
+	if cfg := config.Current(); cfg != nil {
+		return cfg.Limits
+	}
+	return config.LimitsConfig{}
+}
+
+// checkLimit limit 为 0 表示不限制
+func checkLimit(resource string, actual, limit int) error {
+	if limit > 0 && actual > limit {
+		return &LimitExceededError{Resource: resource, Limit: limit, Actual: actual}
+	}
+	return nil
+}
+
+// checkScriptLength 按字符数检查剧本长度
+func checkScriptLength(script string) error {
+	return checkLimit("剧本长度", utf8.RuneCountInString(script), currentLimits().ScriptLength())
+}
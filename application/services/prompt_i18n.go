@@ -8,7 +8,8 @@ import (
 
 // PromptI18n 提示词国际化工具
 type PromptI18n struct {
-	config *config.Config
+	config   *config.Config
+	language string // 覆盖全局语言，为空时使用配置
 }
 
 // NewPromptI18n 创建提示词国际化工具
@@ -16,9 +17,20 @@ func NewPromptI18n(cfg *config.Config) *PromptI18n {
 	return &PromptI18n{config: cfg}
 }
 
+// WithLanguage 返回使用指定语言的副本（如剧本级语言设置），lang 为空时沿用当前设置
+func (p *PromptI18n) WithLanguage(lang string) *PromptI18n {
+	if lang == "" {
+		return p
+	}
+	return &PromptI18n{config: p.config, language: lang}
+}
+
 // GetLanguage 获取当前语言设置
 func (p *PromptI18n) GetLanguage() string {
-	lang := p.config.App.Language
+	if p.language != "" {
+		return p.language
+	}
+	lang := config.CurrentOr(p.config).App.Language
 	if lang == "" {
 		return "zh" // 默认中文
 	}
@@ -143,6 +155,68 @@ func (p *PromptI18n) GetStoryboardSystemPrompt() string {
 - 情绪强度必须准确反映剧本氛围变化`
 }
 
+// GetStoryboardFromImagesPrompt 获取根据参考图片反推分镜的系统提示词
+func (p *PromptI18n) GetStoryboardFromImagesPrompt() string {
+	if p.IsEnglish() {
+		return `[Role] You are a senior film storyboard artist who can read a sequence of reference images and turn them into a shot list.
+
+[Task] Each provided image corresponds to one shot, in the given order. Describe each image as a storyboard shot and infer a coherent story flow between shots.
+
+[Requirements]
+1. Output exactly one shot per image, keeping the image order
+2. Describe what is visible: shot type, angle, location, time and lighting, character actions and expressions
+3. Infer plausible dialogue, the result of the action and the atmosphere from the image content; use an empty string for dialogue when there is none
+4. characters must only contain IDs from the character list; scene_id must be an ID from the scene list or null
+5. duration is an integer number of seconds (4-12)
+
+[Output Format] Output JSON only, no explanation:
+{"storyboards": [{"shot_number": 1, "title": "", "shot_type": "", "angle": "", "time": "", "location": "", "scene_id": null, "movement": "", "action": "", "dialogue": "", "result": "", "atmosphere": "", "emotion": "", "duration": 6, "bgm_prompt": "", "sound_effect": "", "characters": [], "is_primary": true}]}`
+	}
+
+	return `【角色】你是资深影视分镜师，能够读懂一组参考图片并将其整理为分镜头脚本。
+
+【任务】提供的每张图片按顺序对应一个镜头。请将每张图片描述为一个分镜，并推断镜头之间连贯的剧情走向。
+
+【要求】
+1. 每张图片输出且仅输出一个镜头，保持图片顺序
+2. 描述画面中可见的内容：景别、镜头角度、地点、时间与光线、人物动作与表情
+3. 根据画面内容推断合理的对白、动作结果和环境氛围；无对白时dialogue为空字符串
+4. characters只能填写角色列表中的ID；scene_id必须是场景列表中的ID，没有合适场景时填null
+5. duration为整数秒（4-12）
+
+【输出格式】只输出JSON，不要任何解释：
+{"storyboards": [{"shot_number": 1, "title": "", "shot_type": "", "angle": "", "time": "", "location": "", "scene_id": null, "movement": "", "action": "", "dialogue": "", "result": "", "atmosphere": "", "emotion": "", "duration": 6, "bgm_prompt": "", "sound_effect": "", "characters": [], "is_primary": true}]}`
+}
+
+// GetStyleConsistencyCheckPrompt 获取场景图画风一致性检查提示词，第1张图片为参考图，sceneList 为待检查场景清单
+func (p *PromptI18n) GetStyleConsistencyCheckPrompt(style, sceneList string) string {
+	if p.IsEnglish() {
+		styleHint := ""
+		if style != "" {
+			styleHint = fmt.Sprintf("\nThe art style set for this drama: %s", style)
+		}
+		return fmt.Sprintf(`Image 1 is the reference scene image; the other images are other scene images from the same drama. Compare only the art style (drawing style, rendering, color tone, line work and texture). Do not compare scene content, time of day or composition.%s
+
+[Scenes to Check]
+%s
+
+[Output Format] Output JSON only, no explanation. Use an empty array for inconsistent when all styles match:
+{"inconsistent": [{"index": image number, "note": "how the style differs from the reference"}]}`, styleHint, sceneList)
+	}
+
+	styleHint := ""
+	if style != "" {
+		styleHint = fmt.Sprintf("\n剧本设定的画风：%s", style)
+	}
+	return fmt.Sprintf(`第1张图片是参考场景图，其余图片是同一部剧的其他场景图。请只比较画风（绘画风格、渲染方式、色调、线条与质感），不要比较场景内容、时间或构图。%s
+
+【待检查场景】
+%s
+
+【输出格式】只输出JSON，不要任何解释，画风都一致时 inconsistent 为空数组：
+{"inconsistent": [{"index": 图片序号, "note": "与参考图画风不一致之处"}]}`, styleHint, sceneList)
+}
+
 // GetSceneExtractionPrompt 获取场景提取提示词
 func (p *PromptI18n) GetSceneExtractionPrompt(style string) string {
 	// 默认图片比例
@@ -599,6 +673,7 @@ func (p *PromptI18n) FormatUserPrompt(key string, args ...interface{}) string {
 			"episode_count":          "\nNumber of episodes: %d episodes",
 			"episode_importance":     "\n\n**Important: Must plan complete storylines for all %d episodes in the episodes array, each with clear story content!**",
 			"character_request":      "Script content:\n%s\n\nPlease extract and organize detailed character profiles for up to %d main characters from the script.",
+			"character_more_request": "Script content:\n%s\n\nThese characters have already been extracted: %s\nPlease extract detailed profiles for %d more main characters from the script. Do not repeat any of the characters above.",
 			"episode_script_request": "Drama outline:\n%s\n%s\nPlease create detailed scripts for %d episodes based on the above outline and characters.\n\n**Important requirements:**\n- Must generate all %d episodes, from episode 1 to episode %d, cannot skip any\n- Each episode is about 3-5 minutes (150-300 seconds)\n- The duration field for each episode should be set reasonably based on script content length, not all the same value\n- The episodes array in the returned JSON must contain %d elements",
 			"frame_info":             "Shot information:\n%s\n\nPlease directly generate the image prompt for the first frame without any explanation:",
 			"key_frame_info":         "Shot information:\n%s\n\nPlease directly generate the image prompt for the key frame without any explanation:",
@@ -614,6 +689,7 @@ func (p *PromptI18n) FormatUserPrompt(key string, args ...interface{}) string {
 			"shot_description_label": "Shot description: %s",
 			"scene_label":            "Scene: %s, %s",
 			"characters_label":       "Characters: %s",
+			"excluded_label":         "Without (must not appear in frame): %s",
 			"action_label":           "Action: %s",
 			"result_label":           "Result: %s",
 			"dialogue_label":         "Dialogue: %s",
@@ -622,6 +698,10 @@ func (p *PromptI18n) FormatUserPrompt(key string, args ...interface{}) string {
 			"angle_label":            "Angle: %s",
 			"movement_label":         "Movement: %s",
 			"drama_info_template":    "Title: %s\nSummary: %s\nGenre: %s",
+			"style_ref_label":        "【Style Reference Shots】",
+			"style_ref_instruction":  "The following are representative shots from episode %d (%d shots in total, about %d seconds per shot on average). Keep the shot size distribution, camera angles, camera movement habits, description detail and pacing consistent with them. Only borrow the style, do not copy the plot:",
+			"style_check_scene_item": "Image %d: %s (%s)",
+			"json_repair_request":    "The following output was supposed to be valid JSON in the format described by the system prompt, but it could not be parsed.\nParse error: %v\n\nFix it so that it is valid, complete JSON with the same structure and content. Output only the JSON, with no explanation and no Markdown code fences.\n\nOutput to fix:\n%s",
 		},
 		"zh": {
 			"outline_request":        "请为以下主题创作短剧大纲：\n\n主题：%s",
@@ -630,6 +710,7 @@ func (p *PromptI18n) FormatUserPrompt(key string, args ...interface{}) string {
 			"episode_count":          "\n剧集数量：%d集",
 			"episode_importance":     "\n\n**重要：必须在episodes数组中规划完整的%d集剧情，每集都要有明确的故事内容！**",
 			"character_request":      "剧本内容：\n%s\n\n请从剧本中提取并整理最多 %d 个主要角色的详细设定。",
+			"character_more_request": "剧本内容：\n%s\n\n以下角色已经提取过：%s\n请从剧本中再提取 %d 个主要角色的详细设定，不要重复上面的角色。",
 			"episode_script_request": "剧本大纲：\n%s\n%s\n请基于以上大纲和角色，创作 %d 集的详细剧本。\n\n**重要要求：**\n- 必须生成完整的 %d 集，从第1集到第%d集，不能遗漏\n- 每集约3-5分钟（150-300秒）\n- 每集的duration字段要根据剧本内容长度合理设置，不要都设置为同一个值\n- 返回的JSON中episodes数组必须包含 %d 个元素",
 			"frame_info":             "镜头信息：\n%s\n\n请直接生成首帧的图像提示词，不要任何解释：",
 			"key_frame_info":         "镜头信息：\n%s\n\n请直接生成关键帧的图像提示词，不要任何解释：",
@@ -645,6 +726,7 @@ func (p *PromptI18n) FormatUserPrompt(key string, args ...interface{}) string {
 			"shot_description_label": "镜头描述: %s",
 			"scene_label":            "场景: %s, %s",
 			"characters_label":       "角色: %s",
+			"excluded_label":         "画面中不出现的角色: %s",
 			"action_label":           "动作: %s",
 			"result_label":           "结果: %s",
 			"dialogue_label":         "对白: %s",
@@ -653,6 +735,10 @@ func (p *PromptI18n) FormatUserPrompt(key string, args ...interface{}) string {
 			"angle_label":            "角度: %s",
 			"movement_label":         "运镜: %s",
 			"drama_info_template":    "剧名：%s\n简介：%s\n类型：%s",
+			"style_ref_label":        "【风格参考镜头】",
+			"style_ref_instruction":  "以下是第%d集的代表性镜头（该集共%d个镜头，平均每个镜头约%d秒）。请保持与其一致的景别分布、镜头角度、运镜习惯、描述详细程度和叙事节奏，只借鉴风格，不要照搬剧情：",
+			"style_check_scene_item": "第%d张：%s（%s）",
+			"json_repair_request":    "以下输出本应是符合系统提示词所述格式的合法JSON，但无法解析。\n解析错误：%v\n\n请修复为结构和内容不变的合法、完整的JSON。只输出JSON，不要任何解释，也不要使用Markdown代码块。\n\n待修复的输出：\n%s",
 		},
 	}
 
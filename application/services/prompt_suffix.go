@@ -0,0 +1,33 @@
+package services
+
+import (
+	"github.com/drama-generator/backend/pkg/config"
+)
+
+// promptSuffixes 追加到分镜图片/视频提示词末尾的画质描述
+type promptSuffixes struct {
+	Image string
+	Video string
+}
+
+// promptSuffixesForEpisode 获取剧集所属剧本的提示词后缀，剧本未设置的项使用 style 配置的默认值
+func (s *StoryboardService) promptSuffixesForEpisode(episodeID interface{}) promptSuffixes {
+	var suffixes promptSuffixes
+	if err := s.db.Table("dramas").
+		Select("dramas.image_prompt_suffix AS image, dramas.video_prompt_suffix AS video").
+		Joins("INNER JOIN episodes ON episodes.drama_id = dramas.id").
+		Where("episodes.id = ?", episodeID).
+		Scan(&suffixes).Error; err != nil {
+		s.log.Warnw("Failed to load drama prompt suffixes", "error", err, "episode_id", episodeID)
+	}
+
+	if cfg := config.Current(); cfg != nil {
+		if suffixes.Image == "" {
+			suffixes.Image = cfg.Style.ImagePromptSuffix
+		}
+		if suffixes.Video == "" {
+			suffixes.Video = cfg.Style.VideoPromptSuffix
+		}
+	}
+	return suffixes
+}
@@ -93,7 +93,7 @@ func (s *PropService) processPropExtraction(taskID string, episode models.Episod
 	promptTemplate := s.promptI18n.GetPropExtractionPrompt(drama.Style)
 	prompt := fmt.Sprintf(promptTemplate, script)
 
-	response, err := s.aiService.GenerateText(prompt, "", ai.WithMaxTokens(2000))
+	response, err := s.aiService.GenerateTextForDrama(episode.DramaID, "", prompt, "", ai.WithMaxTokens(2000))
 	if err != nil {
 		s.taskService.UpdateTaskError(taskID, err)
 		return
@@ -181,7 +181,7 @@ func (s *PropService) processPropImageGeneration(taskID string, prop models.Prop
 		Prompt:    *prop.Prompt,
 		Size:      imageSize,
 		Style:     &imageStyle,
-		Provider:  s.config.AI.DefaultImageProvider, // 使用默认配置
+		Provider:  config.CurrentOr(s.config).AI.DefaultImageProvider, // 使用默认配置
 	}
 
 	// 调用 ImageGenerationService
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/drama-generator/backend/pkg/image"
+)
+
+// imageProviders 有专用图片客户端的标准服务商，其他服务商按 OpenAI 兼容接口处理
+var imageProviders = map[string]bool{
+	"openai":     true,
+	"volcengine": true,
+	"gemini":     true,
+}
+
+// builtinProviderAliases 内置的服务商别名，可被 ai.provider_aliases 覆盖
+var builtinProviderAliases = map[string]string{
+	"dalle":    "openai",
+	"chatfire": "openai",
+	"volces":   "volcengine",
+	"doubao":   "volcengine",
+	"ark":      "volcengine",
+	"google":   "gemini",
+}
+
+// resolveProviderAlias 将服务商名称解析为标准名称：先查配置的别名，再查内置别名，都没有时原样返回
+func resolveProviderAlias(provider string, aliases map[string]string) string {
+	name := strings.ToLower(strings.TrimSpace(provider))
+	if canonical, ok := aliases[name]; ok {
+		name = strings.ToLower(strings.TrimSpace(canonical))
+	}
+	if canonical, ok := builtinProviderAliases[name]; ok {
+		return canonical
+	}
+	return name
+}
+
+// resolveImageProvider 解析图片服务商对应的客户端类型，无法识别的名称记录日志后按 openai 处理
+func (s *ImageGenerationService) resolveImageProvider(provider string) string {
+	canonical := resolveProviderAlias(provider, s.cfg().AI.ProviderAliases)
+	if imageProviders[canonical] {
+		return canonical
+	}
+	if canonical != "" {
+		s.log.Warnw("Unknown image provider, using OpenAI-compatible client",
+			"provider", provider,
+			"resolved", canonical)
+	}
+	return "openai"
+}
+
+// ProviderCapabilities 查找图片服务商支持的参数：ai.provider_aliases 中的自定义名称先解析为标准服务商，
+// 有内置能力的名称（如 dalle 与 openai 共用客户端但能力不同）直接使用，其余按内置别名解析
+func (s *ImageGenerationService) ProviderCapabilities(provider string) image.Capabilities {
+	aliases := s.cfg().AI.ProviderAliases
+	name := strings.ToLower(strings.TrimSpace(provider))
+	if _, custom := aliases[name]; !custom {
+		if caps, ok := image.LookupCapabilities(name); ok {
+			return caps
+		}
+	}
+	return image.GetCapabilities(resolveProviderAlias(name, aliases))
+}
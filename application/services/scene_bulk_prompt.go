@@ -0,0 +1,111 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"gorm.io/gorm"
+)
+
+// BulkScenePromptRequest 批量修改剧本场景提示词：将 find 替换为 replace，和/或在末尾追加 append
+type BulkScenePromptRequest struct {
+	Find    string `json:"find"`
+	Replace string `json:"replace"`
+	Append  string `json:"append"`  // 追加到所有场景提示词末尾的风格说明，已包含该说明的场景不重复追加
+	Preview bool   `json:"preview"` // 只返回将受影响的场景，不写入数据库
+}
+
+// BulkScenePromptChange 单个场景提示词的修改前后对比
+type BulkScenePromptChange struct {
+	SceneID  uint   `json:"scene_id"`
+	Location string `json:"location"`
+	Time     string `json:"time"`
+	Before   string `json:"before"`
+	After    string `json:"after"`
+}
+
+// BulkScenePromptResult 批量修改结果，Preview 为 true 时尚未写入
+type BulkScenePromptResult struct {
+	Preview bool                    `json:"preview"`
+	Changed int                     `json:"changed"`
+	Scenes  []BulkScenePromptChange `json:"scenes"`
+}
+
+// applyBulkPromptEdit 对单个提示词执行替换和追加，返回修改后的提示词
+func applyBulkPromptEdit(prompt string, req *BulkScenePromptRequest) string {
+	if req.Find != "" {
+		prompt = strings.ReplaceAll(prompt, req.Find, req.Replace)
+	}
+	if directive := strings.TrimSpace(req.Append); directive != "" && !strings.Contains(prompt, directive) {
+		prompt = strings.TrimRight(prompt, " ")
+		if prompt != "" {
+			separator := ", "
+			if containsHan(directive) {
+				separator = "，"
+			}
+			prompt += separator
+		}
+		prompt += directive
+	}
+	return prompt
+}
+
+// BulkUpdateScenePrompts 在剧本所有场景的提示词中批量查找替换或追加风格说明，在一个事务中写入并标记为手动修改
+func (s *StoryboardCompositionService) BulkUpdateScenePrompts(dramaID uint, req *BulkScenePromptRequest) (*BulkScenePromptResult, error) {
+	if req.Find == "" && strings.TrimSpace(req.Append) == "" {
+		return nil, &ServiceError{Kind: ErrInvalidInput, Message: "find 和 append 至少需要提供一个"}
+	}
+
+	var drama models.Drama
+	if err := s.db.Select("id").Where("id = ?", dramaID).First(&drama).Error; err != nil {
+		return nil, ErrDramaNotFound
+	}
+
+	var scenes []models.Scene
+	if err := s.db.Where("drama_id = ?", dramaID).Order("id ASC").Find(&scenes).Error; err != nil {
+		return nil, fmt.Errorf("获取场景失败: %w", err)
+	}
+
+	result := &BulkScenePromptResult{Preview: req.Preview, Scenes: []BulkScenePromptChange{}}
+	var changed []*models.Scene
+	for i := range scenes {
+		scene := &scenes[i]
+		after := applyBulkPromptEdit(scene.Prompt, req)
+		if after == scene.Prompt {
+			continue
+		}
+		result.Scenes = append(result.Scenes, BulkScenePromptChange{
+			SceneID:  scene.ID,
+			Location: scene.Location,
+			Time:     scene.Time,
+			Before:   scene.Prompt,
+			After:    after,
+		})
+		scene.Prompt = after
+		changed = append(changed, scene)
+	}
+	result.Changed = len(changed)
+
+	if req.Preview || len(changed) == 0 {
+		return result, nil
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, scene := range changed {
+			if err := tx.Model(&models.Scene{}).Where("id = ?", scene.ID).Update("prompt", scene.Prompt).Error; err != nil {
+				return err
+			}
+			if err := markSceneFieldsEdited(tx, scene, SceneFieldPrompt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("批量更新场景提示词失败: %w", err)
+	}
+
+	s.log.Infow("Scene prompts bulk updated", "drama_id", dramaID, "changed", len(changed))
+	return result, nil
+}
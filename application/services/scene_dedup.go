@@ -0,0 +1,90 @@
+package services
+
+import (
+	"fmt"
+	"math"
+)
+
+// 场景去重方式
+const (
+	SceneDedupExact     = "exact"     // 按 location|time 字符串精确去重（默认）
+	SceneDedupEmbedding = "embedding" // 按语义向量相似度聚类去重
+)
+
+const defaultSceneDedupThreshold = 0.85
+
+// dedupBackgroundsByEmbedding 将语义相近的背景（如"维修店"与"维修店内部"）合并为一个场景
+func (s *ImageGenerationService) dedupBackgroundsByEmbedding(backgrounds []BackgroundInfo) ([]BackgroundInfo, error) {
+	if len(backgrounds) < 2 {
+		return backgrounds, nil
+	}
+
+	threshold := s.cfg().AI.SceneDedupThreshold
+	if threshold <= 0 || threshold > 1 {
+		threshold = defaultSceneDedupThreshold
+	}
+
+	inputs := make([]string, len(backgrounds))
+	for i, bg := range backgrounds {
+		inputs[i] = fmt.Sprintf("%s %s", bg.Location, bg.Time)
+	}
+
+	embeddings, err := s.aiService.CreateEmbeddings(inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	// 贪心聚类：每个背景归入第一个相似度达到阈值的簇，以簇首向量为代表
+	var merged []BackgroundInfo
+	var heads [][]float64
+	for i, bg := range backgrounds {
+		target := -1
+		for j, head := range heads {
+			if cosineSimilarity(embeddings[i], head) >= threshold {
+				target = j
+				break
+			}
+		}
+
+		if target < 0 {
+			merged = append(merged, bg)
+			heads = append(heads, embeddings[i])
+			continue
+		}
+
+		s.log.Infow("Merging similar background",
+			"location", bg.Location,
+			"time", bg.Time,
+			"into_location", merged[target].Location,
+			"into_time", merged[target].Time)
+		merged[target].StoryboardNumbers = append(merged[target].StoryboardNumbers, bg.StoryboardNumbers...)
+		merged[target].SceneIDs = append(merged[target].SceneIDs, bg.SceneIDs...)
+		merged[target].StoryboardCount += bg.StoryboardCount
+	}
+
+	s.log.Infow("Backgrounds deduplicated by embedding",
+		"before", len(backgrounds),
+		"after", len(merged),
+		"threshold", threshold)
+
+	return merged, nil
+}
+
+// cosineSimilarity 计算两个向量的余弦相似度
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	models "github.com/drama-generator/backend/domain/models"
+)
+
+// fallbackBackground AI 未提取到任何场景时，根据剧集标题和简介生成一个通用场景，避免后续背景生成无从进行
+// style 为提取时使用的画面风格，为空时不指定风格
+func fallbackBackground(episode *models.Episode, i18n *PromptI18n, style string) BackgroundInfo {
+	title := strings.TrimSpace(episode.Title)
+	description := strings.TrimSpace(getString(episode.Description))
+
+	if i18n.IsEnglish() {
+		location := title
+		if location == "" {
+			location = fmt.Sprintf("Episode %d Main Setting", episode.EpisodeNum)
+		}
+		prompt := fmt.Sprintf("A cinematic pure background scene for the story \"%s\".", location)
+		if description != "" {
+			prompt += " Setting: " + description + "."
+		}
+		prompt += " The scene shows the main environment of the story with no characters. Style: "
+		if style != "" {
+			prompt += style + ", "
+		}
+		prompt += "rich details, high quality, atmospheric lighting."
+		return BackgroundInfo{Location: location, Time: "Day", Prompt: prompt, StoryboardCount: 1}
+	}
+
+	location := title
+	if location == "" {
+		location = fmt.Sprintf("第%d集主场景", episode.EpisodeNum)
+	}
+	prompt := fmt.Sprintf("一个电影感的纯背景场景，展现故事「%s」的主要环境。", location)
+	if description != "" {
+		prompt += "故事背景：" + description + "。"
+	}
+	prompt += "画面不包含人物。风格："
+	if style != "" {
+		prompt += style + "，"
+	}
+	prompt += "细节丰富，高质量，氛围光照。"
+	return BackgroundInfo{Location: location, Time: "白天", Prompt: prompt, StoryboardCount: 1}
+}
+
+// sceneFallbackStyle 通用场景的画面风格：提取时指定的风格优先，其次剧本风格，最后全局默认风格
+func (s *ImageGenerationService) sceneFallbackStyle(dramaID uint, style string) string {
+	if style != "" {
+		return style
+	}
+	var drama models.Drama
+	if err := s.db.Select("id", "style").Where("id = ?", dramaID).First(&drama).Error; err != nil {
+		s.log.Warnw("Failed to load drama style for fallback scene", "error", err, "drama_id", dramaID)
+	}
+	return resolveImageStyle(drama.Style)
+}
+
+// episodeHasScenes 剧集是否已有场景
+func (s *ImageGenerationService) episodeHasScenes(episodeID uint) bool {
+	var count int64
+	if err := s.db.Model(&models.Scene{}).Where("episode_id = ?", episodeID).Count(&count).Error; err != nil {
+		return false
+	}
+	return count > 0
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/drama-generator/backend/domain/models"
+	"gorm.io/gorm"
+)
+
+// 场景提取结果的保存方式
+const (
+	SceneExtractReplace = "replace" // 删除章节现有场景后重新创建（默认）
+	SceneExtractMerge   = "merge"   // 按 location+time 与现有场景合并，保留用户手动修改过的字段
+)
+
+// 可被用户手动修改、合并提取时受保护的场景字段
+const (
+	SceneFieldLocation = "location"
+	SceneFieldTime     = "time"
+	SceneFieldPrompt   = "prompt"
+)
+
+// sceneExtractKey 合并提取时匹配场景的键，忽略大小写和首尾空白
+func sceneExtractKey(location, time string) string {
+	return strings.ToLower(strings.TrimSpace(location)) + "|" + strings.ToLower(strings.TrimSpace(time))
+}
+
+// sceneFieldEdited 字段是否被用户手动修改过
+func sceneFieldEdited(scene *models.Scene, field string) bool {
+	for _, f := range scene.EditedFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// markSceneFieldsEdited 记录用户手动修改过的场景字段，之后合并提取不会覆盖这些字段
+func markSceneFieldsEdited(db *gorm.DB, scene *models.Scene, fields ...string) error {
+	edited := scene.EditedFields
+	for _, field := range fields {
+		if !sceneFieldEdited(scene, field) {
+			edited = append(edited, field)
+		}
+	}
+	if len(edited) == len(scene.EditedFields) {
+		return nil
+	}
+	scene.EditedFields = edited
+	return db.Model(&models.Scene{ID: scene.ID}).Select("edited_fields").
+		Updates(&models.Scene{EditedFields: edited}).Error
+}
+
+// mergeExtractedBackgrounds 将提取结果与章节现有场景合并：
+// 匹配到的场景只更新未被手动修改的字段，新场景直接创建，
+// 未匹配且没有手动修改的旧场景删除，有手动修改的保留
+func (s *ImageGenerationService) mergeExtractedBackgrounds(taskID string, episode *models.Episode, backgrounds []BackgroundInfo) {
+	created, updated, kept, deleted := 0, 0, 0, 0
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing []models.Scene
+		if err := tx.Where("episode_id = ?", episode.ID).Order("id ASC").Find(&existing).Error; err != nil {
+			return err
+		}
+
+		byKey := make(map[string]*models.Scene, len(existing)*2)
+		for i := range existing {
+			scene := &existing[i]
+			if scene.ExtractKey != "" {
+				byKey[scene.ExtractKey] = scene
+			}
+			if key := sceneExtractKey(scene.Location, scene.Time); byKey[key] == nil {
+				byKey[key] = scene
+			}
+		}
+
+		matched := make(map[uint]bool, len(existing))
+		for _, bg := range backgrounds {
+			key := sceneExtractKey(bg.Location, bg.Time)
+			if scene := byKey[key]; scene != nil && !matched[scene.ID] {
+				matched[scene.ID] = true
+				updates := map[string]interface{}{
+					"storyboard_count": max(bg.StoryboardCount, 1),
+					"extract_key":      key,
+				}
+				if !sceneFieldEdited(scene, SceneFieldLocation) {
+					updates["location"] = bg.Location
+				}
+				if !sceneFieldEdited(scene, SceneFieldTime) {
+					updates["time"] = bg.Time
+				}
+				if !sceneFieldEdited(scene, SceneFieldPrompt) {
+					updates["prompt"] = bg.Prompt
+				}
+				if err := tx.Model(&models.Scene{}).Where("id = ?", scene.ID).Updates(updates).Error; err != nil {
+					return err
+				}
+				updated++
+				continue
+			}
+
+			episodeID := episode.ID
+			scene := &models.Scene{
+				DramaID:         episode.DramaID,
+				EpisodeID:       &episodeID,
+				Location:        bg.Location,
+				Time:            bg.Time,
+				Prompt:          bg.Prompt,
+				StoryboardCount: max(bg.StoryboardCount, 1),
+				Status:          "pending",
+				ExtractKey:      key,
+			}
+			if err := tx.Create(scene).Error; err != nil {
+				return err
+			}
+			created++
+		}
+
+		for i := range existing {
+			scene := &existing[i]
+			if matched[scene.ID] {
+				continue
+			}
+			if len(scene.EditedFields) > 0 {
+				kept++
+				continue
+			}
+			if err := tx.Delete(&models.Scene{}, scene.ID).Error; err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		s.log.Errorw("Failed to merge extracted scenes", "error", err, "task_id", taskID)
+		s.taskService.UpdateTaskStatus(taskID, "failed", 0, "保存场景信息失败: "+err.Error())
+		return
+	}
+
+	var scenes []models.Scene
+	if err := s.db.Where("episode_id = ?", episode.ID).Order("id ASC").Find(&scenes).Error; err != nil {
+		s.log.Warnw("Failed to reload merged scenes", "error", err, "task_id", taskID)
+	}
+
+	s.taskService.UpdateTaskResult(taskID, map[string]interface{}{
+		"scenes":     scenes,
+		"count":      len(scenes),
+		"created":    created,
+		"updated":    updated,
+		"kept":       kept,
+		"deleted":    deleted,
+		"episode_id": episode.ID,
+		"drama_id":   episode.DramaID,
+	})
+
+	s.log.Infow("Background extraction merged",
+		"task_id", taskID,
+		"episode_id", episode.ID,
+		"created", created,
+		"updated", updated,
+		"kept", kept,
+		"deleted", deleted)
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/drama-generator/backend/domain/models"
+)
+
+// SceneGalleryShot 场景画廊中的单个分镜图片
+type SceneGalleryShot struct {
+	StoryboardID     uint    `json:"storyboard_id"`
+	EpisodeID        uint    `json:"episode_id"`
+	StoryboardNumber int     `json:"storyboard_number"`
+	Title            *string `json:"title,omitempty"`
+	ComposedImage    string  `json:"composed_image"`
+}
+
+// SceneGalleryEntry 场景画廊中的一个场景：场景信息及其背景图作为标题，下面是使用该场景的分镜图片
+type SceneGalleryEntry struct {
+	Scene models.Scene       `json:"scene"`
+	Shots []SceneGalleryShot `json:"shots"`
+}
+
+// GetSceneGallery 按场景分组返回剧本的图片，分页以场景为单位
+func (s *ImageGenerationService) GetSceneGallery(dramaID uint, page, pageSize int) ([]SceneGalleryEntry, int64, error) {
+	var drama models.Drama
+	if err := s.db.Select("id").Where("id = ?", dramaID).First(&drama).Error; err != nil {
+		return nil, 0, ErrDramaNotFound
+	}
+
+	query := s.db.Model(&models.Scene{}).Where("drama_id = ?", dramaID)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("统计场景失败: %w", err)
+	}
+
+	var scenes []models.Scene
+	offset := (page - 1) * pageSize
+	if err := query.Order("id ASC").Offset(offset).Limit(pageSize).Find(&scenes).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取场景失败: %w", err)
+	}
+	if len(scenes) == 0 {
+		return []SceneGalleryEntry{}, total, nil
+	}
+
+	sceneIDs := make([]uint, len(scenes))
+	for i, scene := range scenes {
+		sceneIDs[i] = scene.ID
+	}
+
+	var storyboards []models.Storyboard
+	if err := s.db.Select("id", "episode_id", "scene_id", "storyboard_number", "title", "composed_image").
+		Where("scene_id IN ? AND composed_image IS NOT NULL AND composed_image <> ''", sceneIDs).
+		Order("episode_id ASC, storyboard_number ASC").
+		Find(&storyboards).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取分镜图片失败: %w", err)
+	}
+
+	shotsByScene := make(map[uint][]SceneGalleryShot, len(scenes))
+	for _, sb := range storyboards {
+		shotsByScene[*sb.SceneID] = append(shotsByScene[*sb.SceneID], SceneGalleryShot{
+			StoryboardID:     sb.ID,
+			EpisodeID:        sb.EpisodeID,
+			StoryboardNumber: sb.StoryboardNumber,
+			Title:            sb.Title,
+			ComposedImage:    *sb.ComposedImage,
+		})
+	}
+
+	entries := make([]SceneGalleryEntry, len(scenes))
+	for i, scene := range scenes {
+		shots := shotsByScene[scene.ID]
+		if shots == nil {
+			shots = []SceneGalleryShot{}
+		}
+		entries[i] = SceneGalleryEntry{Scene: scene, Shots: shots}
+	}
+	return entries, total, nil
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/utils"
+)
+
+// RegenerateSceneImage 重新生成单个场景的背景图：取消该场景仍在排队或进行中的生成，
+// prompt 非空时同时更新场景提示词，为空时使用场景已保存的提示词
+func (s *ImageGenerationService) RegenerateSceneImage(sceneID string, prompt string) (*models.ImageGeneration, error) {
+	sid, err := strconv.ParseUint(sceneID, 10, 32)
+	if err != nil {
+		return nil, &ServiceError{Kind: ErrInvalidInput, Message: "无效的场景ID"}
+	}
+	sceneIDUint := uint(sid)
+
+	var scene models.Scene
+	if err := s.db.Where("id = ?", sceneIDUint).First(&scene).Error; err != nil {
+		return nil, ErrSceneNotFound
+	}
+
+	prompt = utils.SanitizePrompt(prompt)
+	if prompt != "" && prompt != scene.Prompt {
+		if err := s.db.Model(&scene).Update("prompt", prompt).Error; err != nil {
+			return nil, fmt.Errorf("更新场景提示词失败: %w", err)
+		}
+		if err := markSceneFieldsEdited(s.db, &scene, SceneFieldPrompt); err != nil {
+			s.log.Warnw("Failed to mark scene prompt as edited", "error", err, "scene_id", sceneIDUint)
+		}
+	}
+	if prompt == "" {
+		prompt = strings.TrimSpace(scene.Prompt)
+	}
+	if prompt == "" {
+		prompt = fmt.Sprintf("%s场景，%s", scene.Location, scene.Time)
+	}
+
+	if cancelled := s.cancelSceneImageGenerations(sceneIDUint); cancelled > 0 {
+		s.log.Infow("Cancelled in-flight scene image generations", "scene_id", sceneIDUint, "count", cancelled)
+	}
+
+	if err := s.db.Model(&models.Scene{}).Where("id = ?", sceneIDUint).Update("status", "generating").Error; err != nil {
+		s.log.Warnw("Failed to mark scene as generating", "error", err, "scene_id", sceneIDUint)
+	}
+
+	imageGen, err := s.GenerateImage(&GenerateImageRequest{
+		SceneID:   &sceneIDUint,
+		DramaID:   fmt.Sprintf("%d", scene.DramaID),
+		ImageType: string(models.ImageTypeScene),
+		Prompt:    prompt,
+	})
+	if err != nil {
+		s.db.Model(&models.Scene{}).Where("id = ?", sceneIDUint).Update("status", "failed")
+		return nil, err
+	}
+
+	s.log.Infow("Scene image regeneration queued", "scene_id", sceneIDUint, "image_gen_id", imageGen.ID)
+	return imageGen, nil
+}
+
+// cancelSceneImageGenerations 将场景仍在排队或进行中的背景图生成标记为已取消，返回取消数量
+// 已在服务商处执行的请求无法中止，其结果在完成时会被丢弃
+func (s *ImageGenerationService) cancelSceneImageGenerations(sceneID uint) int64 {
+	now := time.Now()
+	result := s.db.Model(&models.ImageGeneration{}).
+		Where("scene_id = ? AND image_type = ? AND status IN ?", sceneID, string(models.ImageTypeScene),
+			[]models.ImageGenerationStatus{models.ImageStatusPending, models.ImageStatusProcessing}).
+		Updates(map[string]interface{}{
+			"status":       models.ImageStatusCancelled,
+			"error_msg":    "已被新的生成任务替代",
+			"completed_at": now,
+		})
+	if result.Error != nil {
+		s.log.Warnw("Failed to cancel scene image generations", "error", result.Error, "scene_id", sceneID)
+		return 0
+	}
+	return result.RowsAffected
+}
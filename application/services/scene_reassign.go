@@ -0,0 +1,67 @@
+package services
+
+import (
+	"fmt"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"gorm.io/gorm"
+)
+
+// SceneReassignResult 场景移动到其他剧集的结果，UnlinkedStoryboards 为原剧集中解除关联的分镜数
+type SceneReassignResult struct {
+	SceneID             uint  `json:"scene_id"`
+	FromEpisodeID       *uint `json:"from_episode_id"`
+	ToEpisodeID         uint  `json:"to_episode_id"`
+	UnlinkedStoryboards int64 `json:"unlinked_storyboards"`
+}
+
+// ReassignScene 将场景移动到同一剧本的另一集；原剧集中引用该场景的分镜解除关联，
+// 避免分镜指向不属于本集的场景，移动后可在新剧集中重新关联
+func (s *StoryboardCompositionService) ReassignScene(sceneID string, newEpisodeID uint) (*SceneReassignResult, error) {
+	var scene models.Scene
+	if err := s.db.Where("id = ?", sceneID).First(&scene).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrSceneNotFound
+		}
+		return nil, fmt.Errorf("failed to find scene: %w", err)
+	}
+
+	var target models.Episode
+	if err := s.db.Select("id", "drama_id").Where("id = ?", newEpisodeID).First(&target).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrEpisodeNotFound
+		}
+		return nil, fmt.Errorf("failed to find episode: %w", err)
+	}
+	if target.DramaID != scene.DramaID {
+		return nil, &ServiceError{Kind: ErrInvalidInput, Message: "目标剧集与场景不属于同一剧本"}
+	}
+	if scene.EpisodeID != nil && *scene.EpisodeID == target.ID {
+		return nil, &ServiceError{Kind: ErrInvalidInput, Message: "场景已属于该剧集"}
+	}
+
+	result := &SceneReassignResult{SceneID: scene.ID, FromEpisodeID: scene.EpisodeID, ToEpisodeID: target.ID}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Scene{}).Where("id = ?", scene.ID).Update("episode_id", target.ID).Error; err != nil {
+			return err
+		}
+		if scene.EpisodeID == nil {
+			return nil
+		}
+		unlinked := tx.Model(&models.Storyboard{}).
+			Where("episode_id = ? AND scene_id = ?", *scene.EpisodeID, scene.ID).
+			Update("scene_id", nil)
+		if unlinked.Error != nil {
+			return unlinked.Error
+		}
+		result.UnlinkedStoryboards = unlinked.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to reassign scene: %w", err)
+	}
+
+	s.log.Infow("Scene reassigned", "scene_id", scene.ID, "from_episode_id", scene.EpisodeID,
+		"to_episode_id", target.ID, "unlinked_storyboards", result.UnlinkedStoryboards)
+	return result, nil
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"fmt"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+// RegenerateAllSceneImages 为剧本下所有场景重新生成图片（异步），正在生成中的场景会被跳过
+func (s *ImageGenerationService) RegenerateAllSceneImages(dramaID string) (string, error) {
+	var drama models.Drama
+	if err := s.db.Where("id = ?", dramaID).First(&drama).Error; err != nil {
+		return "", ErrDramaNotFound
+	}
+
+	var sceneCount int64
+	if err := s.db.Model(&models.Scene{}).Where("drama_id = ?", drama.ID).Count(&sceneCount).Error; err != nil {
+		return "", fmt.Errorf("获取场景失败: %w", err)
+	}
+	if sceneCount == 0 {
+		return "", fmt.Errorf("该剧本还没有场景")
+	}
+
+	task, err := s.taskService.CreateTask("scene_regeneration", dramaID)
+	if err != nil {
+		s.log.Errorw("Failed to create task", "error", err)
+		return "", fmt.Errorf("创建任务失败: %w", err)
+	}
+
+	s.log.Infow("Regenerating all scene images asynchronously",
+		"task_id", task.ID,
+		"drama_id", dramaID,
+		"scene_count", sceneCount)
+
+	go s.processSceneRegeneration(task.ID, drama.ID)
+
+	return task.ID, nil
+}
+
+// processSceneRegeneration 逐个场景提交生成请求，实际调用由全局队列按并发上限执行
+func (s *ImageGenerationService) processSceneRegeneration(taskID string, dramaID uint) {
+	if err := s.taskService.UpdateTaskStatus(taskID, "processing", 10, "正在提交场景图片生成..."); err != nil {
+		s.log.Errorw("Failed to update task status", "error", err, "task_id", taskID)
+		return
+	}
+
+	var scenes []models.Scene
+	if err := s.db.Where("drama_id = ?", dramaID).Order("id ASC").Find(&scenes).Error; err != nil {
+		s.log.Errorw("Failed to load scenes", "error", err, "task_id", taskID)
+		if updateErr := s.taskService.UpdateTaskError(taskID, fmt.Errorf("获取场景失败: %w", err)); updateErr != nil {
+			s.log.Errorw("Failed to update task error", "error", updateErr, "task_id", taskID)
+		}
+		return
+	}
+
+	// 有 pending/processing 生成记录的场景视为正在生成
+	var busySceneIDs []uint
+	if err := s.db.Model(&models.ImageGeneration{}).
+		Where("drama_id = ? AND scene_id IS NOT NULL AND status IN ?", dramaID,
+			[]models.ImageGenerationStatus{models.ImageStatusPending, models.ImageStatusProcessing}).
+		Distinct().Pluck("scene_id", &busySceneIDs).Error; err != nil {
+		s.log.Warnw("Failed to load generating scenes", "error", err, "task_id", taskID)
+	}
+	busy := make(map[uint]bool, len(busySceneIDs))
+	for _, id := range busySceneIDs {
+		busy[id] = true
+	}
+
+	var imageGenIDs []uint
+	var skipped []uint
+	failed := 0
+	for i, scene := range scenes {
+		if busy[scene.ID] || scene.Status == "generating" {
+			skipped = append(skipped, scene.ID)
+			continue
+		}
+
+		imageGens, err := s.GenerateImagesForScene(fmt.Sprintf("%d", scene.ID))
+		if err != nil {
+			failed++
+			s.log.Errorw("Failed to regenerate scene image", "error", err, "scene_id", scene.ID, "task_id", taskID)
+			continue
+		}
+		for _, imageGen := range imageGens {
+			imageGenIDs = append(imageGenIDs, imageGen.ID)
+		}
+
+		progress := 10 + (i+1)*80/len(scenes)
+		if err := s.taskService.UpdateTaskStatus(taskID, "processing", progress,
+			fmt.Sprintf("已提交 %d/%d 个场景", i+1, len(scenes))); err != nil {
+			s.log.Warnw("Failed to update task progress", "error", err, "task_id", taskID)
+		}
+	}
+
+	s.log.Infow("Scene regeneration submitted",
+		"task_id", taskID,
+		"drama_id", dramaID,
+		"queued", len(imageGenIDs),
+		"skipped", len(skipped),
+		"failed", failed)
+
+	if err := s.taskService.UpdateTaskResult(taskID, gin.H{
+		"queued":               len(imageGenIDs),
+		"skipped":              len(skipped),
+		"failed":               failed,
+		"total":                len(scenes),
+		"image_generation_ids": imageGenIDs,
+		"skipped_scene_ids":    skipped,
+	}); err != nil {
+		s.log.Errorw("Failed to update task result", "error", err, "task_id", taskID)
+	}
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/ai"
+	"github.com/drama-generator/backend/pkg/utils"
+)
+
+// styleCheckBatchSize 每次请求视觉模型时与参考图一起对比的场景图数量
+const styleCheckBatchSize = 6
+
+// StyleInconsistency 画风与参考图不一致的场景
+type StyleInconsistency struct {
+	SceneID  uint   `json:"scene_id"`
+	Location string `json:"location"`
+	Time     string `json:"time"`
+	Note     string `json:"note"`
+}
+
+// StyleConsistencyResult 剧本场景图画风检查结果，Inconsistent 中的场景建议重新生成
+type StyleConsistencyResult struct {
+	ReferenceSceneID uint                 `json:"reference_scene_id"`
+	Checked          int                  `json:"checked"`
+	Inconsistent     []StyleInconsistency `json:"inconsistent"`
+	SkippedSceneIDs  []uint               `json:"skipped_scene_ids"` // 没有图片或图片无法读取的场景
+	FailedSceneIDs   []uint               `json:"failed_scene_ids"`  // 视觉模型调用或解析失败、未能完成检查的场景
+}
+
+// styleCheckItem 视觉模型返回的单个不一致场景，index 为图片序号（参考图为 1）
+type styleCheckItem struct {
+	Index int    `json:"index"`
+	Note  string `json:"note"`
+}
+
+// styleCheckResponse 视觉模型返回的画风检查结果
+type styleCheckResponse struct {
+	Inconsistent []styleCheckItem `json:"inconsistent"`
+}
+
+// styleCheckScene 待检查的场景及其可供视觉模型读取的图片
+type styleCheckScene struct {
+	scene models.Scene
+	image string
+}
+
+// CheckStyleConsistency 创建画风检查任务，在后台用视觉模型将剧本的场景图与参考场景图逐批对比，立即返回任务ID
+// 任务结果为 StyleConsistencyResult；referenceSceneID 为 0 时使用第一个有图片的场景作为参考；需在配置中开启 style_consistency_check
+func (s *ImageGenerationService) CheckStyleConsistency(dramaID string, referenceSceneID uint) (string, error) {
+	cfg := s.cfg()
+	if !cfg.AI.StyleConsistencyCheck {
+		return "", &ServiceError{Kind: ErrInvalidInput, Message: "未开启画风一致性检查（ai.style_consistency_check）"}
+	}
+
+	var drama models.Drama
+	if err := s.db.Where("id = ?", dramaID).First(&drama).Error; err != nil {
+		return "", ErrDramaNotFound
+	}
+
+	var scenes []models.Scene
+	if err := s.db.Where("drama_id = ?", drama.ID).Order("id ASC").Find(&scenes).Error; err != nil {
+		return "", fmt.Errorf("获取场景失败: %w", err)
+	}
+
+	result := &StyleConsistencyResult{
+		Inconsistent:    []StyleInconsistency{},
+		SkippedSceneIDs: []uint{},
+		FailedSceneIDs:  []uint{},
+	}
+	var reference *styleCheckScene
+	var candidates []styleCheckScene
+	for _, scene := range scenes {
+		img := s.sceneVisionImage(scene)
+		if img == "" {
+			if scene.ID == referenceSceneID {
+				return "", &ServiceError{Kind: ErrInvalidInput, Message: "参考场景还没有可用的图片"}
+			}
+			result.SkippedSceneIDs = append(result.SkippedSceneIDs, scene.ID)
+			continue
+		}
+		item := styleCheckScene{scene: scene, image: img}
+		if reference == nil && (referenceSceneID == 0 || scene.ID == referenceSceneID) {
+			reference = &item
+			continue
+		}
+		candidates = append(candidates, item)
+	}
+	if reference == nil {
+		if referenceSceneID != 0 {
+			return "", &ServiceError{Kind: ErrNotFound, Message: "参考场景不存在或不属于该剧本"}
+		}
+		return "", &ServiceError{Kind: ErrInvalidInput, Message: "该剧本还没有已生成图片的场景"}
+	}
+	result.ReferenceSceneID = reference.scene.ID
+
+	client, err := s.aiService.GetVisionClient(cfg.AI.StyleConsistencyModel)
+	if err != nil {
+		return "", err
+	}
+
+	task, err := s.taskService.CreateTask("style_consistency_check", dramaID)
+	if err != nil {
+		s.log.Errorw("Failed to create style consistency check task", "error", err, "drama_id", drama.ID)
+		return "", fmt.Errorf("创建任务失败: %w", err)
+	}
+
+	go s.processStyleConsistencyCheck(task.ID, client, drama, *reference, candidates, result)
+
+	s.log.Infow("Style consistency check task created", "task_id", task.ID, "drama_id", drama.ID, "reference_scene_id", result.ReferenceSceneID, "scenes", len(candidates))
+	return task.ID, nil
+}
+
+// processStyleConsistencyCheck 后台逐批对比场景图，完成后写入任务结果
+func (s *ImageGenerationService) processStyleConsistencyCheck(taskID string, client ai.VisionClient, drama models.Drama, reference styleCheckScene, candidates []styleCheckScene, result *StyleConsistencyResult) {
+	i18n := s.promptI18n.WithLanguage(drama.Language)
+	for start := 0; start < len(candidates); start += styleCheckBatchSize {
+		progress := start * 100 / len(candidates)
+		s.taskService.UpdateTaskStatus(taskID, "processing", progress,
+			fmt.Sprintf("正在检查画风 (%d/%d)...", start, len(candidates)))
+
+		batch := candidates[start:min(start+styleCheckBatchSize, len(candidates))]
+		items, err := s.checkStyleBatch(client, i18n, drama.Style, reference, batch)
+		if err != nil {
+			s.log.Warnw("Style consistency check failed for batch", "error", err, "task_id", taskID, "drama_id", drama.ID, "batch_start", start)
+			for _, c := range batch {
+				result.FailedSceneIDs = append(result.FailedSceneIDs, c.scene.ID)
+			}
+			continue
+		}
+		result.Checked += len(batch)
+
+		// 序号 1 为参考图，场景图从 2 开始
+		for _, item := range items {
+			i := item.Index - 2
+			if i < 0 || i >= len(batch) {
+				continue
+			}
+			scene := batch[i].scene
+			result.Inconsistent = append(result.Inconsistent, StyleInconsistency{
+				SceneID:  scene.ID,
+				Location: scene.Location,
+				Time:     scene.Time,
+				Note:     item.Note,
+			})
+		}
+	}
+
+	s.log.Infow("Style consistency check completed",
+		"task_id", taskID,
+		"drama_id", drama.ID,
+		"reference_scene_id", result.ReferenceSceneID,
+		"checked", result.Checked,
+		"inconsistent", len(result.Inconsistent),
+		"failed", len(result.FailedSceneIDs))
+	if err := s.taskService.UpdateTaskResult(taskID, result); err != nil {
+		s.log.Errorw("Failed to update task result", "error", err, "task_id", taskID)
+	}
+}
+
+// checkStyleBatch 将一批场景图与参考图一起发送给视觉模型，返回画风不一致的图片序号
+func (s *ImageGenerationService) checkStyleBatch(client ai.VisionClient, i18n *PromptI18n, style string, reference styleCheckScene, batch []styleCheckScene) ([]styleCheckItem, error) {
+	images := make([]string, 0, len(batch)+1)
+	images = append(images, reference.image)
+	var sceneList []string
+	for i, c := range batch {
+		images = append(images, c.image)
+		sceneList = append(sceneList, i18n.FormatUserPrompt("style_check_scene_item", i+2, c.scene.Location, c.scene.Time))
+	}
+
+	prompt := i18n.GetStyleConsistencyCheckPrompt(style, strings.Join(sceneList, "\n"))
+
+	text, err := client.GenerateTextWithImages(prompt, "", images, ai.WithTemperature(0))
+	if err != nil {
+		return nil, err
+	}
+
+	var resp styleCheckResponse
+	if err := utils.SafeParseAIJSON(text, &resp); err != nil {
+		return nil, fmt.Errorf("解析画风检查结果失败: %w", err)
+	}
+	return resp.Inconsistent, nil
+}
+
+// sceneVisionImage 场景图的视觉模型输入：优先读取本地文件转为 data URI，否则使用远程地址，没有图片时返回空
+func (s *ImageGenerationService) sceneVisionImage(scene models.Scene) string {
+	if scene.LocalPath != nil && *scene.LocalPath != "" {
+		if dataURI, err := s.loadImageAsBase64(*scene.LocalPath); err == nil {
+			return dataURI
+		}
+	}
+	return getString(scene.ImageURL)
+}
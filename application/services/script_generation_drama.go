@@ -0,0 +1,170 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/ai"
+)
+
+// dramaCharactersPerEpisode 全剧角色提取时每集最多提取的角色数
+const dramaCharactersPerEpisode = 10
+
+// dramaCharacter 全剧提取中按名字合并后的角色及其出场剧集
+type dramaCharacter struct {
+	profile    generatedCharacter
+	episodeIDs []uint
+}
+
+// GenerateCharactersForDrama 逐集从剧本中提取角色，按名字在全剧范围去重，并关联到角色出场的各集（异步），返回任务ID
+func (s *ScriptGenerationService) GenerateCharactersForDrama(dramaID string) (string, error) {
+	var drama models.Drama
+	if err := s.db.Where("id = ?", dramaID).First(&drama).Error; err != nil {
+		return "", ErrDramaNotFound
+	}
+
+	var count int64
+	if err := s.db.Model(&models.Episode{}).
+		Where("drama_id = ? AND script_content IS NOT NULL AND script_content != ''", drama.ID).
+		Count(&count).Error; err != nil {
+		return "", fmt.Errorf("获取剧集失败: %w", err)
+	}
+	if count == 0 {
+		return "", &ServiceError{Kind: ErrInvalidInput, Message: "该剧本还没有包含剧本内容的剧集"}
+	}
+
+	task, err := s.taskService.CreateTask("drama_character_generation", dramaID)
+	if err != nil {
+		s.log.Errorw("Failed to create drama character generation task", "error", err)
+		return "", fmt.Errorf("创建任务失败: %w", err)
+	}
+
+	go s.processDramaCharacterGeneration(task.ID, drama)
+
+	s.log.Infow("Drama character generation task created", "task_id", task.ID, "drama_id", dramaID, "episodes", count)
+	return task.ID, nil
+}
+
+// processDramaCharacterGeneration 逐集提取角色；单集失败时记录原因并继续处理其余剧集
+func (s *ScriptGenerationService) processDramaCharacterGeneration(taskID string, drama models.Drama) {
+	s.taskService.UpdateTaskStatus(taskID, "processing", 0, "正在逐集提取角色...")
+
+	var episodes []models.Episode
+	if err := s.db.Where("drama_id = ? AND script_content IS NOT NULL AND script_content != ''", drama.ID).
+		Order("episode_number ASC").Find(&episodes).Error; err != nil {
+		s.taskService.UpdateTaskError(taskID, fmt.Errorf("获取剧集失败: %w", err))
+		return
+	}
+
+	client, model, err := s.aiService.GetAIClientForDrama("text", drama.ID, "")
+	if err != nil {
+		s.log.Errorw("Failed to get AI client", "error", err, "task_id", taskID)
+		s.taskService.UpdateTaskError(taskID, fmt.Errorf("AI生成失败: %w", err))
+		return
+	}
+	s.log.Infow("Using model for drama character generation", "model", model, "task_id", taskID)
+
+	i18n := s.promptI18n.WithLanguage(drama.Language)
+	systemPrompt := i18n.GetCharacterExtractionPrompt(drama.Style)
+
+	// 按名字（忽略大小写和首尾空白）合并各集的角色，保留首次出现时的设定
+	var order []string
+	merged := make(map[string]*dramaCharacter)
+	failedEpisodes := make(map[uint]string)
+	for i, episode := range episodes {
+		s.taskService.UpdateTaskStatus(taskID, "processing", 80*i/len(episodes),
+			fmt.Sprintf("正在提取第 %d/%d 集的角色...", i+1, len(episodes)))
+
+		userPrompt := i18n.FormatUserPrompt("character_request", getString(episode.ScriptContent), dramaCharactersPerEpisode)
+		chars, err := GenerateStructured[[]generatedCharacter](s.aiService, client, i18n, userPrompt, systemPrompt, ai.WithTemperature(0.7))
+		if err != nil {
+			s.log.Warnw("Failed to extract characters for episode", "error", err, "episode_id", episode.ID, "task_id", taskID)
+			failedEpisodes[episode.ID] = err.Error()
+			continue
+		}
+
+		for _, char := range dedupGeneratedCharacters(chars) {
+			key := characterNameKey(char.Name)
+			if existing, ok := merged[key]; ok {
+				existing.episodeIDs = append(existing.episodeIDs, episode.ID)
+				continue
+			}
+			char.Name = strings.TrimSpace(char.Name)
+			merged[key] = &dramaCharacter{profile: char, episodeIDs: []uint{episode.ID}}
+			order = append(order, key)
+		}
+	}
+
+	if len(failedEpisodes) == len(episodes) {
+		s.taskService.UpdateTaskError(taskID, fmt.Errorf("所有剧集的角色提取均失败"))
+		return
+	}
+
+	s.taskService.UpdateTaskStatus(taskID, "processing", 90, "正在保存角色...")
+
+	var characters []models.Character
+	episodeCharacters := make(map[uint][]models.Character)
+	for _, key := range order {
+		entry := merged[key]
+		character, err := s.findOrCreateDramaCharacter(drama.ID, entry.profile)
+		if err != nil {
+			s.log.Errorw("Failed to create character", "error", err, "name", entry.profile.Name, "task_id", taskID)
+			continue
+		}
+		characters = append(characters, *character)
+		for _, episodeID := range entry.episodeIDs {
+			episodeCharacters[episodeID] = append(episodeCharacters[episodeID], *character)
+		}
+	}
+
+	appearances := make(map[uint][]uint, len(episodeCharacters))
+	for _, episode := range episodes {
+		chars := episodeCharacters[episode.ID]
+		if len(chars) == 0 {
+			continue
+		}
+		if err := s.db.Model(&episode).Association("Characters").Append(chars); err != nil {
+			s.log.Errorw("Failed to associate characters with episode", "error", err, "episode_id", episode.ID, "task_id", taskID)
+			continue
+		}
+		for _, char := range chars {
+			appearances[episode.ID] = append(appearances[episode.ID], char.ID)
+		}
+	}
+
+	s.taskService.UpdateTaskResult(taskID, map[string]interface{}{
+		"characters":      characters,
+		"count":           len(characters),
+		"episodes":        appearances,
+		"failed_episodes": failedEpisodes,
+	})
+
+	s.log.Infow("Drama character generation completed",
+		"task_id", taskID,
+		"drama_id", drama.ID,
+		"character_count", len(characters),
+		"failed_episodes", len(failedEpisodes))
+}
+
+// findOrCreateDramaCharacter 剧本中已有同名角色（与合并时相同，忽略大小写和首尾空白）时直接使用（不覆盖已有设定），否则创建新角色
+func (s *ScriptGenerationService) findOrCreateDramaCharacter(dramaID uint, char generatedCharacter) (*models.Character, error) {
+	var existing models.Character
+	if err := s.db.Where("drama_id = ? AND LOWER(TRIM(name)) = ?", dramaID, characterNameKey(char.Name)).First(&existing).Error; err == nil {
+		return &existing, nil
+	}
+
+	character := models.Character{
+		DramaID:     dramaID,
+		Name:        char.Name,
+		Role:        &char.Role,
+		Description: &char.Description,
+		Personality: &char.Personality,
+		Appearance:  &char.Appearance,
+		VoiceStyle:  &char.VoiceStyle,
+	}
+	if err := s.db.Create(&character).Error; err != nil {
+		return nil, err
+	}
+	return &character, nil
+}
@@ -3,12 +3,12 @@ package services
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/drama-generator/backend/domain/models"
 	"github.com/drama-generator/backend/pkg/ai"
 	"github.com/drama-generator/backend/pkg/config"
 	"github.com/drama-generator/backend/pkg/logger"
-	"github.com/drama-generator/backend/pkg/utils"
 	"gorm.io/gorm"
 )
 
@@ -44,7 +44,7 @@ type GenerateCharactersRequest struct {
 func (s *ScriptGenerationService) GenerateCharacters(req *GenerateCharactersRequest) (string, error) {
 	var drama models.Drama
 	if err := s.db.Where("id = ? ", req.DramaID).First(&drama).Error; err != nil {
-		return "", fmt.Errorf("drama not found")
+		return "", ErrDramaNotFound
 	}
 
 	// 创建任务
@@ -79,59 +79,67 @@ func (s *ScriptGenerationService) processCharacterGeneration(taskID string, req
 		return
 	}
 
-	systemPrompt := s.promptI18n.GetCharacterExtractionPrompt(drama.Style)
+	i18n := s.promptI18n.WithLanguage(drama.Language)
+	systemPrompt := i18n.GetCharacterExtractionPrompt(drama.Style)
 
 	outlineText := req.Outline
 	if outlineText == "" {
-		outlineText = s.promptI18n.FormatUserPrompt("drama_info_template", drama.Title, drama.Description, drama.Genre)
+		outlineText = i18n.FormatUserPrompt("drama_info_template", drama.Title, drama.Description, drama.Genre)
 	}
 
-	userPrompt := s.promptI18n.FormatUserPrompt("character_request", outlineText, count)
+	userPrompt := i18n.FormatUserPrompt("character_request", outlineText, count)
 
 	temperature := req.Temperature
 	if temperature == 0 {
 		temperature = 0.7
 	}
 
-	// 如果指定了模型，使用指定的模型；否则使用默认配置
-	var text string
-	var err error
-	if req.Model != "" {
-		s.log.Infow("Using specified model for character generation", "model", req.Model, "task_id", taskID)
-		client, getErr := s.aiService.GetAIClientForModel("text", req.Model)
-		if getErr != nil {
-			s.log.Warnw("Failed to get client for specified model, using default", "model", req.Model, "error", getErr, "task_id", taskID)
-			text, err = s.aiService.GenerateText(userPrompt, systemPrompt, ai.WithTemperature(temperature))
-		} else {
-			text, err = client.GenerateText(userPrompt, systemPrompt, ai.WithTemperature(temperature))
-		}
-	} else {
-		text, err = s.aiService.GenerateText(userPrompt, systemPrompt, ai.WithTemperature(temperature))
+	// 剧本绑定了配置时使用绑定的配置；否则如果指定了模型，使用指定的模型；否则使用默认配置
+	client, model, err := s.aiService.GetAIClientForDrama("text", drama.ID, req.Model)
+	if err != nil {
+		s.log.Errorw("Failed to get AI client", "error", err, "task_id", taskID)
+		s.taskService.UpdateTaskError(taskID, fmt.Errorf("AI生成失败: %w", err))
+		return
 	}
+	s.log.Infow("Using model for character generation", "model", model, "task_id", taskID)
 
+	text, err := client.GenerateText(userPrompt, systemPrompt, ai.WithTemperature(temperature))
 	if err != nil {
 		s.log.Errorw("Failed to generate characters", "error", err, "task_id", taskID)
-		s.taskService.UpdateTaskStatus(taskID, "failed", 0, "AI生成失败: "+err.Error())
+		s.taskService.UpdateTaskError(taskID, fmt.Errorf("AI生成失败: %w", err))
 		return
 	}
 
 	s.log.Infow("AI response received for character generation", "length", len(text), "preview", text[:minInt(200, len(text))], "task_id", taskID)
 
-	// AI直接返回数组格式
-	var result []struct {
-		Name        string `json:"name"`
-		Role        string `json:"role"`
-		Description string `json:"description"`
-		Personality string `json:"personality"`
-		Appearance  string `json:"appearance"`
-		VoiceStyle  string `json:"voice_style"`
-	}
-
-	if err := utils.SafeParseAIJSON(text, &result); err != nil {
+	// AI直接返回数组格式，无法解析时请求AI修复
+	result, err := repairStructured(s.aiService, client, i18n, text, systemPrompt, parseAIJSON[[]generatedCharacter], ai.WithTemperature(temperature))
+	if err != nil {
 		s.log.Errorw("Failed to parse characters JSON", "error", err, "raw_response", text[:minInt(500, len(text))], "task_id", taskID)
 		s.taskService.UpdateTaskStatus(taskID, "failed", 0, "解析AI返回结果失败")
 		return
 	}
+	result = dedupGeneratedCharacters(result)
+
+	// 数量不足时追加请求剩余角色，排除已生成的名字；追加失败时保留已有结果
+	for attempt := 1; attempt < maxCharacterGenerationAttempts && len(result) < count-characterCountTolerance(count); attempt++ {
+		remaining := count - len(result)
+		s.log.Infow("Too few characters generated, requesting more",
+			"task_id", taskID, "requested", count, "generated", len(result), "remaining", remaining)
+		s.taskService.UpdateTaskStatus(taskID, "processing", 50, fmt.Sprintf("角色数量不足，正在补充 %d 个角色...", remaining))
+
+		morePrompt := i18n.FormatUserPrompt("character_more_request", outlineText, generatedCharacterNames(result), remaining)
+		more, err := GenerateStructured[[]generatedCharacter](s.aiService, client, i18n, morePrompt, systemPrompt, ai.WithTemperature(temperature))
+		if err != nil {
+			s.log.Warnw("Follow-up character generation failed", "error", err, "task_id", taskID)
+			break
+		}
+		result = dedupGeneratedCharacters(append(result, more...))
+	}
+	if len(result) > count+characterCountTolerance(count) {
+		s.log.Infow("Too many characters generated, truncating", "task_id", taskID, "requested", count, "generated", len(result))
+		result = result[:count]
+	}
 
 	var characters []models.Character
 	for _, char := range result {
@@ -190,6 +198,53 @@ func (s *ScriptGenerationService) processCharacterGeneration(taskID string, req
 	s.log.Infow("Character generation completed", "task_id", taskID, "drama_id", req.DramaID, "character_count", len(characters))
 }
 
+// maxCharacterGenerationAttempts 角色生成最多请求次数：首次请求 + 一次补充请求
+const maxCharacterGenerationAttempts = 2
+
+// generatedCharacter AI返回的角色设定
+type generatedCharacter struct {
+	Name        string `json:"name"`
+	Role        string `json:"role"`
+	Description string `json:"description"`
+	Personality string `json:"personality"`
+	Appearance  string `json:"appearance"`
+	VoiceStyle  string `json:"voice_style"`
+}
+
+// characterCountTolerance 生成数量与请求数量允许的偏差，每 10 个角色允许差 1 个
+func characterCountTolerance(count int) int {
+	return count / 10
+}
+
+// dedupGeneratedCharacters 按名字去重（忽略大小写和首尾空白），丢弃无名角色，保持原顺序
+func dedupGeneratedCharacters(chars []generatedCharacter) []generatedCharacter {
+	seen := make(map[string]bool, len(chars))
+	result := make([]generatedCharacter, 0, len(chars))
+	for _, char := range chars {
+		key := characterNameKey(char.Name)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, char)
+	}
+	return result
+}
+
+// characterNameKey 角色名的比较键，忽略大小写和首尾空白
+func characterNameKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// generatedCharacterNames 已生成角色的名字列表，用于补充请求中排除
+func generatedCharacterNames(chars []generatedCharacter) string {
+	names := make([]string, len(chars))
+	for i, char := range chars {
+		names[i] = char.Name
+	}
+	return strings.Join(names, ", ")
+}
+
 // GenerateScenesForEpisode 已废弃，使用 StoryboardService.GenerateStoryboard 替代
 // ParseScript 已废弃，使用 GenerateCharacters 替代
 
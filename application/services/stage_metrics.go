@@ -0,0 +1,62 @@
+package services
+
+import (
+	"time"
+
+	"github.com/drama-generator/backend/pkg/ai"
+	"github.com/drama-generator/backend/pkg/metrics"
+)
+
+// 指标中的任务类型
+const (
+	metricTaskStoryboard = "storyboard"
+	metricTaskImage      = "image"
+)
+
+// 生成流程各阶段名称
+const (
+	StageAICall       = "ai_call"       // 调用文本模型
+	StageParse        = "parse"         // 解析AI返回结果
+	StageSave         = "save"          // 写入数据库
+	StageProviderCall = "provider_call" // 调用图片服务商
+	StagePoll         = "poll"          // 异步任务轮询等待
+	StageDownload     = "download"      // 下载结果到本地存储
+)
+
+var stageDuration = metrics.NewHistogram(
+	"drama_stage_duration_seconds",
+	"Duration of each generation stage in seconds",
+	metrics.DefaultDurationBuckets,
+	"task_type", "stage", "provider",
+)
+
+// textClientProvider 返回文本客户端对应的服务商，用作指标标签
+func textClientProvider(client interface{}) string {
+	if limited, ok := client.(*rateLimitedClient); ok {
+		return limited.provider
+	}
+	return "unknown"
+}
+
+// acquireTextCall 限流客户端先在共享限流器排队，再返回绕过限流的内层客户端，排队时间不计入 ai_call 阶段耗时
+func acquireTextCall(client ai.AIClient) ai.AIClient {
+	if limited, ok := client.(*rateLimitedClient); ok {
+		limited.throttle()
+		return limited.AIClient
+	}
+	return client
+}
+
+// acquireVisionCall 同 acquireTextCall，用于视觉模型调用
+func acquireVisionCall(client ai.VisionClient) ai.VisionClient {
+	if limited, ok := client.(*rateLimitedClient); ok {
+		limited.throttle()
+		return limited.AIClient
+	}
+	return client
+}
+
+// observeStage 记录从 start 到现在的阶段耗时
+func observeStage(taskType, stage, provider string, start time.Time) {
+	stageDuration.Observe(time.Since(start).Seconds(), taskType, stage, provider)
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/ai"
+)
+
+// bgmEnrichBatchSize 丰富BGM提示词时每次请求处理的镜头数
+const bgmEnrichBatchSize = 12
+
+// bgmShotInput 发送给AI的单个镜头信息
+type bgmShotInput struct {
+	ShotNumber  int    `json:"shot_number"`
+	Location    string `json:"location,omitempty"`
+	Time        string `json:"time,omitempty"`
+	Action      string `json:"action,omitempty"`
+	Dialogue    string `json:"dialogue,omitempty"`
+	Result      string `json:"result,omitempty"`
+	Emotion     string `json:"emotion,omitempty"`
+	Atmosphere  string `json:"atmosphere,omitempty"`
+	BgmPrompt   string `json:"bgm_prompt,omitempty"`
+	ContextOnly bool   `json:"context_only,omitempty"` // 只作为上下文的相邻镜头，不需要输出
+}
+
+// bgmPromptResult AI返回的单个镜头BGM提示词
+type bgmPromptResult struct {
+	ShotNumber int    `json:"shot_number"`
+	BgmPrompt  string `json:"bgm_prompt"`
+}
+
+// storyboardEmotion 从分镜描述中取出【情绪】一行
+func storyboardEmotion(description string) string {
+	for _, line := range strings.Split(description, "\n") {
+		if emotion, ok := strings.CutPrefix(strings.TrimSpace(line), "【情绪】"); ok {
+			return strings.TrimSpace(emotion)
+		}
+	}
+	return ""
+}
+
+// EnrichBgmPrompts 根据每个镜头的情绪、氛围和前后镜头，用AI重写剧集所有分镜的 bgm_prompt（异步），返回任务ID
+// 只更新 bgm_prompt 及随之变化的 video_prompt，不重新生成分镜；锁定的镜头保持不变
+func (s *StoryboardService) EnrichBgmPrompts(episodeID string, model string) (string, error) {
+	var episode models.Episode
+	if err := s.db.Preload("Drama").Where("id = ?", episodeID).First(&episode).Error; err != nil {
+		return "", ErrEpisodeNotFound
+	}
+
+	var count int64
+	if err := s.db.Model(&models.Storyboard{}).Where("episode_id = ?", episode.ID).Count(&count).Error; err != nil {
+		return "", fmt.Errorf("获取分镜失败: %w", err)
+	}
+	if count == 0 {
+		return "", &ServiceError{Kind: ErrInvalidInput, Message: "该剧集还没有分镜"}
+	}
+
+	client, model, err := s.aiService.GetAIClientForDrama("text", episode.DramaID, model)
+	if err != nil {
+		return "", err
+	}
+
+	task, err := s.taskService.CreateTask("bgm_prompt_enrichment", episodeID)
+	if err != nil {
+		s.log.Errorw("Failed to create task", "error", err)
+		return "", fmt.Errorf("创建任务失败: %w", err)
+	}
+
+	go s.processBgmEnrichment(task.ID, episode, client)
+
+	s.log.Infow("BGM prompt enrichment task created", "task_id", task.ID, "episode_id", episodeID, "storyboards", count, "model", model)
+	return task.ID, nil
+}
+
+// processBgmEnrichment 分批请求AI生成BGM提示词并写回分镜；单批失败时保留原提示词并继续处理其余批次
+func (s *StoryboardService) processBgmEnrichment(taskID string, episode models.Episode, client ai.AIClient) {
+	s.taskService.UpdateTaskStatus(taskID, "processing", 0, "正在生成BGM提示词...")
+
+	var storyboards []models.Storyboard
+	if err := s.db.Where("episode_id = ?", episode.ID).Order("storyboard_number ASC").Find(&storyboards).Error; err != nil {
+		s.taskService.UpdateTaskError(taskID, fmt.Errorf("获取分镜失败: %w", err))
+		return
+	}
+
+	inputs := make([]bgmShotInput, len(storyboards))
+	for i := range storyboards {
+		sb := &storyboards[i]
+		inputs[i] = bgmShotInput{
+			ShotNumber: sb.StoryboardNumber,
+			Location:   getString(sb.Location),
+			Time:       getString(sb.Time),
+			Action:     getString(sb.Action),
+			Dialogue:   getString(sb.Dialogue),
+			Result:     getString(sb.Result),
+			Emotion:    storyboardEmotion(getString(sb.Description)),
+			Atmosphere: getString(sb.Atmosphere),
+			BgmPrompt:  getString(sb.BgmPrompt),
+		}
+	}
+
+	i18n := s.promptI18n.WithLanguage(episode.Drama.Language)
+	systemPrompt := bgmEnrichSystemPrompt(i18n.IsEnglish())
+	videoRatio := s.videoRatioForEpisode(episode.ID)
+	videoSuffix := s.promptSuffixesForEpisode(episode.ID).Video
+
+	updated, skippedLocked, failedBatches := 0, 0, 0
+	batches := (len(storyboards) + bgmEnrichBatchSize - 1) / bgmEnrichBatchSize
+	for start := 0; start < len(storyboards); start += bgmEnrichBatchSize {
+		end := min(start+bgmEnrichBatchSize, len(storyboards))
+		s.taskService.UpdateTaskStatus(taskID, "processing", 90*start/len(storyboards),
+			fmt.Sprintf("正在生成第 %d/%d 批镜头的BGM提示词...", start/bgmEnrichBatchSize+1, batches))
+
+		// 前后各带一个镜头作为上下文，使相邻镜头的音乐衔接自然
+		var batch []bgmShotInput
+		if start > 0 {
+			prev := inputs[start-1]
+			prev.ContextOnly = true
+			batch = append(batch, prev)
+		}
+		batch = append(batch, inputs[start:end]...)
+		if end < len(inputs) {
+			next := inputs[end]
+			next.ContextOnly = true
+			batch = append(batch, next)
+		}
+		shotsJSON, _ := json.MarshalIndent(batch, "", "  ")
+
+		results, err := GenerateStructured[[]bgmPromptResult](s.aiService, client, i18n, string(shotsJSON), systemPrompt, ai.WithTemperature(0.7))
+		if err != nil {
+			s.log.Warnw("Failed to enrich BGM prompts for batch", "error", err, "task_id", taskID, "from_shot", inputs[start].ShotNumber)
+			failedBatches++
+			continue
+		}
+
+		prompts := make(map[int]string, len(results))
+		for _, r := range results {
+			if prompt := strings.TrimSpace(r.BgmPrompt); prompt != "" {
+				prompts[r.ShotNumber] = prompt
+			}
+		}
+		for i := start; i < end; i++ {
+			sb := &storyboards[i]
+			prompt, ok := prompts[sb.StoryboardNumber]
+			if !ok {
+				continue
+			}
+			if sb.Locked {
+				skippedLocked++
+				continue
+			}
+			// video_prompt 中带有 BGM 描述，需按新的 bgm_prompt 重新生成
+			sb.BgmPrompt = &prompt
+			videoPrompt := s.generateVideoPrompt(storyboardFromModel(sb), videoRatio, videoSuffix)
+			if err := s.db.Model(&models.Storyboard{}).Where("id = ?", sb.ID).Updates(map[string]interface{}{
+				"bgm_prompt":   prompt,
+				"video_prompt": videoPrompt,
+			}).Error; err != nil {
+				s.log.Errorw("Failed to save BGM prompt", "error", err, "storyboard_id", sb.ID)
+				continue
+			}
+			updated++
+		}
+	}
+
+	if failedBatches == batches {
+		s.taskService.UpdateTaskError(taskID, fmt.Errorf("BGM提示词生成失败"))
+		return
+	}
+
+	s.taskService.UpdateTaskResult(taskID, map[string]interface{}{
+		"episode_id":     episode.ID,
+		"updated":        updated,
+		"skipped_locked": skippedLocked,
+		"failed_batches": failedBatches,
+	})
+	s.log.Infow("BGM prompts enriched", "task_id", taskID, "episode_id", episode.ID, "updated", updated, "failed_batches", failedBatches)
+}
+
+// bgmEnrichSystemPrompt 生成BGM提示词的系统提示词
+func bgmEnrichSystemPrompt(english bool) string {
+	if english {
+		return `You are a film music supervisor. For each shot in the JSON list, write a specific background music prompt for a music generation model, based on the shot's emotion, atmosphere, action and dialogue, and on how the mood flows from the previous shot to the next.
+
+Each prompt should name the genre, instrumentation, tempo (BPM or slow/medium/fast), dynamics, and how the music should move within the shot (e.g. builds, drops out, resolves). Keep music continuous across adjacent shots with similar mood; change it clearly at emotional turning points. Improve on the existing bgm_prompt instead of repeating it. Keep each prompt under 60 words.
+
+Shots with "context_only": true are provided only as context; do not output them.
+
+Output only a JSON array, no explanation:
+[{"shot_number": 1, "bgm_prompt": "..."}]`
+	}
+	return `你是影视配乐总监。请根据列表中每个镜头的情绪、氛围、动作和对白，以及与前后镜头的情绪衔接，为音乐生成模型写出具体的背景音乐提示词。
+
+每条提示词需包含：曲风、主要乐器、速度（BPM 或 慢/中/快）、力度，以及音乐在镜头内的走向（如渐强、骤停、回落）。情绪相近的相邻镜头保持音乐连贯，情绪转折处要明确变化。在已有的 bgm_prompt 基础上改进，不要简单重复。每条不超过80字。
+
+标记为 "context_only": true 的镜头只作为上下文，不需要输出。
+
+只输出JSON数组，不要任何解释：
+[{"shot_number": 1, "bgm_prompt": "..."}]`
+}
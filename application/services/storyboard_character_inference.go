@@ -0,0 +1,87 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+
+	models "github.com/drama-generator/backend/domain/models"
+)
+
+// dialogueSpeakerPattern 匹配对话中的说话人，如 `陈峥："..."`、`李芳（低声）：...`、`John: "..."`
+var dialogueSpeakerPattern = regexp.MustCompile(`(?:^|[\s"”」。！？!?，,;；])([^\s：:"“”「」（）()，,。！？!?;；]{1,20})(?:[（(][^）)]*[）)])?\s*[：:]`)
+
+// inferCharactersFromDialogue AI返回的分镜缺少角色时，根据对话中的说话人补全角色关联
+func (s *StoryboardService) inferCharactersFromDialogue(taskID, episodeID string, storyboards []Storyboard) {
+	var episode models.Episode
+	if err := s.db.Select("id, drama_id").Where("id = ?", episodeID).First(&episode).Error; err != nil {
+		return
+	}
+
+	var characters []models.Character
+	if err := s.db.Where("drama_id = ?", episode.DramaID).Find(&characters).Error; err != nil || len(characters) == 0 {
+		return
+	}
+
+	inferred := 0
+	for i := range storyboards {
+		sb := &storyboards[i]
+		if len(sb.Characters) > 0 || sb.Dialogue == "" {
+			continue
+		}
+
+		ids := matchDialogueSpeakers(sb.Dialogue, characters)
+		if len(ids) == 0 {
+			continue
+		}
+
+		sb.Characters = ids
+		inferred++
+		s.log.Infow("Inferred storyboard characters from dialogue",
+			"task_id", taskID,
+			"shot_number", sb.ShotNumber,
+			"character_ids", ids,
+			"dialogue", sb.Dialogue)
+	}
+
+	if inferred > 0 {
+		s.log.Infow("Storyboard characters inferred", "task_id", taskID, "episode_id", episodeID, "count", inferred)
+	}
+}
+
+// matchDialogueSpeakers 提取对话中的说话人并与剧本角色匹配，按出现顺序返回去重后的角色ID
+func matchDialogueSpeakers(dialogue string, characters []models.Character) []uint {
+	var ids []uint
+	seen := make(map[uint]bool)
+	for _, m := range dialogueSpeakerPattern.FindAllStringSubmatch(dialogue, -1) {
+		speaker := strings.TrimSpace(m[1])
+		if speaker == "" {
+			continue
+		}
+		char := findCharacterByName(speaker, characters)
+		if char == nil || seen[char.ID] {
+			continue
+		}
+		seen[char.ID] = true
+		ids = append(ids, char.ID)
+	}
+	return ids
+}
+
+// findCharacterByName 优先精确匹配角色名，否则匹配包含关系（如"老陈峥"与"陈峥"），单字名不做模糊匹配
+func findCharacterByName(name string, characters []models.Character) *models.Character {
+	for i := range characters {
+		if strings.EqualFold(characters[i].Name, name) {
+			return &characters[i]
+		}
+	}
+	for i := range characters {
+		charName := characters[i].Name
+		if len([]rune(charName)) < 2 || len([]rune(name)) < 2 {
+			continue
+		}
+		if strings.Contains(name, charName) || strings.Contains(charName, name) {
+			return &characters[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package services
+
+import "strings"
+
+const (
+	chunkBoundaryMaxOverlap  = 3   // 段边界最多检查的重叠镜头数
+	chunkBoundarySimilarity  = 0.8 // 动作/对话相似度达到该值视为同一镜头
+	chunkBoundaryMinTextSize = 4   // 动作和对话都过短时不做判断，避免误删
+)
+
+// mergeStoryboardChunks 合并分段生成的分镜：上一段末尾与下一段开头重复的镜头只保留一份，并重新编号
+func mergeStoryboardChunks(chunks [][]Storyboard) []Storyboard {
+	var merged []Storyboard
+	for _, chunk := range chunks {
+		overlap := chunkBoundaryOverlap(merged, chunk)
+		merged = append(merged, chunk[overlap:]...)
+	}
+
+	for i := range merged {
+		merged[i].ShotNumber = i + 1
+	}
+	return merged
+}
+
+// chunkBoundaryOverlap 返回 next 开头与 prev 末尾重复的镜头数，优先匹配最长的重叠
+func chunkBoundaryOverlap(prev, next []Storyboard) int {
+	maxOverlap := min(chunkBoundaryMaxOverlap, min(len(prev), len(next)))
+	for n := maxOverlap; n > 0; n-- {
+		tail := prev[len(prev)-n:]
+		matched := true
+		for i := 0; i < n; i++ {
+			if !isSameShot(tail[i], next[i]) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return n
+		}
+	}
+	return 0
+}
+
+// isSameShot 按动作和对话的相似度判断两个镜头是否描述同一内容
+func isSameShot(a, b Storyboard) bool {
+	keyA := shotContentKey(a)
+	keyB := shotContentKey(b)
+	if len([]rune(keyA)) < chunkBoundaryMinTextSize || len([]rune(keyB)) < chunkBoundaryMinTextSize {
+		return false
+	}
+	return bigramSimilarity(keyA, keyB) >= chunkBoundarySimilarity
+}
+
+// shotContentKey 提取用于比较的镜头内容（动作+对话），去掉空白和常见标点
+func shotContentKey(sb Storyboard) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			return -1
+		case strings.ContainsRune("，。、！？：；“”\"'‘’,.!?:;（）()", r):
+			return -1
+		}
+		return r
+	}, sb.Action+sb.Dialogue)
+}
+
+// bigramSimilarity 计算两个字符串字符二元组集合的 Jaccard 相似度，适用于中文文本
+func bigramSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	setA := runeBigrams(a)
+	setB := runeBigrams(b)
+	if len(setA) == 0 || len(setB) == 0 {
+		return 0
+	}
+
+	intersection := 0
+	for bg := range setA {
+		if setB[bg] {
+			intersection++
+		}
+	}
+	union := len(setA) + len(setB) - intersection
+	return float64(intersection) / float64(union)
+}
+
+// runeBigrams 返回字符串中所有相邻字符对
+func runeBigrams(s string) map[[2]rune]bool {
+	runes := []rune(s)
+	set := make(map[[2]rune]bool, len(runes))
+	for i := 0; i+1 < len(runes); i++ {
+		set[[2]rune{runes[i], runes[i+1]}] = true
+	}
+	return set
+}
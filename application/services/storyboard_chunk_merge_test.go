@@ -0,0 +1,90 @@
+package services
+
+import "testing"
+
+func shot(action, dialogue string) Storyboard {
+	return Storyboard{Action: action, Dialogue: dialogue}
+}
+
+// TestMergeStoryboardChunks tests dropping shots repeated at chunk boundaries
+func TestMergeStoryboardChunks(t *testing.T) {
+	tests := []struct {
+		name       string
+		chunks     [][]Storyboard
+		wantCount  int
+		wantAction []string
+	}{
+		{
+			name: "no overlap",
+			chunks: [][]Storyboard{
+				{shot("陈峥推开仓库大门，手电筒光束扫过货架", ""), shot("李芳跟在身后，警惕地环顾四周", "")},
+				{shot("两人走到保险箱前蹲下查看", "陈峥：\"就是这个。\"")},
+			},
+			wantCount:  3,
+			wantAction: []string{"陈峥推开仓库大门，手电筒光束扫过货架", "李芳跟在身后，警惕地环顾四周", "两人走到保险箱前蹲下查看"},
+		},
+		{
+			name: "last shot repeated as first shot",
+			chunks: [][]Storyboard{
+				{shot("陈峥推开仓库大门，手电筒光束扫过货架", ""), shot("李芳跟在身后，警惕地环顾四周", "李芳：\"小心点。\"")},
+				{shot("李芳跟在身后，警惕地环顾四周", "李芳：\"小心点！\""), shot("两人走到保险箱前蹲下查看", "")},
+			},
+			wantCount:  3,
+			wantAction: []string{"陈峥推开仓库大门，手电筒光束扫过货架", "李芳跟在身后，警惕地环顾四周", "两人走到保险箱前蹲下查看"},
+		},
+		{
+			name: "slightly reworded duplicate",
+			chunks: [][]Storyboard{
+				{shot("陈峥弯腰双手握住撬棍用力撬动保险箱门，手臂青筋暴起", "")},
+				{shot("陈峥弯腰双手握住撬棍用力撬动保险箱门，手臂青筋凸起", ""), shot("保险箱门弹开", "")},
+			},
+			wantCount:  2,
+			wantAction: []string{"陈峥弯腰双手握住撬棍用力撬动保险箱门，手臂青筋暴起", "保险箱门弹开"},
+		},
+		{
+			name: "two overlapping shots",
+			chunks: [][]Storyboard{
+				{shot("陈峥推开仓库大门，手电筒光束扫过货架", ""), shot("李芳跟在身后，警惕地环顾四周", ""), shot("两人走到保险箱前蹲下查看", "")},
+				{shot("李芳跟在身后，警惕地环顾四周", ""), shot("两人走到保险箱前蹲下查看", ""), shot("陈峥拿出撬棍", "")},
+			},
+			wantCount:  4,
+			wantAction: []string{"陈峥推开仓库大门，手电筒光束扫过货架", "李芳跟在身后，警惕地环顾四周", "两人走到保险箱前蹲下查看", "陈峥拿出撬棍"},
+		},
+		{
+			name: "short actions are never treated as duplicates",
+			chunks: [][]Storyboard{
+				{shot("点头", "")},
+				{shot("点头", "")},
+			},
+			wantCount:  2,
+			wantAction: []string{"点头", "点头"},
+		},
+		{
+			name: "empty chunk in the middle",
+			chunks: [][]Storyboard{
+				{shot("陈峥推开仓库大门，手电筒光束扫过货架", "")},
+				{},
+				{shot("陈峥推开仓库大门，手电筒光束扫过货架", ""), shot("李芳跟在身后，警惕地环顾四周", "")},
+			},
+			wantCount:  2,
+			wantAction: []string{"陈峥推开仓库大门，手电筒光束扫过货架", "李芳跟在身后，警惕地环顾四周"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := mergeStoryboardChunks(tt.chunks)
+			if len(merged) != tt.wantCount {
+				t.Fatalf("got %d shots, want %d", len(merged), tt.wantCount)
+			}
+			for i, sb := range merged {
+				if sb.ShotNumber != i+1 {
+					t.Errorf("shot %d has shot_number %d, want %d", i, sb.ShotNumber, i+1)
+				}
+				if sb.Action != tt.wantAction[i] {
+					t.Errorf("shot %d action = %q, want %q", i, sb.Action, tt.wantAction[i])
+				}
+			}
+		})
+	}
+}
@@ -75,7 +75,7 @@ func (s *StoryboardCompositionService) GetScenesForEpisode(episodeID string) ([]
 	err := s.db.Preload("Drama").Where("id = ?", episodeID).First(&episode).Error
 	if err != nil {
 		s.log.Errorw("Episode not found", "episode_id", episodeID, "error", err)
-		return nil, fmt.Errorf("episode not found")
+		return nil, ErrEpisodeNotFound
 	}
 
 	s.log.Infow("GetScenesForEpisode auth check",
@@ -133,7 +133,7 @@ func (s *StoryboardCompositionService) GetScenesForEpisode(episodeID string) ([]
 	if len(storyboardIDs) > 0 {
 		var imageGens []models.ImageGeneration
 		// 查询已完成的图片生成记录，每个镜头只取最新的一条
-		if err := s.db.Where("storyboard_id IN ? AND status = ?", storyboardIDs, models.ImageStatusCompleted).
+		if err := s.db.Where("storyboard_id IN ? AND status = ? AND preview = ?", storyboardIDs, models.ImageStatusCompleted, false).
 			Order("created_at DESC").
 			Find(&imageGens).Error; err == nil {
 			// 为每个镜头保留最新的一条记录
@@ -284,7 +284,7 @@ func (s *StoryboardCompositionService) UpdateScene(sceneID string, req *UpdateSc
 	var storyboard models.Storyboard
 	err := s.db.Preload("Episode.Drama").Where("id = ?", sceneID).First(&storyboard).Error
 	if err != nil {
-		return fmt.Errorf("scene not found")
+		return ErrSceneNotFound
 	}
 
 	// 构建更新数据
@@ -316,6 +316,12 @@ func (s *StoryboardCompositionService) UpdateScene(sceneID string, req *UpdateSc
 	}
 	if req.Dialogue != nil {
 		updates["dialogue"] = req.Dialogue
+		// map 更新不经过 serializer，结构化台词需手动序列化
+		linesJSON, err := json.Marshal(parseDialogue(*req.Dialogue))
+		if err != nil {
+			return fmt.Errorf("failed to serialize dialogue lines: %w", err)
+		}
+		updates["dialogue_lines"] = string(linesJSON)
 	}
 	if req.Description != nil {
 		updates["description"] = req.Description
@@ -358,13 +364,13 @@ func (s *StoryboardCompositionService) GenerateSceneImage(req *GenerateSceneImag
 	var scene models.Scene
 	err := s.db.Where("id = ?", req.SceneID).First(&scene).Error
 	if err != nil {
-		return nil, fmt.Errorf("scene not found")
+		return nil, ErrSceneNotFound
 	}
 
 	// 验证权限：通过DramaID查询Drama
 	var drama models.Drama
 	if err := s.db.Where("id = ? ", scene.DramaID).First(&drama).Error; err != nil {
-		return nil, fmt.Errorf("unauthorized")
+		return nil, ErrUnauthorized
 	}
 
 	// 构建场景图片生成提示词
@@ -419,12 +425,15 @@ func (s *StoryboardCompositionService) UpdateScenePrompt(sceneID string, req *Up
 	var scene models.Scene
 	if err := s.db.Where("id = ?", sceneID).First(&scene).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return fmt.Errorf("scene not found")
+			return ErrSceneNotFound
 		}
 		return fmt.Errorf("failed to find scene: %w", err)
 	}
 
 	scene.Prompt = req.Prompt
+	if !sceneFieldEdited(&scene, SceneFieldPrompt) {
+		scene.EditedFields = append(scene.EditedFields, SceneFieldPrompt)
+	}
 	if err := s.db.Save(&scene).Error; err != nil {
 		return fmt.Errorf("failed to update scene prompt: %w", err)
 	}
@@ -446,20 +455,24 @@ func (s *StoryboardCompositionService) UpdateSceneInfo(sceneID string, req *Upda
 	var scene models.Scene
 	if err := s.db.Where("id = ?", sceneID).First(&scene).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return fmt.Errorf("scene not found")
+			return ErrSceneNotFound
 		}
 		return fmt.Errorf("failed to find scene: %w", err)
 	}
 
 	updates := make(map[string]interface{})
+	var edited []string
 	if req.Location != nil {
 		updates["location"] = *req.Location
+		edited = append(edited, SceneFieldLocation)
 	}
 	if req.Time != nil {
 		updates["time"] = *req.Time
+		edited = append(edited, SceneFieldTime)
 	}
 	if req.Prompt != nil {
 		updates["prompt"] = *req.Prompt
+		edited = append(edited, SceneFieldPrompt)
 	}
 	if req.Description != nil {
 		updates["description"] = *req.Description
@@ -476,6 +489,10 @@ func (s *StoryboardCompositionService) UpdateSceneInfo(sceneID string, req *Upda
 			return fmt.Errorf("failed to update scene: %w", err)
 		}
 	}
+	// 记录手动修改的字段，重新提取场景（merge 模式）时不会覆盖
+	if err := markSceneFieldsEdited(s.db, &scene, edited...); err != nil {
+		s.log.Warnw("Failed to mark scene fields as edited", "error", err, "scene_id", sceneID)
+	}
 
 	s.log.Infow("Scene info updated", "scene_id", sceneID, "updates", updates)
 	return nil
@@ -485,7 +502,7 @@ func (s *StoryboardCompositionService) DeleteScene(sceneID string) error {
 	var scene models.Scene
 	if err := s.db.Where("id = ?", sceneID).First(&scene).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return fmt.Errorf("scene not found")
+			return ErrSceneNotFound
 		}
 		return fmt.Errorf("failed to find scene: %w", err)
 	}
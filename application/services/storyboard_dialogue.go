@@ -0,0 +1,106 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+
+	models "github.com/drama-generator/backend/domain/models"
+)
+
+// dialogueMarkerPattern 匹配台词的起始标记：`（独白）`、`（旁白）`，或说话人 `陈峥：`、`李芳（低声）：`、`John:`
+// 分组：1 独白/旁白标记，2 说话人，3 说话人后括号内的说明
+var dialogueMarkerPattern = regexp.MustCompile(`(?:^|[\s"”」。！？!?，,;；])(?:[（(](独白|旁白|内心独白)[）)]\s*[：:]?|([^\s：:"“”「」（）()，,。！？!?;；]{1,20})\s*(?:[（(]([^）)]*)[）)])?\s*[：:])`)
+
+// dialogueQuotedPattern 匹配缺少冒号的台词，如 `陈峥"我们被耍了"`
+var dialogueQuotedPattern = regexp.MustCompile(`^([^\s"“”「」（）()：:，,。！？!?;；]{1,20})\s*["“「]`)
+
+// dialogueQuotes 台词两端可能出现的引号
+const dialogueQuotes = " \t\r\n\"“”「」'‘’"
+
+// dialogueMarkerType 独白/旁白标记对应的台词类型，其他内容返回空
+func dialogueMarkerType(marker string) string {
+	switch strings.TrimSpace(marker) {
+	case "独白", "内心独白":
+		return models.DialogueTypeMonologue
+	case "旁白":
+		return models.DialogueTypeNarration
+	}
+	return ""
+}
+
+// insideQuotes 文本末尾是否处于未闭合的引号内
+func insideQuotes(text string) bool {
+	straight := strings.Count(text, "\"")
+	curly := strings.Count(text, "“") - strings.Count(text, "”")
+	corner := strings.Count(text, "「") - strings.Count(text, "」")
+	return straight%2 == 1 || curly > 0 || corner > 0
+}
+
+// parseDialogue 将分镜 dialogue 字段解析为结构化台词，支持 `角色："台词"`、`（独白）内容`、`（旁白）内容`
+// 以及多人对话；首个标记之前没有说话人的内容，带引号时视为说话人未知的对白，否则视为旁白
+func parseDialogue(raw string) []models.DialogueLine {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+
+	type marker struct {
+		start, end int
+		speaker    string
+		lineType   string
+	}
+	var markers []marker
+	for _, m := range dialogueMarkerPattern.FindAllStringSubmatchIndex(raw, -1) {
+		mk := marker{end: m[1]}
+		if m[2] >= 0 {
+			mk.start = m[0] + strings.LastIndexAny(raw[m[0]:m[2]], "（(")
+			mk.lineType = dialogueMarkerType(raw[m[2]:m[3]])
+		} else if insideQuotes(raw[:m[4]]) {
+			// 台词内容里的冒号不作为说话人标记
+			continue
+		} else {
+			mk.start = m[4]
+			mk.speaker = strings.TrimSpace(raw[m[4]:m[5]])
+			mk.lineType = models.DialogueTypeSpeech
+			// `独白：...`、`陈峥（独白）：...` 按独白/旁白处理
+			if t := dialogueMarkerType(mk.speaker); t != "" {
+				mk.speaker, mk.lineType = "", t
+			} else if m[6] >= 0 {
+				if t := dialogueMarkerType(raw[m[6]:m[7]]); t != "" {
+					mk.lineType = t
+				}
+			}
+		}
+		markers = append(markers, mk)
+	}
+
+	var lines []models.DialogueLine
+	addLine := func(speaker, lineType, text string) {
+		if text = strings.Trim(text, dialogueQuotes); text != "" {
+			lines = append(lines, models.DialogueLine{Speaker: speaker, Type: lineType, Text: text})
+		}
+	}
+
+	preambleEnd := len(raw)
+	if len(markers) > 0 {
+		preambleEnd = markers[0].start
+	}
+	if preamble := strings.TrimSpace(raw[:preambleEnd]); preamble != "" {
+		if m := dialogueQuotedPattern.FindStringSubmatchIndex(preamble); m != nil {
+			addLine(preamble[m[2]:m[3]], models.DialogueTypeSpeech, preamble[m[3]:])
+		} else if strings.IndexAny(preamble, "\"“「") == 0 {
+			addLine("", models.DialogueTypeSpeech, preamble)
+		} else {
+			addLine("", models.DialogueTypeNarration, preamble)
+		}
+	}
+
+	for i, mk := range markers {
+		end := len(raw)
+		if i+1 < len(markers) {
+			end = markers[i+1].start
+		}
+		addLine(mk.speaker, mk.lineType, raw[mk.end:end])
+	}
+	return lines
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	models "github.com/drama-generator/backend/domain/models"
+)
+
+func TestParseDialogue(t *testing.T) {
+	speech := func(speaker, text string) models.DialogueLine {
+		return models.DialogueLine{Speaker: speaker, Type: models.DialogueTypeSpeech, Text: text}
+	}
+
+	tests := []struct {
+		name string
+		raw  string
+		want []models.DialogueLine
+	}{
+		{"empty", "  ", nil},
+		{"single speaker", `陈峥："我们被耍了。"`, []models.DialogueLine{speech("陈峥", "我们被耍了。")}},
+		{
+			"multiple speakers",
+			`陈峥："我们被耍了，这里根本没有我们要找的东西。" 李芳："现在怎么办？"`,
+			[]models.DialogueLine{speech("陈峥", "我们被耍了，这里根本没有我们要找的东西。"), speech("李芳", "现在怎么办？")},
+		},
+		{"speaker note", `李芳（低声）：快走`, []models.DialogueLine{speech("李芳", "快走")}},
+		{"ascii colon", `John: "Run!"`, []models.DialogueLine{speech("John", "Run!")}},
+		{"colon inside quotes", `陈峥："时间：不多了"`, []models.DialogueLine{speech("陈峥", "时间：不多了")}},
+		{
+			"monologue",
+			"（独白）这么多年了，里面到底藏着什么秘密？",
+			[]models.DialogueLine{{Type: models.DialogueTypeMonologue, Text: "这么多年了，里面到底藏着什么秘密？"}},
+		},
+		{
+			"character monologue",
+			"陈峥（独白）：终于到了",
+			[]models.DialogueLine{{Speaker: "陈峥", Type: models.DialogueTypeMonologue, Text: "终于到了"}},
+		},
+		{
+			"narration then speech",
+			`(旁白)三天后。 陈峥："出发。"`,
+			[]models.DialogueLine{{Type: models.DialogueTypeNarration, Text: "三天后。"}, speech("陈峥", "出发。")},
+		},
+		{"missing colon", `陈峥"我们被耍了"`, []models.DialogueLine{speech("陈峥", "我们被耍了")}},
+		{"quoted without speaker", `“谁在那里？”`, []models.DialogueLine{speech("", "谁在那里？")}},
+		{"plain text", "远处传来钟声", []models.DialogueLine{{Type: models.DialogueTypeNarration, Text: "远处传来钟声"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseDialogue(tt.raw); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseDialogue(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+// EpisodeStoryboardOutcome 批量生成中单个剧集的结果
+type EpisodeStoryboardOutcome struct {
+	EpisodeID  uint   `json:"episode_id"`
+	EpisodeNum int    `json:"episode_number"`
+	TaskID     string `json:"task_id,omitempty"`
+	Status     string `json:"status"` // completed, failed, skipped
+	Error      string `json:"error,omitempty"`
+}
+
+// GenerateStoryboardsForDrama 为剧本下所有有剧本内容的剧集依次生成分镜（异步），没有剧本内容的剧集会被跳过
+// 每个剧集仍创建独立的分镜生成任务，父任务汇总各剧集进度和结果
+func (s *StoryboardService) GenerateStoryboardsForDrama(dramaID string, model string) (string, error) {
+	var drama models.Drama
+	if err := s.db.Select("id").Where("id = ?", dramaID).First(&drama).Error; err != nil {
+		return "", ErrDramaNotFound
+	}
+
+	var episodes []models.Episode
+	if err := s.db.Select("id", "episode_number", "script_content").
+		Where("drama_id = ?", drama.ID).Order("episode_number ASC").Find(&episodes).Error; err != nil {
+		return "", fmt.Errorf("获取剧集失败: %w", err)
+	}
+
+	var withScript []models.Episode
+	var skipped []EpisodeStoryboardOutcome
+	for _, ep := range episodes {
+		if ep.ScriptContent == nil || strings.TrimSpace(*ep.ScriptContent) == "" {
+			skipped = append(skipped, EpisodeStoryboardOutcome{
+				EpisodeID: ep.ID, EpisodeNum: ep.EpisodeNum, Status: "skipped", Error: "剧集没有剧本内容",
+			})
+			continue
+		}
+		withScript = append(withScript, ep)
+	}
+	if len(withScript) == 0 {
+		return "", &ServiceError{Kind: ErrInvalidInput, Message: "该剧本没有包含剧本内容的剧集"}
+	}
+
+	task, err := s.taskService.CreateTask("drama_storyboard_generation", dramaID)
+	if err != nil {
+		s.log.Errorw("Failed to create task", "error", err)
+		return "", fmt.Errorf("创建任务失败: %w", err)
+	}
+
+	s.log.Infow("Generating storyboards for drama asynchronously",
+		"task_id", task.ID,
+		"drama_id", drama.ID,
+		"episodes", len(withScript),
+		"skipped", len(skipped))
+
+	go s.processDramaStoryboardGeneration(task.ID, model, withScript, skipped)
+
+	return task.ID, nil
+}
+
+// processDramaStoryboardGeneration 逐集生成分镜；各集串行执行，AI调用经过共享的文本限流器
+func (s *StoryboardService) processDramaStoryboardGeneration(taskID, model string, episodes []models.Episode, skipped []EpisodeStoryboardOutcome) {
+	outcomes := make([]EpisodeStoryboardOutcome, 0, len(episodes)+len(skipped))
+	completed, failed := 0, 0
+
+	for i, ep := range episodes {
+		progress := 5 + 90*i/len(episodes)
+		if err := s.taskService.UpdateTaskStatus(taskID, "processing", progress,
+			fmt.Sprintf("正在生成第%d集分镜（%d/%d）...", ep.EpisodeNum, i+1, len(episodes))); err != nil {
+			s.log.Warnw("Failed to update task progress", "error", err, "task_id", taskID)
+		}
+
+		outcome := s.generateEpisodeStoryboardSync(ep, model)
+		if outcome.Status == "completed" {
+			completed++
+		} else {
+			failed++
+			s.log.Warnw("Episode storyboard generation failed",
+				"task_id", taskID, "episode_id", ep.ID, "child_task_id", outcome.TaskID, "error", outcome.Error)
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	outcomes = append(outcomes, skipped...)
+
+	s.log.Infow("Drama storyboard generation finished",
+		"task_id", taskID,
+		"completed", completed,
+		"failed", failed,
+		"skipped", len(skipped))
+
+	if err := s.taskService.UpdateTaskResult(taskID, gin.H{
+		"completed": completed,
+		"failed":    failed,
+		"skipped":   len(skipped),
+		"total":     len(episodes) + len(skipped),
+		"episodes":  outcomes,
+	}); err != nil {
+		s.log.Errorw("Failed to update task result", "error", err, "task_id", taskID)
+	}
+}
+
+// generateEpisodeStoryboardSync 为单个剧集创建分镜生成任务并同步执行，返回该剧集的结果
+func (s *StoryboardService) generateEpisodeStoryboardSync(ep models.Episode, model string) EpisodeStoryboardOutcome {
+	outcome := EpisodeStoryboardOutcome{EpisodeID: ep.ID, EpisodeNum: ep.EpisodeNum}
+	episodeID := fmt.Sprint(ep.ID)
+
+	childTaskID, built, err := s.startStoryboardTask(episodeID, nil)
+	if err != nil {
+		outcome.Status = "failed"
+		outcome.Error = err.Error()
+		return outcome
+	}
+	outcome.TaskID = childTaskID
+
+	s.processStoryboardGeneration(childTaskID, episodeID, model, built)
+
+	child, err := s.taskService.GetTask(childTaskID)
+	if err != nil {
+		outcome.Status = "failed"
+		outcome.Error = fmt.Sprintf("获取任务状态失败: %v", err)
+		return outcome
+	}
+	if child.Status != "completed" {
+		outcome.Status = "failed"
+		outcome.Error = child.Error
+		return outcome
+	}
+	outcome.Status = "completed"
+	return outcome
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/drama-generator/backend/domain/models"
+)
+
+// EpisodeDuration 剧集时长重算结果
+type EpisodeDuration struct {
+	EpisodeID       string `json:"episode_id"`
+	StoryboardCount int64  `json:"storyboard_count"`
+	DurationSeconds int    `json:"duration_seconds"`
+	DurationMinutes int    `json:"duration_minutes"`
+}
+
+// RecalculateEpisodeDuration 按当前分镜时长之和重新计算剧集时长
+// 与分镜生成时的口径一致：剧集 duration 保存为分钟数（向上取整）
+func (s *StoryboardService) RecalculateEpisodeDuration(episodeID string) (*EpisodeDuration, error) {
+	var episode models.Episode
+	if err := s.db.Select("id").Where("id = ?", episodeID).First(&episode).Error; err != nil {
+		return nil, ErrEpisodeNotFound
+	}
+
+	var stats struct {
+		Count int64
+		Total int
+	}
+	if err := s.db.Model(&models.Storyboard{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(duration), 0) AS total").
+		Where("episode_id = ?", episode.ID).
+		Scan(&stats).Error; err != nil {
+		return nil, fmt.Errorf("统计分镜时长失败: %w", err)
+	}
+
+	durationMinutes := (stats.Total + 59) / 60
+	if err := s.db.Model(&models.Episode{}).Where("id = ?", episode.ID).Update("duration", durationMinutes).Error; err != nil {
+		return nil, fmt.Errorf("更新剧集时长失败: %w", err)
+	}
+
+	s.log.Infow("Episode duration recalculated",
+		"episode_id", episode.ID,
+		"storyboard_count", stats.Count,
+		"duration_seconds", stats.Total,
+		"duration_minutes", durationMinutes)
+
+	return &EpisodeDuration{
+		EpisodeID:       episodeID,
+		StoryboardCount: stats.Count,
+		DurationSeconds: stats.Total,
+		DurationMinutes: durationMinutes,
+	}, nil
+}
+
+// syncEpisodeDuration 分镜增删改后同步剧集时长，失败只记录日志
+func (s *StoryboardService) syncEpisodeDuration(episodeID uint) {
+	if _, err := s.RecalculateEpisodeDuration(fmt.Sprint(episodeID)); err != nil {
+		s.log.Warnw("Failed to sync episode duration", "error", err, "episode_id", episodeID)
+	}
+}
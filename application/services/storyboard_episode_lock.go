@@ -0,0 +1,30 @@
+package services
+
+import (
+	"strconv"
+	"sync"
+)
+
+// episodeGenerationLocks 正在生成分镜的剧集，避免两个任务同时删除并重建同一剧集的分镜
+var episodeGenerationLocks sync.Map
+
+// episodeLockKey 统一剧集ID格式，使 "01" 与 "1" 落到同一把锁
+func episodeLockKey(episodeID string) string {
+	if id, err := strconv.ParseUint(episodeID, 10, 64); err == nil {
+		return strconv.FormatUint(id, 10)
+	}
+	return episodeID
+}
+
+// lockEpisodeGeneration 获取剧集的分镜生成锁，已有任务在进行时返回 ErrGenerationInProgress
+func lockEpisodeGeneration(episodeID string) error {
+	if _, loaded := episodeGenerationLocks.LoadOrStore(episodeLockKey(episodeID), struct{}{}); loaded {
+		return ErrGenerationInProgress
+	}
+	return nil
+}
+
+// unlockEpisodeGeneration 释放剧集的分镜生成锁
+func unlockEpisodeGeneration(episodeID string) {
+	episodeGenerationLocks.Delete(episodeLockKey(episodeID))
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"fmt"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"gorm.io/gorm"
+)
+
+// withoutExcludedCharacters 去掉镜头中明确不出现的角色
+func withoutExcludedCharacters(characters []models.Character, excluded []uint) []models.Character {
+	if len(excluded) == 0 {
+		return characters
+	}
+	skip := make(map[uint]bool, len(excluded))
+	for _, id := range excluded {
+		skip[id] = true
+	}
+	result := make([]models.Character, 0, len(characters))
+	for _, char := range characters {
+		if !skip[char.ID] {
+			result = append(result, char)
+		}
+	}
+	return result
+}
+
+// excludedCharacterNames 查询镜头排除的角色名称，用于在提示词中说明这些角色不出现
+func excludedCharacterNames(db *gorm.DB, sb *models.Storyboard) []string {
+	if len(sb.ExcludedCharacters) == 0 {
+		return nil
+	}
+	var names []string
+	if err := db.Model(&models.Character{}).Where("id IN ?", sb.ExcludedCharacters).
+		Order("id ASC").Pluck("name", &names).Error; err != nil {
+		return nil
+	}
+	return names
+}
+
+// parseExcludedCharacters 解析更新请求中的 excluded_characters，只允许同一剧本的角色
+func (s *StoryboardService) parseExcludedCharacters(episodeID uint, value interface{}) ([]uint, error) {
+	if value == nil {
+		return []uint{}, nil
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, &ServiceError{Kind: ErrInvalidInput, Message: "excluded_characters 必须是角色ID数组"}
+	}
+
+	ids := make([]uint, 0, len(items))
+	seen := make(map[uint]bool, len(items))
+	for _, item := range items {
+		num, ok := item.(float64)
+		if !ok || num <= 0 || num != float64(uint(num)) {
+			return nil, &ServiceError{Kind: ErrInvalidInput, Message: "excluded_characters 必须是角色ID数组"}
+		}
+		if id := uint(num); !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	var episode models.Episode
+	if err := s.db.Select("id", "drama_id").Where("id = ?", episodeID).First(&episode).Error; err != nil {
+		return nil, ErrEpisodeNotFound
+	}
+	var count int64
+	if err := s.db.Model(&models.Character{}).Where("id IN ? AND drama_id = ?", ids, episode.DramaID).
+		Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check excluded characters: %w", err)
+	}
+	if int(count) != len(ids) {
+		return nil, &ServiceError{Kind: ErrInvalidInput, Message: "excluded_characters 包含不属于该剧本的角色"}
+	}
+	return ids, nil
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/ai"
+	"github.com/drama-generator/backend/pkg/config"
+)
+
+// maxStoryboardSourceImages 反推分镜时单次最多提交的图片数
+const maxStoryboardSourceImages = 20
+
+// GenerateStoryboardFromImages 根据用户已有的参考图片反推分镜（每张图片一个镜头），结果与普通分镜生成一样保存（异步）
+func (s *StoryboardService) GenerateStoryboardFromImages(episodeID string, imageURLs []string, model string) (string, error) {
+	if len(imageURLs) == 0 {
+		return "", fmt.Errorf("请至少提供一张图片")
+	}
+	if len(imageURLs) > maxStoryboardSourceImages {
+		return "", fmt.Errorf("图片数量不能超过%d张", maxStoryboardSourceImages)
+	}
+
+	var episode models.Episode
+	if err := s.db.Preload("Drama").Where("id = ?", episodeID).First(&episode).Error; err != nil {
+		return "", fmt.Errorf("剧集不存在或无权限访问")
+	}
+
+	images := make([]string, 0, len(imageURLs))
+	for _, url := range imageURLs {
+		resolved, err := s.resolveVisionImage(url)
+		if err != nil {
+			return "", err
+		}
+		images = append(images, resolved)
+	}
+
+	visionClient, err := s.aiService.GetVisionClient(model)
+	if err != nil {
+		return "", err
+	}
+
+	var characters []models.Character
+	if err := s.db.Where("drama_id = ?", episode.DramaID).Order("name ASC").Find(&characters).Error; err != nil {
+		return "", fmt.Errorf("获取角色列表失败: %w", err)
+	}
+	var scenes []models.Scene
+	if err := s.db.Where("drama_id = ?", episode.DramaID).Order("location ASC, time ASC").Find(&scenes).Error; err != nil {
+		s.log.Warnw("Failed to get scenes", "error", err)
+	}
+
+	var charInfoList []string
+	for _, char := range characters {
+		charInfoList = append(charInfoList, fmt.Sprintf(`{"id": %d, "name": "%s", "appearance": "%s"}`, char.ID, char.Name, getString(char.Appearance)))
+	}
+	var sceneInfoList []string
+	for _, bg := range scenes {
+		sceneInfoList = append(sceneInfoList, fmt.Sprintf(`{"id": %d, "location": "%s", "time": "%s"}`, bg.ID, bg.Location, bg.Time))
+	}
+
+	i18n := s.promptI18n.WithLanguage(episode.Drama.Language)
+	countInstruction := fmt.Sprintf("共%d张图片，请按顺序输出%d个镜头。", len(images), len(images))
+	if i18n.IsEnglish() {
+		countInstruction = fmt.Sprintf("There are %d images. Output %d shots in order.", len(images), len(images))
+	}
+	prompt := fmt.Sprintf("%s\n[%s]\n\n%s\n[%s]\n\n%s",
+		i18n.FormatUserPrompt("character_list_label"), strings.Join(charInfoList, ", "),
+		i18n.FormatUserPrompt("scene_list_label"), strings.Join(sceneInfoList, ", "),
+		countInstruction)
+
+	if err := lockEpisodeGeneration(episodeID); err != nil {
+		return "", err
+	}
+
+	task, err := s.taskService.CreateTask("storyboard_from_images", episodeID)
+	if err != nil {
+		unlockEpisodeGeneration(episodeID)
+		s.log.Errorw("Failed to create task", "error", err)
+		return "", fmt.Errorf("创建任务失败: %w", err)
+	}
+
+	s.log.Infow("Generating storyboard from images asynchronously",
+		"task_id", task.ID,
+		"episode_id", episodeID,
+		"image_count", len(images),
+		"model", model)
+
+	go s.processStoryboardFromImages(task.ID, episodeID, episode.DramaID, i18n, visionClient, prompt, i18n.GetStoryboardFromImagesPrompt(), images)
+
+	return task.ID, nil
+}
+
+// processStoryboardFromImages 后台调用视觉模型生成分镜并保存
+func (s *StoryboardService) processStoryboardFromImages(taskID, episodeID string, dramaID uint, i18n *PromptI18n, client ai.VisionClient, prompt, systemPrompt string, images []string) {
+	defer unlockEpisodeGeneration(episodeID)
+
+	if err := s.taskService.UpdateTaskStatus(taskID, "processing", 10, "正在根据图片生成分镜头..."); err != nil {
+		s.log.Errorw("Failed to update task status", "error", err, "task_id", taskID)
+		return
+	}
+
+	callClient := acquireVisionCall(client)
+	callStart := time.Now()
+	text, err := callClient.GenerateTextWithImages(prompt, systemPrompt, images, ai.WithMaxTokens(16000))
+	observeStage(metricTaskStoryboard, StageAICall, textClientProvider(client), callStart)
+	if err != nil {
+		s.log.Errorw("Failed to generate storyboard from images", "error", err, "task_id", taskID)
+		if updateErr := s.taskService.UpdateTaskError(taskID, fmt.Errorf("根据图片生成分镜头失败: %w", err)); updateErr != nil {
+			s.log.Errorw("Failed to update task error", "error", updateErr, "task_id", taskID)
+		}
+		return
+	}
+
+	// 视觉模型的输出由剧本的文本模型修复
+	var repairClient textGenerator
+	if textClient, _, err := s.aiService.GetAIClientForDrama("text", dramaID, ""); err != nil {
+		s.log.Warnw("No text client for repairing storyboard output", "error", err, "task_id", taskID)
+	} else {
+		repairClient = textClient
+	}
+	s.saveGeneratedStoryboards(taskID, episodeID, i18n, text, repairClient, ai.WithMaxTokens(16000))
+}
+
+// resolveVisionImage 将图片地址转换为视觉模型可访问的形式：远程URL原样使用，本地存储路径转为 data URI
+func (s *StoryboardService) resolveVisionImage(url string) (string, error) {
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "data:") {
+		return url, nil
+	}
+
+	storagePath := config.CurrentOr(s.config).Storage.LocalPath
+	fullPath := filepath.Join(storagePath, filepath.Clean("/"+url))
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		return "", fmt.Errorf("读取图片失败: %s", url)
+	}
+
+	mimeType := http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "", fmt.Errorf("不是有效的图片: %s", url)
+	}
+
+	return fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(data)), nil
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/drama-generator/backend/domain/models"
+)
+
+// 导入分镜的单镜头时长范围（秒）
+const (
+	minImportShotDuration = 1
+	maxImportShotDuration = 60
+)
+
+// ImportStoryboards 导入外部编辑的分镜，替换剧集现有分镜；与AI生成走同一保存流程（重新生成提示词并更新剧集时长）
+func (s *StoryboardService) ImportStoryboards(episodeID string, storyboards []Storyboard) (int, error) {
+	var episode models.Episode
+	if err := s.db.Select("id", "drama_id").Where("id = ?", episodeID).First(&episode).Error; err != nil {
+		return 0, ErrEpisodeNotFound
+	}
+	if len(storyboards) == 0 {
+		return 0, &ServiceError{Kind: ErrInvalidInput, Message: "导入的分镜不能为空"}
+	}
+
+	if err := s.validateImportedStoryboards(episode.DramaID, storyboards); err != nil {
+		return 0, err
+	}
+
+	// 与AI生成互斥，避免导入过程中被生成任务覆盖
+	if err := lockEpisodeGeneration(episodeID); err != nil {
+		return 0, err
+	}
+	defer unlockEpisodeGeneration(episodeID)
+
+	if err := s.saveStoryboards(fmt.Sprint(episode.ID), storyboards); err != nil {
+		return 0, err
+	}
+	s.syncEpisodeDuration(episode.ID)
+
+	s.log.Infow("Storyboards imported", "episode_id", episode.ID, "count", len(storyboards))
+	return len(storyboards), nil
+}
+
+// validateImportedStoryboards 校验镜头编号、时长，以及角色和场景是否属于同一剧本，并清理文本字段
+func (s *StoryboardService) validateImportedStoryboards(dramaID uint, storyboards []Storyboard) error {
+	shotNumbers := make(map[int]bool, len(storyboards))
+	characterIDs := make(map[uint]bool)
+	sceneIDs := make(map[uint]bool)
+
+	for i := range storyboards {
+		sb := &storyboards[i]
+		if sb.ShotNumber <= 0 {
+			sb.ShotNumber = i + 1
+		}
+		if shotNumbers[sb.ShotNumber] {
+			return &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf("镜头编号 %d 重复", sb.ShotNumber)}
+		}
+		shotNumbers[sb.ShotNumber] = true
+
+		if sb.Duration < minImportShotDuration || sb.Duration > maxImportShotDuration {
+			return &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf("镜头 %d 时长应在 %d-%d 秒之间",
+				sb.ShotNumber, minImportShotDuration, maxImportShotDuration)}
+		}
+
+		for _, id := range sb.Characters {
+			characterIDs[id] = true
+		}
+		if sb.SceneID != nil {
+			sceneIDs[*sb.SceneID] = true
+		}
+
+		sanitizeStrings(&sb.Title, &sb.ShotType, &sb.Angle, &sb.Time, &sb.Location, &sb.Movement, &sb.Action,
+			&sb.Dialogue, &sb.Result, &sb.Atmosphere, &sb.Emotion, &sb.BgmPrompt, &sb.SoundEffect)
+	}
+
+	if missing, err := s.missingIDs(&models.Character{}, dramaID, characterIDs); err != nil {
+		return err
+	} else if len(missing) > 0 {
+		return &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf("角色不存在或不属于该剧本: %v", missing)}
+	}
+	if missing, err := s.missingIDs(&models.Scene{}, dramaID, sceneIDs); err != nil {
+		return err
+	} else if len(missing) > 0 {
+		return &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf("场景不存在或不属于该剧本: %v", missing)}
+	}
+	return nil
+}
+
+// missingIDs 返回 ids 中不属于该剧本的记录ID
+func (s *StoryboardService) missingIDs(model interface{}, dramaID uint, ids map[uint]bool) ([]uint, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	wanted := make([]uint, 0, len(ids))
+	for id := range ids {
+		wanted = append(wanted, id)
+	}
+
+	var found []uint
+	if err := s.db.Model(model).Where("id IN ? AND drama_id = ?", wanted, dramaID).Pluck("id", &found).Error; err != nil {
+		return nil, fmt.Errorf("校验关联数据失败: %w", err)
+	}
+	exists := make(map[uint]bool, len(found))
+	for _, id := range found {
+		exists[id] = true
+	}
+
+	var missing []uint
+	for _, id := range wanted {
+		if !exists[id] {
+			missing = append(missing, id)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i] < missing[j] })
+	return missing, nil
+}
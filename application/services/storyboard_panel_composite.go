@@ -0,0 +1,195 @@
+package services
+
+import (
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/image"
+	"github.com/gin-gonic/gin"
+)
+
+// CompositePanelsRequest 多格拼图请求
+type CompositePanelsRequest struct {
+	Layout             string `json:"layout" binding:"omitempty,oneof=horizontal_n grid_2x2"` // 默认 horizontal_n
+	ImageGenerationIDs []uint `json:"image_generation_ids"`                                   // 指定参与拼图的帧图片及顺序，为空时按帧类型自动选取
+	Gutter             *int   `json:"gutter" binding:"omitempty,min=0,max=200"`               // 格间距（像素）
+	BorderColor        string `json:"border_color"`                                           // 间距颜色 #RRGGBB
+	PanelNumbers       bool   `json:"panel_numbers"`                                          // 在每格左上角绘制序号
+}
+
+// panelFrameOrder 自动选取帧图片时的排列顺序
+var panelFrameOrder = map[string]int{
+	string(FrameTypeFirst):  0,
+	string(FrameTypeKey):    1,
+	string(FrameTypeLast):   2,
+	string(FrameTypePanel):  3,
+	string(FrameTypeAction): 4,
+}
+
+// CompositePanels 将分镜已生成的帧图片拼接为一张多格图（异步），结果写入分镜的 composed_image
+func (s *StoryboardService) CompositePanels(storyboardID string, req *CompositePanelsRequest) (string, error) {
+	var storyboard models.Storyboard
+	if err := s.db.First(&storyboard, storyboardID).Error; err != nil {
+		return "", ErrStoryboardNotFound
+	}
+
+	if req.Layout == "" {
+		req.Layout = image.LayoutHorizontal
+	}
+	opts := []image.CompositeOption{image.WithPanelNumbers(req.PanelNumbers)}
+	if req.Gutter != nil {
+		opts = append(opts, image.WithGutter(*req.Gutter))
+	}
+	if req.BorderColor != "" {
+		c, err := image.ParseHexColor(req.BorderColor)
+		if err != nil {
+			return "", &ServiceError{Kind: ErrInvalidInput, Message: "border_color 格式应为 #RRGGBB"}
+		}
+		opts = append(opts, image.WithBorderColor(c))
+	}
+
+	frames, err := s.panelFrameImages(storyboard.ID, req.ImageGenerationIDs, image.LayoutCapacity(req.Layout))
+	if err != nil {
+		return "", err
+	}
+
+	task, err := s.taskService.CreateTask("panel_composite", storyboardID)
+	if err != nil {
+		s.log.Errorw("Failed to create task", "error", err)
+		return "", fmt.Errorf("创建任务失败: %w", err)
+	}
+
+	s.log.Infow("Compositing panels asynchronously",
+		"task_id", task.ID,
+		"storyboard_id", storyboardID,
+		"layout", req.Layout,
+		"frame_count", len(frames))
+
+	go s.processPanelComposite(task.ID, storyboard.ID, frames, req.Layout, opts)
+
+	return task.ID, nil
+}
+
+// panelFrameImages 获取参与拼图的帧图片本地路径：指定ID时按给定顺序，否则按帧类型顺序选取已完成的图片
+func (s *StoryboardService) panelFrameImages(storyboardID uint, ids []uint, capacity int) ([]string, error) {
+	var gens []models.ImageGeneration
+	query := s.db.Where("storyboard_id = ? AND status = ? AND frame_type IS NOT NULL AND preview = ?", storyboardID, models.ImageStatusCompleted, false)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+	if err := query.Order("created_at ASC").Find(&gens).Error; err != nil {
+		return nil, fmt.Errorf("获取帧图片失败: %w", err)
+	}
+
+	var ordered []models.ImageGeneration
+	if len(ids) > 0 {
+		byID := make(map[uint]models.ImageGeneration, len(gens))
+		for _, gen := range gens {
+			byID[gen.ID] = gen
+		}
+		for _, id := range ids {
+			gen, ok := byID[id]
+			if !ok {
+				return nil, &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf("图片 %d 不是该分镜已完成的帧图片", id)}
+			}
+			ordered = append(ordered, gen)
+		}
+	} else {
+		ordered = gens
+		sortByFrameOrder(ordered)
+	}
+
+	if len(ordered) == 0 {
+		return nil, &ServiceError{Kind: ErrInvalidInput, Message: "该分镜还没有已生成的帧图片"}
+	}
+	if capacity > 0 && len(ordered) > capacity {
+		if len(ids) > 0 {
+			return nil, &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf("该布局最多 %d 格", capacity)}
+		}
+		ordered = ordered[:capacity]
+	}
+
+	frames := make([]string, 0, len(ordered))
+	for _, gen := range ordered {
+		if gen.LocalPath == nil || *gen.LocalPath == "" {
+			return nil, &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf("图片 %d 尚未缓存到本地", gen.ID)}
+		}
+		frames = append(frames, filepath.Join(s.config.Storage.LocalPath, *gen.LocalPath))
+	}
+	return frames, nil
+}
+
+// sortByFrameOrder 按首帧、关键帧、尾帧的顺序稳定排序
+func sortByFrameOrder(gens []models.ImageGeneration) {
+	rank := func(gen models.ImageGeneration) int {
+		if gen.FrameType != nil {
+			if r, ok := panelFrameOrder[*gen.FrameType]; ok {
+				return r
+			}
+		}
+		return len(panelFrameOrder)
+	}
+	sort.SliceStable(gens, func(i, j int) bool {
+		return rank(gens[i]) < rank(gens[j])
+	})
+}
+
+// processPanelComposite 后台拼接帧图片并保存
+func (s *StoryboardService) processPanelComposite(taskID string, storyboardID uint, frames []string, layout string, opts []image.CompositeOption) {
+	if err := s.taskService.UpdateTaskStatus(taskID, "processing", 20, "正在拼接帧图片..."); err != nil {
+		s.log.Errorw("Failed to update task status", "error", err, "task_id", taskID)
+		return
+	}
+
+	composite, err := image.CompositePanels(frames, layout, opts...)
+	if err != nil {
+		s.log.Errorw("Failed to composite panels", "error", err, "task_id", taskID)
+		s.failStoryboardTask(taskID, fmt.Errorf("拼接帧图片失败: %w", err))
+		return
+	}
+
+	relativePath := filepath.Join("composites", fmt.Sprintf("storyboard_%d_%s.png", storyboardID, time.Now().Format("20060102_150405")))
+	absolutePath := filepath.Join(s.config.Storage.LocalPath, relativePath)
+	if err := os.MkdirAll(filepath.Dir(absolutePath), 0755); err != nil {
+		s.failStoryboardTask(taskID, fmt.Errorf("创建目录失败: %w", err))
+		return
+	}
+
+	f, err := os.Create(absolutePath)
+	if err != nil {
+		s.failStoryboardTask(taskID, fmt.Errorf("保存拼图失败: %w", err))
+		return
+	}
+	err = png.Encode(f, composite)
+	f.Close()
+	if err != nil {
+		os.Remove(absolutePath)
+		s.failStoryboardTask(taskID, fmt.Errorf("保存拼图失败: %w", err))
+		return
+	}
+
+	imageURL := fmt.Sprintf("%s/%s", s.config.Storage.BaseURL, filepath.ToSlash(relativePath))
+	if err := s.db.Model(&models.Storyboard{}).Where("id = ?", storyboardID).
+		Update("composed_image", imageURL).Error; err != nil {
+		s.log.Errorw("Failed to update composed image", "error", err, "task_id", taskID)
+		s.failStoryboardTask(taskID, fmt.Errorf("更新分镜失败: %w", err))
+		return
+	}
+
+	s.log.Infow("Panels composited", "task_id", taskID, "storyboard_id", storyboardID, "path", relativePath)
+
+	if err := s.taskService.UpdateTaskResult(taskID, gin.H{
+		"storyboard_id":  storyboardID,
+		"composed_image": imageURL,
+		"local_path":     filepath.ToSlash(relativePath),
+		"frame_count":    len(frames),
+		"layout":         layout,
+	}); err != nil {
+		s.log.Errorw("Failed to update task result", "error", err, "task_id", taskID)
+	}
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// storyboardSceneLink AI返回的分镜-场景匹配结果
+type storyboardSceneLink struct {
+	StoryboardID uint  `json:"storyboard_id"`
+	SceneID      *uint `json:"scene_id"`
+}
+
+// LinkStoryboardsToScenes 生成分镜后单独由AI判断每个分镜所属场景，并更新scene_id（异步）
+func (s *StoryboardService) LinkStoryboardsToScenes(episodeID string) (string, error) {
+	var episode models.Episode
+	if err := s.db.Where("id = ?", episodeID).First(&episode).Error; err != nil {
+		return "", ErrEpisodeNotFound
+	}
+
+	var storyboards []models.Storyboard
+	if err := s.db.Where("episode_id = ?", episode.ID).Order("storyboard_number ASC").Find(&storyboards).Error; err != nil {
+		return "", fmt.Errorf("获取分镜失败: %w", err)
+	}
+	if len(storyboards) == 0 {
+		return "", fmt.Errorf("该剧集还没有分镜")
+	}
+
+	var scenes []models.Scene
+	if err := s.db.Where("drama_id = ?", episode.DramaID).Order("location ASC, time ASC").Find(&scenes).Error; err != nil {
+		return "", fmt.Errorf("获取场景失败: %w", err)
+	}
+	if len(scenes) == 0 {
+		return "", fmt.Errorf("该剧本还没有场景，请先提取场景")
+	}
+
+	prompt, err := buildSceneLinkingPrompt(storyboards, scenes)
+	if err != nil {
+		return "", err
+	}
+
+	taskID, err := s.aiService.GenerateTextAsync(TextTaskRequest{
+		TaskType:    "storyboard_scene_linking",
+		ResourceID:  episodeID,
+		DramaID:     episode.DramaID,
+		Prompt:      prompt,
+		Message:     "正在匹配分镜与场景...",
+		ErrorPrefix: "场景匹配失败",
+	}, func(taskID, text string) (interface{}, error) {
+		return s.saveSceneLinks(taskID, text, storyboards, scenes)
+	})
+	if err != nil {
+		s.log.Errorw("Failed to start scene linking", "error", err, "episode_id", episodeID)
+		return "", err
+	}
+
+	s.log.Infow("Linking storyboards to scenes asynchronously",
+		"task_id", taskID,
+		"episode_id", episodeID,
+		"storyboard_count", len(storyboards),
+		"scene_count", len(scenes))
+
+	return taskID, nil
+}
+
+// sceneLinkingScene 场景匹配提示词中的场景背景
+type sceneLinkingScene struct {
+	ID       uint   `json:"id"`
+	Location string `json:"location"`
+	Time     string `json:"time"`
+}
+
+// sceneLinkingStoryboard 场景匹配提示词中的分镜
+type sceneLinkingStoryboard struct {
+	StoryboardID uint   `json:"storyboard_id"`
+	Location     string `json:"location"`
+	Time         string `json:"time"`
+	Description  string `json:"description"`
+}
+
+// buildSceneLinkingPrompt 构建分镜与场景匹配的提示词
+func buildSceneLinkingPrompt(storyboards []models.Storyboard, scenes []models.Scene) (string, error) {
+	var sceneInfoList []string
+	for _, scene := range scenes {
+		info, err := json.Marshal(sceneLinkingScene{ID: scene.ID, Location: scene.Location, Time: scene.Time})
+		if err != nil {
+			return "", fmt.Errorf("序列化场景失败: %w", err)
+		}
+		sceneInfoList = append(sceneInfoList, string(info))
+	}
+
+	var sbInfoList []string
+	for _, sb := range storyboards {
+		info, err := json.Marshal(sceneLinkingStoryboard{
+			StoryboardID: sb.ID,
+			Location:     getString(sb.Location),
+			Time:         getString(sb.Time),
+			Description:  getString(sb.Description),
+		})
+		if err != nil {
+			return "", fmt.Errorf("序列化分镜失败: %w", err)
+		}
+		sbInfoList = append(sbInfoList, string(info))
+	}
+
+	return fmt.Sprintf(`请为每个分镜选择最匹配的场景背景。
+
+【场景背景列表】
+[%s]
+
+【分镜列表】
+[%s]
+
+【匹配规则】
+1. 根据分镜的地点、时间和描述，判断其发生在哪个场景背景中
+2. 地点语义相同即可匹配（如"维修店"与"维修店内部"），时间优先匹配相近的时段
+3. scene_id必须是场景背景列表中的id（数字），没有合适场景时填null
+4. 每个分镜都必须输出一条结果
+
+【输出格式】只输出JSON，不要任何解释：
+{"links": [{"storyboard_id": 1, "scene_id": 2}]}`, strings.Join(sceneInfoList, ", "), strings.Join(sbInfoList, ",\n")), nil
+}
+
+// saveSceneLinks 解析AI返回的匹配结果并更新分镜的scene_id
+func (s *StoryboardService) saveSceneLinks(taskID, text string, storyboards []models.Storyboard, scenes []models.Scene) (interface{}, error) {
+	if err := s.taskService.UpdateTaskStatus(taskID, "processing", 60, "正在保存匹配结果..."); err != nil {
+		s.log.Warnw("Failed to update task status", "error", err, "task_id", taskID)
+	}
+
+	validScenes := make(map[uint]bool, len(scenes))
+	for _, scene := range scenes {
+		validScenes[scene.ID] = true
+	}
+	validStoryboards := make(map[uint]bool, len(storyboards))
+	for _, sb := range storyboards {
+		validStoryboards[sb.ID] = true
+	}
+
+	// AI可能返回数组或 {"links": [...]} 两种格式
+	var links []storyboardSceneLink
+	if err := utils.SafeParseAIJSON(text, &links); err != nil {
+		var wrapped struct {
+			Links []storyboardSceneLink `json:"links"`
+		}
+		if err := utils.SafeParseAIJSON(text, &wrapped); err != nil {
+			s.log.Errorw("Failed to parse scene links", "error", err, "response", text[:min(500, len(text))], "task_id", taskID)
+			return nil, fmt.Errorf("解析场景匹配结果失败: %w", err)
+		}
+		links = wrapped.Links
+	}
+
+	linked, unlinked := 0, 0
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, link := range links {
+			if !validStoryboards[link.StoryboardID] {
+				continue
+			}
+			// 忽略AI编造的场景ID
+			if link.SceneID != nil && !validScenes[*link.SceneID] {
+				link.SceneID = nil
+			}
+			if err := tx.Model(&models.Storyboard{}).Where("id = ?", link.StoryboardID).
+				Update("scene_id", link.SceneID).Error; err != nil {
+				return err
+			}
+			if link.SceneID != nil {
+				linked++
+			} else {
+				unlinked++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("保存场景匹配结果失败: %w", err)
+	}
+
+	s.log.Infow("Storyboards linked to scenes", "task_id", taskID, "linked", linked, "unlinked", unlinked)
+
+	return gin.H{
+		"linked":   linked,
+		"unlinked": unlinked,
+		"total":    len(storyboards),
+	}, nil
+}
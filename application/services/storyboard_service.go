@@ -1,10 +1,12 @@
 package services
 
 import (
+	"errors"
 	"strconv"
 
 	"fmt"
 	"strings"
+	"time"
 
 	models "github.com/drama-generator/backend/domain/models"
 	"github.com/drama-generator/backend/pkg/ai"
@@ -61,23 +63,28 @@ type GenerateStoryboardResult struct {
 	Total       int          `json:"total"`
 }
 
-func (s *StoryboardService) GenerateStoryboard(episodeID string, model string) (string, error) {
+// buildStoryboardPrompt 组装分镜生成的完整提示词（系统提示、剧本、格式要求、角色和场景列表、风格参考）
+func (s *StoryboardService) buildStoryboardPrompt(episodeID string, styleReferenceEpisodeID *uint) (*StoryboardPromptPreview, error) {
 	// 从数据库获取剧集信息
 	var episode struct {
 		ID            string
 		ScriptContent *string
 		Description   *string
 		DramaID       string
+		Language      string
 	}
 
 	err := s.db.Table("episodes").
-		Select("episodes.id, episodes.script_content, episodes.description, episodes.drama_id").
+		Select("episodes.id, episodes.script_content, episodes.description, episodes.drama_id, dramas.language").
 		Joins("INNER JOIN dramas ON dramas.id = episodes.drama_id").
 		Where("episodes.id = ?", episodeID).
 		First(&episode).Error
 
 	if err != nil {
-		return "", fmt.Errorf("剧集不存在或无权限访问")
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEpisodeNotFound
+		}
+		return nil, fmt.Errorf("获取剧集失败: %w", err)
 	}
 
 	// 获取剧本内容
@@ -87,13 +94,16 @@ func (s *StoryboardService) GenerateStoryboard(episodeID string, model string) (
 	} else if episode.Description != nil && *episode.Description != "" {
 		scriptContent = *episode.Description
 	} else {
-		return "", fmt.Errorf("剧本内容为空，请先生成剧集内容")
+		return nil, &ServiceError{Kind: ErrInvalidInput, Message: "剧本内容为空，请先生成剧集内容"}
+	}
+	if err := checkScriptLength(scriptContent); err != nil {
+		return nil, err
 	}
 
 	// 获取该剧本的所有角色
 	var characters []models.Character
 	if err := s.db.Where("drama_id = ?", episode.DramaID).Order("name ASC").Find(&characters).Error; err != nil {
-		return "", fmt.Errorf("获取角色列表失败: %w", err)
+		return nil, fmt.Errorf("获取角色列表失败: %w", err)
 	}
 
 	// 构建角色列表字符串（包含ID和名称）
@@ -122,16 +132,17 @@ func (s *StoryboardService) GenerateStoryboard(episodeID string, model string) (
 		sceneList = fmt.Sprintf("[%s]", strings.Join(sceneInfoList, ", "))
 	}
 
-	// 使用国际化提示词
-	systemPrompt := s.promptI18n.GetStoryboardSystemPrompt()
+	// 使用国际化提示词（剧本可覆盖全局语言）
+	i18n := s.promptI18n.WithLanguage(episode.Language)
+	systemPrompt := i18n.GetStoryboardSystemPrompt()
 
-	scriptLabel := s.promptI18n.FormatUserPrompt("script_content_label")
-	taskLabel := s.promptI18n.FormatUserPrompt("task_label")
-	taskInstruction := s.promptI18n.FormatUserPrompt("task_instruction")
-	charListLabel := s.promptI18n.FormatUserPrompt("character_list_label")
-	charConstraint := s.promptI18n.FormatUserPrompt("character_constraint")
-	sceneListLabel := s.promptI18n.FormatUserPrompt("scene_list_label")
-	sceneConstraint := s.promptI18n.FormatUserPrompt("scene_constraint")
+	scriptLabel := i18n.FormatUserPrompt("script_content_label")
+	taskLabel := i18n.FormatUserPrompt("task_label")
+	taskInstruction := i18n.FormatUserPrompt("task_instruction")
+	charListLabel := i18n.FormatUserPrompt("character_list_label")
+	charConstraint := i18n.FormatUserPrompt("character_constraint")
+	sceneListLabel := i18n.FormatUserPrompt("scene_list_label")
+	sceneConstraint := i18n.FormatUserPrompt("scene_constraint")
 
 	prompt := fmt.Sprintf(`%s
 
@@ -320,32 +331,112 @@ func (s *StoryboardService) GenerateStoryboard(episodeID string, model string) (
 - 为视频生成AI提供足够的画面构建信息
 - 避免抽象词汇，使用具象的视觉化描述`, systemPrompt, scriptLabel, scriptContent, taskLabel, taskInstruction, charListLabel, characterList, charConstraint, sceneListLabel, sceneList, sceneConstraint)
 
+	// 引用其他剧集的代表性镜头作为风格示例，保持跨集一致
+	if styleReferenceEpisodeID != nil {
+		styleReference, err := s.buildStyleReferencePrompt(i18n, episodeID, episode.DramaID, *styleReferenceEpisodeID)
+		if err != nil {
+			return nil, err
+		}
+		prompt += styleReference
+	}
+
+	return &StoryboardPromptPreview{
+		EpisodeID:               episodeID,
+		DramaID:                 episode.DramaID,
+		Language:                i18n.GetLanguage(),
+		SystemPrompt:            systemPrompt,
+		Prompt:                  prompt,
+		PromptLength:            len([]rune(prompt)),
+		ScriptLength:            len(scriptContent),
+		CharacterList:           characterList,
+		CharacterCount:          len(characters),
+		SceneList:               sceneList,
+		SceneCount:              len(scenes),
+		StyleReferenceEpisodeID: styleReferenceEpisodeID,
+		script:                  scriptContent,
+	}, nil
+}
+
+// StoryboardPromptPreview 分镜生成提示词预览
+type StoryboardPromptPreview struct {
+	EpisodeID               string `json:"episode_id"`
+	DramaID                 string `json:"drama_id"`
+	Language                string `json:"language"`
+	SystemPrompt            string `json:"system_prompt"`
+	Prompt                  string `json:"prompt"` // 实际发送给AI的完整提示词（已包含系统提示）
+	PromptLength            int    `json:"prompt_length"`
+	ScriptLength            int    `json:"script_length"`
+	CharacterList           string `json:"character_list"`
+	CharacterCount          int    `json:"character_count"`
+	SceneList               string `json:"scene_list"`
+	SceneCount              int    `json:"scene_count"`
+	StyleReferenceEpisodeID *uint  `json:"style_reference_episode_id,omitempty"`
+
+	script string // 提示词中的剧本原文，分段生成时替换
+}
+
+// PreviewStoryboardPrompt 返回生成分镜时将发送给AI的完整提示词，不调用AI
+func (s *StoryboardService) PreviewStoryboardPrompt(episodeID string, styleReferenceEpisodeID *uint) (*StoryboardPromptPreview, error) {
+	return s.buildStoryboardPrompt(episodeID, styleReferenceEpisodeID)
+}
+
+func (s *StoryboardService) GenerateStoryboard(episodeID string, model string, styleReferenceEpisodeID *uint) (string, error) {
+	taskID, built, err := s.startStoryboardTask(episodeID, styleReferenceEpisodeID)
+	if err != nil {
+		return "", err
+	}
+
+	// 启动后台goroutine处理AI调用和后续逻辑
+	go s.processStoryboardGeneration(taskID, episodeID, model, built)
+
+	// 立即返回任务ID
+	return taskID, nil
+}
+
+// startStoryboardTask 构建提示词、获取剧集生成锁并创建分镜生成任务；成功时锁由 processStoryboardGeneration 释放
+func (s *StoryboardService) startStoryboardTask(episodeID string, styleReferenceEpisodeID *uint) (string, *StoryboardPromptPreview, error) {
+	built, err := s.buildStoryboardPrompt(episodeID, styleReferenceEpisodeID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := lockEpisodeGeneration(episodeID); err != nil {
+		return "", nil, err
+	}
+
+	if styleReferenceEpisodeID != nil {
+		if err := s.db.Model(&models.Episode{}).Where("id = ?", episodeID).
+			Update("style_reference_episode_id", *styleReferenceEpisodeID).Error; err != nil {
+			s.log.Warnw("Failed to save style reference episode", "error", err, "episode_id", episodeID)
+		}
+	}
+
 	// 创建异步任务
 	task, err := s.taskService.CreateTask("storyboard_generation", episodeID)
 	if err != nil {
+		unlockEpisodeGeneration(episodeID)
 		s.log.Errorw("Failed to create task", "error", err)
-		return "", fmt.Errorf("创建任务失败: %w", err)
+		return "", nil, fmt.Errorf("创建任务失败: %w", err)
 	}
 
 	s.log.Infow("Generating storyboard asynchronously",
 		"task_id", task.ID,
 		"episode_id", episodeID,
-		"drama_id", episode.DramaID,
-		"script_length", len(scriptContent),
-		"character_count", len(characters),
-		"characters", characterList,
-		"scene_count", len(scenes),
-		"scenes", sceneList)
-
-	// 启动后台goroutine处理AI调用和后续逻辑
-	go s.processStoryboardGeneration(task.ID, episodeID, model, prompt)
-
-	// 立即返回任务ID
-	return task.ID, nil
+		"drama_id", built.DramaID,
+		"script_length", built.ScriptLength,
+		"character_count", built.CharacterCount,
+		"characters", built.CharacterList,
+		"scene_count", built.SceneCount,
+		"scenes", built.SceneList,
+		"style_reference_episode_id", styleReferenceEpisodeID)
+
+	return task.ID, built, nil
 }
 
 // processStoryboardGeneration 后台处理故事板生成
-func (s *StoryboardService) processStoryboardGeneration(taskID, episodeID, model, prompt string) {
+func (s *StoryboardService) processStoryboardGeneration(taskID, episodeID, model string, built *StoryboardPromptPreview) {
+	defer unlockEpisodeGeneration(episodeID)
+
 	// 更新任务状态为处理中
 	if err := s.taskService.UpdateTaskStatus(taskID, "processing", 10, "开始生成分镜头..."); err != nil {
 		s.log.Errorw("Failed to update task status", "error", err, "task_id", taskID)
@@ -353,69 +444,190 @@ func (s *StoryboardService) processStoryboardGeneration(taskID, episodeID, model
 	}
 
 	s.log.Infow("Processing storyboard generation", "task_id", taskID, "episode_id", episodeID)
+	i18n := s.promptI18n.WithLanguage(built.Language)
+
+	// 调用AI服务生成（剧本绑定了配置时使用绑定的配置，否则如果指定了模型则使用指定的模型）
+	dramaID, _ := strconv.ParseUint(built.DramaID, 10, 64)
+	client, model, err := s.aiService.GetAIClientForDrama("text", uint(dramaID), model)
+	if err != nil {
+		s.failStoryboardTask(taskID, fmt.Errorf("生成分镜头失败: %w", err))
+		return
+	}
+	s.log.Infow("Using model for storyboard generation", "model", model, "task_id", taskID)
 
-	// 调用AI服务生成（如果指定了模型则使用指定的模型）
-	// 设置较大的max_tokens以确保完整返回所有分镜的JSON
-	var text string
-	var err error
-	if model != "" {
-		s.log.Infow("Using specified model for storyboard generation", "model", model, "task_id", taskID)
-		client, getErr := s.aiService.GetAIClientForModel("text", model)
-		if getErr != nil {
-			s.log.Warnw("Failed to get client for specified model, using default", "model", model, "error", getErr, "task_id", taskID)
-			text, err = s.aiService.GenerateText(prompt, "", ai.WithMaxTokens(16000))
-		} else {
-			text, err = client.GenerateText(prompt, "", ai.WithMaxTokens(16000))
+	// 按模型上下文计算 max_tokens，剧本放不下时分段生成后合并
+	budget, fits := computeStoryboardBudget(model, built.Prompt)
+	if fits {
+		s.log.Infow("Storyboard token budget",
+			"task_id", taskID,
+			"model", model,
+			"context_tokens", budget.ContextTokens,
+			"prompt_tokens", budget.PromptTokens,
+			"max_tokens", budget.MaxTokens)
+
+		callClient := acquireTextCall(client)
+		callStart := time.Now()
+		text, err := callClient.GenerateText(built.Prompt, "", ai.WithMaxTokens(budget.MaxTokens))
+		observeStage(metricTaskStoryboard, StageAICall, textClientProvider(client), callStart)
+		if err != nil {
+			s.log.Errorw("Failed to generate storyboard", "error", err, "task_id", taskID)
+			s.failStoryboardTask(taskID, fmt.Errorf("生成分镜头失败: %w", err))
+			return
 		}
-	} else {
-		text, err = s.aiService.GenerateText(prompt, "", ai.WithMaxTokens(16000))
+		s.saveGeneratedStoryboards(taskID, episodeID, i18n, text, client, ai.WithMaxTokens(budget.MaxTokens))
+		return
 	}
 
+	chunks, err := splitScriptForBudget(model, built.Prompt, built.script)
 	if err != nil {
-		s.log.Errorw("Failed to generate storyboard", "error", err, "task_id", taskID)
-		if updateErr := s.taskService.UpdateTaskError(taskID, fmt.Errorf("生成分镜头失败: %w", err)); updateErr != nil {
-			s.log.Errorw("Failed to update task error", "error", updateErr, "task_id", taskID)
-		}
+		s.log.Errorw("Storyboard prompt exceeds model context", "error", err, "task_id", taskID, "model", model)
+		s.failStoryboardTask(taskID, err)
 		return
 	}
 
+	s.log.Infow("Script too long for model context, generating storyboard in chunks",
+		"task_id", taskID,
+		"model", model,
+		"context_tokens", budget.ContextTokens,
+		"prompt_tokens", budget.PromptTokens,
+		"chunks", len(chunks))
+
+	var results [][]Storyboard
+	generated := 0
+	for i, chunk := range chunks {
+		progress := 10 + 40*i/len(chunks)
+		if err := s.taskService.UpdateTaskStatus(taskID, "processing", progress,
+			fmt.Sprintf("正在生成分镜头（第%d/%d段）...", i+1, len(chunks))); err != nil {
+			s.log.Errorw("Failed to update task status", "error", err, "task_id", taskID)
+			return
+		}
+
+		prompt := storyboardChunkPrompt(built, chunk, i+1, len(chunks), generated+1)
+		chunkBudget, _ := computeStoryboardBudget(model, prompt)
+		s.log.Infow("Storyboard chunk token budget",
+			"task_id", taskID,
+			"chunk", i+1,
+			"prompt_tokens", chunkBudget.PromptTokens,
+			"max_tokens", chunkBudget.MaxTokens)
+
+		callClient := acquireTextCall(client)
+		callStart := time.Now()
+		text, err := callClient.GenerateText(prompt, "", ai.WithMaxTokens(chunkBudget.MaxTokens))
+		observeStage(metricTaskStoryboard, StageAICall, textClientProvider(client), callStart)
+		if err != nil {
+			s.log.Errorw("Failed to generate storyboard chunk", "error", err, "task_id", taskID, "chunk", i+1)
+			s.failStoryboardTask(taskID, fmt.Errorf("生成分镜头失败（第%d段）: %w", i+1, err))
+			return
+		}
+
+		parseStart := time.Now()
+		storyboards, err := repairStructured(s.aiService, client, i18n, text, storyboardJSONFormat, parseStoryboardText, ai.WithMaxTokens(chunkBudget.MaxTokens))
+		observeStage(metricTaskStoryboard, StageParse, "", parseStart)
+		if err != nil {
+			s.log.Errorw("Failed to parse storyboard chunk", "error", err, "response", text[:min(500, len(text))], "task_id", taskID, "chunk", i+1)
+			s.failStoryboardTask(taskID, fmt.Errorf("解析分镜头结果失败（第%d段）: %w", i+1, err))
+			return
+		}
+		results = append(results, storyboards)
+		generated += len(storyboards)
+
+		// 已解析的镜头先写入任务结果，前端轮询任务即可逐段看到分镜
+		merged := mergeStoryboardChunks(results)
+		s.publishPartialStoryboards(taskID, 10+40*(i+1)/len(chunks),
+			fmt.Sprintf("已生成 %d 个分镜头（第%d/%d段）", len(merged), i+1, len(chunks)), merged, 0)
+	}
+
+	// 段边界处的重复镜头只保留一份，合并后统一重新编号
+	all := mergeStoryboardChunks(results)
+	if dropped := generated - len(all); dropped > 0 {
+		s.log.Infow("Dropped duplicate shots at chunk boundaries", "task_id", taskID, "dropped", dropped)
+	}
+
+	s.saveParsedStoryboards(taskID, episodeID, all)
+}
+
+// publishPartialStoryboards 将已解析/已保存的镜头写入任务的阶段性结果（partial=true），total 为 0 表示总数未知；失败只记录日志
+func (s *StoryboardService) publishPartialStoryboards(taskID string, progress int, message string, storyboards []Storyboard, total int) {
+	if err := s.taskService.UpdateTaskPartialResult(taskID, progress, message, gin.H{
+		"storyboards": storyboards,
+		"count":       len(storyboards),
+		"total":       total,
+		"partial":     true,
+	}); err != nil {
+		s.log.Warnw("Failed to update partial task result", "error", err, "task_id", taskID)
+	}
+}
+
+// failStoryboardTask 将分镜生成任务标记为失败
+func (s *StoryboardService) failStoryboardTask(taskID string, err error) {
+	if updateErr := s.taskService.UpdateTaskError(taskID, err); updateErr != nil {
+		s.log.Errorw("Failed to update task error", "error", updateErr, "task_id", taskID)
+	}
+}
+
+// storyboardJSONFormat 分镜输出的JSON结构，分镜提示词没有单独的系统提示词，修复输出时用它说明格式
+const storyboardJSONFormat = `Output only a JSON object with this structure:
+{"storyboards": [{"shot_number": 1, "title": "", "shot_type": "", "angle": "", "time": "", "location": "", "scene_id": 1, "movement": "", "action": "", "dialogue": "", "result": "", "atmosphere": "", "emotion": "", "duration": 6, "bgm_prompt": "", "sound_effect": "", "characters": [1], "is_primary": true}]}
+shot_number, duration and scene_id are integers (scene_id may be null), characters is an array of integer IDs, is_primary is a boolean, all other fields are strings.`
+
+// saveGeneratedStoryboards 解析AI返回的分镜JSON并保存，同时更新剧集时长和任务结果
+// 无法解析时由 client 按 i18n 的语言修复输出，client 为 nil 时不修复
+func (s *StoryboardService) saveGeneratedStoryboards(taskID, episodeID string, i18n *PromptI18n, text string, client textGenerator, options ...func(*ai.ChatCompletionRequest)) {
 	// 更新任务进度
 	if err := s.taskService.UpdateTaskStatus(taskID, "processing", 50, "分镜头生成完成，正在解析结果..."); err != nil {
 		s.log.Errorw("Failed to update task status", "error", err, "task_id", taskID)
 		return
 	}
 
-	// 解析JSON结果
-	// AI可能返回两种格式：
-	// 1. 数组格式: [{...}, {...}]
-	// 2. 对象格式: {"storyboards": [{...}, {...}]}
-	var result GenerateStoryboardResult
+	s.log.Infow("Storyboard response received", "response_bytes", len(text), "task_id", taskID)
+	parseStart := time.Now()
+	storyboards, err := repairStructured(s.aiService, client, i18n, text, storyboardJSONFormat, parseStoryboardText, options...)
+	observeStage(metricTaskStoryboard, StageParse, "", parseStart)
+	if err != nil {
+		s.log.Errorw("Failed to parse storyboard JSON in both formats", "error", err, "response", text[:min(500, len(text))], "task_id", taskID)
+		s.failStoryboardTask(taskID, fmt.Errorf("解析分镜头结果失败: %w", err))
+		return
+	}
+	s.log.Infow("Parsed storyboard result", "count", len(storyboards), "task_id", taskID)
+
+	s.saveParsedStoryboards(taskID, episodeID, storyboards)
+}
 
-	// 先尝试解析为数组格式
+// parseStoryboardText 解析AI返回的分镜JSON
+// AI可能返回两种格式：
+// 1. 数组格式: [{...}, {...}]
+// 2. 对象格式: {"storyboards": [{...}, {...}]}
+func parseStoryboardText(text string) ([]Storyboard, error) {
 	var storyboards []Storyboard
 	if err := utils.SafeParseAIJSON(text, &storyboards); err == nil {
-		// 成功解析为数组，包装为对象
-		result.Storyboards = storyboards
-		result.Total = len(storyboards)
-		s.log.Infow("Parsed storyboard as array format", "count", len(storyboards), "task_id", taskID)
-	} else {
-		// 尝试解析为对象格式
-		if err := utils.SafeParseAIJSON(text, &result); err != nil {
-			s.log.Errorw("Failed to parse storyboard JSON in both formats", "error", err, "response", text[:min(500, len(text))], "task_id", taskID)
-			if updateErr := s.taskService.UpdateTaskError(taskID, fmt.Errorf("解析分镜头结果失败: %w", err)); updateErr != nil {
-				s.log.Errorw("Failed to update task error", "error", updateErr, "task_id", taskID)
-			}
-			return
-		}
-		result.Total = len(result.Storyboards)
-		s.log.Infow("Parsed storyboard as object format", "count", len(result.Storyboards), "task_id", taskID)
+		return storyboards, nil
+	}
+
+	var result GenerateStoryboardResult
+	if err := utils.SafeParseAIJSON(text, &result); err != nil {
+		return nil, err
 	}
+	return result.Storyboards, nil
+}
+
+// saveParsedStoryboards 保存解析后的分镜，同时更新剧集时长和任务结果
+func (s *StoryboardService) saveParsedStoryboards(taskID, episodeID string, storyboards []Storyboard) {
+	result := GenerateStoryboardResult{Storyboards: storyboards, Total: len(storyboards)}
 
-	// 计算总时长（所有分镜时长之和）
+	// AI有时会漏填characters，根据对话中的说话人补全
+	s.inferCharactersFromDialogue(taskID, episodeID, result.Storyboards)
+
+	// 计算总时长（所有分镜时长之和，包括保留的锁定镜头）
 	totalDuration := 0
 	for _, sb := range result.Storyboards {
 		totalDuration += sb.Duration
 	}
+	var lockedDuration int
+	if err := s.db.Model(&models.Storyboard{}).Select("COALESCE(SUM(duration), 0)").
+		Where("episode_id = ? AND locked = ?", episodeID, true).Scan(&lockedDuration).Error; err != nil {
+		s.log.Warnw("Failed to sum locked shot durations", "error", err, "task_id", taskID)
+	}
+	totalDuration += lockedDuration
 
 	s.log.Infow("Storyboard generated",
 		"task_id", taskID,
@@ -430,13 +642,18 @@ func (s *StoryboardService) processStoryboardGeneration(taskID, episodeID, model
 	}
 
 	// 保存分镜头到数据库
-	if err := s.saveStoryboards(episodeID, result.Storyboards); err != nil {
+	saveStart := time.Now()
+	err := s.saveStoryboards(episodeID, result.Storyboards)
+	observeStage(metricTaskStoryboard, StageSave, "", saveStart)
+	if err != nil {
 		s.log.Errorw("Failed to save storyboards", "error", err, "task_id", taskID)
 		if updateErr := s.taskService.UpdateTaskError(taskID, fmt.Errorf("保存分镜头失败: %w", err)); updateErr != nil {
 			s.log.Errorw("Failed to update task error", "error", updateErr, "task_id", taskID)
 		}
 		return
 	}
+	// 事务提交后再发布阶段性结果：任务表通过另一个连接写入，在事务内写会与事务争用连接（SQLite 只有一个连接）
+	s.publishPartialStoryboards(taskID, 90, fmt.Sprintf("已保存 %d 个分镜头", result.Total), result.Storyboards, result.Total)
 
 	// 更新任务进度
 	if err := s.taskService.UpdateTaskStatus(taskID, "processing", 90, "正在更新剧集时长..."); err != nil {
@@ -473,8 +690,8 @@ func (s *StoryboardService) processStoryboardGeneration(taskID, episodeID, model
 	s.log.Infow("Storyboard generation completed", "task_id", taskID, "episode_id", episodeID)
 }
 
-// generateImagePrompt 生成专门用于图片生成的提示词（首帧静态画面）
-func (s *StoryboardService) generateImagePrompt(sb Storyboard) string {
+// generateImagePrompt 生成专门用于图片生成的提示词（首帧静态画面），dramaStyle 为剧本风格，suffix 为末尾的画质描述
+func (s *StoryboardService) generateImagePrompt(sb Storyboard, dramaStyle string, suffix string) string {
 	var parts []string
 
 	// 1. 完整的场景背景描述
@@ -499,13 +716,28 @@ func (s *StoryboardService) generateImagePrompt(sb Storyboard) string {
 		parts = append(parts, sb.Emotion)
 	}
 
-	// 4. 动漫风格
-	parts = append(parts, "anime style, first frame")
+	// 4. 风格和帧类型后缀
+	parts = appendStyleAndFrame(parts, dramaStyle, FrameTypeFirst)
 
-	if len(parts) > 0 {
-		return strings.Join(parts, ", ")
+	// 5. 画质描述
+	if suffix != "" {
+		parts = append(parts, suffix)
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// dramaStyleForEpisode 获取剧集所属剧本的风格，查询失败时返回空字符串
+func (s *StoryboardService) dramaStyleForEpisode(episodeID interface{}) string {
+	var style string
+	if err := s.db.Table("dramas").
+		Select("dramas.style").
+		Joins("INNER JOIN episodes ON episodes.drama_id = dramas.id").
+		Where("episodes.id = ?", episodeID).
+		Scan(&style).Error; err != nil {
+		s.log.Warnw("Failed to load drama style", "error", err, "episode_id", episodeID)
 	}
-	return "anime scene"
+	return style
 }
 
 // extractInitialPose 提取初始静态姿态（去除动作过程）
@@ -617,10 +849,14 @@ func extractCompositionType(shotType string) string {
 	return comp
 }
 
-// generateVideoPrompt 生成专门用于视频生成的提示词（包含运镜和动态元素）
-func (s *StoryboardService) generateVideoPrompt(sb Storyboard) string {
+// generateVideoPrompt 按指定画面比例生成专门用于视频生成的提示词（包含运镜和动态元素）
+func (s *StoryboardService) generateVideoPrompt(sb Storyboard, videoRatio string, suffix string) string {
+	return s.generateVideoPromptWithStyle(sb, "", videoRatio, suffix)
+}
+
+// generateVideoPromptWithStyle 按指定风格和画面比例生成视频提示词，style 为空时不附加风格，suffix 为空时不附加画质描述
+func (s *StoryboardService) generateVideoPromptWithStyle(sb Storyboard, style string, videoRatio string, suffix string) string {
 	var parts []string
-	videoRatio := "16:9"
 	// 1. 人物动作
 	if sb.Action != "" {
 		parts = append(parts, fmt.Sprintf("Action: %s", sb.Action))
@@ -674,7 +910,15 @@ func (s *StoryboardService) generateVideoPrompt(sb Storyboard) string {
 		parts = append(parts, fmt.Sprintf("Sound effects: %s", sb.SoundEffect))
 	}
 
-	// 9. 视频比例
+	// 9. 画面风格
+	if style != "" {
+		parts = append(parts, fmt.Sprintf("Style: %s", style))
+	}
+	if suffix != "" {
+		parts = append(parts, fmt.Sprintf("Quality: %s", suffix))
+	}
+
+	// 10. 视频比例
 	parts = append(parts, fmt.Sprintf("=VideoRatio: %s", videoRatio))
 	if len(parts) > 0 {
 		return strings.Join(parts, ". ")
@@ -695,12 +939,26 @@ func (s *StoryboardService) saveStoryboards(episodeID string, storyboards []Stor
 		s.log.Errorw("AI返回的分镜数量为0，拒绝保存以避免删除现有分镜", "episode_id", episodeID)
 		return fmt.Errorf("AI生成分镜失败：返回的分镜数量为0")
 	}
+	var lockedCount int64
+	if err := s.db.Model(&models.Storyboard{}).Where("episode_id = ? AND locked = ?", uint(epID), true).
+		Count(&lockedCount).Error; err != nil {
+		return fmt.Errorf("查询锁定镜头失败: %w", err)
+	}
+	if err := checkLimit("分镜数量", len(storyboards)+int(lockedCount), currentLimits().StoryboardsPerEpisode()); err != nil {
+		return err
+	}
 
 	s.log.Infow("开始保存分镜头",
 		"episode_id", episodeID,
 		"episode_id_uint", uint(epID),
 		"storyboard_count", len(storyboards))
 
+	dramaStyle := s.dramaStyleForEpisode(uint(epID))
+	videoRatio := s.videoRatioForEpisode(uint(epID))
+	suffixes := s.promptSuffixesForEpisode(uint(epID))
+
+	strict := config.CurrentOr(s.config).AI.StrictStoryboardSave
+
 	// 开启事务
 	return s.db.Transaction(func(tx *gorm.DB) error {
 		// 验证该章节是否存在
@@ -716,10 +974,21 @@ func (s *StoryboardService) saveStoryboards(episodeID string, storyboards []Stor
 			"drama_id", episode.DramaID,
 			"title", episode.Title)
 
-		// 获取该剧集所有的分镜ID（使用 uint 类型）
+		// 锁定的镜头保留原内容和编号，新镜头按顺序编号并跳过锁定镜头占用的编号
+		var lockedNumbers []int
+		if err := tx.Model(&models.Storyboard{}).
+			Where("episode_id = ? AND locked = ?", uint(epID), true).
+			Pluck("storyboard_number", &lockedNumbers).Error; err != nil {
+			return err
+		}
+		if len(lockedNumbers) > 0 {
+			numberAroundLockedShots(storyboards, lockedNumbers)
+		}
+
+		// 获取该剧集所有未锁定的分镜ID（使用 uint 类型）
 		var storyboardIDs []uint
 		if err := tx.Model(&models.Storyboard{}).
-			Where("episode_id = ?", uint(epID)).
+			Where("episode_id = ? AND locked = ?", uint(epID), false).
 			Pluck("id", &storyboardIDs).Error; err != nil {
 			return err
 		}
@@ -728,7 +997,8 @@ func (s *StoryboardService) saveStoryboards(episodeID string, storyboards []Stor
 			"episode_id_string", episodeID,
 			"episode_id_uint", uint(epID),
 			"existing_storyboard_count", len(storyboardIDs),
-			"storyboard_ids", storyboardIDs)
+			"storyboard_ids", storyboardIDs,
+			"locked_count", len(lockedNumbers))
 
 		// 如果有分镜，先清理关联的image_generations的storyboard_id
 		if len(storyboardIDs) > 0 {
@@ -747,7 +1017,7 @@ func (s *StoryboardService) saveStoryboards(episodeID string, storyboards []Stor
 			"episode_id_from_db", episode.ID,
 			"will_delete_count", len(storyboardIDs))
 
-		result := tx.Where("episode_id = ?", uint(epID)).Delete(&models.Storyboard{})
+		result := tx.Where("episode_id = ? AND locked = ?", uint(epID), false).Delete(&models.Storyboard{})
 		if result.Error != nil {
 			s.log.Errorw("删除旧分镜失败", "episode_id", uint(epID), "error", result.Error)
 			return result.Error
@@ -767,8 +1037,8 @@ func (s *StoryboardService) saveStoryboards(episodeID string, storyboards []Stor
 				sb.ShotType, sb.Movement, sb.Action, sb.Dialogue, sb.Result, sb.Emotion)
 
 			// 生成两种专用提示词
-			imagePrompt := s.generateImagePrompt(sb) // 专用于图片生成
-			videoPrompt := s.generateVideoPrompt(sb) // 专用于视频生成
+			imagePrompt := s.generateImagePrompt(sb, dramaStyle, suffixes.Image) // 专用于图片生成
+			videoPrompt := s.generateVideoPrompt(sb, videoRatio, suffixes.Video) // 专用于视频生成
 
 			// 处理 dialogue 字段
 			var dialoguePtr *string
@@ -839,6 +1109,7 @@ func (s *StoryboardService) saveStoryboards(episodeID string, storyboards []Stor
 				BgmPrompt:        bgmPromptPtr,
 				SoundEffect:      soundEffectPtr,
 				Duration:         sb.Duration,
+				DialogueLines:    parseDialogue(sb.Dialogue),
 			}
 
 			if err := tx.Create(&scene).Error; err != nil {
@@ -847,20 +1118,8 @@ func (s *StoryboardService) saveStoryboards(episodeID string, storyboards []Stor
 			}
 
 			// 关联角色
-			if len(sb.Characters) > 0 {
-				var characters []models.Character
-				if err := tx.Where("id IN ?", sb.Characters).Find(&characters).Error; err != nil {
-					s.log.Warnw("Failed to load characters for association", "error", err, "character_ids", sb.Characters)
-				} else if len(characters) > 0 {
-					if err := tx.Model(&scene).Association("Characters").Append(characters); err != nil {
-						s.log.Warnw("Failed to associate characters", "error", err, "shot_number", sb.ShotNumber)
-					} else {
-						s.log.Infow("Characters associated successfully",
-							"shot_number", sb.ShotNumber,
-							"character_ids", sb.Characters,
-							"count", len(characters))
-					}
-				}
+			if err := s.associateStoryboardCharacters(tx, &scene, sb, strict); err != nil {
+				return err
 			}
 		}
 
@@ -869,6 +1128,74 @@ func (s *StoryboardService) saveStoryboards(episodeID string, storyboards []Stor
 	})
 }
 
+// associateStoryboardCharacters 关联镜头中出场的角色
+// strict 为 true 时查询失败、角色ID不存在或关联失败都返回错误使事务回滚，否则只记录警告并继续保存
+func (s *StoryboardService) associateStoryboardCharacters(tx *gorm.DB, scene *models.Storyboard, sb Storyboard, strict bool) error {
+	if len(sb.Characters) == 0 {
+		return nil
+	}
+
+	var characters []models.Character
+	if err := tx.Where("id IN ?", sb.Characters).Find(&characters).Error; err != nil {
+		if strict {
+			return fmt.Errorf("镜头 %d 查询角色失败: %w", sb.ShotNumber, err)
+		}
+		s.log.Warnw("Failed to load characters for association", "error", err, "character_ids", sb.Characters)
+		return nil
+	}
+
+	if len(characters) < len(sb.Characters) {
+		found := make(map[uint]bool, len(characters))
+		for _, char := range characters {
+			found[char.ID] = true
+		}
+		var missing []uint
+		for _, id := range sb.Characters {
+			if !found[id] {
+				missing = append(missing, id)
+			}
+		}
+		if len(missing) > 0 {
+			if strict {
+				return fmt.Errorf("镜头 %d 关联的角色不存在: %v", sb.ShotNumber, missing)
+			}
+			s.log.Warnw("Some characters not found for association", "shot_number", sb.ShotNumber, "missing_ids", missing)
+		}
+	}
+	if len(characters) == 0 {
+		return nil
+	}
+
+	if err := tx.Model(scene).Association("Characters").Append(characters); err != nil {
+		if strict {
+			return fmt.Errorf("镜头 %d 关联角色失败: %w", sb.ShotNumber, err)
+		}
+		s.log.Warnw("Failed to associate characters", "error", err, "shot_number", sb.ShotNumber)
+		return nil
+	}
+	s.log.Infow("Characters associated successfully",
+		"shot_number", sb.ShotNumber,
+		"character_ids", sb.Characters,
+		"count", len(characters))
+	return nil
+}
+
+// numberAroundLockedShots 为新镜头按顺序分配编号，跳过锁定镜头已占用的编号
+func numberAroundLockedShots(storyboards []Storyboard, lockedNumbers []int) {
+	taken := make(map[int]bool, len(lockedNumbers))
+	for _, n := range lockedNumbers {
+		taken[n] = true
+	}
+	next := 1
+	for i := range storyboards {
+		for taken[next] {
+			next++
+		}
+		storyboards[i].ShotNumber = next
+		next++
+	}
+}
+
 // CreateStoryboardRequest 创建分镜请求
 type CreateStoryboardRequest struct {
 	EpisodeID        uint    `json:"episode_id"`
@@ -889,10 +1216,29 @@ type CreateStoryboardRequest struct {
 	SoundEffect      *string `json:"sound_effect"`
 	Duration         int     `json:"duration"`
 	Characters       []uint  `json:"characters"`
+	VideoRatio       string  `json:"video_ratio"` // 本次生成视频提示词使用的画面比例，为空时使用剧集设置
 }
 
 // CreateStoryboard 创建单个分镜
 func (s *StoryboardService) CreateStoryboard(req *CreateStoryboardRequest) (*models.Storyboard, error) {
+	videoRatio := req.VideoRatio
+	if videoRatio == "" {
+		videoRatio = s.videoRatioForEpisode(req.EpisodeID)
+	} else if err := validateVideoRatio(videoRatio); err != nil {
+		return nil, err
+	}
+
+	sanitizeStrings(req.Title, req.ShotType, req.Angle, req.Time, req.Location, req.Movement, req.Description,
+		req.Action, req.Result, req.Atmosphere, req.Dialogue, req.BgmPrompt, req.SoundEffect)
+
+	var count int64
+	if err := s.db.Model(&models.Storyboard{}).Where("episode_id = ?", req.EpisodeID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to count storyboards: %w", err)
+	}
+	if err := checkLimit("分镜数量", int(count)+1, currentLimits().StoryboardsPerEpisode()); err != nil {
+		return nil, err
+	}
+
 	// 构建Storyboard对象
 	sb := Storyboard{
 		ShotNumber:  req.StoryboardNumber,
@@ -917,8 +1263,9 @@ func (s *StoryboardService) CreateStoryboard(req *CreateStoryboardRequest) (*mod
 	}
 
 	// 生成提示词
-	imagePrompt := s.generateImagePrompt(sb)
-	videoPrompt := s.generateVideoPrompt(sb)
+	suffixes := s.promptSuffixesForEpisode(req.EpisodeID)
+	imagePrompt := s.generateImagePrompt(sb, s.dramaStyleForEpisode(req.EpisodeID), suffixes.Image)
+	videoPrompt := s.generateVideoPrompt(sb, videoRatio, suffixes.Video)
 
 	// 构建 description
 	desc := ""
@@ -946,6 +1293,7 @@ func (s *StoryboardService) CreateStoryboard(req *CreateStoryboardRequest) (*mod
 		BgmPrompt:        req.BgmPrompt,
 		SoundEffect:      req.SoundEffect,
 		Duration:         req.Duration,
+		DialogueLines:    parseDialogue(getString(req.Dialogue)),
 	}
 
 	if err := s.db.Create(modelSB).Error; err != nil {
@@ -962,19 +1310,48 @@ func (s *StoryboardService) CreateStoryboard(req *CreateStoryboardRequest) (*mod
 		}
 	}
 
+	s.syncEpisodeDuration(modelSB.EpisodeID)
+
 	s.log.Infow("Storyboard created", "id", modelSB.ID, "episode_id", req.EpisodeID)
 	return modelSB, nil
 }
 
 // DeleteStoryboard 删除分镜
 func (s *StoryboardService) DeleteStoryboard(storyboardID uint) error {
+	var storyboard models.Storyboard
+	if err := s.db.Select("id, episode_id").Where("id = ?", storyboardID).First(&storyboard).Error; err != nil {
+		return ErrStoryboardNotFound
+	}
+
 	result := s.db.Where("id = ? ", storyboardID).Delete(&models.Storyboard{})
 	if result.Error != nil {
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("storyboard not found")
+		return ErrStoryboardNotFound
 	}
+
+	s.syncEpisodeDuration(storyboard.EpisodeID)
+	return nil
+}
+
+// SetStoryboardLocked 锁定或解锁分镜，锁定的镜头在重新生成分镜时保留
+func (s *StoryboardService) SetStoryboardLocked(storyboardID uint, locked bool) error {
+	result := s.db.Model(&models.Storyboard{}).Where("id = ?", storyboardID).Update("locked", locked)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := s.db.Model(&models.Storyboard{}).Where("id = ?", storyboardID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrStoryboardNotFound
+		}
+	}
+
+	s.log.Infow("Storyboard lock updated", "storyboard_id", storyboardID, "locked", locked)
 	return nil
 }
 
@@ -991,3 +1368,95 @@ func getString(s *string) string {
 	}
 	return *s
 }
+
+// sanitizeStrings 就地清理用户输入的可选文本字段，nil 跳过
+func sanitizeStrings(fields ...*string) {
+	for _, f := range fields {
+		if f != nil {
+			*f = utils.SanitizePrompt(*f)
+		}
+	}
+}
+
+// BulkUpdateStoryboardCharacters 在一个事务中批量替换多个分镜的角色关联（storyboardID -> 角色ID列表）
+func (s *StoryboardService) BulkUpdateStoryboardCharacters(updates map[uint][]uint) error {
+	if len(updates) == 0 {
+		return fmt.Errorf("没有需要更新的分镜")
+	}
+
+	storyboardIDs := make([]uint, 0, len(updates))
+	characterIDSet := make(map[uint]bool)
+	for sbID, charIDs := range updates {
+		storyboardIDs = append(storyboardIDs, sbID)
+		for _, charID := range charIDs {
+			characterIDSet[charID] = true
+		}
+	}
+
+	// 所有分镜必须存在且属于同一个剧本
+	var dramaIDs []uint
+	var found int64
+	if err := s.db.Model(&models.Storyboard{}).
+		Joins("INNER JOIN episodes ON episodes.id = storyboards.episode_id").
+		Where("storyboards.id IN ?", storyboardIDs).
+		Count(&found).Error; err != nil {
+		return fmt.Errorf("查询分镜失败: %w", err)
+	}
+	if int(found) != len(storyboardIDs) {
+		return fmt.Errorf("部分分镜不存在")
+	}
+	if err := s.db.Model(&models.Storyboard{}).
+		Joins("INNER JOIN episodes ON episodes.id = storyboards.episode_id").
+		Where("storyboards.id IN ?", storyboardIDs).
+		Distinct().Pluck("episodes.drama_id", &dramaIDs).Error; err != nil {
+		return fmt.Errorf("查询分镜所属剧本失败: %w", err)
+	}
+	if len(dramaIDs) != 1 {
+		return fmt.Errorf("批量更新的分镜必须属于同一个剧本")
+	}
+	dramaID := dramaIDs[0]
+
+	// 应用任何修改前，先校验全部角色都属于该剧本
+	characterMap := make(map[uint]models.Character)
+	if len(characterIDSet) > 0 {
+		characterIDs := make([]uint, 0, len(characterIDSet))
+		for id := range characterIDSet {
+			characterIDs = append(characterIDs, id)
+		}
+		var characters []models.Character
+		if err := s.db.Where("id IN ? AND drama_id = ?", characterIDs, dramaID).Find(&characters).Error; err != nil {
+			return fmt.Errorf("查询角色失败: %w", err)
+		}
+		if len(characters) != len(characterIDs) {
+			return fmt.Errorf("部分角色不存在或不属于该剧本")
+		}
+		for _, char := range characters {
+			characterMap[char.ID] = char
+		}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for sbID, charIDs := range updates {
+			storyboard := models.Storyboard{ID: sbID}
+			characters := make([]models.Character, 0, len(charIDs))
+			for _, charID := range charIDs {
+				characters = append(characters, characterMap[charID])
+			}
+			if err := tx.Model(&storyboard).Association("Characters").Clear(); err != nil {
+				return fmt.Errorf("清除分镜 %d 的角色关联失败: %w", sbID, err)
+			}
+			if len(characters) > 0 {
+				if err := tx.Model(&storyboard).Association("Characters").Append(characters); err != nil {
+					return fmt.Errorf("更新分镜 %d 的角色关联失败: %w", sbID, err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.log.Infow("Storyboard characters bulk updated", "drama_id", dramaID, "storyboard_count", len(updates))
+	return nil
+}
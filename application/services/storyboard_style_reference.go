@@ -0,0 +1,85 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	models "github.com/drama-generator/backend/domain/models"
+)
+
+// maxStyleReferenceShots 风格参考时最多引用的示例镜头数
+const maxStyleReferenceShots = 5
+
+// styleReferenceShot 风格参考提示词中的示例镜头
+type styleReferenceShot struct {
+	ShotNumber int    `json:"shot_number"`
+	Title      string `json:"title"`
+	ShotType   string `json:"shot_type"`
+	Angle      string `json:"angle"`
+	Movement   string `json:"movement"`
+	Action     string `json:"action"`
+	Atmosphere string `json:"atmosphere"`
+	Duration   int    `json:"duration"`
+}
+
+// buildStyleReferencePrompt 从参考剧集中挑选代表性镜头，构建风格示例提示词
+func (s *StoryboardService) buildStyleReferencePrompt(i18n *PromptI18n, episodeID, dramaID string, refEpisodeID uint) (string, error) {
+	var refEpisode models.Episode
+	if err := s.db.Where("id = ? AND drama_id = ?", refEpisodeID, dramaID).First(&refEpisode).Error; err != nil {
+		return "", &ServiceError{Kind: ErrNotFound, Message: "参考剧集不存在或不属于当前剧本"}
+	}
+	if fmt.Sprintf("%d", refEpisode.ID) == episodeID {
+		return "", &ServiceError{Kind: ErrInvalidInput, Message: "参考剧集不能是当前剧集"}
+	}
+
+	var storyboards []models.Storyboard
+	if err := s.db.Where("episode_id = ?", refEpisode.ID).
+		Order("storyboard_number ASC").
+		Find(&storyboards).Error; err != nil {
+		return "", fmt.Errorf("获取参考剧集分镜失败: %w", err)
+	}
+	if len(storyboards) == 0 {
+		return "", &ServiceError{Kind: ErrInvalidInput, Message: "参考剧集还没有分镜"}
+	}
+
+	// 均匀抽样，覆盖参考剧集的开头、中段和结尾
+	var samples []models.Storyboard
+	if len(storyboards) <= maxStyleReferenceShots {
+		samples = storyboards
+	} else {
+		step := float64(len(storyboards)-1) / float64(maxStyleReferenceShots-1)
+		for i := 0; i < maxStyleReferenceShots; i++ {
+			samples = append(samples, storyboards[int(float64(i)*step+0.5)])
+		}
+	}
+
+	totalDuration := 0
+	for _, sb := range storyboards {
+		totalDuration += sb.Duration
+	}
+
+	var shotList []string
+	for _, sb := range samples {
+		shot, err := json.Marshal(styleReferenceShot{
+			ShotNumber: sb.StoryboardNumber,
+			Title:      getString(sb.Title),
+			ShotType:   getString(sb.ShotType),
+			Angle:      getString(sb.Angle),
+			Movement:   getString(sb.Movement),
+			Action:     getString(sb.Action),
+			Atmosphere: getString(sb.Atmosphere),
+			Duration:   sb.Duration,
+		})
+		if err != nil {
+			return "", fmt.Errorf("序列化参考镜头失败: %w", err)
+		}
+		shotList = append(shotList, string(shot))
+	}
+
+	label := i18n.FormatUserPrompt("style_ref_label")
+	instruction := i18n.FormatUserPrompt("style_ref_instruction",
+		refEpisode.EpisodeNum, len(storyboards), totalDuration/len(storyboards))
+
+	return fmt.Sprintf("\n\n%s\n%s\n[%s]", label, instruction, strings.Join(shotList, ",\n")), nil
+}
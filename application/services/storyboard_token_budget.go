@@ -0,0 +1,130 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/drama-generator/backend/pkg/config"
+)
+
+const (
+	defaultContextTokens      = 128000
+	defaultMaxOutputTokens    = 16000
+	minStoryboardOutputTokens = 4000 // 低于该值时分镜 JSON 很可能被截断，改为分段生成
+	tokenBudgetSafetyMargin   = 1000 // 估算误差和消息格式开销
+)
+
+// storyboardTokenBudget 单次分镜生成调用的 token 预算
+type storyboardTokenBudget struct {
+	Model         string
+	ContextTokens int
+	PromptTokens  int
+	MaxTokens     int
+}
+
+// estimateTokens 粗略估算文本 token 数：非 ASCII 字符（中文等）按每字 1 个，ASCII 按每 4 个字符 1 个
+func estimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < 128 {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return other + (ascii+3)/4
+}
+
+// modelLimitFor 获取模型的 token 上限，未配置的项使用默认值
+func modelLimitFor(model string) config.ModelLimit {
+	var limit config.ModelLimit
+	if cfg := config.Current(); cfg != nil {
+		// viper 会将 map 的 key 转为小写
+		limit = cfg.AI.ModelLimits[strings.ToLower(model)]
+	}
+	if limit.ContextTokens <= 0 {
+		limit.ContextTokens = defaultContextTokens
+	}
+	if limit.MaxOutputTokens <= 0 {
+		limit.MaxOutputTokens = defaultMaxOutputTokens
+	}
+	if limit.MaxOutputTokens > limit.ContextTokens {
+		limit.MaxOutputTokens = limit.ContextTokens
+	}
+	return limit
+}
+
+// computeStoryboardBudget 根据模型上下文减去提示词大小计算安全的 max_tokens，不足 minStoryboardOutputTokens 时返回 ok=false
+func computeStoryboardBudget(model, prompt string) (storyboardTokenBudget, bool) {
+	limit := modelLimitFor(model)
+	budget := storyboardTokenBudget{
+		Model:         model,
+		ContextTokens: limit.ContextTokens,
+		PromptTokens:  estimateTokens(prompt),
+	}
+	budget.MaxTokens = min(limit.MaxOutputTokens, limit.ContextTokens-budget.PromptTokens-tokenBudgetSafetyMargin)
+	return budget, budget.MaxTokens >= minStoryboardOutputTokens
+}
+
+// splitScriptForBudget 计算剧本分段：除剧本外的提示词开销固定，剩余上下文约 1/3 留给剧本、2/3 留给输出
+// （分镜描述远比剧本原文详细），按段落边界切分
+func splitScriptForBudget(model, prompt, script string) ([]string, error) {
+	limit := modelLimitFor(model)
+	overhead := estimateTokens(prompt) - estimateTokens(script)
+	remaining := limit.ContextTokens - overhead - tokenBudgetSafetyMargin
+	if remaining < minStoryboardOutputTokens*3/2 {
+		return nil, fmt.Errorf("模型 %s 的上下文（%d tokens）不足以容纳分镜提示词", model, limit.ContextTokens)
+	}
+
+	output := min(limit.MaxOutputTokens, remaining*2/3)
+	chunkTokens := remaining - output
+	return splitTextByTokens(script, chunkTokens), nil
+}
+
+// splitTextByTokens 按段落将文本切分为不超过 maxTokens 的若干段，单个超长段落按字符硬切
+func splitTextByTokens(text string, maxTokens int) []string {
+	var chunks []string
+	var current strings.Builder
+	currentTokens := 0
+
+	flush := func() {
+		if chunk := strings.TrimSpace(current.String()); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		current.Reset()
+		currentTokens = 0
+	}
+
+	for _, para := range strings.SplitAfter(text, "\n") {
+		paraTokens := estimateTokens(para)
+		if currentTokens+paraTokens > maxTokens {
+			flush()
+		}
+		if paraTokens <= maxTokens {
+			current.WriteString(para)
+			currentTokens += paraTokens
+			continue
+		}
+
+		for _, r := range para {
+			rt := estimateTokens(string(r))
+			if currentTokens+rt > maxTokens {
+				flush()
+			}
+			current.WriteRune(r)
+			currentTokens += rt
+		}
+	}
+	flush()
+
+	return chunks
+}
+
+// storyboardChunkPrompt 将完整提示词中的剧本替换为其中一段，并说明分段位置和起始镜头编号
+func storyboardChunkPrompt(built *StoryboardPromptPreview, chunk string, index, total, startShot int) string {
+	prompt := strings.Replace(built.Prompt, built.script, chunk, 1)
+	if built.Language == "en" {
+		return prompt + fmt.Sprintf("\n\n[Segment note] The script is long and has been split into %d segments. Only break down segment %d above. Start shot_number at %d.", total, index, startShot)
+	}
+	return prompt + fmt.Sprintf("\n\n【分段说明】剧本较长，已分为%d段，本次只拆解上面的第%d段，shot_number从%d开始编号。", total, index, startShot)
+}
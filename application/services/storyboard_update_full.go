@@ -2,16 +2,35 @@ package services
 
 import (
 	"fmt"
+	"slices"
+	"sort"
 
 	"github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/utils"
+	"gorm.io/gorm"
 )
 
-// UpdateStoryboard 更新分镜的所有字段，并重新生成提示词
-func (s *StoryboardService) UpdateStoryboard(storyboardID string, updates map[string]interface{}) error {
+// UpdateStoryboard 更新分镜字段，只写入与当前值不同的字段；提示词依赖的字段变化时重新生成对应的 image_prompt / video_prompt
+// updates 中的 video_ratio 只用于本次生成视频提示词
+func (s *StoryboardService) UpdateStoryboard(storyboardID string, updates map[string]interface{}) (*StoryboardUpdateResult, error) {
 	// 查找分镜
 	var storyboard models.Storyboard
 	if err := s.db.First(&storyboard, storyboardID).Error; err != nil {
-		return fmt.Errorf("storyboard not found: %w", err)
+		return nil, fmt.Errorf("storyboard not found: %w", err)
+	}
+
+	videoRatio, _ := updates["video_ratio"].(string)
+	explicitRatio := videoRatio != ""
+	if !explicitRatio {
+		videoRatio = s.videoRatioForEpisode(storyboard.EpisodeID)
+	} else if err := validateVideoRatio(videoRatio); err != nil {
+		return nil, err
+	}
+
+	for key, val := range updates {
+		if str, ok := val.(string); ok {
+			updates[key] = utils.SanitizePrompt(str)
+		}
 	}
 
 	// 构建用于重新生成提示词的Storyboard结构
@@ -81,6 +100,15 @@ func (s *StoryboardService) UpdateStoryboard(storyboardID string, updates map[st
 		sceneID := uint(val)
 		updateData["scene_id"] = sceneID
 	}
+	var excludedCharacters []uint
+	_, updateExcluded := updates["excluded_characters"]
+	if updateExcluded {
+		ids, err := s.parseExcludedCharacters(storyboard.EpisodeID, updates["excluded_characters"])
+		if err != nil {
+			return nil, err
+		}
+		excludedCharacters = ids
+	}
 
 	// 使用当前数据库值填充缺失字段（用于生成提示词）
 	if sb.Title == "" && storyboard.Title != nil {
@@ -123,20 +151,228 @@ func (s *StoryboardService) UpdateStoryboard(storyboardID string, updates map[st
 		sb.Duration = storyboard.Duration
 	}
 
-	// 只重新生成video_prompt
-	// image_prompt不自动更新，因为可能对应多张已生成的帧图片
-	videoPrompt := s.generateVideoPrompt(sb)
+	// 只保留与数据库中不同的字段，提示词按变化的字段决定是否重新生成
+	current := storyboardFromModel(&storyboard)
+	currentValues := storyboardComparableValues(&storyboard)
+	for key, val := range updateData {
+		if currentValues[key] == val {
+			delete(updateData, key)
+		}
+	}
+	if updateExcluded && slices.Equal(excludedCharacters, storyboard.ExcludedCharacters) {
+		updateExcluded = false
+	}
+
+	result := &StoryboardUpdateResult{ChangedFields: []string{}, UpdatedPrompts: []string{}}
+	for key := range updateData {
+		result.ChangedFields = append(result.ChangedFields, key)
+	}
+	if updateExcluded {
+		result.ChangedFields = append(result.ChangedFields, "excluded_characters")
+	}
+	sort.Strings(result.ChangedFields)
 
-	updateData["video_prompt"] = videoPrompt
+	suffixes := s.promptSuffixesForEpisode(storyboard.EpisodeID)
+	if promptInputsChanged(current, sb, imagePromptFields) {
+		updateData["image_prompt"] = s.generateImagePrompt(sb, s.dramaStyleForEpisode(storyboard.EpisodeID), suffixes.Image)
+		result.UpdatedPrompts = append(result.UpdatedPrompts, "image_prompt")
+	}
+	// 显式指定画面比例时即使字段未变也按新比例重新生成
+	if promptInputsChanged(current, sb, videoPromptFields) || explicitRatio {
+		updateData["video_prompt"] = s.generateVideoPrompt(sb, videoRatio, suffixes.Video)
+		result.UpdatedPrompts = append(result.UpdatedPrompts, "video_prompt")
+	}
 
 	// 更新数据库
-	if err := s.db.Model(&storyboard).Updates(updateData).Error; err != nil {
-		return fmt.Errorf("failed to update storyboard: %w", err)
+	if len(updateData) > 0 {
+		if err := s.db.Model(&storyboard).Updates(updateData).Error; err != nil {
+			return nil, fmt.Errorf("failed to update storyboard: %w", err)
+		}
+	}
+	// 序列化字段需按结构体更新，map 更新不会经过 serializer
+	if updateExcluded {
+		if err := s.db.Model(&models.Storyboard{ID: storyboard.ID}).Select("excluded_characters").
+			Updates(&models.Storyboard{ExcludedCharacters: excludedCharacters}).Error; err != nil {
+			return nil, fmt.Errorf("failed to update excluded characters: %w", err)
+		}
+	}
+	if _, ok := updateData["dialogue"]; ok {
+		if err := s.db.Model(&models.Storyboard{ID: storyboard.ID}).Select("dialogue_lines").
+			Updates(&models.Storyboard{DialogueLines: parseDialogue(sb.Dialogue)}).Error; err != nil {
+			return nil, fmt.Errorf("failed to update dialogue lines: %w", err)
+		}
+	}
+
+	if _, ok := updateData["duration"]; ok {
+		s.syncEpisodeDuration(storyboard.EpisodeID)
 	}
 
 	s.log.Infow("Storyboard updated successfully",
 		"storyboard_id", storyboardID,
-		"fields_updated", len(updateData))
+		"changed_fields", result.ChangedFields,
+		"updated_prompts", result.UpdatedPrompts)
+
+	return result, nil
+}
+
+// StoryboardUpdateResult 分镜更新结果：实际发生变化的字段和随之重新生成的提示词
+type StoryboardUpdateResult struct {
+	ChangedFields  []string `json:"changed_fields"`
+	UpdatedPrompts []string `json:"updated_prompts"` // image_prompt / video_prompt
+}
+
+// 影响各提示词的分镜字段，与 generateImagePrompt / generateVideoPrompt 使用的字段一致
+var (
+	imagePromptFields = []string{"location", "time", "action"}
+	videoPromptFields = []string{"action", "dialogue", "movement", "shot_type", "angle", "location", "time", "atmosphere", "result", "bgm_prompt", "sound_effect"}
+)
+
+// promptInputsChanged 判断提示词依赖的字段是否有变化
+func promptInputsChanged(before, after Storyboard, fields []string) bool {
+	for _, field := range fields {
+		if promptFieldValue(before, field) != promptFieldValue(after, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// promptFieldValue 按字段名取提示词生成所用的值
+func promptFieldValue(sb Storyboard, field string) string {
+	switch field {
+	case "location":
+		return sb.Location
+	case "time":
+		return sb.Time
+	case "action":
+		return sb.Action
+	case "dialogue":
+		return sb.Dialogue
+	case "movement":
+		return sb.Movement
+	case "shot_type":
+		return sb.ShotType
+	case "angle":
+		return sb.Angle
+	case "atmosphere":
+		return sb.Atmosphere
+	case "result":
+		return sb.Result
+	case "bgm_prompt":
+		return sb.BgmPrompt
+	case "sound_effect":
+		return sb.SoundEffect
+	}
+	return ""
+}
+
+// storyboardComparableValues 数据库中分镜各可编辑字段的当前值，类型与 UpdateStoryboard 构建的更新值一致
+func storyboardComparableValues(storyboard *models.Storyboard) map[string]interface{} {
+	values := map[string]interface{}{
+		"title":        getString(storyboard.Title),
+		"shot_type":    getString(storyboard.ShotType),
+		"angle":        getString(storyboard.Angle),
+		"movement":     getString(storyboard.Movement),
+		"location":     getString(storyboard.Location),
+		"time":         getString(storyboard.Time),
+		"action":       getString(storyboard.Action),
+		"dialogue":     getString(storyboard.Dialogue),
+		"result":       getString(storyboard.Result),
+		"atmosphere":   getString(storyboard.Atmosphere),
+		"description":  getString(storyboard.Description),
+		"bgm_prompt":   getString(storyboard.BgmPrompt),
+		"sound_effect": getString(storyboard.SoundEffect),
+		"duration":     storyboard.Duration,
+	}
+	if storyboard.SceneID != nil {
+		values["scene_id"] = *storyboard.SceneID
+	}
+	return values
+}
+
+// RefreshStoryboardPrompts 根据分镜当前字段重新生成 image_prompt 和 video_prompt
+func (s *StoryboardService) RefreshStoryboardPrompts(storyboardID string) (*models.Storyboard, error) {
+	var storyboard models.Storyboard
+	if err := s.db.First(&storyboard, storyboardID).Error; err != nil {
+		return nil, ErrStoryboardNotFound
+	}
+
+	sb := storyboardFromModel(&storyboard)
+	suffixes := s.promptSuffixesForEpisode(storyboard.EpisodeID)
+	imagePrompt := s.generateImagePrompt(sb, s.dramaStyleForEpisode(storyboard.EpisodeID), suffixes.Image)
+	videoPrompt := s.generateVideoPrompt(sb, s.videoRatioForEpisode(storyboard.EpisodeID), suffixes.Video)
+
+	if err := s.db.Model(&storyboard).Updates(map[string]interface{}{
+		"image_prompt": imagePrompt,
+		"video_prompt": videoPrompt,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to refresh storyboard prompts: %w", err)
+	}
+	storyboard.ImagePrompt = &imagePrompt
+	storyboard.VideoPrompt = &videoPrompt
+
+	s.log.Infow("Storyboard prompts refreshed", "storyboard_id", storyboardID)
+	return &storyboard, nil
+}
+
+// storyboardFromModel 将数据库分镜转换为提示词生成所用的结构
+func storyboardFromModel(storyboard *models.Storyboard) Storyboard {
+	return Storyboard{
+		ShotNumber:  storyboard.StoryboardNumber,
+		Title:       getString(storyboard.Title),
+		ShotType:    getString(storyboard.ShotType),
+		Angle:       getString(storyboard.Angle),
+		Time:        getString(storyboard.Time),
+		Location:    getString(storyboard.Location),
+		SceneID:     storyboard.SceneID,
+		Movement:    getString(storyboard.Movement),
+		Action:      getString(storyboard.Action),
+		Dialogue:    getString(storyboard.Dialogue),
+		Result:      getString(storyboard.Result),
+		Atmosphere:  getString(storyboard.Atmosphere),
+		Duration:    storyboard.Duration,
+		BgmPrompt:   getString(storyboard.BgmPrompt),
+		SoundEffect: getString(storyboard.SoundEffect),
+	}
+}
+
+// RegenerateVideoPrompts 按新的风格和画面比例重新生成剧集内所有分镜的 video_prompt，不影响其他字段；ratio 为空时使用剧集设置
+func (s *StoryboardService) RegenerateVideoPrompts(episodeID string, style string, ratio string) (int, error) {
+	if ratio == "" {
+		ratio = s.videoRatioForEpisode(episodeID)
+	}
+	if err := validateVideoRatio(ratio); err != nil {
+		return 0, err
+	}
+
+	var storyboards []models.Storyboard
+	if err := s.db.Where("episode_id = ?", episodeID).Order("storyboard_number ASC").Find(&storyboards).Error; err != nil {
+		return 0, fmt.Errorf("获取分镜失败: %w", err)
+	}
+	if len(storyboards) == 0 {
+		return 0, fmt.Errorf("该剧集还没有分镜")
+	}
+
+	suffix := s.promptSuffixesForEpisode(episodeID).Video
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for i := range storyboards {
+			videoPrompt := s.generateVideoPromptWithStyle(storyboardFromModel(&storyboards[i]), style, ratio, suffix)
+			if err := tx.Model(&models.Storyboard{}).Where("id = ?", storyboards[i].ID).
+				Update("video_prompt", videoPrompt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("更新视频提示词失败: %w", err)
+	}
+
+	s.log.Infow("Video prompts regenerated",
+		"episode_id", episodeID,
+		"style", style,
+		"ratio", ratio,
+		"count", len(storyboards))
 
-	return nil
+	return len(storyboards), nil
 }
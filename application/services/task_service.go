@@ -2,10 +2,13 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/ai"
+	"github.com/drama-generator/backend/pkg/image"
 	"github.com/drama-generator/backend/pkg/logger"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -36,6 +39,7 @@ func (s *TaskService) CreateTask(taskType, resourceID string) (*models.AsyncTask
 	if err := s.db.Create(task).Error; err != nil {
 		return nil, fmt.Errorf("failed to create task: %w", err)
 	}
+	tasksTotal.Inc(taskType, "created")
 
 	return task, nil
 }
@@ -59,18 +63,31 @@ func (s *TaskService) UpdateTaskStatus(taskID, status string, progress int, mess
 		Updates(updates).Error
 }
 
-// UpdateTaskError 更新任务错误
+// UpdateTaskError 更新任务错误，被服务商安全策略拦截的错误记录 content_policy 错误代码
 func (s *TaskService) UpdateTaskError(taskID string, err error) error {
 	now := time.Now()
-	return s.db.Model(&models.AsyncTask{}).
+	if updateErr := s.db.Model(&models.AsyncTask{}).
 		Where("id = ?", taskID).
 		Updates(map[string]interface{}{
 			"status":       "failed",
 			"error":        err.Error(),
+			"error_code":   taskErrorCode(err),
 			"progress":     0,
 			"completed_at": &now,
 			"updated_at":   time.Now(),
-		}).Error
+		}).Error; updateErr != nil {
+		return updateErr
+	}
+	s.recordTaskOutcome(taskID, metricStatusFailed)
+	return nil
+}
+
+// taskErrorCode 按错误类型返回任务失败原因代码，无法归类时返回空
+func taskErrorCode(err error) string {
+	if errors.Is(err, ai.ErrContentPolicy) || errors.Is(err, image.ErrContentPolicy) {
+		return models.TaskErrorContentPolicy
+	}
+	return ""
 }
 
 // UpdateTaskResult 更新任务结果
@@ -81,7 +98,7 @@ func (s *TaskService) UpdateTaskResult(taskID string, result interface{}) error
 	}
 
 	now := time.Now()
-	return s.db.Model(&models.AsyncTask{}).
+	if err := s.db.Model(&models.AsyncTask{}).
 		Where("id = ?", taskID).
 		Updates(map[string]interface{}{
 			"status":       "completed",
@@ -89,9 +106,42 @@ func (s *TaskService) UpdateTaskResult(taskID string, result interface{}) error
 			"result":       string(resultJSON),
 			"completed_at": &now,
 			"updated_at":   time.Now(),
+		}).Error; err != nil {
+		return err
+	}
+	s.recordTaskOutcome(taskID, metricStatusCompleted)
+	return nil
+}
+
+// UpdateTaskPartialResult 写入阶段性结果，任务保持 processing 状态，完成时由 UpdateTaskResult 覆盖
+func (s *TaskService) UpdateTaskPartialResult(taskID string, progress int, message string, result interface{}) error {
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return s.db.Model(&models.AsyncTask{}).
+		Where("id = ?", taskID).
+		Updates(map[string]interface{}{
+			"status":     "processing",
+			"progress":   progress,
+			"message":    message,
+			"result":     string(resultJSON),
+			"updated_at": time.Now(),
 		}).Error
 }
 
+// recordTaskOutcome 按任务类型记录任务结束状态和耗时
+func (s *TaskService) recordTaskOutcome(taskID, status string) {
+	var task models.AsyncTask
+	if err := s.db.Select("type", "created_at").Where("id = ?", taskID).First(&task).Error; err != nil {
+		s.log.Debugw("Failed to load task for metrics", "error", err, "task_id", taskID)
+		return
+	}
+	tasksTotal.Inc(task.Type, status)
+	taskDuration.Observe(time.Since(task.CreatedAt).Seconds(), task.Type, status)
+}
+
 // GetTask 获取任务信息
 func (s *TaskService) GetTask(taskID string) (*models.AsyncTask, error) {
 	var task models.AsyncTask
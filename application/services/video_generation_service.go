@@ -79,7 +79,7 @@ func (s *VideoGenerationService) GenerateVideo(request *GenerateVideoRequest) (*
 	if request.StoryboardID != nil {
 		var storyboard models.Storyboard
 		if err := s.db.Preload("Episode").Where("id = ?", *request.StoryboardID).First(&storyboard).Error; err != nil {
-			return nil, fmt.Errorf("storyboard not found")
+			return nil, ErrStoryboardNotFound
 		}
 		if fmt.Sprintf("%d", storyboard.Episode.DramaID) != request.DramaID {
 			return nil, fmt.Errorf("storyboard does not belong to drama")
@@ -89,7 +89,7 @@ func (s *VideoGenerationService) GenerateVideo(request *GenerateVideoRequest) (*
 	if request.ImageGenID != nil {
 		var imageGen models.ImageGeneration
 		if err := s.db.Where("id = ?", *request.ImageGenID).First(&imageGen).Error; err != nil {
-			return nil, fmt.Errorf("image generation not found")
+			return nil, ErrImageGenerationNotFound
 		}
 	}
 
@@ -202,7 +202,7 @@ func (s *VideoGenerationService) ProcessVideoGeneration(videoGenID uint) {
 
 	s.db.Model(&videoGen).Update("status", models.VideoStatusProcessing)
 
-	client, err := s.getVideoClient(videoGen.Provider, videoGen.Model)
+	client, err := s.getVideoClient(videoGenID, videoGen.Provider, videoGen.Model)
 	if err != nil {
 		s.log.Errorw("Failed to get video client", "error", err, "provider", videoGen.Provider, "model", videoGen.Model)
 		s.updateVideoGenError(videoGenID, err.Error())
@@ -373,7 +373,7 @@ func (s *VideoGenerationService) pollTaskStatus(videoGenID uint, taskID string,
 		return
 	}
 
-	client, err := s.getVideoClient(provider, model)
+	client, err := s.getVideoClient(videoGenID, provider, model)
 	if err != nil {
 		s.log.Errorw("Failed to get video client for polling", "error", err)
 		s.updateVideoGenError(videoGenID, "failed to get video client")
@@ -572,7 +572,8 @@ func (s *VideoGenerationService) updateVideoGenError(videoGenID uint, errorMsg s
 	}
 }
 
-func (s *VideoGenerationService) getVideoClient(provider string, modelName string) (video.VideoClient, error) {
+// getVideoClient 获取视频客户端，未指定模型时按视频记录ID粘滞选择配置，保证提交与轮询使用同一服务商
+func (s *VideoGenerationService) getVideoClient(videoGenID uint, provider string, modelName string) (video.VideoClient, error) {
 	// 根据模型名称获取AI配置
 	var config *models.AIServiceConfig
 	var err error
@@ -581,13 +582,13 @@ func (s *VideoGenerationService) getVideoClient(provider string, modelName strin
 		config, err = s.aiService.GetConfigForModel("video", modelName)
 		if err != nil {
 			s.log.Warnw("Failed to get config for model, using default", "model", modelName, "error", err)
-			config, err = s.aiService.GetDefaultConfig("video")
+			config, err = s.aiService.GetStickyConfig("video", fmt.Sprintf("video:%d", videoGenID))
 			if err != nil {
 				return nil, fmt.Errorf("no video AI config found: %w", err)
 			}
 		}
 	} else {
-		config, err = s.aiService.GetDefaultConfig("video")
+		config, err = s.aiService.GetStickyConfig("video", fmt.Sprintf("video:%d", videoGenID))
 		if err != nil {
 			return nil, fmt.Errorf("no video AI config found: %w", err)
 		}
@@ -692,7 +693,7 @@ func (s *VideoGenerationService) ListVideoGenerations(dramaID *uint, storyboardI
 func (s *VideoGenerationService) GenerateVideoFromImage(imageGenID uint) (*models.VideoGeneration, error) {
 	var imageGen models.ImageGeneration
 	if err := s.db.First(&imageGen, imageGenID).Error; err != nil {
-		return nil, fmt.Errorf("image generation not found")
+		return nil, ErrImageGenerationNotFound
 	}
 
 	if imageGen.Status != models.ImageStatusCompleted || imageGen.ImageURL == nil {
@@ -727,7 +728,7 @@ func (s *VideoGenerationService) GenerateVideoFromImage(imageGenID uint) (*model
 func (s *VideoGenerationService) BatchGenerateVideosForEpisode(episodeID string) ([]*models.VideoGeneration, error) {
 	var episode models.Episode
 	if err := s.db.Preload("Storyboards").Where("id = ?", episodeID).First(&episode).Error; err != nil {
-		return nil, fmt.Errorf("episode not found")
+		return nil, ErrEpisodeNotFound
 	}
 
 	var results []*models.VideoGeneration
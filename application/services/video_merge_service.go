@@ -45,13 +45,34 @@ type MergeVideoRequest struct {
 	Scenes    []models.SceneClip `json:"scenes" binding:"required,min=1"`
 	Provider  string             `json:"provider"`
 	Model     string             `json:"model"`
+	// MasterVolume 整体音量倍数，为空表示原音量
+	MasterVolume *float64 `json:"master_volume"`
+}
+
+// maxClipVolume 片段和整体音量倍数的上限
+const maxClipVolume = 2.0
+
+// validateVolume 校验音量倍数在 0 到 maxClipVolume 之间
+func validateVolume(volume *float64, name string) error {
+	if volume != nil && (*volume < 0 || *volume > maxClipVolume) {
+		return &ServiceError{Kind: ErrInvalidInput, Message: fmt.Sprintf("%s必须在0到%.0f之间", name, maxClipVolume)}
+	}
+	return nil
+}
+
+// effectiveVolume 将可选的音量倍数转换为 ffmpeg 使用的值，为空表示原音量
+func effectiveVolume(volume *float64) float64 {
+	if volume == nil {
+		return 1
+	}
+	return *volume
 }
 
 func (s *VideoMergeService) MergeVideos(req *MergeVideoRequest) (*models.VideoMerge, error) {
 	// 验证episode权限
 	var episode models.Episode
 	if err := s.db.Preload("Drama").Where("id = ?", req.EpisodeID).First(&episode).Error; err != nil {
-		return nil, fmt.Errorf("episode not found")
+		return nil, ErrEpisodeNotFound
 	}
 
 	// 验证所有场景都有视频
@@ -59,6 +80,12 @@ func (s *VideoMergeService) MergeVideos(req *MergeVideoRequest) (*models.VideoMe
 		if scene.VideoURL == "" {
 			return nil, fmt.Errorf("scene %d has no video", i+1)
 		}
+		if err := validateVolume(scene.Volume, fmt.Sprintf("片段%d的音量", i+1)); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateVolume(req.MasterVolume, "整体音量"); err != nil {
+		return nil, err
 	}
 
 	provider := req.Provider
@@ -87,6 +114,8 @@ func (s *VideoMergeService) MergeVideos(req *MergeVideoRequest) (*models.VideoMe
 		Model:     &req.Model,
 		Scenes:    scenesJSON,
 		Status:    models.VideoMergeStatusPending,
+
+		MasterVolume: req.MasterVolume,
 	}
 
 	if err := s.db.Create(videoMerge).Error; err != nil {
@@ -120,8 +149,27 @@ func (s *VideoMergeService) processMergeVideo(mergeID uint) {
 		return
 	}
 
+	sort.Slice(scenes, func(i, j int) bool {
+		return scenes[i].Order < scenes[j].Order
+	})
+
+	// 匹配分镜音效；已有匹配结果时沿用，保证重新合成得到相同的音轨
+	soundEffects := videoMerge.SoundEffects
+	if len(soundEffects) == 0 {
+		resolved, err := s.resolveSoundEffects(scenes)
+		if err != nil {
+			s.log.Warnw("Failed to resolve sound effects", "error", err, "id", mergeID)
+		} else if len(resolved) > 0 {
+			soundEffects = resolved
+			if err := s.db.Model(&models.VideoMerge{ID: mergeID}).Select("sound_effects").
+				Updates(&models.VideoMerge{SoundEffects: resolved}).Error; err != nil {
+				s.log.Warnw("Failed to save sound effect mapping", "error", err, "id", mergeID)
+			}
+		}
+	}
+
 	// 调用视频合并API
-	result, err := s.mergeVideoClips(client, scenes)
+	result, err := s.mergeVideoClips(client, scenes, soundEffects, effectiveVolume(videoMerge.MasterVolume))
 	if err != nil {
 		s.updateMergeError(mergeID, err.Error())
 		return
@@ -139,7 +187,7 @@ func (s *VideoMergeService) processMergeVideo(mergeID uint) {
 	s.completeMerge(mergeID, result)
 }
 
-func (s *VideoMergeService) mergeVideoClips(client video.VideoClient, scenes []models.SceneClip) (*video.VideoResult, error) {
+func (s *VideoMergeService) mergeVideoClips(client video.VideoClient, scenes []models.SceneClip, soundEffects []models.SoundEffectMapping, masterVolume float64) (*video.VideoResult, error) {
 	if len(scenes) == 0 {
 		return nil, fmt.Errorf("no scenes to merge")
 	}
@@ -171,6 +219,9 @@ func (s *VideoMergeService) mergeVideoClips(client video.VideoClient, scenes []m
 			StartTime:  scene.StartTime,
 			EndTime:    scene.EndTime,
 			Transition: scene.Transition,
+			Volume:     effectiveVolume(scene.Volume),
+			// ffmpeg 把 0 视为原音量，音量为 0 时按静音处理
+			Muted: scene.Muted || effectiveVolume(scene.Volume) == 0 || masterVolume == 0,
 		}
 
 		s.log.Infow("Clip added to merge queue",
@@ -179,7 +230,9 @@ func (s *VideoMergeService) mergeVideoClips(client video.VideoClient, scenes []m
 			"video_path", videoPath,
 			"duration", scene.Duration,
 			"start_time", scene.StartTime,
-			"end_time", scene.EndTime)
+			"end_time", scene.EndTime,
+			"volume", effectiveVolume(scene.Volume),
+			"muted", scene.Muted)
 	}
 
 	// 创建视频输出目录
@@ -194,8 +247,9 @@ func (s *VideoMergeService) mergeVideoClips(client video.VideoClient, scenes []m
 
 	// 使用FFmpeg合成视频
 	mergedPath, err := s.ffmpeg.MergeVideos(&ffmpeg.MergeOptions{
-		OutputPath: outputPath,
-		Clips:      clips,
+		OutputPath:   outputPath,
+		Clips:        clips,
+		MasterVolume: masterVolume,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("ffmpeg merge failed: %w", err)
@@ -203,6 +257,8 @@ func (s *VideoMergeService) mergeVideoClips(client video.VideoClient, scenes []m
 
 	s.log.Infow("Video merged successfully", "path", mergedPath)
 
+	s.mixSoundEffects(mergedPath, soundEffects, masterVolume)
+
 	// 生成相对路径（不包含协议、IP、端口）
 	relPath := filepath.Join("videos", "merged", fileName)
 
@@ -383,6 +439,8 @@ type TimelineClip struct {
 	EndTime      float64                `json:"end_time"`
 	Duration     float64                `json:"duration"`
 	Transition   map[string]interface{} `json:"transition"`
+	Volume       *float64               `json:"volume"` // 片段音量倍数 0-2，为空表示原音量
+	Muted        bool                   `json:"muted"`  // 静音片段原声
 }
 
 // getAssetIDString 将 AssetID 转换为字符串
@@ -406,6 +464,8 @@ func getAssetIDString(assetID interface{}) string {
 type FinalizeEpisodeRequest struct {
 	EpisodeID string         `json:"episode_id"`
 	Clips     []TimelineClip `json:"clips"`
+	// MasterVolume 整体音量倍数 0-2，为空表示原音量
+	MasterVolume *float64 `json:"master_volume"`
 }
 
 // FinalizeEpisode 完成集数制作，根据时间线场景顺序合成最终视频
@@ -413,7 +473,20 @@ func (s *VideoMergeService) FinalizeEpisode(episodeID string, timelineData *Fina
 	// 验证episode存在且属于该用户
 	var episode models.Episode
 	if err := s.db.Preload("Drama").Preload("Storyboards").Where("id = ?", episodeID).First(&episode).Error; err != nil {
-		return nil, fmt.Errorf("episode not found")
+		return nil, ErrEpisodeNotFound
+	}
+
+	var masterVolume *float64
+	if timelineData != nil {
+		for i, clip := range timelineData.Clips {
+			if err := validateVolume(clip.Volume, fmt.Sprintf("片段%d的音量", i+1)); err != nil {
+				return nil, err
+			}
+		}
+		if err := validateVolume(timelineData.MasterVolume, "整体音量"); err != nil {
+			return nil, err
+		}
+		masterVolume = timelineData.MasterVolume
 	}
 
 	// 构建分镜ID映射
@@ -516,6 +589,8 @@ func (s *VideoMergeService) FinalizeEpisode(episodeID string, timelineData *Fina
 				StartTime:  clip.StartTime,
 				EndTime:    clip.EndTime,
 				Transition: clip.Transition,
+				Volume:     clip.Volume,
+				Muted:      clip.Muted,
 			}
 			s.log.Infow("Adding scene clip with transition",
 				"scene_id", sceneID,
@@ -620,6 +695,8 @@ func (s *VideoMergeService) FinalizeEpisode(episodeID string, timelineData *Fina
 		Title:     title,
 		Scenes:    sceneClips,
 		Provider:  "doubao", // 默认使用doubao
+
+		MasterVolume: masterVolume,
 	}
 
 	// 执行视频合成
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/infrastructure/external/ffmpeg"
+	"github.com/drama-generator/backend/pkg/config"
+)
+
+// defaultSoundEffectVolume 未配置音量时音效的默认音量
+const defaultSoundEffectVolume = 0.8
+
+// soundEffectExts 音效库中识别的音频文件扩展名
+var soundEffectExts = map[string]bool{
+	".mp3": true, ".wav": true, ".m4a": true, ".aac": true, ".ogg": true, ".flac": true,
+}
+
+// soundKeyword 音效关键词及对应的音效文件（相对音效库目录）
+type soundKeyword struct {
+	keyword string
+	file    string
+}
+
+// soundConfig 当前音效库配置，未配置音效库时返回 nil
+func soundConfig() *config.SoundConfig {
+	cfg := config.Current()
+	if cfg == nil || cfg.Sound.LibraryPath == "" {
+		return nil
+	}
+	return &cfg.Sound
+}
+
+// soundEffectVolume 配置的音效音量，未配置或超出范围时使用默认值
+func soundEffectVolume(cfg *config.SoundConfig) float64 {
+	if cfg.Volume <= 0 || cfg.Volume > 1 {
+		return defaultSoundEffectVolume
+	}
+	return cfg.Volume
+}
+
+// soundKeywords 返回按长度降序排列的关键词，较长（更具体）的关键词优先匹配
+// 未配置关键词时使用音效库中的文件名（不含扩展名）作为关键词
+func soundKeywords(cfg *config.SoundConfig) ([]soundKeyword, error) {
+	var keywords []soundKeyword
+	if len(cfg.Keywords) > 0 {
+		for keyword, file := range cfg.Keywords {
+			if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" && file != "" {
+				keywords = append(keywords, soundKeyword{keyword: keyword, file: file})
+			}
+		}
+	} else {
+		entries, err := os.ReadDir(cfg.LibraryPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sound library: %w", err)
+		}
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if entry.IsDir() || !soundEffectExts[ext] {
+				continue
+			}
+			stem := strings.ToLower(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+			keywords = append(keywords, soundKeyword{keyword: stem, file: entry.Name()})
+		}
+	}
+
+	sort.Slice(keywords, func(i, j int) bool {
+		if len(keywords[i].keyword) != len(keywords[j].keyword) {
+			return len(keywords[i].keyword) > len(keywords[j].keyword)
+		}
+		return keywords[i].keyword < keywords[j].keyword
+	})
+	return keywords, nil
+}
+
+// matchSoundKeyword 返回描述中第一个命中的关键词，没有命中时返回 nil
+func matchSoundKeyword(description string, keywords []soundKeyword) *soundKeyword {
+	lower := strings.ToLower(description)
+	for i := range keywords {
+		if strings.Contains(lower, keywords[i].keyword) {
+			return &keywords[i]
+		}
+	}
+	return nil
+}
+
+// clipDuration 片段在合成视频中的时长，与 ffmpeg 合成时的裁剪规则一致
+func clipDuration(clip models.SceneClip) float64 {
+	if clip.EndTime > 0 && clip.EndTime > clip.StartTime {
+		return clip.EndTime - clip.StartTime
+	}
+	return clip.Duration
+}
+
+// resolveSoundEffects 按片段顺序读取各镜头的 sound_effect 描述并匹配音效文件
+// scenes 需已按 Order 排序；未配置音效库时返回 nil
+func (s *VideoMergeService) resolveSoundEffects(scenes []models.SceneClip) ([]models.SoundEffectMapping, error) {
+	cfg := soundConfig()
+	if cfg == nil {
+		return nil, nil
+	}
+	keywords, err := soundKeywords(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(scenes))
+	for _, scene := range scenes {
+		ids = append(ids, scene.SceneID)
+	}
+	var storyboards []models.Storyboard
+	if err := s.db.Select("id", "sound_effect").Where("id IN ?", ids).Find(&storyboards).Error; err != nil {
+		return nil, fmt.Errorf("failed to load storyboards: %w", err)
+	}
+	descriptions := make(map[uint]string, len(storyboards))
+	for _, sb := range storyboards {
+		if sb.SoundEffect != nil {
+			descriptions[sb.ID] = strings.TrimSpace(*sb.SoundEffect)
+		}
+	}
+
+	var mappings []models.SoundEffectMapping
+	var start float64
+	for _, scene := range scenes {
+		duration := clipDuration(scene)
+		if description := descriptions[scene.SceneID]; description != "" {
+			mapping := models.SoundEffectMapping{
+				StoryboardID: scene.SceneID,
+				Description:  description,
+				Start:        start,
+				Duration:     duration,
+			}
+			if match := matchSoundKeyword(description, keywords); match != nil {
+				mapping.Keyword = match.keyword
+				mapping.File = match.file
+			}
+			mappings = append(mappings, mapping)
+		}
+		start += duration
+	}
+	return mappings, nil
+}
+
+// mixSoundEffects 将匹配到的音效混入已合成的视频，音效音量同样乘以整体音量；失败时保留无音效的合成结果
+func (s *VideoMergeService) mixSoundEffects(videoPath string, mappings []models.SoundEffectMapping, masterVolume float64) {
+	cfg := soundConfig()
+	if cfg == nil || len(mappings) == 0 {
+		return
+	}
+	volume := soundEffectVolume(cfg) * masterVolume
+	if volume == 0 {
+		return
+	}
+
+	var cues []ffmpeg.SoundEffectCue
+	for _, mapping := range mappings {
+		if mapping.File == "" {
+			continue
+		}
+		path := filepath.Join(cfg.LibraryPath, mapping.File)
+		if _, err := os.Stat(path); err != nil {
+			s.log.Warnw("Sound effect file not found", "file", path, "storyboard_id", mapping.StoryboardID)
+			continue
+		}
+		cues = append(cues, ffmpeg.SoundEffectCue{
+			Path:     path,
+			Start:    mapping.Start,
+			Duration: mapping.Duration,
+			Volume:   volume,
+		})
+	}
+	if len(cues) == 0 {
+		return
+	}
+
+	tmpPath := strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) + "_sfx" + filepath.Ext(videoPath)
+	if err := s.ffmpeg.MixSoundEffects(videoPath, tmpPath, cues); err != nil {
+		s.log.Warnw("Failed to mix sound effects, keeping merged video without them", "error", err, "path", videoPath)
+		os.Remove(tmpPath)
+		return
+	}
+	if err := os.Rename(tmpPath, videoPath); err != nil {
+		s.log.Warnw("Failed to replace merged video with sound effect mix", "error", err, "path", videoPath)
+		os.Remove(tmpPath)
+		return
+	}
+	s.log.Infow("Sound effects added to merged video", "path", videoPath, "effects", len(cues))
+}
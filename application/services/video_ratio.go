@@ -0,0 +1,56 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/config"
+)
+
+// defaultVideoRatio 未配置时视频提示词使用的画面比例
+const defaultVideoRatio = "16:9"
+
+// supportedVideoRatios 视频提示词支持的画面比例，覆盖横屏、竖屏、方形和信息流常用比例
+var supportedVideoRatios = []string{"16:9", "9:16", "1:1", "4:5", "4:3", "3:4", "21:9"}
+
+func isSupportedVideoRatio(ratio string) bool {
+	for _, r := range supportedVideoRatios {
+		if r == ratio {
+			return true
+		}
+	}
+	return false
+}
+
+// validateVideoRatio 校验画面比例是否在支持列表中
+func validateVideoRatio(ratio string) error {
+	if isSupportedVideoRatio(ratio) {
+		return nil
+	}
+	return &ServiceError{
+		Kind:    ErrInvalidInput,
+		Message: fmt.Sprintf("不支持的画面比例: %s，可选 %s", ratio, strings.Join(supportedVideoRatios, "、")),
+	}
+}
+
+// configuredVideoRatio 返回 style.default_video_ratio，未配置或不受支持时为 16:9
+func configuredVideoRatio() string {
+	if cfg := config.Current(); cfg != nil && isSupportedVideoRatio(cfg.Style.DefaultVideoRatio) {
+		return cfg.Style.DefaultVideoRatio
+	}
+	return defaultVideoRatio
+}
+
+// videoRatioForEpisode 获取剧集的视频画面比例，剧集未设置时使用全局默认值
+func (s *StoryboardService) videoRatioForEpisode(episodeID interface{}) string {
+	var ratio *string
+	if err := s.db.Model(&models.Episode{}).Select("video_ratio").
+		Where("id = ?", episodeID).Scan(&ratio).Error; err != nil {
+		s.log.Warnw("Failed to load episode video ratio", "error", err, "episode_id", episodeID)
+	}
+	if ratio != nil && isSupportedVideoRatio(*ratio) {
+		return *ratio
+	}
+	return configuredVideoRatio()
+}
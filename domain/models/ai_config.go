@@ -8,21 +8,25 @@ import (
 )
 
 type AIServiceConfig struct {
-	ID            uint       `gorm:"primaryKey;autoIncrement" json:"id"`
-	ServiceType   string     `gorm:"type:varchar(50);not null" json:"service_type"` // text, image, video
-	Provider      string     `gorm:"type:varchar(50)" json:"provider"`              // openai, gemini, volcengine, etc.
-	Name          string     `gorm:"type:varchar(100);not null" json:"name"`
-	BaseURL       string     `gorm:"type:varchar(255);not null" json:"base_url"`
-	APIKey        string     `gorm:"type:varchar(255);not null" json:"api_key"`
-	Model         ModelField `gorm:"type:text" json:"model"`
-	Endpoint      string     `gorm:"type:varchar(255)" json:"endpoint"`
-	QueryEndpoint string     `gorm:"type:varchar(255)" json:"query_endpoint"`
-	Priority      int        `gorm:"default:0" json:"priority"` // 优先级，数值越大优先级越高
-	IsDefault     bool       `gorm:"default:false" json:"is_default"`
-	IsActive      bool       `gorm:"default:true" json:"is_active"`
-	Settings      string     `gorm:"type:text" json:"settings"`
-	CreatedAt     time.Time  `gorm:"not null;autoCreateTime" json:"created_at"`
-	UpdatedAt     time.Time  `gorm:"not null;autoUpdateTime" json:"updated_at"`
+	ID             uint              `gorm:"primaryKey;autoIncrement" json:"id"`
+	ServiceType    string            `gorm:"type:varchar(50);not null" json:"service_type"` // text, image, video, embedding
+	Provider       string            `gorm:"type:varchar(50)" json:"provider"`              // openai, gemini, volcengine, etc.
+	Name           string            `gorm:"type:varchar(100);not null" json:"name"`
+	BaseURL        string            `gorm:"type:varchar(255);not null" json:"base_url"`
+	APIKey         string            `gorm:"type:varchar(255);not null" json:"api_key"`
+	Model          ModelField        `gorm:"type:text" json:"model"`
+	Endpoint       string            `gorm:"type:varchar(255)" json:"endpoint"`
+	QueryEndpoint  string            `gorm:"type:varchar(255)" json:"query_endpoint"`
+	Priority       int               `gorm:"default:0" json:"priority"` // 优先级，数值越大优先级越高
+	Weight         int               `json:"weight"`                    // 加权选择模式下的权重，0 表示不参与分配；创建时未指定则为 1
+	IsDefault      bool              `gorm:"default:false" json:"is_default"`
+	IsActive       bool              `gorm:"default:true" json:"is_active"`
+	Settings       string            `gorm:"type:text" json:"settings"`
+	ExtraHeaders   map[string]string `gorm:"serializer:json;type:text" json:"extra_headers"`   // 附加请求头，如 Azure 的 api-version
+	SafetySettings map[string]string `gorm:"serializer:json;type:text" json:"safety_settings"` // Gemini 安全过滤阈值（危害类别 -> 阈值）
+	VisionSupport  *bool             `json:"vision_support"`                                   // 是否支持图片输入，为空时按模型名自动判断
+	CreatedAt      time.Time         `gorm:"not null;autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time         `gorm:"not null;autoUpdateTime" json:"updated_at"`
 }
 
 func (c *AIServiceConfig) TableName() string {
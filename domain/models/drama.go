@@ -17,39 +17,64 @@ type Drama struct {
 	TotalDuration int            `gorm:"default:0" json:"total_duration"`
 	Status        string         `gorm:"type:varchar(20);default:'draft';not null" json:"status"`
 	Thumbnail     *string        `gorm:"type:varchar(500)" json:"thumbnail"`
+	Watermark     bool           `gorm:"default:false" json:"watermark"`   // 生成图片是否加水印（试用账号）
+	Language      string         `gorm:"type:varchar(10)" json:"language"` // 生成提示词语言 zh/en，为空时使用全局配置
 	Tags          datatypes.JSON `gorm:"type:json" json:"tags"`
 	Metadata      datatypes.JSON `gorm:"type:json" json:"metadata"`
 	CreatedAt     time.Time      `gorm:"not null;autoCreateTime" json:"created_at"`
 	UpdatedAt     time.Time      `gorm:"not null;autoUpdateTime" json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
 
+	// 绑定的AI配置，为空时使用全局默认配置
+	TextConfigID  *uint `gorm:"index" json:"text_config_id"`
+	ImageConfigID *uint `gorm:"index" json:"image_config_id"`
+
+	// 追加到分镜图片/视频提示词末尾的画质描述，为空时使用 style 配置的默认值
+	ImagePromptSuffix string `gorm:"type:text" json:"image_prompt_suffix"`
+	VideoPromptSuffix string `gorm:"type:text" json:"video_prompt_suffix"`
+
 	Episodes   []Episode   `gorm:"foreignKey:DramaID" json:"episodes,omitempty"`
 	Characters []Character `gorm:"foreignKey:DramaID" json:"characters,omitempty"`
 	Scenes     []Scene     `gorm:"foreignKey:DramaID" json:"scenes,omitempty"`
 	Props      []Prop      `gorm:"foreignKey:DramaID" json:"props,omitempty"`
 }
 
+// 台词类型
+const (
+	DialogueTypeSpeech    = "dialogue"  // 角色对白
+	DialogueTypeMonologue = "monologue" // 独白
+	DialogueTypeNarration = "narration" // 旁白
+)
+
+// DialogueLine 一句结构化台词，Speaker 为空表示说话人未知（独白、旁白或缺少说话人）
+type DialogueLine struct {
+	Speaker string `json:"speaker,omitempty"`
+	Type    string `json:"type"`
+	Text    string `json:"text"`
+}
+
 func (d *Drama) TableName() string {
 	return "dramas"
 }
 
 type Character struct {
-	ID              uint           `gorm:"primaryKey;autoIncrement" json:"id"`
-	DramaID         uint           `gorm:"not null;index" json:"drama_id"`
-	Name            string         `gorm:"type:varchar(100);not null" json:"name"`
-	Role            *string        `gorm:"type:varchar(50)" json:"role"`
-	Description     *string        `gorm:"type:text" json:"description"`
-	Appearance      *string        `gorm:"type:text" json:"appearance"`
-	Personality     *string        `gorm:"type:text" json:"personality"`
-	VoiceStyle      *string        `gorm:"type:varchar(200)" json:"voice_style"`
-	ImageURL        *string        `gorm:"type:varchar(500)" json:"image_url"`
-	LocalPath       *string        `gorm:"type:text" json:"local_path,omitempty"`
-	ReferenceImages datatypes.JSON `gorm:"type:json" json:"reference_images"`
-	SeedValue       *string        `gorm:"type:varchar(100)" json:"seed_value"`
-	SortOrder       int            `gorm:"default:0" json:"sort_order"`
-	CreatedAt       time.Time      `gorm:"not null;autoCreateTime" json:"created_at"`
-	UpdatedAt       time.Time      `gorm:"not null;autoUpdateTime" json:"updated_at"`
-	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+	ID              uint            `gorm:"primaryKey;autoIncrement" json:"id"`
+	DramaID         uint            `gorm:"not null;index" json:"drama_id"`
+	Name            string          `gorm:"type:varchar(100);not null" json:"name"`
+	Role            *string         `gorm:"type:varchar(50)" json:"role"`
+	Description     *string         `gorm:"type:text" json:"description"`
+	Appearance      *string         `gorm:"type:text" json:"appearance"`
+	Personality     *string         `gorm:"type:text" json:"personality"`
+	VoiceStyle      *string         `gorm:"type:varchar(200)" json:"voice_style"`
+	ImageURL        *string         `gorm:"type:varchar(500)" json:"image_url"`
+	LocalPath       *string         `gorm:"type:text" json:"local_path,omitempty"`
+	ReferenceImages datatypes.JSON  `gorm:"type:json" json:"reference_images"`
+	SeedValue       *string         `gorm:"type:varchar(100)" json:"seed_value"`
+	PortraitParams  *PortraitParams `gorm:"serializer:json;type:text" json:"portrait_params,omitempty"` // 当前形象图的生成参数，用于复现
+	SortOrder       int             `gorm:"default:0" json:"sort_order"`
+	CreatedAt       time.Time       `gorm:"not null;autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time       `gorm:"not null;autoUpdateTime" json:"updated_at"`
+	DeletedAt       gorm.DeletedAt  `gorm:"index" json:"-"`
 
 	// 多对多关系：角色可以属于多个章节
 	Episodes []Episode `gorm:"many2many:episode_characters;" json:"episodes,omitempty"`
@@ -63,20 +88,36 @@ func (c *Character) TableName() string {
 	return "characters"
 }
 
+// PortraitParams 生成角色形象图时使用的完整参数，相同参数可复现同一形象
+type PortraitParams struct {
+	ImageGenerationID uint    `json:"image_generation_id"`
+	Prompt            string  `json:"prompt"`
+	NegativePrompt    *string `json:"negative_prompt,omitempty"`
+	Provider          string  `json:"provider"`
+	Model             string  `json:"model"`
+	Size              string  `json:"size"`
+	Quality           string  `json:"quality"`
+	Seed              *int64  `json:"seed,omitempty"`
+}
+
 type Episode struct {
-	ID            uint           `gorm:"primaryKey;autoIncrement" json:"id"`
-	DramaID       uint           `gorm:"not null;index" json:"drama_id"`
-	EpisodeNum    int            `gorm:"column:episode_number;not null" json:"episode_number"`
-	Title         string         `gorm:"type:varchar(200);not null" json:"title"`
-	ScriptContent *string        `gorm:"type:longtext" json:"script_content"`
-	Description   *string        `gorm:"type:text" json:"description"`
-	Duration      int            `gorm:"default:0" json:"duration"` // 总时长（秒）
-	Status        string         `gorm:"type:varchar(20);default:'draft'" json:"status"`
-	VideoURL      *string        `gorm:"type:varchar(500)" json:"video_url"`
-	Thumbnail     *string        `gorm:"type:varchar(500)" json:"thumbnail"`
-	CreatedAt     time.Time      `gorm:"not null;autoCreateTime" json:"created_at"`
-	UpdatedAt     time.Time      `gorm:"not null;autoUpdateTime" json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                      uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	DramaID                 uint           `gorm:"not null;index" json:"drama_id"`
+	EpisodeNum              int            `gorm:"column:episode_number;not null" json:"episode_number"`
+	Title                   string         `gorm:"type:varchar(200);not null" json:"title"`
+	ScriptContent           *string        `gorm:"type:longtext" json:"script_content"`
+	Description             *string        `gorm:"type:text" json:"description"`
+	Duration                int            `gorm:"default:0" json:"duration"` // 总时长（秒）
+	Status                  string         `gorm:"type:varchar(20);default:'draft'" json:"status"`
+	VideoURL                *string        `gorm:"type:varchar(500)" json:"video_url"`
+	Thumbnail               *string        `gorm:"type:varchar(500)" json:"thumbnail"`
+	StyleReferenceEpisodeID *uint          `gorm:"index" json:"style_reference_episode_id"` // 分镜风格参考剧集
+	VideoRatio              *string        `gorm:"type:varchar(10)" json:"video_ratio"`     // 视频画面比例，为空时使用全局默认值
+	Tags                    []string       `gorm:"serializer:json;type:text" json:"tags"`   // 剧集标签，如 action、flashback
+	PipelineStage           string         `gorm:"type:varchar(20)" json:"pipeline_stage"`  // 流水线最后完成的阶段，见 PipelineStages
+	CreatedAt               time.Time      `gorm:"not null;autoCreateTime" json:"created_at"`
+	UpdatedAt               time.Time      `gorm:"not null;autoUpdateTime" json:"updated_at"`
+	DeletedAt               gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// 关联
 	Drama       Drama        `gorm:"foreignKey:DramaID" json:"drama,omitempty"`
@@ -89,6 +130,26 @@ func (e *Episode) TableName() string {
 	return "episodes"
 }
 
+// 剧集流水线阶段
+const (
+	PipelineStageExtract    = "extract"    // 提取场景
+	PipelineStageStoryboard = "storyboard" // 生成分镜
+	PipelineStageFrames     = "frames"     // 生成首帧提示词
+	PipelineStageImages     = "images"     // 生成分镜图片
+	PipelineStageVideos     = "videos"     // 生成分镜视频
+	PipelineStageFinalize   = "finalize"   // 合成成片
+)
+
+// PipelineStages 流水线阶段的执行顺序
+var PipelineStages = []string{
+	PipelineStageExtract,
+	PipelineStageStoryboard,
+	PipelineStageFrames,
+	PipelineStageImages,
+	PipelineStageVideos,
+	PipelineStageFinalize,
+}
+
 type Storyboard struct {
 	ID               uint           `gorm:"primaryKey;autoIncrement" json:"id"`
 	EpisodeID        uint           `gorm:"not null;index:idx_storyboards_episode_id" json:"episode_id"`
@@ -113,10 +174,16 @@ type Storyboard struct {
 	ComposedImage    *string        `gorm:"type:text" json:"composed_image"`
 	VideoURL         *string        `gorm:"type:text" json:"video_url"`
 	Status           string         `gorm:"type:varchar(20);default:'pending'" json:"status"`
+	Locked           bool           `gorm:"default:false" json:"locked"` // 锁定的镜头在重新生成分镜时保留
 	CreatedAt        time.Time      `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt        time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
 	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
 
+	// ExcludedCharacters 该镜头画面中明确不出现的角色ID，即使场景中有该角色
+	ExcludedCharacters []uint `gorm:"serializer:json;type:text" json:"excluded_characters"`
+	// DialogueLines 由 Dialogue 解析出的结构化台词，供字幕和配音使用
+	DialogueLines []DialogueLine `gorm:"serializer:json;type:text" json:"dialogue_lines"`
+
 	Episode    Episode     `gorm:"foreignKey:EpisodeID;constraint:OnDelete:CASCADE" json:"episode,omitempty"`
 	Background *Scene      `gorm:"foreignKey:SceneID" json:"background,omitempty"`
 	Characters []Character `gorm:"many2many:storyboard_characters;" json:"characters,omitempty"`
@@ -137,7 +204,9 @@ type Scene struct {
 	StoryboardCount int            `gorm:"default:1" json:"storyboard_count"`
 	ImageURL        *string        `gorm:"type:varchar(500)" json:"image_url"`
 	LocalPath       *string        `gorm:"type:text" json:"local_path"`
-	Status          string         `gorm:"type:varchar(20);default:'pending'" json:"status"` // pending, generated, failed
+	Status          string         `gorm:"type:varchar(20);default:'pending'" json:"status"`         // pending, generated, failed
+	EditedFields    []string       `gorm:"serializer:json;type:text" json:"edited_fields,omitempty"` // 用户手动修改过的字段（location/time/prompt），合并提取时不覆盖
+	ExtractKey      string         `gorm:"type:varchar(320)" json:"-"`                               // AI提取时的 location|time，用户改名后仍可与重新提取的结果对应
 	CreatedAt       time.Time      `gorm:"not null;autoCreateTime" json:"created_at"`
 	UpdatedAt       time.Time      `gorm:"not null;autoUpdateTime" json:"updated_at"`
 	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
@@ -145,6 +214,7 @@ type Scene struct {
 	// 运行时字段（不存储到数据库）
 	ImageGenerationStatus *string `gorm:"-" json:"image_generation_status,omitempty"`
 	ImageGenerationError  *string `gorm:"-" json:"image_generation_error,omitempty"`
+	LatestImageURL        *string `gorm:"-" json:"latest_image_url,omitempty"`
 }
 
 func (s *Scene) TableName() string {
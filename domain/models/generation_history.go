@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// GenerationHistory 图片生成历史，记录每次生成完成后写入关联实体的图片，便于浏览和恢复
+type GenerationHistory struct {
+	ID                uint      `gorm:"primarykey" json:"id"`
+	EntityType        string    `gorm:"size:20;not null;index:idx_generation_histories_entity" json:"entity_type"` // storyboard, scene, character, prop
+	EntityID          uint      `gorm:"not null;index:idx_generation_histories_entity" json:"entity_id"`
+	ImageGenerationID uint      `gorm:"not null;index" json:"image_generation_id"`
+	ImageURL          string    `gorm:"type:text;not null" json:"image_url"`
+	LocalPath         *string   `gorm:"type:text" json:"local_path,omitempty"`
+	CreatedAt         time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (GenerationHistory) TableName() string {
+	return "generation_histories"
+}
+
+// 生成历史关联的实体类型
+const (
+	HistoryEntityStoryboard = "storyboard"
+	HistoryEntityScene      = "scene"
+	HistoryEntityCharacter  = "character"
+	HistoryEntityProp       = "prop"
+)
@@ -28,15 +28,39 @@ type ImageGeneration struct {
 	ImageURL        *string               `gorm:"type:text" json:"image_url,omitempty"`
 	MinioURL        *string               `gorm:"type:text" json:"minio_url,omitempty"`
 	LocalPath       *string               `gorm:"type:text" json:"local_path,omitempty"`
+	CacheFailed     bool                  `gorm:"default:false" json:"cache_failed"`  // 本地缓存下载失败
+	RetryCount      int                   `gorm:"default:0" json:"retry_count"`       // 已重试次数
+	QANote          *string               `gorm:"type:text" json:"qa_note,omitempty"` // 角色出镜检查发现的问题
+	OriginalPath    *string               `gorm:"type:text" json:"-"`                 // 加水印前的原图路径，不对外返回
+	InputHash       *string               `gorm:"size:64;index" json:"-"`             // 生成输入（提示词、参数、参考图集合）的哈希，用于结果复用
+	CachedFromID    *uint                 `json:"cached_from_id,omitempty"`           // 复用的已完成生成记录ID
 	Status          ImageGenerationStatus `gorm:"size:20;not null;default:'pending'" json:"status"`
+	Priority        int                   `gorm:"default:0" json:"priority"`    // 排队优先级，数值越大越先执行
+	Preview         bool                  `gorm:"default:false" json:"preview"` // 低成本预览图，不下载到本地，也不写回分镜/场景/角色/道具
 	TaskID          *string               `gorm:"size:200" json:"task_id,omitempty"`
 	ErrorMsg        *string               `gorm:"type:text" json:"error_msg,omitempty"`
+	ErrorCode       *string               `gorm:"size:50" json:"error_code,omitempty"`     // 失败原因代码，如 blank_output
+	RawResponse     *string               `gorm:"type:text" json:"raw_response,omitempty"` // 服务商原始响应（已脱敏），需开启 capture_raw_response
 	Width           *int                  `json:"width,omitempty"`
 	Height          *int                  `json:"height,omitempty"`
 	ReferenceImages datatypes.JSON        `gorm:"type:json" json:"reference_images,omitempty"`
 	CreatedAt       time.Time             `json:"created_at"`
 	UpdatedAt       time.Time             `json:"updated_at"`
 	CompletedAt     *time.Time            `json:"completed_at,omitempty"`
+	QueuePosition   *int                  `gorm:"-" json:"queue_position,omitempty"` // 排队位置，仅 pending 状态返回
+
+	// 跨语言生成：Prompt 保留原始提示词，实际发送给服务商的是译文
+	TranslatePromptTo string  `gorm:"size:10" json:"translate_prompt_to,omitempty"`
+	TranslatedPrompt  *string `gorm:"type:text" json:"translated_prompt,omitempty"`
+
+	// MasterPath 转码前服务商返回的原始文件（母版）的相对路径，需开启 storage.keep_original
+	MasterPath *string `gorm:"type:text" json:"master_path,omitempty"`
+
+	// BatchID 服务商一次返回多张图片时，同一次请求产生的记录共享该ID；首张写入原记录，其余为同批次的新记录
+	BatchID *string `gorm:"size:64;index" json:"batch_id,omitempty"`
+
+	// ContentTags 视觉模型根据图片内容生成的标签（已转为小写），需开启 image_auto_tag
+	ContentTags *ImageContentTags `gorm:"serializer:json;type:text" json:"content_tags,omitempty"`
 
 	Storyboard *Storyboard `gorm:"foreignKey:StoryboardID" json:"storyboard,omitempty"`
 	Drama      Drama       `gorm:"foreignKey:DramaID" json:"drama,omitempty"`
@@ -49,6 +73,13 @@ func (ImageGeneration) TableName() string {
 	return "image_generations"
 }
 
+// ImageContentTags 图片内容标签
+type ImageContentTags struct {
+	Objects []string `json:"objects"` // 画面中的主要物体和元素
+	Mood    []string `json:"mood"`    // 情绪氛围
+	Colors  []string `json:"colors"`  // 主色调
+}
+
 type ImageGenerationStatus string
 
 const (
@@ -56,6 +87,13 @@ const (
 	ImageStatusProcessing ImageGenerationStatus = "processing"
 	ImageStatusCompleted  ImageGenerationStatus = "completed"
 	ImageStatusFailed     ImageGenerationStatus = "failed"
+	ImageStatusCancelled  ImageGenerationStatus = "cancelled" // 被新的生成任务替代
+)
+
+// 图片生成失败原因代码
+const (
+	ImageErrorBlankOutput   = "blank_output"   // 服务商返回了纯色或近乎空白的图片
+	ImageErrorContentPolicy = "content_policy" // 提示词或结果被服务商安全策略拦截
 )
 
 type ImageProvider string
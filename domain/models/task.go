@@ -14,6 +14,7 @@ type AsyncTask struct {
 	Progress    int            `gorm:"default:0" json:"progress"`            // 0-100
 	Message     string         `gorm:"size:500" json:"message,omitempty"`    // 当前状态消息
 	Error       string         `gorm:"type:text" json:"error,omitempty"`     // 错误信息
+	ErrorCode   string         `gorm:"size:50" json:"error_code,omitempty"`  // 失败原因代码，如 content_policy
 	Result      string         `gorm:"type:text" json:"result,omitempty"`    // JSON格式的结果数据
 	ResourceID  string         `gorm:"size:36;index" json:"resource_id"`     // 关联资源ID（如episode_id）
 	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"created_at"`
@@ -21,3 +22,8 @@ type AsyncTask struct {
 	CompletedAt *time.Time     `json:"completed_at,omitempty"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 }
+
+// 任务失败原因代码
+const (
+	TaskErrorContentPolicy = "content_policy" // 请求或生成内容被服务商安全策略拦截
+)
@@ -17,21 +17,25 @@ const (
 )
 
 type VideoMerge struct {
-	ID          uint             `gorm:"primaryKey;autoIncrement" json:"id"`
-	EpisodeID   uint             `gorm:"not null;index" json:"episode_id"`
-	DramaID     uint             `gorm:"not null;index" json:"drama_id"`
-	Title       string           `gorm:"type:varchar(200)" json:"title"`
-	Provider    string           `gorm:"type:varchar(50);not null" json:"provider"`
-	Model       *string          `gorm:"type:varchar(100)" json:"model,omitempty"`
-	Status      VideoMergeStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
-	Scenes      datatypes.JSON   `gorm:"type:json;not null" json:"scenes"`
-	MergedURL   *string          `gorm:"type:varchar(500)" json:"merged_url,omitempty"`
-	Duration    *int             `gorm:"type:int" json:"duration,omitempty"`
-	TaskID      *string          `gorm:"type:varchar(100)" json:"task_id,omitempty"`
-	ErrorMsg    *string          `gorm:"type:text" json:"error_msg,omitempty"`
-	CreatedAt   time.Time        `gorm:"not null;autoCreateTime" json:"created_at"`
-	CompletedAt *time.Time       `json:"completed_at,omitempty"`
-	DeletedAt   gorm.DeletedAt   `gorm:"index" json:"-"`
+	ID        uint             `gorm:"primaryKey;autoIncrement" json:"id"`
+	EpisodeID uint             `gorm:"not null;index" json:"episode_id"`
+	DramaID   uint             `gorm:"not null;index" json:"drama_id"`
+	Title     string           `gorm:"type:varchar(200)" json:"title"`
+	Provider  string           `gorm:"type:varchar(50);not null" json:"provider"`
+	Model     *string          `gorm:"type:varchar(100)" json:"model,omitempty"`
+	Status    VideoMergeStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	Scenes    datatypes.JSON   `gorm:"type:json;not null" json:"scenes"`
+	MergedURL *string          `gorm:"type:varchar(500)" json:"merged_url,omitempty"`
+	Duration  *int             `gorm:"type:int" json:"duration,omitempty"`
+	TaskID    *string          `gorm:"type:varchar(100)" json:"task_id,omitempty"`
+	ErrorMsg  *string          `gorm:"type:text" json:"error_msg,omitempty"`
+	// MasterVolume 整体音量倍数，为空表示原音量
+	MasterVolume *float64 `json:"master_volume,omitempty"`
+	// SoundEffects 分镜音效描述与音效文件的匹配结果，保存下来以便重新合成时得到相同的音轨
+	SoundEffects []SoundEffectMapping `gorm:"serializer:json;type:text" json:"sound_effects,omitempty"`
+	CreatedAt    time.Time            `gorm:"not null;autoCreateTime" json:"created_at"`
+	CompletedAt  *time.Time           `json:"completed_at,omitempty"`
+	DeletedAt    gorm.DeletedAt       `gorm:"index" json:"-"`
 
 	Episode Episode `gorm:"foreignKey:EpisodeID" json:"episode,omitempty"`
 	Drama   Drama   `gorm:"foreignKey:DramaID" json:"drama,omitempty"`
@@ -45,6 +49,18 @@ type SceneClip struct {
 	Duration   float64                `json:"duration"`
 	Order      int                    `json:"order"`
 	Transition map[string]interface{} `json:"transition"`
+	Volume     *float64               `json:"volume,omitempty"` // 片段音量倍数，为空表示原音量
+	Muted      bool                   `json:"muted,omitempty"`  // 静音该片段的原声
+}
+
+// SoundEffectMapping 单个镜头的音效匹配结果，File 为空表示没有匹配的音效
+type SoundEffectMapping struct {
+	StoryboardID uint    `json:"storyboard_id"`
+	Description  string  `json:"description"`
+	Keyword      string  `json:"keyword,omitempty"`
+	File         string  `json:"file,omitempty"`
+	Start        float64 `json:"start"`    // 在合成视频中的开始时间（秒）
+	Duration     float64 `json:"duration"` // 镜头时长（秒），音效超出部分被截断
 }
 
 func (v *VideoMerge) TableName() string {
@@ -70,7 +70,11 @@ func NewDatabase(cfg config.DatabaseConfig) (*gorm.DB, error) {
 }
 
 func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	// 在迁移前记录 weight 列是否存在，新增该列时需要为已有配置回填默认权重
+	addingWeight := db.Migrator().HasTable(&models.AIServiceConfig{}) &&
+		!db.Migrator().HasColumn(&models.AIServiceConfig{}, "weight")
+
+	if err := db.AutoMigrate(
 		// 核心模型
 		&models.Drama{},
 		&models.Episode{},
@@ -84,6 +88,7 @@ func AutoMigrate(db *gorm.DB) error {
 		&models.ImageGeneration{},
 		&models.VideoGeneration{},
 		&models.VideoMerge{},
+		&models.GenerationHistory{},
 
 		// AI配置
 		&models.AIServiceConfig{},
@@ -95,5 +100,22 @@ func AutoMigrate(db *gorm.DB) error {
 
 		// 任务管理
 		&models.AsyncTask{},
-	)
+	); err != nil {
+		return err
+	}
+
+	return backfillConfigWeights(db, addingWeight)
+}
+
+// backfillConfigWeights 为没有权重的AI配置回填默认权重 1，避免开启加权选择后已有配置因权重为 0 被排除
+// 刚新增 weight 列时已有配置的 0 也回填；之后的 0 是用户显式设置的，保持不变
+func backfillConfigWeights(db *gorm.DB, addingWeight bool) error {
+	query := db.Model(&models.AIServiceConfig{}).Where("weight IS NULL")
+	if addingWeight {
+		query = db.Model(&models.AIServiceConfig{}).Where("weight IS NULL OR weight = ?", 0)
+	}
+	if err := query.Update("weight", 1).Error; err != nil {
+		return fmt.Errorf("failed to backfill ai config weights: %w", err)
+	}
+	return nil
 }
@@ -34,11 +34,32 @@ type VideoClip struct {
 	StartTime  float64
 	EndTime    float64
 	Transition map[string]interface{}
+	Volume     float64 // 音量倍数，0 或 1 表示原音量
+	Muted      bool    // 静音片段原声
 }
 
 type MergeOptions struct {
-	OutputPath string
-	Clips      []VideoClip
+	OutputPath   string
+	Clips        []VideoClip
+	MasterVolume float64 // 整体音量倍数，与片段音量相乘，0 或 1 表示原音量
+}
+
+// audioFilter 片段的音量滤镜，不需要调整音量时返回空字符串
+func (c VideoClip) audioFilter(master float64) string {
+	if c.Muted {
+		return "volume=0"
+	}
+	volume := 1.0
+	if c.Volume > 0 {
+		volume = c.Volume
+	}
+	if master > 0 {
+		volume *= master
+	}
+	if volume == 1 {
+		return ""
+	}
+	return fmt.Sprintf("volume=%.3f", volume)
 }
 
 func (f *FFmpeg) MergeVideos(opts *MergeOptions) (string, error) {
@@ -65,7 +86,7 @@ func (f *FFmpeg) MergeVideos(opts *MergeOptions) (string, error) {
 
 		// 裁剪视频片段（根据StartTime和EndTime）
 		trimmedPath := filepath.Join(f.tempDir, fmt.Sprintf("trimmed_%d_%d.mp4", time.Now().Unix(), i))
-		err = f.trimVideo(localPath, trimmedPath, clip.StartTime, clip.EndTime)
+		err = f.trimVideo(localPath, trimmedPath, clip.StartTime, clip.EndTime, clip.audioFilter(opts.MasterVolume))
 		if err != nil {
 			f.cleanup(downloadedPaths)
 			f.cleanup(trimmedPaths)
@@ -161,29 +182,37 @@ func (f *FFmpeg) downloadVideo(url, destPath string) (string, error) {
 	return destPath, nil
 }
 
-func (f *FFmpeg) trimVideo(inputPath, outputPath string, startTime, endTime float64) error {
+func (f *FFmpeg) trimVideo(inputPath, outputPath string, startTime, endTime float64, audioFilter string) error {
 	f.log.Infow("Trimming video",
 		"input", inputPath,
 		"output", outputPath,
 		"start", startTime,
-		"end", endTime)
+		"end", endTime,
+		"audio_filter", audioFilter)
+
+	// 音量调整只在片段有音轨时生效
+	var audioArgs []string
+	if audioFilter != "" && f.hasAudioStream(inputPath) {
+		audioArgs = []string{"-af", audioFilter}
+	}
+	encodeArgs := append(append([]string{
+		"-c:v", "libx264",
+		"-preset", "fast",
+		"-crf", "23",
+	}, audioArgs...),
+		"-c:a", "aac",
+		"-b:a", "128k",
+		"-movflags", "+faststart",
+		"-y",
+		outputPath,
+	)
 
 	// 如果startTime和endTime都为0，或者endTime <= startTime，复制整个视频
 	// 使用重新编码而非-c copy以确保输出文件完整性
 	if (startTime == 0 && endTime == 0) || endTime <= startTime {
 		f.log.Infow("No valid trim range, re-encoding entire video")
 
-		cmd := exec.Command("ffmpeg",
-			"-i", inputPath,
-			"-c:v", "libx264",
-			"-preset", "fast",
-			"-crf", "23",
-			"-c:a", "aac",
-			"-b:a", "128k",
-			"-movflags", "+faststart",
-			"-y",
-			outputPath,
-		)
+		cmd := exec.Command("ffmpeg", append([]string{"-i", inputPath}, encodeArgs...)...)
 
 		output, err := cmd.CombinedOutput()
 		if err != nil {
@@ -199,37 +228,12 @@ func (f *FFmpeg) trimVideo(inputPath, outputPath string, startTime, endTime floa
 	// -ss: 开始时间（秒）
 	// -to/-t: 结束时间或持续时间
 	// 使用重新编码而非-c copy以确保输出文件完整性，避免Windows环境下流信息丢失
-	var cmd *exec.Cmd
+	args := []string{"-i", inputPath, "-ss", fmt.Sprintf("%.2f", startTime)}
 	if endTime > 0 {
-		// 有明确的结束时间
-		cmd = exec.Command("ffmpeg",
-			"-i", inputPath,
-			"-ss", fmt.Sprintf("%.2f", startTime),
-			"-to", fmt.Sprintf("%.2f", endTime),
-			"-c:v", "libx264",
-			"-preset", "fast",
-			"-crf", "23",
-			"-c:a", "aac",
-			"-b:a", "128k",
-			"-movflags", "+faststart",
-			"-y",
-			outputPath,
-		)
-	} else {
-		// 只有开始时间，裁剪到视频末尾
-		cmd = exec.Command("ffmpeg",
-			"-i", inputPath,
-			"-ss", fmt.Sprintf("%.2f", startTime),
-			"-c:v", "libx264",
-			"-preset", "fast",
-			"-crf", "23",
-			"-c:a", "aac",
-			"-b:a", "128k",
-			"-movflags", "+faststart",
-			"-y",
-			outputPath,
-		)
+		// 有明确的结束时间；否则裁剪到视频末尾
+		args = append(args, "-to", fmt.Sprintf("%.2f", endTime))
 	}
+	cmd := exec.Command("ffmpeg", append(args, encodeArgs...)...)
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -883,3 +887,71 @@ func (f *FFmpeg) generateSilence(outputPath string, duration float64) (string, e
 	f.log.Infow("Silence audio generated successfully", "output", outputPath)
 	return outputPath, nil
 }
+
+// SoundEffectCue 在合成视频指定时间点混入的音效
+type SoundEffectCue struct {
+	Path     string  // 音效文件路径
+	Start    float64 // 开始时间（秒）
+	Duration float64 // 最长播放时长（秒），0 表示播放完整音效
+	Volume   float64 // 音量 0-1
+}
+
+// MixSoundEffects 将音效按时间点混入视频音轨后写入 outputPath，视频流直接复制
+// 视频没有音轨时只使用音效，音效之外的部分为静音
+func (f *FFmpeg) MixSoundEffects(videoPath, outputPath string, cues []SoundEffectCue) error {
+	if len(cues) == 0 {
+		return fmt.Errorf("no sound effects to mix")
+	}
+
+	args := []string{"-i", videoPath}
+	for _, cue := range cues {
+		args = append(args, "-i", cue.Path)
+	}
+
+	var filters []string
+	var labels []string
+	hasAudio := f.hasAudioStream(videoPath)
+	if hasAudio {
+		labels = append(labels, "[0:a]")
+	}
+	for i, cue := range cues {
+		delayMs := int(cue.Start * 1000)
+		filter := fmt.Sprintf("[%d:a]", i+1)
+		if cue.Duration > 0 {
+			filter += fmt.Sprintf("atrim=0:%.2f,", cue.Duration)
+		}
+		filter += fmt.Sprintf("adelay=%d|%d,volume=%.2f[s%d]", delayMs, delayMs, cue.Volume, i)
+		filters = append(filters, filter)
+		labels = append(labels, fmt.Sprintf("[s%d]", i))
+	}
+
+	// 有原音轨时以原音轨长度为准；否则补齐静音并以视频长度截断
+	if hasAudio {
+		filters = append(filters, fmt.Sprintf("%samix=inputs=%d:duration=first:normalize=0[aout]", strings.Join(labels, ""), len(labels)))
+	} else {
+		filters = append(filters, fmt.Sprintf("%samix=inputs=%d:duration=longest:normalize=0,apad[aout]", strings.Join(labels, ""), len(labels)))
+	}
+
+	args = append(args,
+		"-filter_complex", strings.Join(filters, ";"),
+		"-map", "0:v",
+		"-map", "[aout]",
+		"-c:v", "copy",
+		"-c:a", "aac",
+		"-b:a", "192k",
+		"-shortest",
+		"-y",
+		outputPath,
+	)
+
+	f.log.Infow("Mixing sound effects", "video", videoPath, "cues", len(cues), "has_audio", hasAudio)
+	cmd := exec.Command("ffmpeg", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		f.log.Errorw("FFmpeg sound effect mixing failed", "error", err, "output", string(output))
+		return fmt.Errorf("ffmpeg sound effect mixing failed: %w, output: %s", err, string(output))
+	}
+
+	f.log.Infow("Sound effects mixed", "output", outputPath)
+	return nil
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DownloadImageWithRetry 下载图片到本地存储，失败时按指数退避重试，并校验文件确实是可用图片
+func (s *LocalStorage) DownloadImageWithRetry(url, category string, maxRetries int, backoff time.Duration) (*DownloadResult, error) {
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff * time.Duration(1<<(attempt-1)))
+		}
+
+		result, err := s.DownloadFromURLWithPath(url, category)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if err := ValidateImageFile(result.AbsolutePath); err != nil {
+			// 删除损坏的缓存文件，避免后续访问出错
+			os.Remove(result.AbsolutePath)
+			lastErr = err
+			continue
+		}
+
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("download image failed after %d attempts: %w", maxRetries+1, lastErr)
+}
+
+// SaveDataURI 将 base64 data URI 图片保存到本地存储，并校验文件确实是可用图片
+func (s *LocalStorage) SaveDataURI(dataURI, category string) (*DownloadResult, error) {
+	header, data, ok := strings.Cut(strings.TrimPrefix(dataURI, "data:"), ",")
+	if !ok || !strings.HasSuffix(header, ";base64") {
+		return nil, fmt.Errorf("invalid data URI image")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode data URI: %w", err)
+	}
+
+	dir := filepath.Join(s.basePath, category)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create category directory: %w", err)
+	}
+	ext := getFileExtension("", strings.TrimSuffix(header, ";base64"))
+	filename := fmt.Sprintf("%s_%s%s", time.Now().Format("20060102_150405"), uuid.New().String()[:8], ext)
+	filePath := filepath.Join(dir, filename)
+	if err := os.WriteFile(filePath, decoded, 0644); err != nil {
+		return nil, fmt.Errorf("failed to save file: %w", err)
+	}
+	if err := ValidateImageFile(filePath); err != nil {
+		os.Remove(filePath)
+		return nil, err
+	}
+
+	return &DownloadResult{
+		URL:          fmt.Sprintf("%s/%s/%s", s.baseURL, category, filename),
+		RelativePath: filepath.Join(category, filename),
+		AbsolutePath: filePath,
+	}, nil
+}
+
+// ValidateImageFile 校验文件非空且文件头为可识别的图片格式
+func ValidateImageFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	header := make([]byte, 512)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			return fmt.Errorf("downloaded file is empty")
+		}
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	header = header[:n]
+
+	// WebP 标准库无法解码，只校验 RIFF....WEBP 文件头
+	if n >= 12 && bytes.Equal(header[0:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WEBP")) {
+		return nil
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek file: %w", err)
+	}
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return fmt.Errorf("downloaded file is not a valid image: %w", err)
+	}
+	if cfg.Width == 0 || cfg.Height == 0 {
+		return fmt.Errorf("downloaded image has invalid size %dx%d", cfg.Width, cfg.Height)
+	}
+
+	return nil
+}
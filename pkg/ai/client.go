@@ -1,8 +1,14 @@
 package ai
 
+import "errors"
+
+// ErrContentPolicy 请求或生成内容被服务商安全策略拦截
+var ErrContentPolicy = errors.New("content blocked by safety policy")
+
 // AIClient 定义文本生成客户端接口
 type AIClient interface {
 	GenerateText(prompt string, systemPrompt string, options ...func(*ChatCompletionRequest)) (string, error)
+	GenerateTextWithImages(prompt string, systemPrompt string, images []string, options ...func(*ChatCompletionRequest)) (string, error)
 	GenerateImage(prompt string, size string, n int) ([]string, error)
 	TestConnection() error
 }
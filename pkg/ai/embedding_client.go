@@ -0,0 +1,121 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// EmbeddingClient 定义文本向量化客户端接口
+type EmbeddingClient interface {
+	CreateEmbeddings(inputs []string) ([][]float64, error)
+}
+
+// OpenAIEmbeddingClient OpenAI 兼容的 /embeddings 接口客户端
+type OpenAIEmbeddingClient struct {
+	BaseURL      string
+	APIKey       string
+	Model        string
+	Endpoint     string
+	HTTPClient   *http.Client
+	ExtraHeaders map[string]string // 附加请求头，如 api-version
+}
+
+type EmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type EmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+}
+
+func NewOpenAIEmbeddingClient(baseURL, apiKey, model, endpoint string) *OpenAIEmbeddingClient {
+	if endpoint == "" {
+		endpoint = "/embeddings"
+	}
+
+	return &OpenAIEmbeddingClient{
+		BaseURL:  baseURL,
+		APIKey:   apiKey,
+		Model:    model,
+		Endpoint: endpoint,
+		HTTPClient: &http.Client{
+			Timeout: 2 * time.Minute,
+		},
+	}
+}
+
+func (c *OpenAIEmbeddingClient) CreateEmbeddings(inputs []string) ([][]float64, error) {
+	if len(inputs) == 0 {
+		return [][]float64{}, nil
+	}
+
+	jsonData, err := json.Marshal(EmbeddingRequest{Model: c.Model, Input: inputs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := c.BaseURL + c.Endpoint
+
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+c.APIKey)
+	for k, v := range c.ExtraHeaders {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp ErrorResponse
+		if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Message != "" {
+			return nil, fmt.Errorf("API error: %s", errResp.Error.Message)
+		}
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var embResp EmbeddingResponse
+	if err := json.Unmarshal(body, &embResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if len(embResp.Data) != len(inputs) {
+		return nil, fmt.Errorf("embedding count mismatch: expected %d, got %d", len(inputs), len(embResp.Data))
+	}
+
+	// 按 index 还原输入顺序
+	embeddings := make([][]float64, len(inputs))
+	for _, item := range embResp.Data {
+		if item.Index < 0 || item.Index >= len(inputs) {
+			return nil, fmt.Errorf("invalid embedding index: %d", item.Index)
+		}
+		embeddings[item.Index] = item.Embedding
+	}
+
+	return embeddings, nil
+}
+
+// SetExtraHeaders 设置每个请求都会附带的自定义请求头
+func (c *OpenAIEmbeddingClient) SetExtraHeaders(headers map[string]string) {
+	c.ExtraHeaders = headers
+}
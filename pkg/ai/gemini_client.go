@@ -6,21 +6,31 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 )
 
 type GeminiClient struct {
-	BaseURL    string
-	APIKey     string
-	Model      string
-	Endpoint   string
-	HTTPClient *http.Client
+	BaseURL        string
+	APIKey         string
+	Model          string
+	Endpoint       string
+	HTTPClient     *http.Client
+	ExtraHeaders   map[string]string     // 附加请求头，如 api-version
+	SafetySettings []GeminiSafetySetting // 安全过滤阈值，为空时使用服务端默认值
 }
 
 type GeminiTextRequest struct {
-	Contents          []GeminiContent    `json:"contents"`
-	SystemInstruction *GeminiInstruction `json:"systemInstruction,omitempty"`
+	Contents          []GeminiContent       `json:"contents"`
+	SystemInstruction *GeminiInstruction    `json:"systemInstruction,omitempty"`
+	SafetySettings    []GeminiSafetySetting `json:"safetySettings,omitempty"`
+}
+
+// GeminiSafetySetting 单个危害类别的拦截阈值，如 HARM_CATEGORY_HARASSMENT / BLOCK_ONLY_HIGH
+type GeminiSafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
 }
 
 type GeminiContent struct {
@@ -29,7 +39,14 @@ type GeminiContent struct {
 }
 
 type GeminiPart struct {
-	Text string `json:"text"`
+	Text       string            `json:"text,omitempty"`
+	InlineData *GeminiInlineData `json:"inlineData,omitempty"`
+}
+
+// GeminiInlineData 内联的图片数据（base64）
+type GeminiInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
 }
 
 type GeminiInstruction struct {
@@ -51,6 +68,9 @@ type GeminiTextResponse struct {
 			Probability string `json:"probability"`
 		} `json:"safetyRatings"`
 	} `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
 	UsageMetadata struct {
 		PromptTokenCount     int `json:"promptTokenCount"`
 		CandidatesTokenCount int `json:"candidatesTokenCount"`
@@ -99,6 +119,15 @@ func (c *GeminiClient) GenerateText(prompt string, systemPrompt string, options
 		}
 	}
 
+	return c.generateContent(model, reqBody)
+}
+
+// generateContent 发送 generateContent 请求并返回第一个候选的文本
+func (c *GeminiClient) generateContent(model string, reqBody GeminiTextRequest) (string, error) {
+	if len(c.SafetySettings) > 0 {
+		reqBody.SafetySettings = c.SafetySettings
+	}
+
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
 		fmt.Printf("Gemini: Failed to marshal request: %v\n", err)
@@ -126,6 +155,9 @@ func (c *GeminiClient) GenerateText(prompt string, systemPrompt string, options
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.ExtraHeaders {
+		req.Header.Set(k, v)
+	}
 
 	fmt.Printf("Gemini: Executing HTTP request...\n")
 	resp, err := c.HTTPClient.Do(req)
@@ -167,12 +199,19 @@ func (c *GeminiClient) GenerateText(prompt string, systemPrompt string, options
 
 	fmt.Printf("Gemini: Successfully parsed response, candidates count: %d\n", len(result.Candidates))
 
+	if reason := result.PromptFeedback.BlockReason; reason != "" {
+		return "", fmt.Errorf("%w: prompt blocked (%s)", ErrContentPolicy, reason)
+	}
+
 	if len(result.Candidates) == 0 {
 		fmt.Printf("Gemini: No candidates in response\n")
 		return "", fmt.Errorf("no candidates in response")
 	}
 
 	if len(result.Candidates[0].Content.Parts) == 0 {
+		if reason := result.Candidates[0].FinishReason; IsGeminiSafetyFinishReason(reason) {
+			return "", fmt.Errorf("%w: response blocked (%s)", ErrContentPolicy, reason)
+		}
 		fmt.Printf("Gemini: No parts in first candidate\n")
 		return "", fmt.Errorf("no parts in response")
 	}
@@ -197,3 +236,37 @@ func (c *GeminiClient) TestConnection() error {
 	}
 	return err
 }
+
+// SetExtraHeaders 设置每个请求都会附带的自定义请求头
+func (c *GeminiClient) SetExtraHeaders(headers map[string]string) {
+	c.ExtraHeaders = headers
+}
+
+// SetSafetySettings 设置安全过滤阈值（危害类别 -> 阈值），按类别排序保证请求体稳定
+func (c *GeminiClient) SetSafetySettings(settings map[string]string) {
+	c.SafetySettings = GeminiSafetySettingsFromMap(settings)
+}
+
+// GeminiSafetySettingsFromMap 将 类别 -> 阈值 的配置转换为请求字段，按类别排序；文本和图片客户端共用
+func GeminiSafetySettingsFromMap(settings map[string]string) []GeminiSafetySetting {
+	categories := make([]string, 0, len(settings))
+	for category := range settings {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	result := make([]GeminiSafetySetting, 0, len(categories))
+	for _, category := range categories {
+		result = append(result, GeminiSafetySetting{Category: category, Threshold: settings[category]})
+	}
+	return result
+}
+
+// IsGeminiSafetyFinishReason 判断候选结果是否因安全策略被截断或拦截
+func IsGeminiSafetyFinishReason(reason string) bool {
+	switch reason {
+	case "SAFETY", "PROHIBITED_CONTENT", "BLOCKLIST", "SPII", "IMAGE_SAFETY", "IMAGE_PROHIBITED_CONTENT":
+		return true
+	}
+	return false
+}
@@ -11,16 +11,17 @@ import (
 )
 
 type OpenAIClient struct {
-	BaseURL    string
-	APIKey     string
-	Model      string
-	Endpoint   string
-	HTTPClient *http.Client
+	BaseURL      string
+	APIKey       string
+	Model        string
+	Endpoint     string
+	HTTPClient   *http.Client
+	ExtraHeaders map[string]string // 附加请求头，如 api-version
 }
 
 type ChatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"` // 纯文本为 string，多模态为 []ContentPart
 }
 
 type ChatCompletionRequest struct {
@@ -149,6 +150,9 @@ func (c *OpenAIClient) doChatRequest(req *ChatCompletionRequest) (*ChatCompletio
 
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+c.APIKey)
+	for k, v := range c.ExtraHeaders {
+		httpReq.Header.Set(k, v)
+	}
 
 	fmt.Printf("OpenAI: Executing HTTP request...\n")
 	resp, err := c.HTTPClient.Do(httpReq)
@@ -292,6 +296,9 @@ func (c *OpenAIClient) GenerateImage(prompt string, size string, n int) ([]strin
 
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+c.APIKey)
+	for k, v := range c.ExtraHeaders {
+		httpReq.Header.Set(k, v)
+	}
 
 	resp, err := c.HTTPClient.Do(httpReq)
 	if err != nil {
@@ -366,3 +373,8 @@ func shouldRetryWithMaxCompletionTokens(err error, req *ChatCompletionRequest) b
 	}
 	return false
 }
+
+// SetExtraHeaders 设置每个请求都会附带的自定义请求头
+func (c *OpenAIClient) SetExtraHeaders(headers map[string]string) {
+	c.ExtraHeaders = headers
+}
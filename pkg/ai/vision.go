@@ -0,0 +1,144 @@
+package ai
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxVisionImageBytes 单张输入图片的最大字节数
+const maxVisionImageBytes = 20 * 1024 * 1024
+
+// VisionClient 支持图片输入的文本客户端
+type VisionClient interface {
+	GenerateTextWithImages(prompt string, systemPrompt string, images []string, options ...func(*ChatCompletionRequest)) (string, error)
+}
+
+// ContentPart OpenAI 多模态消息内容片段
+type ContentPart struct {
+	Type     string        `json:"type"` // text 或 image_url
+	Text     string        `json:"text,omitempty"`
+	ImageURL *ImageURLPart `json:"image_url,omitempty"`
+}
+
+// ImageURLPart 图片片段，URL 可以是 http(s) 地址或 data URI
+type ImageURLPart struct {
+	URL string `json:"url"`
+}
+
+// GenerateTextWithImages 发送带图片的对话请求，images 为图片URL或 data URI
+func (c *OpenAIClient) GenerateTextWithImages(prompt string, systemPrompt string, images []string, options ...func(*ChatCompletionRequest)) (string, error) {
+	messages := []ChatMessage{}
+
+	if systemPrompt != "" {
+		messages = append(messages, ChatMessage{
+			Role:    "system",
+			Content: systemPrompt,
+		})
+	}
+
+	parts := make([]ContentPart, 0, len(images)+1)
+	parts = append(parts, ContentPart{Type: "text", Text: prompt})
+	for _, img := range images {
+		parts = append(parts, ContentPart{Type: "image_url", ImageURL: &ImageURLPart{URL: img}})
+	}
+	messages = append(messages, ChatMessage{
+		Role:    "user",
+		Content: parts,
+	})
+
+	resp, err := c.ChatCompletion(messages, options...)
+	if err != nil {
+		return "", err
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no response from API")
+	}
+
+	return resp.Choices[0].Message.Content, nil
+}
+
+// GenerateTextWithImages 发送带图片的请求，Gemini 只接受内联数据，远程图片会先下载再以 base64 发送
+func (c *GeminiClient) GenerateTextWithImages(prompt string, systemPrompt string, images []string, options ...func(*ChatCompletionRequest)) (string, error) {
+	parts := make([]GeminiPart, 0, len(images)+1)
+	parts = append(parts, GeminiPart{Text: prompt})
+	for _, img := range images {
+		inline, err := toGeminiInlineData(img, c.HTTPClient)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, GeminiPart{InlineData: inline})
+	}
+
+	reqBody := GeminiTextRequest{
+		Contents: []GeminiContent{
+			{
+				Parts: parts,
+				Role:  "user",
+			},
+		},
+	}
+	if systemPrompt != "" {
+		reqBody.SystemInstruction = &GeminiInstruction{
+			Parts: []GeminiPart{{Text: systemPrompt}},
+		}
+	}
+
+	return c.generateContent(c.Model, reqBody)
+}
+
+// toGeminiInlineData 将 data URI 或远程图片URL转换为 Gemini 内联数据
+func toGeminiInlineData(img string, httpClient *http.Client) (*GeminiInlineData, error) {
+	if strings.HasPrefix(img, "data:") {
+		header, data, ok := strings.Cut(strings.TrimPrefix(img, "data:"), ",")
+		if !ok || !strings.HasSuffix(header, ";base64") {
+			return nil, fmt.Errorf("invalid data URI image")
+		}
+		return &GeminiInlineData{MimeType: strings.TrimSuffix(header, ";base64"), Data: data}, nil
+	}
+
+	resp, err := httpClient.Get(img)
+	if err != nil {
+		return nil, fmt.Errorf("download image: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download image failed (status %d)", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxVisionImageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("read image: %w", err)
+	}
+	if len(data) > maxVisionImageBytes {
+		return nil, fmt.Errorf("image too large (max %d MB)", maxVisionImageBytes/1024/1024)
+	}
+
+	mimeType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(mimeType, "image/") {
+		mimeType = http.DetectContentType(data)
+	}
+
+	return &GeminiInlineData{MimeType: mimeType, Data: base64.StdEncoding.EncodeToString(data)}, nil
+}
+
+// visionModelKeywords 已知支持图片输入的模型名关键字，用于未显式配置时的能力探测
+var visionModelKeywords = []string{
+	"gpt-4o", "gpt-4.1", "gpt-4-turbo", "gpt-4-vision", "gpt-5", "o3", "o4",
+	"gemini", "claude-3", "claude-sonnet", "claude-opus",
+	"vision", "-vl", "4v", "qvq", "doubao-seed", "doubao-1.5-vision",
+}
+
+// ModelSupportsVision 根据模型名判断是否支持图片输入
+func ModelSupportsVision(model string) bool {
+	name := strings.ToLower(model)
+	for _, keyword := range visionModelKeywords {
+		if strings.Contains(name, keyword) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,16 +2,21 @@ package config
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	App      AppConfig      `mapstructure:"app"`
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Storage  StorageConfig  `mapstructure:"storage"`
-	AI       AIConfig       `mapstructure:"ai"`
+	App       AppConfig       `mapstructure:"app"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Database  DatabaseConfig  `mapstructure:"database"`
+	Storage   StorageConfig   `mapstructure:"storage"`
+	AI        AIConfig        `mapstructure:"ai"`
+	Watermark WatermarkConfig `mapstructure:"watermark"`
+	Limits    LimitsConfig    `mapstructure:"limits"`
+	Style     StyleConfig     `mapstructure:"style"`
+	Sound     SoundConfig     `mapstructure:"sound"`
 }
 
 type AppConfig struct {
@@ -27,6 +32,7 @@ type ServerConfig struct {
 	CORSOrigins  []string `mapstructure:"cors_origins"`
 	ReadTimeout  int      `mapstructure:"read_timeout"`
 	WriteTimeout int      `mapstructure:"write_timeout"`
+	AdminToken   string   `mapstructure:"admin_token"` // 管理接口令牌，为空时禁用管理接口
 }
 
 type DatabaseConfig struct {
@@ -43,15 +49,152 @@ type DatabaseConfig struct {
 }
 
 type StorageConfig struct {
-	Type      string `mapstructure:"type"`       // local, minio
-	LocalPath string `mapstructure:"local_path"` // 本地存储路径
-	BaseURL   string `mapstructure:"base_url"`   // 访问URL前缀
+	Type            string `mapstructure:"type"`              // local, minio
+	LocalPath       string `mapstructure:"local_path"`        // 本地存储路径
+	BaseURL         string `mapstructure:"base_url"`          // 访问URL前缀
+	DownloadRetries int    `mapstructure:"download_retries"`  // 图片下载失败重试次数
+	DownloadBackoff int    `mapstructure:"download_backoff"`  // 首次重试等待秒数，之后指数递增
+	MarkCacheFailed bool   `mapstructure:"mark_cache_failed"` // 下载最终失败时标记 cache_failed
+	ImageFormat     string `mapstructure:"image_format"`      // 下载后转码的目标格式（jpeg、png），为空时保留服务商返回的格式
+	ImageQuality    int    `mapstructure:"image_quality"`     // JPEG 转码质量 1-100，默认 85
+	KeepOriginal    bool   `mapstructure:"keep_original"`     // 转码后保留服务商返回的原始文件作为母版
+	// ImagePathTemplate 生成图片的缓存目录模板，支持 {drama_id} {episode_id} {image_type} {yyyy} {mm} {dd} {yyyy-mm}，为空时使用 images
+	ImagePathTemplate string `mapstructure:"image_path_template"`
+}
+
+// WatermarkConfig 生成图片水印配置，仅对开启了水印的剧本生效
+type WatermarkConfig struct {
+	Enabled     bool    `mapstructure:"enabled"`
+	Text        string  `mapstructure:"text"`         // 文字水印（ASCII）
+	LogoPath    string  `mapstructure:"logo_path"`    // Logo PNG 路径，设置后优先于文字
+	Opacity     float64 `mapstructure:"opacity"`      // 不透明度 0-1
+	Position    string  `mapstructure:"position"`     // bottom-right, bottom-left, top-right, top-left, center
+	PrivatePath string  `mapstructure:"private_path"` // 无水印原图保存目录，不对外提供静态访问
+}
+
+// SoundConfig 合成视频时的音效库，按分镜 sound_effect 描述中的关键词匹配音效文件
+type SoundConfig struct {
+	LibraryPath string            `mapstructure:"library_path"` // 音效文件目录，为空时不添加音效
+	Keywords    map[string]string `mapstructure:"keywords"`     // 关键词 -> 音效文件（相对 library_path），未配置时按文件名匹配
+	Volume      float64           `mapstructure:"volume"`       // 音效音量 0-1，默认 0.8
+}
+
+// StyleConfig 图片提示词中的风格与帧类型后缀
+type StyleConfig struct {
+	DefaultStyle      string            `mapstructure:"default_style"`       // 剧本未设置风格时使用的风格
+	FrameSuffixes     map[string]string `mapstructure:"frame_suffixes"`      // 按帧类型（first/key/last/action）覆盖提示词后缀
+	DefaultVideoRatio string            `mapstructure:"default_video_ratio"` // 剧集未设置画面比例时视频提示词使用的比例，默认 16:9
+	ImagePromptSuffix string            `mapstructure:"image_prompt_suffix"` // 剧本未设置时追加到图片提示词末尾的画质描述
+	VideoPromptSuffix string            `mapstructure:"video_prompt_suffix"` // 剧本未设置时追加到视频提示词末尾的画质描述
+}
+
+// LimitsConfig 输入规模上限，0 使用默认值，负数表示不限制
+type LimitsConfig struct {
+	MaxScriptLength          int `mapstructure:"max_script_length"`           // 单集剧本最大字符数
+	MaxEpisodesPerDrama      int `mapstructure:"max_episodes_per_drama"`      // 每个剧本最多集数
+	MaxStoryboardsPerEpisode int `mapstructure:"max_storyboards_per_episode"` // 每集最多分镜数
+	MaxRequestBodyKB         int `mapstructure:"max_request_body_kb"`         // 非上传类请求体最大KB数
+}
+
+// 输入规模默认上限
+const (
+	DefaultMaxScriptLength          = 50000
+	DefaultMaxEpisodesPerDrama      = 200
+	DefaultMaxStoryboardsPerEpisode = 300
+	DefaultMaxRequestBodyKB         = 2048
+)
+
+// ScriptLength 返回生效的剧本长度上限，0 表示不限制
+func (l LimitsConfig) ScriptLength() int {
+	return effectiveLimit(l.MaxScriptLength, DefaultMaxScriptLength)
+}
+
+// EpisodesPerDrama 返回生效的集数上限，0 表示不限制
+func (l LimitsConfig) EpisodesPerDrama() int {
+	return effectiveLimit(l.MaxEpisodesPerDrama, DefaultMaxEpisodesPerDrama)
+}
+
+// StoryboardsPerEpisode 返回生效的分镜数上限，0 表示不限制
+func (l LimitsConfig) StoryboardsPerEpisode() int {
+	return effectiveLimit(l.MaxStoryboardsPerEpisode, DefaultMaxStoryboardsPerEpisode)
+}
+
+// RequestBodyBytes 返回生效的请求体字节数上限，0 表示不限制
+func (l LimitsConfig) RequestBodyBytes() int64 {
+	return int64(effectiveLimit(l.MaxRequestBodyKB, DefaultMaxRequestBodyKB)) * 1024
+}
+
+func effectiveLimit(value, def int) int {
+	if value < 0 {
+		return 0
+	}
+	if value == 0 {
+		return def
+	}
+	return value
 }
 
 type AIConfig struct {
-	DefaultTextProvider  string `mapstructure:"default_text_provider"`
-	DefaultImageProvider string `mapstructure:"default_image_provider"`
-	DefaultVideoProvider string `mapstructure:"default_video_provider"`
+	DefaultTextProvider    string  `mapstructure:"default_text_provider"`
+	DefaultImageProvider   string  `mapstructure:"default_image_provider"`
+	DefaultVideoProvider   string  `mapstructure:"default_video_provider"`
+	ModelSelection         string  `mapstructure:"model_selection"`          // 多个配置时的选择方式：priority（默认）或 weighted
+	SceneDedupThreshold    float64 `mapstructure:"scene_dedup_threshold"`    // 向量去重场景时的相似度阈值（0-1）
+	ImageWorkers           int     `mapstructure:"image_workers"`            // 同时执行的图片生成任务数
+	ImageMaxRetries        int     `mapstructure:"image_max_retries"`        // 单条图片生成最多重试次数
+	CaptureRawResponse     bool    `mapstructure:"capture_raw_response"`     // 保存服务商原始响应用于排查
+	ImageResultCache       bool    `mapstructure:"image_result_cache"`       // 输入完全相同时复用已完成的图片生成结果
+	RequireReferenceImages bool    `mapstructure:"require_reference_images"` // 参考图全部失效时让生成失败，否则不带参考图继续生成
+	CharacterQA            bool    `mapstructure:"character_qa"`             // 分镜图片生成后用视觉模型检查角色是否出镜
+	CharacterQAModel       string  `mapstructure:"character_qa_model"`       // 角色检查使用的视觉模型，为空时使用默认文本模型
+	JSONRepairAttempts     int     `mapstructure:"json_repair_attempts"`     // AI输出无法解析时请求修复的次数，0 使用默认值 2，负数关闭
+	// EpisodeImageConcurrency 剧集批量生图时单批最多同时生成的图片数，0 不单独限制（仍受 image_workers 限制）
+	EpisodeImageConcurrency int `mapstructure:"episode_image_concurrency"`
+	// DramaImageConcurrency 单个剧本最多同时执行（含等待异步结果）的图片生成数，超出的任务在队列中等待，0 使用 image_workers - 1（至少为 1），负数不限制
+	DramaImageConcurrency int `mapstructure:"drama_image_concurrency"`
+	// ProviderAliases 自定义服务商名称（如代理名）到标准服务商 openai/volcengine/gemini 的映射，优先于内置别名
+	ProviderAliases map[string]string `mapstructure:"provider_aliases"`
+	// ImagePromptLimits 按服务商覆盖图片提示词最大长度，如 volcengine: 800
+	ImagePromptLimits map[string]int `mapstructure:"image_prompt_limits"`
+	// TextRateLimits 按服务商限制文本模型每分钟请求数（default 对其他服务商生效），超出时排队等待
+	TextRateLimits map[string]int `mapstructure:"text_rate_limits"`
+	// ModelLimits 按文本模型名配置上下文和输出 token 上限，用于计算分镜生成的 max_tokens
+	ModelLimits map[string]ModelLimit `mapstructure:"model_limits"`
+	// BlankImageStdDev/BlankImageEntropy 空白图检测阈值（亮度标准差/信息熵），负数表示关闭该项检查
+	BlankImageStdDev  float64 `mapstructure:"blank_image_stddev"`
+	BlankImageEntropy float64 `mapstructure:"blank_image_entropy"`
+	// StyleConsistencyCheck 允许用视觉模型检查剧本场景图的画风是否一致，StyleConsistencyModel 为空时使用默认文本模型
+	StyleConsistencyCheck bool   `mapstructure:"style_consistency_check"`
+	StyleConsistencyModel string `mapstructure:"style_consistency_model"`
+	// MaxReferenceImages 单次图片生成最多发送的参考图数量，超出时按优先级保留（有台词/主要角色优先，其次风格参考），0 不限制
+	MaxReferenceImages int `mapstructure:"max_reference_images"`
+	// ImageAutoTag 图片生成完成后用视觉模型生成内容标签（物体、情绪、色调），ImageAutoTagModel 为空时使用默认文本模型
+	ImageAutoTag      bool   `mapstructure:"image_auto_tag"`
+	ImageAutoTagModel string `mapstructure:"image_auto_tag_model"`
+	// ModelCosts 按模型名配置单次调用费用和平均耗时，用于估算剧集流水线成本；键 text/image/video 对该类型未单独配置的模型生效
+	ModelCosts map[string]ModelCost `mapstructure:"model_costs"`
+	// StrictStoryboardSave 保存分镜时角色关联失败则回滚本次保存并返回出错的镜头，关闭时只记录警告
+	StrictStoryboardSave bool `mapstructure:"strict_storyboard_save"`
+	// ReferenceMaxKB 内联（base64）参考图的最大KB数，超出时先缩小，无法缩小时跳过该参考图；0 使用默认值 4096，负数不限制
+	ReferenceMaxKB int `mapstructure:"reference_max_kb"`
+	// ReferenceFetchTimeout 下载需要内联的参考图的超时秒数，0 使用默认值 30
+	ReferenceFetchTimeout int `mapstructure:"reference_fetch_timeout"`
+	// MaxResponseKB 解析AI返回的JSON（如分镜）前允许的最大响应KB数，超出时直接失败不再解析；0 使用默认值 1024，负数不限制
+	MaxResponseKB int `mapstructure:"max_response_kb"`
+	// DefaultModels 按服务商（如 openai、gemini、volcengine）指定未传模型时使用的默认模型，未配置时使用AI配置中的第一个模型
+	DefaultModels map[string]string `mapstructure:"default_models"`
+}
+
+// ModelLimit 文本模型的 token 上限，0 表示使用默认值
+type ModelLimit struct {
+	ContextTokens   int `mapstructure:"context_tokens"`    // 上下文窗口（输入+输出）
+	MaxOutputTokens int `mapstructure:"max_output_tokens"` // 单次最多输出
+}
+
+// ModelCost 模型单次调用的费用和平均耗时，0 表示未配置
+type ModelCost struct {
+	PerCall    float64 `mapstructure:"per_call"`    // 单次调用费用，单位由使用者自行约定
+	AvgSeconds float64 `mapstructure:"avg_seconds"` // 单次调用平均耗时（秒）
 }
 
 func LoadConfig() (*Config, error) {
@@ -71,9 +214,24 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	current.Store(&config)
 	return &config, nil
 }
 
+// Validate 校验无法在运行时回退处理的配置项
+func (c *Config) Validate() error {
+	switch strings.ToLower(strings.TrimSpace(c.Storage.ImageFormat)) {
+	case "", "jpeg", "jpg", "png":
+	default:
+		return fmt.Errorf("unsupported storage.image_format %q: only jpeg and png can be encoded", c.Storage.ImageFormat)
+	}
+	return nil
+}
+
 func (c *DatabaseConfig) DSN() string {
 	if c.Type == "sqlite" {
 		return c.Path
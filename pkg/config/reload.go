@@ -0,0 +1,54 @@
+package config
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	current  atomic.Pointer[Config]
+	reloadMu sync.Mutex
+)
+
+// Current 返回当前生效的配置快照，未加载过配置时返回 nil
+// 快照在替换后不会被修改，调用方在一次请求内应复用同一个快照
+func Current() *Config {
+	return current.Load()
+}
+
+// CurrentOr 返回当前生效的配置，未加载过配置时返回 fallback
+func CurrentOr(fallback *Config) *Config {
+	if cfg := current.Load(); cfg != nil {
+		return cfg
+	}
+	return fallback
+}
+
+// Store 原子替换当前配置，调用方不应再修改传入的配置
+func Store(cfg *Config) {
+	current.Store(cfg)
+}
+
+// ReloadConfig 重新读取配置文件并原子替换当前配置
+// 已在处理中的请求继续使用旧快照；端口、数据库、存储目录等启动时使用的配置仍需重启生效
+func ReloadConfig() (*Config, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Update 基于当前配置复制出新快照，修改后原子替换（写时复制）
+func Update(fallback *Config, mutate func(cfg *Config)) *Config {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	next := *CurrentOr(fallback)
+	mutate(&next)
+	current.Store(&next)
+	return &next
+}
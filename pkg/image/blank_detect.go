@@ -0,0 +1,85 @@
+package image
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"os"
+)
+
+// ImageStats 图片亮度统计信息
+type ImageStats struct {
+	StdDev  float64 `json:"std_dev"` // 亮度标准差（0-255）
+	Entropy float64 `json:"entropy"` // 亮度直方图信息熵（0-8 bit）
+}
+
+// maxAnalyzeSamples 统计时最多采样的像素数，大图按步长抽样
+const maxAnalyzeSamples = 256 * 256
+
+// AnalyzeImage 计算图片亮度的标准差与信息熵，用于识别纯色或近乎空白的图片
+func AnalyzeImage(path string) (*ImageStats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image: %w", err)
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	bounds := img.Bounds()
+	total := bounds.Dx() * bounds.Dy()
+	if total == 0 {
+		return nil, fmt.Errorf("image is empty")
+	}
+
+	step := 1
+	if total > maxAnalyzeSamples {
+		step = int(math.Ceil(math.Sqrt(float64(total) / maxAnalyzeSamples)))
+	}
+
+	var histogram [256]int
+	var sum, sumSq float64
+	count := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			r, g, b, _ := img.At(x, y).RGBA()
+			// ITU-R BT.601 亮度
+			luma := (299*float64(r>>8) + 587*float64(g>>8) + 114*float64(b>>8)) / 1000
+			histogram[int(luma)]++
+			sum += luma
+			sumSq += luma * luma
+			count++
+		}
+	}
+
+	mean := sum / float64(count)
+	variance := sumSq/float64(count) - mean*mean
+	if variance < 0 {
+		variance = 0
+	}
+
+	entropy := 0.0
+	for _, n := range histogram {
+		if n == 0 {
+			continue
+		}
+		p := float64(n) / float64(count)
+		entropy -= p * math.Log2(p)
+	}
+
+	return &ImageStats{StdDev: math.Sqrt(variance), Entropy: entropy}, nil
+}
+
+// IsBlank 标准差或信息熵低于阈值时判定为空白图片，阈值小于等于 0 表示不检查该项
+func (st *ImageStats) IsBlank(minStdDev, minEntropy float64) bool {
+	if minStdDev > 0 && st.StdDev < minStdDev {
+		return true
+	}
+	if minEntropy > 0 && st.Entropy < minEntropy {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package image
+
+// Capabilities 描述某个图片服务商实际支持的生成参数
+type Capabilities struct {
+	NegativePrompt  bool `json:"negative_prompt"`
+	Size            bool `json:"size"`
+	Quality         bool `json:"quality"`
+	Style           bool `json:"style"`
+	Steps           bool `json:"steps"`
+	CfgScale        bool `json:"cfg_scale"`
+	Seed            bool `json:"seed"`
+	ReferenceImages bool `json:"reference_images"`
+	MaxPromptLength int  `json:"max_prompt_length"` // 提示词最大字符数，0 表示不限制
+	EnglishOnly     bool `json:"english_only"`      // 只支持英文提示词，其他语言的提示词会先自动翻译
+
+	// 预览模式使用的最低成本参数，为空表示不指定
+	PreviewSize    string `json:"preview_size,omitempty"`
+	PreviewQuality string `json:"preview_quality,omitempty"`
+}
+
+var (
+	openAICapabilities = Capabilities{
+		Size:            true,
+		Quality:         true,
+		ReferenceImages: true,
+		MaxPromptLength: 4000,
+		PreviewSize:     "1024x1024",
+		PreviewQuality:  "standard",
+	}
+	dalleCapabilities = Capabilities{
+		Size:            true,
+		Quality:         true,
+		ReferenceImages: true,
+		MaxPromptLength: 4000,
+		EnglishOnly:     true,
+		PreviewSize:     "1024x1024",
+		PreviewQuality:  "standard",
+	}
+	volcEngineCapabilities = Capabilities{
+		NegativePrompt:  true,
+		Size:            true,
+		ReferenceImages: true,
+		MaxPromptLength: 1000,
+		// 豆包模型要求总像素不低于约 3.6MP，最低成本即官方下限尺寸
+		PreviewSize: "1920x1920",
+	}
+	geminiCapabilities = Capabilities{
+		NegativePrompt:  true,
+		Size:            true,
+		ReferenceImages: true,
+		PreviewSize:     "1024x1024",
+	}
+)
+
+// providerCapabilities 服务商能力矩阵，key 与 AI 配置中的 provider 一致
+var providerCapabilities = map[string]Capabilities{
+	"openai":     openAICapabilities,
+	"dalle":      dalleCapabilities,
+	"chatfire":   openAICapabilities,
+	"volcengine": volcEngineCapabilities,
+	"volces":     volcEngineCapabilities,
+	"doubao":     volcEngineCapabilities,
+	"gemini":     geminiCapabilities,
+	"google":     geminiCapabilities,
+}
+
+// GetCapabilities 获取服务商支持的参数，未知服务商按 OpenAI 兼容格式处理
+func GetCapabilities(provider string) Capabilities {
+	if caps, ok := providerCapabilities[provider]; ok {
+		return caps
+	}
+	return openAICapabilities
+}
+
+// LookupCapabilities 查找服务商的内置能力，没有该服务商时返回 false
+func LookupCapabilities(provider string) (Capabilities, bool) {
+	caps, ok := providerCapabilities[provider]
+	return caps, ok
+}
+
+// GetCapabilitiesForClient 根据客户端实现获取其支持的参数
+func GetCapabilitiesForClient(client ImageClient) Capabilities {
+	return GetCapabilities(ProviderNameForClient(client))
+}
+
+// ProviderNameForClient 返回客户端实现对应的标准服务商名称
+func ProviderNameForClient(client ImageClient) string {
+	switch client.(type) {
+	case *VolcEngineImageClient:
+		return "volcengine"
+	case *GeminiImageClient:
+		return "gemini"
+	default:
+		return "openai"
+	}
+}
+
+// AllCapabilities 返回完整的能力矩阵
+func AllCapabilities() map[string]Capabilities {
+	result := make(map[string]Capabilities, len(providerCapabilities))
+	for provider, caps := range providerCapabilities {
+		result[provider] = caps
+	}
+	return result
+}
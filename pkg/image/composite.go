@@ -0,0 +1,171 @@
+package image
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 多格拼图布局
+const (
+	LayoutHorizontal = "horizontal_n" // 所有帧横向排成一行
+	LayoutGrid2x2    = "grid_2x2"     // 2x2 四宫格
+)
+
+// CompositeOptions 拼图参数
+type CompositeOptions struct {
+	Gutter       int         // 格间距和外边框宽度（像素），默认 12
+	BorderColor  color.Color // 间距颜色，默认白色
+	PanelNumbers bool        // 在每格左上角绘制序号
+}
+
+type CompositeOption func(*CompositeOptions)
+
+func WithGutter(px int) CompositeOption {
+	return func(o *CompositeOptions) {
+		o.Gutter = px
+	}
+}
+
+func WithBorderColor(c color.Color) CompositeOption {
+	return func(o *CompositeOptions) {
+		o.BorderColor = c
+	}
+}
+
+func WithPanelNumbers(enabled bool) CompositeOption {
+	return func(o *CompositeOptions) {
+		o.PanelNumbers = enabled
+	}
+}
+
+// ParseHexColor 解析 #RRGGBB 格式的颜色
+func ParseHexColor(s string) (color.Color, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) != 6 {
+		return nil, fmt.Errorf("invalid color: %s", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid color: %s", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}, nil
+}
+
+// LayoutCapacity 返回布局最多容纳的帧数，0 表示不限制
+func LayoutCapacity(layout string) int {
+	if layout == LayoutGrid2x2 {
+		return 4
+	}
+	return 0
+}
+
+// CompositePanels 按布局将多张本地帧图片拼接为一张图，每格统一缩放到第一帧的尺寸
+func CompositePanels(frames []string, layout string, opts ...CompositeOption) (image.Image, error) {
+	options := CompositeOptions{Gutter: 12, BorderColor: color.White}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.Gutter < 0 {
+		options.Gutter = 0
+	}
+
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no frames to composite")
+	}
+
+	var cols, rows int
+	switch layout {
+	case LayoutHorizontal:
+		cols, rows = len(frames), 1
+	case LayoutGrid2x2:
+		if len(frames) > 4 {
+			return nil, fmt.Errorf("grid_2x2 layout accepts at most 4 frames, got %d", len(frames))
+		}
+		cols, rows = 2, 2
+	default:
+		return nil, fmt.Errorf("unsupported layout: %s", layout)
+	}
+
+	panels := make([]image.Image, len(frames))
+	for i, path := range frames {
+		img, err := decodeImageFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", i+1, err)
+		}
+		panels[i] = img
+	}
+
+	cellW := panels[0].Bounds().Dx()
+	cellH := panels[0].Bounds().Dy()
+	gutter := options.Gutter
+	canvas := image.NewRGBA(image.Rect(0, 0, cols*cellW+(cols+1)*gutter, rows*cellH+(rows+1)*gutter))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(options.BorderColor), image.Point{}, draw.Src)
+
+	for i, panel := range panels {
+		if panel.Bounds().Dx() != cellW || panel.Bounds().Dy() != cellH {
+			panel = scaleNearest(panel, cellW, cellH)
+		}
+		col, row := i%cols, i/cols
+		x := gutter + col*(cellW+gutter)
+		y := gutter + row*(cellH+gutter)
+		rect := image.Rect(x, y, x+cellW, y+cellH)
+		draw.Draw(canvas, rect, panel, panel.Bounds().Min, draw.Src)
+
+		if options.PanelNumbers {
+			drawPanelNumber(canvas, rect, i+1)
+		}
+	}
+
+	return canvas, nil
+}
+
+// decodeImageFile 读取并解码本地图片文件
+func decodeImageFile(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image: %w", err)
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return img, nil
+}
+
+// drawPanelNumber 在格子左上角绘制白底黑字的序号，字号随格子宽度缩放
+func drawPanelNumber(canvas *image.RGBA, panel image.Rectangle, n int) {
+	runes := []rune(strconv.Itoa(n))
+	scale := panel.Dx() / 80
+	if scale < 2 {
+		scale = 2
+	}
+
+	padding := scale * 2
+	textW := len(runes)*(glyphWidth+1)*scale - scale
+	textH := glyphHeight * scale
+	origin := panel.Min.Add(image.Pt(padding, padding))
+	badge := image.Rect(origin.X, origin.Y, origin.X+textW+padding*2, origin.Y+textH+padding*2).Intersect(panel)
+	draw.Draw(canvas, badge, image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	for i, r := range runes {
+		glyph := watermarkFont[r]
+		offsetX := origin.X + padding + i*(glyphWidth+1)*scale
+		offsetY := origin.Y + padding
+		for row := 0; row < glyphHeight; row++ {
+			for col := 0; col < glyphWidth; col++ {
+				if glyph[row]&(1<<(glyphWidth-1-col)) == 0 {
+					continue
+				}
+				dot := image.Rect(offsetX+col*scale, offsetY+row*scale, offsetX+(col+1)*scale, offsetY+(row+1)*scale)
+				draw.Draw(canvas, dot.Intersect(panel), image.NewUniform(color.Black), image.Point{}, draw.Src)
+			}
+		}
+	}
+}
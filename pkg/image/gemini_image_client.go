@@ -9,14 +9,18 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/drama-generator/backend/pkg/ai"
 )
 
 type GeminiImageClient struct {
-	BaseURL    string
-	APIKey     string
-	Model      string
-	Endpoint   string
-	HTTPClient *http.Client
+	BaseURL        string
+	APIKey         string
+	Model          string
+	Endpoint       string
+	HTTPClient     *http.Client
+	ExtraHeaders   map[string]string        // 附加请求头，如 api-version
+	SafetySettings []ai.GeminiSafetySetting // 安全过滤阈值，为空时使用服务端默认值
 }
 
 type GeminiImageRequest struct {
@@ -26,6 +30,7 @@ type GeminiImageRequest struct {
 	GenerationConfig struct {
 		ResponseModalities []string `json:"responseModalities"`
 	} `json:"generationConfig"`
+	SafetySettings []ai.GeminiSafetySetting `json:"safetySettings,omitempty"`
 }
 
 type GeminiPart struct {
@@ -49,7 +54,11 @@ type GeminiImageResponse struct {
 				Text string `json:"text,omitempty"`
 			} `json:"parts"`
 		} `json:"content"`
+		FinishReason string `json:"finishReason"`
 	} `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
 	UsageMetadata struct {
 		PromptTokenCount     int `json:"promptTokenCount"`
 		CandidatesTokenCount int `json:"candidatesTokenCount"`
@@ -57,27 +66,11 @@ type GeminiImageResponse struct {
 	} `json:"usageMetadata"`
 }
 
-// downloadImageToBase64 下载图片 URL 并转换为 base64
-func downloadImageToBase64(imageURL string) (string, string, error) {
-	resp, err := http.Get(imageURL)
+// downloadImageToBase64 按参考图限制下载图片 URL 并转换为 base64
+func downloadImageToBase64(imageURL string, limits ReferenceLimits) (string, string, error) {
+	imageData, mimeType, err := FetchReference(imageURL, limits)
 	if err != nil {
-		return "", "", fmt.Errorf("download image: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", "", fmt.Errorf("download image failed with status: %d", resp.StatusCode)
-	}
-
-	imageData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", "", fmt.Errorf("read image data: %w", err)
-	}
-
-	// 根据 Content-Type 确定 mimeType
-	mimeType := resp.Header.Get("Content-Type")
-	if mimeType == "" {
-		mimeType = "image/jpeg"
+		return "", "", err
 	}
 
 	base64Data := base64.StdEncoding.EncodeToString(imageData)
@@ -141,7 +134,7 @@ func (c *GeminiImageClient) GenerateImage(prompt string, opts ...ImageOption) (*
 			// 检查是否是 HTTP/HTTPS URL
 			if strings.HasPrefix(refImg, "http://") || strings.HasPrefix(refImg, "https://") {
 				// 下载图片并转换为 base64
-				base64Data, mimeType, err = downloadImageToBase64(refImg)
+				base64Data, mimeType, err = downloadImageToBase64(refImg, options.ReferenceLimits)
 				if err != nil {
 					continue
 				}
@@ -202,6 +195,7 @@ func (c *GeminiImageClient) GenerateImage(prompt string, opts ...ImageOption) (*
 		}{
 			ResponseModalities: []string{"IMAGE"},
 		},
+		SafetySettings: c.SafetySettings,
 	}
 
 	jsonData, err := json.Marshal(reqBody)
@@ -219,6 +213,9 @@ func (c *GeminiImageClient) GenerateImage(prompt string, opts ...ImageOption) (*
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.ExtraHeaders {
+		req.Header.Set(k, v)
+	}
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
@@ -236,31 +233,48 @@ func (c *GeminiImageClient) GenerateImage(prompt string, opts ...ImageOption) (*
 		if len(bodyStr) > 1000 {
 			bodyStr = fmt.Sprintf("%s ... %s", bodyStr[:500], bodyStr[len(bodyStr)-500:])
 		}
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, bodyStr)
+		return nil, withRawResponse(fmt.Errorf("API error (status %d): %s", resp.StatusCode, bodyStr), body)
 	}
 
 	var result GeminiImageResponse
 	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("parse response: %w", err)
+		return nil, withRawResponse(fmt.Errorf("parse response: %w", err), body)
+	}
+
+	if reason := result.PromptFeedback.BlockReason; reason != "" {
+		return nil, withRawResponse(fmt.Errorf("%w: prompt blocked (%s)", ErrContentPolicy, reason), body)
+	}
+	if len(result.Candidates) > 0 && ai.IsGeminiSafetyFinishReason(result.Candidates[0].FinishReason) {
+		return nil, withRawResponse(fmt.Errorf("%w: image blocked (%s)", ErrContentPolicy, result.Candidates[0].FinishReason), body)
 	}
 
 	if len(result.Candidates) == 0 || len(result.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("no image generated in response")
+		return nil, withRawResponse(fmt.Errorf("no image generated in response"), body)
 	}
 
 	base64Data := result.Candidates[0].Content.Parts[0].InlineData.Data
 	if base64Data == "" {
-		return nil, fmt.Errorf("no base64 image data in response")
+		return nil, withRawResponse(fmt.Errorf("no base64 image data in response"), body)
 	}
 
 	dataURI := fmt.Sprintf("data:image/jpeg;base64,%s", base64Data)
 
+	// candidateCount>1 时每个候选各带一张图片
+	dataURIs := []string{dataURI}
+	for _, candidate := range result.Candidates[1:] {
+		if len(candidate.Content.Parts) > 0 && candidate.Content.Parts[0].InlineData.Data != "" {
+			dataURIs = append(dataURIs, fmt.Sprintf("data:image/jpeg;base64,%s", candidate.Content.Parts[0].InlineData.Data))
+		}
+	}
+
 	return &ImageResult{
-		Status:    "completed",
-		ImageURL:  dataURI,
-		Completed: true,
-		Width:     1024,
-		Height:    1024,
+		Status:      "completed",
+		ImageURL:    dataURI,
+		ImageURLs:   dataURIs,
+		Completed:   true,
+		Width:       1024,
+		Height:      1024,
+		RawResponse: string(body),
 	}, nil
 }
 
@@ -275,3 +289,13 @@ func replaceModelPlaceholder(endpoint, model string) string {
 	}
 	return result
 }
+
+// SetExtraHeaders 设置每个请求都会附带的自定义请求头
+func (c *GeminiImageClient) SetExtraHeaders(headers map[string]string) {
+	c.ExtraHeaders = headers
+}
+
+// SetSafetySettings 设置安全过滤阈值（危害类别 -> 阈值），按类别排序保证请求体稳定
+func (c *GeminiImageClient) SetSafetySettings(settings map[string]string) {
+	c.SafetySettings = ai.GeminiSafetySettingsFromMap(settings)
+}
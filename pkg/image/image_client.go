@@ -1,5 +1,10 @@
 package image
 
+import "errors"
+
+// ErrContentPolicy 提示词或生成结果被服务商安全策略拦截
+var ErrContentPolicy = errors.New("content blocked by safety policy")
+
 type ImageClient interface {
 	GenerateImage(prompt string, opts ...ImageOption) (*ImageResult, error)
 	GetTaskStatus(taskID string) (*ImageResult, error)
@@ -9,10 +14,13 @@ type ImageResult struct {
 	TaskID    string
 	Status    string
 	ImageURL  string
+	ImageURLs []string // 服务商一次返回多张图片时的全部地址，第一张与 ImageURL 相同
 	Width     int
 	Height    int
 	Error     string
 	Completed bool
+	// RawResponse 服务商原始响应体，用于排查问题
+	RawResponse string
 }
 
 type ImageOptions struct {
@@ -27,6 +35,7 @@ type ImageOptions struct {
 	Width           int
 	Height          int
 	ReferenceImages []string // 参考图片URL列表
+	ReferenceLimits ReferenceLimits
 }
 
 type ImageOption func(*ImageOptions)
@@ -10,11 +10,12 @@ import (
 )
 
 type OpenAIImageClient struct {
-	BaseURL    string
-	APIKey     string
-	Model      string
-	Endpoint   string
-	HTTPClient *http.Client
+	BaseURL      string
+	APIKey       string
+	Model        string
+	Endpoint     string
+	HTTPClient   *http.Client
+	ExtraHeaders map[string]string // 附加请求头，如 api-version
 }
 
 type DALLERequest struct {
@@ -89,6 +90,9 @@ func (c *OpenAIImageClient) GenerateImage(prompt string, opts ...ImageOption) (*
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	for k, v := range c.ExtraHeaders {
+		req.Header.Set(k, v)
+	}
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
@@ -102,27 +106,44 @@ func (c *OpenAIImageClient) GenerateImage(prompt string, opts ...ImageOption) (*
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, withRawResponse(fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body)), body)
 	}
 
 	fmt.Printf("OpenAI API Response: %s\n", string(body))
 
 	var result DALLEResponse
 	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("parse response: %w, body: %s", err, string(body))
+		return nil, withRawResponse(fmt.Errorf("parse response: %w, body: %s", err, string(body)), body)
 	}
 
 	if len(result.Data) == 0 {
-		return nil, fmt.Errorf("no image generated, response: %s", string(body))
+		return nil, withRawResponse(fmt.Errorf("no image generated, response: %s", string(body)), body)
+	}
+
+	urls := make([]string, 0, len(result.Data))
+	for _, item := range result.Data {
+		if item.URL != "" {
+			urls = append(urls, item.URL)
+		}
+	}
+	if len(urls) == 0 {
+		return nil, withRawResponse(fmt.Errorf("no image url in response"), body)
 	}
 
 	return &ImageResult{
-		Status:    "completed",
-		ImageURL:  result.Data[0].URL,
-		Completed: true,
+		Status:      "completed",
+		ImageURL:    urls[0],
+		ImageURLs:   urls,
+		Completed:   true,
+		RawResponse: string(body),
 	}, nil
 }
 
 func (c *OpenAIImageClient) GetTaskStatus(taskID string) (*ImageResult, error) {
 	return nil, fmt.Errorf("not supported for OpenAI/DALL-E")
 }
+
+// SetExtraHeaders 设置每个请求都会附带的自定义请求头
+func (c *OpenAIImageClient) SetExtraHeaders(headers map[string]string) {
+	c.ExtraHeaders = headers
+}
@@ -0,0 +1,49 @@
+package image
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// truncateBoundaries 截断时优先选择的断点，按优先级排列
+var truncateBoundaries = [][]string{
+	{"\n", "。", "！", "？", ". ", "! ", "? "},
+	{"；", ";", "，", ", ", ",", "、"},
+	{" "},
+}
+
+// TruncatePrompt 将提示词限制在 maxLen 个字符内：prefix 与 suffix（如风格、比例说明）完整保留，
+// 只裁剪中间的 body，并尽量在句子或逗号处断开。返回结果及是否发生了截断
+func TruncatePrompt(prefix, body, suffix string, maxLen int) (string, bool) {
+	full := prefix + body + suffix
+	if maxLen <= 0 || utf8.RuneCountInString(full) <= maxLen {
+		return full, false
+	}
+
+	budget := maxLen - utf8.RuneCountInString(prefix) - utf8.RuneCountInString(suffix)
+	if budget <= 0 {
+		// 固定部分本身已超长，只能保留尽量多的前缀内容
+		return string([]rune(full)[:maxLen]), true
+	}
+
+	runes := []rune(body)
+	cut := string(runes[:budget])
+
+	// 断点不能太靠前，否则会丢失过多内容
+	minKeep := len(cut) / 2
+	for _, group := range truncateBoundaries {
+		best := -1
+		for _, sep := range group {
+			if idx := strings.LastIndex(cut, sep); idx >= minKeep && idx+len(sep) > best {
+				best = idx + len(sep)
+			}
+		}
+		if best > 0 {
+			cut = cut[:best]
+			break
+		}
+	}
+
+	cut = strings.TrimRight(cut, " ,，、;；\n")
+	return prefix + cut + suffix, true
+}
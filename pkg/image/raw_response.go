@@ -0,0 +1,64 @@
+package image
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// ResponseError 服务商调用失败时携带原始响应体，便于排查问题
+type ResponseError struct {
+	Err         error
+	RawResponse string
+}
+
+func (e *ResponseError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ResponseError) Unwrap() error {
+	return e.Err
+}
+
+// withRawResponse 将原始响应体附加到错误上
+func withRawResponse(err error, body []byte) error {
+	return &ResponseError{Err: err, RawResponse: string(body)}
+}
+
+// RawResponseFromError 从错误链中取出原始响应体，没有时返回空字符串
+func RawResponseFromError(err error) string {
+	var respErr *ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.RawResponse
+	}
+	return ""
+}
+
+var (
+	secretFieldPattern = regexp.MustCompile(`(?i)("[^"]*(?:api[_-]?key|secret|token|authorization|password)[^"]*"\s*:\s*)"[^"]*"`)
+	bearerPattern      = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._\-]+`)
+	secretKeyPattern   = regexp.MustCompile(`\b(sk|ak)-[A-Za-z0-9_\-]{8,}`)
+	base64DataPattern  = regexp.MustCompile(`("(?:data|b64_json|bytesBase64Encoded)"\s*:\s*)"([A-Za-z0-9+/=]{256,})"`)
+)
+
+// RedactRawResponse 脱敏原始响应：遮盖 API Key/Token，并省略大段 base64 图片数据
+func RedactRawResponse(raw string, secrets ...string) string {
+	if raw == "" {
+		return ""
+	}
+
+	for _, secret := range secrets {
+		if len(secret) >= 8 {
+			raw = regexp.MustCompile(regexp.QuoteMeta(secret)).ReplaceAllString(raw, "***")
+		}
+	}
+	raw = secretFieldPattern.ReplaceAllString(raw, `$1"***"`)
+	raw = bearerPattern.ReplaceAllString(raw, "${1}***")
+	raw = secretKeyPattern.ReplaceAllString(raw, "$1-***")
+	raw = base64DataPattern.ReplaceAllStringFunc(raw, func(match string) string {
+		parts := base64DataPattern.FindStringSubmatch(match)
+		return fmt.Sprintf(`%s"<base64 %d bytes omitted>"`, parts[1], len(parts[2]))
+	})
+
+	return raw
+}
@@ -0,0 +1,121 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"math"
+	"net/http"
+	"time"
+)
+
+// 内联参考图的默认限制
+const (
+	DefaultReferenceMaxBytes     = 4 << 20
+	DefaultReferenceFetchTimeout = 30 * time.Second
+
+	// referenceDownloadFactor 下载参考图时最多读取 MaxBytes 的倍数，超出的直接拒绝，避免超大文件占满内存
+	referenceDownloadFactor = 8
+	// referenceDownscaleAttempts 缩小参考图的最多尝试次数
+	referenceDownscaleAttempts = 4
+)
+
+// ReferenceLimits 将参考图转为 base64 内联到请求时的大小和下载超时限制
+type ReferenceLimits struct {
+	MaxBytes int64         // 单张参考图最大字节数，超出时缩小后再内联，0 使用默认值，负数不限制
+	Timeout  time.Duration // 下载参考图的超时，0 使用默认值
+	// OnDownscale 参考图被缩小时回调，用于记录日志
+	OnDownscale func(ref string, originalBytes, newBytes int)
+}
+
+func (l ReferenceLimits) maxBytes() int64 {
+	if l.MaxBytes == 0 {
+		return DefaultReferenceMaxBytes
+	}
+	return l.MaxBytes
+}
+
+func (l ReferenceLimits) timeout() time.Duration {
+	if l.Timeout <= 0 {
+		return DefaultReferenceFetchTimeout
+	}
+	return l.Timeout
+}
+
+// WithReferenceLimits 设置内联参考图的大小和下载超时限制
+func WithReferenceLimits(limits ReferenceLimits) ImageOption {
+	return func(o *ImageOptions) {
+		o.ReferenceLimits = limits
+	}
+}
+
+// FetchReference 在超时内下载参考图，超过 MaxBytes 时缩小，无法缩小到限制内时返回错误
+func FetchReference(url string, limits ReferenceLimits) ([]byte, string, error) {
+	client := &http.Client{Timeout: limits.timeout()}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, "", fmt.Errorf("download image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("download image failed with status: %d", resp.StatusCode)
+	}
+
+	reader := io.Reader(resp.Body)
+	maxBytes := limits.maxBytes()
+	if maxBytes > 0 {
+		readLimit := maxBytes * referenceDownloadFactor
+		if resp.ContentLength > readLimit {
+			return nil, "", fmt.Errorf("reference image too large: %d bytes", resp.ContentLength)
+		}
+		reader = io.LimitReader(resp.Body, readLimit+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, "", fmt.Errorf("read image data: %w", err)
+	}
+	if maxBytes > 0 && int64(len(data)) > maxBytes*referenceDownloadFactor {
+		return nil, "", fmt.Errorf("reference image too large: more than %d bytes", maxBytes*referenceDownloadFactor)
+	}
+
+	mimeType := resp.Header.Get("Content-Type")
+	if mimeType == "" {
+		mimeType = "image/jpeg"
+	}
+	return FitReference(url, data, mimeType, limits)
+}
+
+// FitReference 参考图超过 MaxBytes 时按比例缩小并转为 JPEG，返回处理后的数据和 MIME 类型
+func FitReference(ref string, data []byte, mimeType string, limits ReferenceLimits) ([]byte, string, error) {
+	maxBytes := limits.maxBytes()
+	if maxBytes < 0 || int64(len(data)) <= maxBytes {
+		return data, mimeType, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("reference image too large (%d bytes) and cannot be decoded: %w", len(data), err)
+	}
+
+	bounds := src.Bounds()
+	scale := math.Sqrt(float64(maxBytes) / float64(len(data)))
+	for attempt := 0; attempt < referenceDownscaleAttempts; attempt++ {
+		w := max(1, int(float64(bounds.Dx())*scale))
+		h := max(1, int(float64(bounds.Dy())*scale))
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, scaleNearest(src, w, h), &jpeg.Options{Quality: DefaultTranscodeQuality}); err != nil {
+			return nil, "", fmt.Errorf("encode downscaled reference: %w", err)
+		}
+		if int64(buf.Len()) <= maxBytes {
+			if limits.OnDownscale != nil {
+				limits.OnDownscale(ref, len(data), buf.Len())
+			}
+			return buf.Bytes(), "image/jpeg", nil
+		}
+		scale *= 0.75
+	}
+	return nil, "", fmt.Errorf("reference image too large (%d bytes) and cannot be reduced below %d bytes", len(data), maxBytes)
+}
@@ -0,0 +1,93 @@
+package image
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"strings"
+)
+
+// 支持的转码目标格式；标准库没有 WebP 编码器，暂不支持输出 WebP
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+)
+
+// DefaultTranscodeQuality JPEG 转码默认质量
+const DefaultTranscodeQuality = 85
+
+// NormalizeTranscodeFormat 规范化目标格式名称，不支持的格式返回空字符串
+func NormalizeTranscodeFormat(format string) string {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "jpeg", "jpg":
+		return FormatJPEG
+	case "png":
+		return FormatPNG
+	}
+	return ""
+}
+
+// TranscodeExt 目标格式对应的文件扩展名
+func TranscodeExt(format string) string {
+	if format == FormatJPEG {
+		return ".jpg"
+	}
+	return "." + format
+}
+
+// DetectFormat 返回图片文件的编码格式（jpeg、png、gif 等）
+func DetectFormat(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open image: %w", err)
+	}
+	defer f.Close()
+
+	_, format, err := image.DecodeConfig(f)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image config: %w", err)
+	}
+	return format, nil
+}
+
+// TranscodeImage 将 srcPath 图片转码为 format 格式写入 dstPath，quality 仅对 JPEG 生效（1-100）
+func TranscodeImage(srcPath, dstPath, format string, quality int) error {
+	if NormalizeTranscodeFormat(format) == "" {
+		return fmt.Errorf("unsupported target format: %s", format)
+	}
+	if quality <= 0 || quality > 100 {
+		quality = DefaultTranscodeQuality
+	}
+
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to open image: %w", err)
+	}
+	img, _, err := image.Decode(src)
+	src.Close()
+	if err != nil {
+		return fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	switch NormalizeTranscodeFormat(format) {
+	case FormatJPEG:
+		err = jpeg.Encode(dst, img, &jpeg.Options{Quality: quality})
+	default:
+		err = png.Encode(dst, img)
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dstPath)
+		return fmt.Errorf("failed to encode image: %w", err)
+	}
+	return nil
+}
@@ -16,6 +16,7 @@ type VolcEngineImageClient struct {
 	Endpoint      string
 	QueryEndpoint string
 	HTTPClient    *http.Client
+	ExtraHeaders  map[string]string // 附加请求头，如 api-version
 }
 
 type VolcEngineImageRequest struct {
@@ -115,6 +116,9 @@ func (c *VolcEngineImageClient) GenerateImage(prompt string, opts ...ImageOption
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	for k, v := range c.ExtraHeaders {
+		req.Header.Set(k, v)
+	}
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
@@ -130,29 +134,46 @@ func (c *VolcEngineImageClient) GenerateImage(prompt string, opts ...ImageOption
 	fmt.Printf("VolcEngine Image API Response: %s\n", string(body))
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, withRawResponse(fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body)), body)
 	}
 
 	var result VolcEngineImageResponse
 	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("parse response: %w", err)
+		return nil, withRawResponse(fmt.Errorf("parse response: %w", err), body)
 	}
 
 	if result.Error != nil {
-		return nil, fmt.Errorf("volcengine error: %v", result.Error)
+		return nil, withRawResponse(fmt.Errorf("volcengine error: %v", result.Error), body)
 	}
 
 	if len(result.Data) == 0 {
-		return nil, fmt.Errorf("no image generated")
+		return nil, withRawResponse(fmt.Errorf("no image generated"), body)
+	}
+
+	urls := make([]string, 0, len(result.Data))
+	for _, item := range result.Data {
+		if item.URL != "" {
+			urls = append(urls, item.URL)
+		}
+	}
+	if len(urls) == 0 {
+		return nil, withRawResponse(fmt.Errorf("no image url in response"), body)
 	}
 
 	return &ImageResult{
-		Status:    "completed",
-		ImageURL:  result.Data[0].URL,
-		Completed: true,
+		Status:      "completed",
+		ImageURL:    urls[0],
+		ImageURLs:   urls,
+		Completed:   true,
+		RawResponse: string(body),
 	}, nil
 }
 
 func (c *VolcEngineImageClient) GetTaskStatus(taskID string) (*ImageResult, error) {
 	return nil, fmt.Errorf("not supported for VolcEngine Seedream (synchronous generation)")
 }
+
+// SetExtraHeaders 设置每个请求都会附带的自定义请求头
+func (c *VolcEngineImageClient) SetExtraHeaders(headers map[string]string) {
+	c.ExtraHeaders = headers
+}
@@ -0,0 +1,240 @@
+package image
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"strings"
+)
+
+// 水印位置
+const (
+	WatermarkBottomRight = "bottom-right"
+	WatermarkBottomLeft  = "bottom-left"
+	WatermarkTopRight    = "top-right"
+	WatermarkTopLeft     = "top-left"
+	WatermarkCenter      = "center"
+)
+
+// WatermarkOptions 水印参数，Text 与 LogoPath 至少设置一个，同时设置时优先使用 Logo
+type WatermarkOptions struct {
+	Text     string  // 文字水印，仅支持 ASCII 字母、数字和常用符号
+	LogoPath string  // Logo PNG 文件路径（支持透明通道）
+	Opacity  float64 // 不透明度 0-1，默认 0.5
+	Position string  // 水印位置，默认 bottom-right
+}
+
+// ApplyWatermark 读取 srcPath 图片叠加水印后按原格式写入 dstPath
+func ApplyWatermark(srcPath, dstPath string, opts WatermarkOptions) error {
+	if opts.Text == "" && opts.LogoPath == "" {
+		return fmt.Errorf("watermark text or logo is required")
+	}
+	if opts.Opacity <= 0 || opts.Opacity > 1 {
+		opts.Opacity = 0.5
+	}
+
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to open image: %w", err)
+	}
+	img, format, err := image.Decode(src)
+	src.Close()
+	if err != nil {
+		return fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	bounds := img.Bounds()
+	canvas := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(canvas, canvas.Bounds(), img, bounds.Min, draw.Src)
+
+	if opts.LogoPath != "" {
+		if err := drawLogo(canvas, opts); err != nil {
+			return err
+		}
+	} else {
+		drawText(canvas, opts)
+	}
+
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer dst.Close()
+
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(dst, canvas, &jpeg.Options{Quality: 95})
+	case "gif":
+		err = gif.Encode(dst, canvas, nil)
+	default:
+		err = png.Encode(dst, canvas)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	return nil
+}
+
+// drawLogo 将 Logo 缩放到图片宽度的 1/5 以内后叠加
+func drawLogo(canvas *image.RGBA, opts WatermarkOptions) error {
+	f, err := os.Open(opts.LogoPath)
+	if err != nil {
+		return fmt.Errorf("failed to open watermark logo: %w", err)
+	}
+	defer f.Close()
+
+	logo, err := png.Decode(f)
+	if err != nil {
+		return fmt.Errorf("failed to decode watermark logo: %w", err)
+	}
+
+	maxWidth := canvas.Bounds().Dx() / 5
+	if maxWidth > 0 && logo.Bounds().Dx() > maxWidth {
+		logo = scaleNearest(logo, maxWidth, logo.Bounds().Dy()*maxWidth/logo.Bounds().Dx())
+	}
+
+	rect := watermarkRect(canvas.Bounds(), logo.Bounds().Dx(), logo.Bounds().Dy(), opts.Position)
+	mask := image.NewUniform(color.Alpha{A: uint8(255 * opts.Opacity)})
+	draw.DrawMask(canvas, rect, logo, logo.Bounds().Min, mask, image.Point{}, draw.Over)
+	return nil
+}
+
+// drawText 使用内置点阵字体绘制文字水印，字号随图片宽度缩放
+func drawText(canvas *image.RGBA, opts WatermarkOptions) {
+	text := strings.ToUpper(opts.Text)
+	runes := []rune(text)
+	if len(runes) == 0 {
+		return
+	}
+
+	// 文字总宽度约为图片宽度的 1/4
+	scale := canvas.Bounds().Dx() / 4 / (len(runes) * (glyphWidth + 1))
+	if scale < 1 {
+		scale = 1
+	}
+
+	textWidth := len(runes)*(glyphWidth+1)*scale - scale
+	textHeight := glyphHeight * scale
+	mask := image.NewAlpha(image.Rect(0, 0, textWidth, textHeight))
+	alpha := color.Alpha{A: uint8(255 * opts.Opacity)}
+
+	for i, r := range runes {
+		glyph, ok := watermarkFont[r]
+		if !ok {
+			continue
+		}
+		offsetX := i * (glyphWidth + 1) * scale
+		for row := 0; row < glyphHeight; row++ {
+			for col := 0; col < glyphWidth; col++ {
+				if glyph[row]&(1<<(glyphWidth-1-col)) == 0 {
+					continue
+				}
+				for dy := 0; dy < scale; dy++ {
+					for dx := 0; dx < scale; dx++ {
+						mask.SetAlpha(offsetX+col*scale+dx, row*scale+dy, alpha)
+					}
+				}
+			}
+		}
+	}
+
+	rect := watermarkRect(canvas.Bounds(), textWidth, textHeight, opts.Position)
+	// 先绘制偏移的深色阴影，保证浅色背景上也能看清
+	shadow := rect.Add(image.Pt(scale, scale)).Intersect(canvas.Bounds())
+	draw.DrawMask(canvas, shadow, image.NewUniform(color.Black), image.Point{}, mask, image.Point{}, draw.Over)
+	draw.DrawMask(canvas, rect, image.NewUniform(color.White), image.Point{}, mask, image.Point{}, draw.Over)
+}
+
+// watermarkRect 计算水印在画布上的位置，边距为图片宽度的 1/40
+func watermarkRect(bounds image.Rectangle, w, h int, position string) image.Rectangle {
+	margin := bounds.Dx() / 40
+	var x, y int
+	switch position {
+	case WatermarkTopLeft:
+		x, y = margin, margin
+	case WatermarkTopRight:
+		x, y = bounds.Dx()-w-margin, margin
+	case WatermarkBottomLeft:
+		x, y = margin, bounds.Dy()-h-margin
+	case WatermarkCenter:
+		x, y = (bounds.Dx()-w)/2, (bounds.Dy()-h)/2
+	default:
+		x, y = bounds.Dx()-w-margin, bounds.Dy()-h-margin
+	}
+	return image.Rect(x, y, x+w, y+h)
+}
+
+// scaleNearest 最近邻缩放
+func scaleNearest(src image.Image, w, h int) image.Image {
+	if w <= 0 || h <= 0 {
+		return src
+	}
+	sb := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dst.Set(x, y, src.At(sb.Min.X+x*sb.Dx()/w, sb.Min.Y+y*sb.Dy()/h))
+		}
+	}
+	return dst
+}
+
+const (
+	glyphWidth  = 5
+	glyphHeight = 7
+)
+
+// watermarkFont 5x7 点阵字体，每行低 5 位表示像素
+var watermarkFont = map[rune][glyphHeight]byte{
+	' ': {0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	'0': {0x0E, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0E},
+	'1': {0x04, 0x0C, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'2': {0x0E, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1F},
+	'3': {0x1F, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0E},
+	'4': {0x02, 0x06, 0x0A, 0x12, 0x1F, 0x02, 0x02},
+	'5': {0x1F, 0x10, 0x1E, 0x01, 0x01, 0x11, 0x0E},
+	'6': {0x06, 0x08, 0x10, 0x1E, 0x11, 0x11, 0x0E},
+	'7': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8': {0x0E, 0x11, 0x11, 0x0E, 0x11, 0x11, 0x0E},
+	'9': {0x0E, 0x11, 0x11, 0x0F, 0x01, 0x02, 0x0C},
+	'A': {0x0E, 0x11, 0x11, 0x11, 0x1F, 0x11, 0x11},
+	'B': {0x1E, 0x11, 0x11, 0x1E, 0x11, 0x11, 0x1E},
+	'C': {0x0E, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0E},
+	'D': {0x1C, 0x12, 0x11, 0x11, 0x11, 0x12, 0x1C},
+	'E': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x1F},
+	'F': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x10},
+	'G': {0x0E, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0F},
+	'H': {0x11, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
+	'I': {0x0E, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'J': {0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0C},
+	'K': {0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11},
+	'L': {0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1F},
+	'M': {0x11, 0x1B, 0x15, 0x15, 0x11, 0x11, 0x11},
+	'N': {0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11},
+	'O': {0x0E, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'P': {0x1E, 0x11, 0x11, 0x1E, 0x10, 0x10, 0x10},
+	'Q': {0x0E, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0D},
+	'R': {0x1E, 0x11, 0x11, 0x1E, 0x14, 0x12, 0x11},
+	'S': {0x0F, 0x10, 0x10, 0x0E, 0x01, 0x01, 0x1E},
+	'T': {0x1F, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
+	'U': {0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'V': {0x11, 0x11, 0x11, 0x11, 0x11, 0x0A, 0x04},
+	'W': {0x11, 0x11, 0x11, 0x15, 0x15, 0x15, 0x0A},
+	'X': {0x11, 0x11, 0x0A, 0x04, 0x0A, 0x11, 0x11},
+	'Y': {0x11, 0x11, 0x11, 0x0A, 0x04, 0x04, 0x04},
+	'Z': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1F},
+	'-': {0x00, 0x00, 0x00, 0x1F, 0x00, 0x00, 0x00},
+	'_': {0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x1F},
+	'.': {0x00, 0x00, 0x00, 0x00, 0x00, 0x0C, 0x0C},
+	':': {0x00, 0x0C, 0x0C, 0x00, 0x0C, 0x0C, 0x00},
+	'!': {0x04, 0x04, 0x04, 0x04, 0x04, 0x00, 0x04},
+	'?': {0x0E, 0x11, 0x01, 0x02, 0x04, 0x00, 0x04},
+	'/': {0x00, 0x01, 0x02, 0x04, 0x08, 0x10, 0x00},
+	'@': {0x0E, 0x11, 0x01, 0x0D, 0x15, 0x15, 0x0E},
+}
@@ -114,6 +114,10 @@ func NotFound(c *gin.Context, message string) {
 	Error(c, http.StatusNotFound, "NOT_FOUND", message)
 }
 
+func Conflict(c *gin.Context, message string) {
+	Error(c, http.StatusConflict, "CONFLICT", message)
+}
+
 func InternalError(c *gin.Context, message string) {
 	Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", message)
 }
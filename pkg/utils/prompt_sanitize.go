@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// internalMarkerPattern 提示词拼装内部使用的 "=Key:" 标记（如 =VideoRatio:）
+	internalMarkerPattern = regexp.MustCompile(`=\s*([A-Za-z][A-Za-z0-9_]*)\s*:`)
+	horizontalSpaces      = regexp.MustCompile(`[ \t\p{Zs}]+`)
+	extraBlankLines       = regexp.MustCompile(`\n{3,}`)
+)
+
+// SanitizePrompt 清理用户输入的提示词文本：去除控制字符和双向文本控制符，
+// 规范空白（行内空白合并为一个空格、最多保留一个空行），并转义内部标记，避免破坏提示词拼装
+func SanitizePrompt(s string) string {
+	if s == "" {
+		return s
+	}
+
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n':
+			return r
+		case r == '\t' || r == '\r':
+			return ' '
+		case unicode.IsControl(r), isBidiControl(r):
+			return -1
+		}
+		return r
+	}, s)
+
+	s = internalMarkerPattern.ReplaceAllString(s, "$1:")
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(horizontalSpaces.ReplaceAllString(line, " "))
+	}
+	s = strings.Join(lines, "\n")
+	s = extraBlankLines.ReplaceAllString(s, "\n\n")
+
+	return strings.TrimSpace(s)
+}
+
+// isBidiControl 双向文本嵌入/覆盖/隔离控制符及方向标记（LRM、RLM、ALM），可被用来隐藏提示词内容
+// 零宽连接符（U+200D）等用于组合 emoji 和文字的字符保留不动
+func isBidiControl(r rune) bool {
+	return (r >= 0x202A && r <= 0x202E) || (r >= 0x2066 && r <= 0x2069) ||
+		r == 0x200E || r == 0x200F || r == 0x061C
+}
@@ -0,0 +1,60 @@
+package utils
+
+import "testing"
+
+func TestSanitizePrompt(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "plain text unchanged",
+			input: "A girl standing in the rain",
+			want:  "A girl standing in the rain",
+		},
+		{
+			name:  "control characters stripped",
+			input: "hello\x00\x07 world\x1b",
+			want:  "hello world",
+		},
+		{
+			name:  "whitespace normalized",
+			input: "  a \t\t b  \r\n\n\n\n  c  ",
+			want:  "a b\n\nc",
+		},
+		{
+			name:  "internal marker escaped",
+			input: "sunset =VideoRatio: 9:16",
+			want:  "sunset VideoRatio: 9:16",
+		},
+		{
+			name:  "marker with spaces escaped",
+			input: "= VideoRatio : 1:1",
+			want:  "VideoRatio: 1:1",
+		},
+		{
+			name:  "bidi controls removed",
+			input: "safe\u202ehidden\u200ftext\u061c",
+			want:  "safehiddentext",
+		},
+		{
+			name:  "emoji zero width joiner kept",
+			input: "family \U0001F468\u200d\U0001F469\u200d\U0001F467",
+			want:  "family \U0001F468\u200d\U0001F469\u200d\U0001F467",
+		},
+		{
+			name:  "chinese text kept",
+			input: "少女\u3000站在雨中",
+			want:  "少女 站在雨中",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizePrompt(tt.input); got != tt.want {
+				t.Errorf("SanitizePrompt(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -59,8 +59,12 @@ export const dramaAPI = {
     return request.put(`/characters/${id}`, data)
   },
 
-  saveEpisodes(id: string, data: any[]) {
-    return request.put(`/dramas/${id}/episodes`, { episodes: data })
+  saveEpisodes(id: string, data: any[], autoRenumber = false) {
+    return request.put(`/dramas/${id}/episodes`, { episodes: data, auto_renumber: autoRenumber })
+  },
+
+  renumberEpisodes(id: string) {
+    return request.post(`/dramas/${id}/episodes/renumber`)
   },
 
   saveProgress(id: string, data: { current_step: string; step_data?: any }) {
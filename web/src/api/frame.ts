@@ -91,9 +91,23 @@ export interface FramePromptRecord {
   updated_at: string
 }
 
+// 帧提示词分页列表
+export interface FramePromptPage {
+  items: FramePromptRecord[]
+  pagination: {
+    page: number
+    page_size: number
+    total: number
+    total_pages: number
+  }
+}
+
 /**
- * 查询镜头的所有已生成帧提示词
+ * 分页查询镜头已生成的帧提示词，可按帧类型过滤
  */
-export function getStoryboardFramePrompts(storyboardId: number): Promise<{ frame_prompts: FramePromptRecord[] }> {
-  return request.get<{ frame_prompts: FramePromptRecord[] }>(`/storyboards/${storyboardId}/frame-prompts`)
+export function getStoryboardFramePrompts(
+  storyboardId: number,
+  params?: { frame_type?: FrameType; page?: number; page_size?: number }
+): Promise<FramePromptPage> {
+  return request.get<FramePromptPage>(`/storyboards/${storyboardId}/frame-prompts`, { params })
 }
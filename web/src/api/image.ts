@@ -1,4 +1,5 @@
 import type {
+  BatchImageResult,
   GenerateImageRequest,
   ImageGeneration,
   ImageGenerationListParams
@@ -15,7 +16,7 @@ export const imageAPI = {
   },
 
   batchGenerateForEpisode(episodeId: number) {
-    return request.post<ImageGeneration[]>(`/images/episode/${episodeId}/batch`)
+    return request.post<BatchImageResult>(`/images/episode/${episodeId}/batch`)
   },
 
   getImage(id: number) {
@@ -8,6 +8,7 @@ export interface AsyncTask {
     message: string
     result?: any
     error?: string
+    error_code?: string  // 失败原因代码，content_policy 表示被服务商安全策略拦截
     created_at: string
 }
 
@@ -95,6 +95,8 @@ export default {
       providerTip: 'Select AI service provider',
       priority: 'Priority',
       priorityTip: 'Higher values have higher priority. For the same model, higher priority configurations are used first',
+      weight: 'Weight',
+      weightTip: 'Share of requests in weighted selection mode (ai.model_selection: weighted); 0 excludes this configuration',
       model: 'Model',
       modelPlaceholder: 'Enter or select model name',
       modelTip: 'Enter model name directly or select from list, supports multiple models',
@@ -105,7 +107,23 @@ export default {
       apiKey: 'API Key',
       apiKeyPlaceholder: 'sk-...',
       apiKeyTip: 'Your API key',
-      isActive: 'Active Status'
+      isActive: 'Active Status',
+      safetySettings: 'Safety Settings',
+      safetySettingsTip: 'Gemini block threshold per harm category; categories left unset use the server default',
+      safetyDefault: 'Server default',
+      safetyCategories: {
+        HARM_CATEGORY_HARASSMENT: 'Harassment',
+        HARM_CATEGORY_HATE_SPEECH: 'Hate speech',
+        HARM_CATEGORY_SEXUALLY_EXPLICIT: 'Sexually explicit',
+        HARM_CATEGORY_DANGEROUS_CONTENT: 'Dangerous content'
+      },
+      safetyThresholds: {
+        BLOCK_LOW_AND_ABOVE: 'Block low and above',
+        BLOCK_MEDIUM_AND_ABOVE: 'Block medium and above',
+        BLOCK_ONLY_HIGH: 'Block only high',
+        BLOCK_NONE: 'Block none',
+        OFF: 'Off'
+      }
     },
     actions: {
       test: 'Test Connection',
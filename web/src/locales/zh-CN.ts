@@ -113,6 +113,8 @@ export default {
       providerTip: '选择AI服务提供商',
       priority: '优先级',
       priorityTip: '数值越大优先级越高，相同模型时优先使用高优先级配置',
+      weight: '权重',
+      weightTip: '加权选择模式（ai.model_selection: weighted）下按权重分配请求，0 表示不参与分配',
       model: '模型',
       modelPlaceholder: '输入或选择模型名称',
       modelTip: '可直接输入模型名称或从列表选择，支持多个模型',
@@ -123,7 +125,23 @@ export default {
       apiKey: 'API Key',
       apiKeyPlaceholder: 'sk-...',
       apiKeyTip: '您的 API 密钥',
-      isActive: '启用状态'
+      isActive: '启用状态',
+      safetySettings: '安全设置',
+      safetySettingsTip: 'Gemini 各危害类别的拦截阈值，未设置的类别使用服务端默认阈值',
+      safetyDefault: '服务端默认',
+      safetyCategories: {
+        HARM_CATEGORY_HARASSMENT: '骚扰',
+        HARM_CATEGORY_HATE_SPEECH: '仇恨言论',
+        HARM_CATEGORY_SEXUALLY_EXPLICIT: '色情内容',
+        HARM_CATEGORY_DANGEROUS_CONTENT: '危险内容'
+      },
+      safetyThresholds: {
+        BLOCK_LOW_AND_ABOVE: '拦截低及以上风险',
+        BLOCK_MEDIUM_AND_ABOVE: '拦截中及以上风险',
+        BLOCK_ONLY_HIGH: '仅拦截高风险',
+        BLOCK_NONE: '不拦截',
+        OFF: '关闭过滤'
+      }
     },
     actions: {
       test: '测试连接',
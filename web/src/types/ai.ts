@@ -9,8 +9,10 @@ export interface AIServiceConfig {
   endpoint: string
   query_endpoint?: string  // 异步查询端点（用于视频等异步任务）
   priority: number  // 优先级，数值越大优先级越高
+  weight: number  // 加权选择模式下的权重，0 表示不参与分配
   is_active: boolean
   settings?: string
+  safety_settings?: Record<string, string>  // Gemini 安全过滤阈值（危害类别 -> 阈值）
   created_at: string
   updated_at: string
 }
@@ -27,7 +29,9 @@ export interface CreateAIConfigRequest {
   endpoint?: string
   query_endpoint?: string  // 异步查询端点（用于视频等异步任务）
   priority?: number  // 优先级，数值越大优先级越高
+  weight?: number  // 加权选择模式下的权重，0 表示不参与分配，创建时默认 1
   settings?: string
+  safety_settings?: Record<string, string>  // Gemini 安全过滤阈值（危害类别 -> 阈值）
 }
 
 export interface UpdateAIConfigRequest {
@@ -39,8 +43,10 @@ export interface UpdateAIConfigRequest {
   endpoint?: string
   query_endpoint?: string  // 异步查询端点（用于视频等异步任务）
   priority?: number  // 优先级，数值越大优先级越高
+  weight?: number  // 加权选择模式下的权重，0 表示不参与分配，创建时默认 1
   is_active?: boolean
   settings?: string
+  safety_settings?: Record<string, string>  // 传空对象恢复服务端默认阈值
 }
 
 export interface TestConnectionRequest {
@@ -15,6 +15,8 @@ export interface Drama {
   thumbnail?: string
   tags?: any
   metadata?: any
+  text_config_id?: number | null  // 绑定的文本AI配置，为空时使用全局默认配置
+  image_config_id?: number | null  // 绑定的图片AI配置，为空时使用全局默认配置
   created_at: string
   updated_at: string
   characters?: Character[]
@@ -58,6 +60,8 @@ export interface Episode {
   status: string
   video_url?: string
   thumbnail?: string
+  tags?: string[]
+  pipeline_stage?: string
   storyboard_count?: number
   scene_count?: number
   composition_count?: number
@@ -131,6 +135,8 @@ export interface UpdateDramaRequest {
   style?: string
   tags?: string
   status?: DramaStatus
+  text_config_id?: number  // 传 0 解除绑定
+  image_config_id?: number  // 传 0 解除绑定
 }
 
 export interface DramaListQuery {
@@ -19,6 +19,7 @@ export interface ImageGeneration {
   image_url?: string
   image_generation?: any
   local_path?: string
+  master_path?: string
   status: ImageStatus
   task_id?: string
   error_msg?: string
@@ -27,6 +28,14 @@ export interface ImageGeneration {
   created_at: string
   updated_at: string
   completed_at?: string
+  batch_id?: string
+  content_tags?: ImageContentTags
+}
+
+export interface ImageContentTags {
+  objects: string[]
+  mood: string[]
+  colors: string[]
 }
 
 export type ImageStatus = 'pending' | 'processing' | 'completed' | 'failed'
@@ -50,6 +59,7 @@ export interface GenerateImageRequest {
   steps?: number
   cfg_scale?: number
   seed?: number
+  seed_range?: [number, number]
   width?: number
   height?: number
 }
@@ -63,3 +73,21 @@ export interface ImageGenerationListParams {
   page?: number
   page_size?: number
 }
+
+export interface BatchImageItem {
+  storyboard_id: number
+  storyboard_number: number
+  status: 'enqueued' | 'skipped_no_prompt' | 'failed'
+  reason?: string
+  image_generation_id?: number
+}
+
+export interface BatchImageResult {
+  task_id: string
+  total: number
+  enqueued: number
+  skipped: number
+  failed: number
+  items: BatchImageItem[]
+  image_generations: ImageGeneration[]
+}